@@ -0,0 +1,20 @@
+package auth
+
+import (
+	"context"
+
+	"github.com/williamchand/my-wallet/models"
+)
+
+type principalKey struct{}
+
+// NewContext return a copy of ctx carrying the authenticated principal p
+func NewContext(ctx context.Context, p *models.Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// FromContext return the principal authenticated for ctx, nil when the request was not authenticated
+func FromContext(ctx context.Context) *models.Principal {
+	p, _ := ctx.Value(principalKey{}).(*models.Principal)
+	return p
+}
@@ -0,0 +1,334 @@
+package auth
+
+import (
+	"crypto/rsa"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"time"
+
+	jwt "github.com/dgrijalva/jwt-go"
+	"github.com/sirupsen/logrus"
+
+	"github.com/williamchand/my-wallet/models"
+)
+
+// Verifier represent the contract to turn an Authorization header into the calling principal
+type Verifier interface {
+	Verify(authorization string) (*models.Principal, error)
+}
+
+// Issuer represent the contract to mint tokens for a wallet owner
+type Issuer interface {
+	Issue(user *models.User) (*models.Token, error)
+}
+
+// TokenService represent both sides of the token handling
+type TokenService interface {
+	Verifier
+	Issuer
+}
+
+// Key represent one signing key, identified by its kid
+type Key struct {
+	ID             string `mapstructure:"kid"`
+	Algorithm      string `mapstructure:"alg"`
+	Secret         string `mapstructure:"secret"`
+	PublicKeyFile  string `mapstructure:"public_key_file"`
+	PrivateKeyFile string `mapstructure:"private_key_file"`
+}
+
+// Config represent the auth section of config.json
+type Config struct {
+	Issuer    string `mapstructure:"issuer"`
+	Audience  string `mapstructure:"audience"`
+	ActiveKey string `mapstructure:"active_kid"`
+	Leeway    int    `mapstructure:"leeway"`
+	TokenTTL  int    `mapstructure:"token_ttl"`
+	// MaxSession is how long, in seconds, tokens can be refreshed after the first one of their session was issued
+	MaxSession int   `mapstructure:"max_session"`
+	Keys       []Key `mapstructure:"keys"`
+}
+
+// Claims represent the claims carried by the wallet tokens
+type Claims struct {
+	Name     string `json:"name,omitempty"`
+	WalletID string `json:"wallet_id,omitempty"`
+	// Scope is the space separated list of scopes granted to the token
+	Scope string `json:"scope,omitempty"`
+	// AuthTime is when the first token of the session was issued, refreshed tokens keep it
+	AuthTime int64 `json:"auth_time,omitempty"`
+	jwt.StandardClaims
+}
+
+type signingKey struct {
+	method jwt.SigningMethod
+	verify interface{}
+	sign   interface{}
+}
+
+const (
+	defaultTokenTTL   = time.Hour
+	defaultMaxSession = 24 * time.Hour
+)
+
+// JWTAuth issue and verify the signed tokens sent in the Authorization header
+type JWTAuth struct {
+	issuer     string
+	audience   string
+	activeKey  string
+	leeway     time.Duration
+	ttl        time.Duration
+	maxSession time.Duration
+	keys       map[string]*signingKey
+	now        func() time.Time
+}
+
+// NewJWTAuth will create an object that represent the TokenService interface from the given config
+func NewJWTAuth(cfg Config) (*JWTAuth, error) {
+	if len(cfg.Keys) == 0 {
+		return nil, fmt.Errorf("auth: no keys configured")
+	}
+
+	keys := make(map[string]*signingKey, len(cfg.Keys))
+	for _, k := range cfg.Keys {
+		if k.ID == "" {
+			return nil, fmt.Errorf("auth: key without kid")
+		}
+		if _, ok := keys[k.ID]; ok {
+			return nil, fmt.Errorf("auth: duplicate kid %q", k.ID)
+		}
+		sk, err := loadKey(k)
+		if err != nil {
+			return nil, err
+		}
+		keys[k.ID] = sk
+	}
+
+	activeKey := cfg.ActiveKey
+	if activeKey == "" {
+		activeKey = cfg.Keys[0].ID
+	}
+	active, ok := keys[activeKey]
+	if !ok {
+		return nil, fmt.Errorf("auth: active kid %q is not configured", activeKey)
+	}
+	if active.sign == nil {
+		return nil, fmt.Errorf("auth: active kid %q has no private key to sign with", activeKey)
+	}
+
+	ttl := time.Duration(cfg.TokenTTL) * time.Second
+	if ttl <= 0 {
+		ttl = defaultTokenTTL
+	}
+	maxSession := time.Duration(cfg.MaxSession) * time.Second
+	if maxSession <= 0 {
+		maxSession = defaultMaxSession
+	}
+
+	return &JWTAuth{
+		issuer:     cfg.Issuer,
+		audience:   cfg.Audience,
+		activeKey:  activeKey,
+		leeway:     time.Duration(cfg.Leeway) * time.Second,
+		ttl:        ttl,
+		maxSession: maxSession,
+		keys:       keys,
+		now:        time.Now,
+	}, nil
+}
+
+func loadKey(k Key) (*signingKey, error) {
+	switch k.Algorithm {
+	case jwt.SigningMethodHS256.Alg():
+		if k.Secret == "" {
+			return nil, fmt.Errorf("auth: key %q has no secret", k.ID)
+		}
+		secret := []byte(k.Secret)
+		return &signingKey{method: jwt.SigningMethodHS256, verify: secret, sign: secret}, nil
+	case jwt.SigningMethodRS256.Alg():
+		if k.PublicKeyFile == "" {
+			return nil, fmt.Errorf("auth: key %q has no public_key_file", k.ID)
+		}
+		pem, err := ioutil.ReadFile(k.PublicKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		pub, err := jwt.ParseRSAPublicKeyFromPEM(pem)
+		if err != nil {
+			return nil, fmt.Errorf("auth: key %q: %v", k.ID, err)
+		}
+		sk := &signingKey{method: jwt.SigningMethodRS256, verify: pub}
+		if k.PrivateKeyFile != "" {
+			pem, err := ioutil.ReadFile(k.PrivateKeyFile)
+			if err != nil {
+				return nil, err
+			}
+			var priv *rsa.PrivateKey
+			priv, err = jwt.ParseRSAPrivateKeyFromPEM(pem)
+			if err != nil {
+				return nil, fmt.Errorf("auth: key %q: %v", k.ID, err)
+			}
+			sk.sign = priv
+		}
+		return sk, nil
+	default:
+		return nil, fmt.Errorf("auth: key %q has unsupported alg %q", k.ID, k.Algorithm)
+	}
+}
+
+// Verify will check the token in the given Authorization header and return the principal it was issued to
+func (j *JWTAuth) Verify(authorization string) (*models.Principal, error) {
+	raw, err := bearerToken(authorization)
+	if err != nil {
+		return nil, err
+	}
+
+	claims := &Claims{}
+	parser := &jwt.Parser{SkipClaimsValidation: true}
+	_, err = parser.ParseWithClaims(raw, claims, j.keyFunc)
+	if err != nil {
+		logrus.Debug("auth: ", err)
+		return nil, models.ErrUnauthorized
+	}
+
+	if err := j.validate(claims); err != nil {
+		logrus.Debug("auth: ", err)
+		return nil, models.ErrUnauthorized
+	}
+
+	name := claims.Name
+	if name == "" {
+		name = claims.Subject
+	}
+	// tokens minted before the wallet_id claim existed used the customer id as wallet id
+	walletID := claims.WalletID
+	if walletID == "" {
+		walletID = claims.Subject
+	}
+
+	// tokens minted before the scope claim existed were all issued to wallet owners
+	scopes := strings.Fields(claims.Scope)
+	if len(scopes) == 0 {
+		scopes = models.OwnerScopes
+	}
+
+	// tokens minted before the auth_time claim existed started their session when they were issued
+	authTime := claims.AuthTime
+	if authTime == 0 {
+		authTime = claims.IssuedAt
+	}
+	principal := &models.Principal{
+		CustomerID: claims.Subject,
+		Name:       name,
+		WalletID:   walletID,
+		Scopes:     scopes,
+	}
+	if authTime != 0 {
+		principal.AuthTime = time.Unix(authTime, 0)
+	}
+
+	return principal, nil
+}
+
+// Issue will sign a new token for the given user with the active key. A user with an AuthTime gets a token of the
+// same session, which never outlives max_session: once it is over Issue fails with ErrUnauthorized.
+func (j *JWTAuth) Issue(user *models.User) (*models.Token, error) {
+	now := j.now()
+	authTime := user.AuthTime
+	if authTime.IsZero() {
+		authTime = now
+	}
+	expiresAt := now.Add(j.ttl)
+	if end := authTime.Add(j.maxSession); end.Before(expiresAt) {
+		expiresAt = end
+	}
+	if !expiresAt.After(now) {
+		return nil, models.ErrUnauthorized
+	}
+	claims := &Claims{
+		Name:     user.Name,
+		WalletID: user.WalletID,
+		Scope:    strings.Join(user.Scopes, " "),
+		AuthTime: authTime.Unix(),
+		StandardClaims: jwt.StandardClaims{
+			Subject:   user.ID,
+			Issuer:    j.issuer,
+			Audience:  j.audience,
+			IssuedAt:  now.Unix(),
+			NotBefore: now.Unix(),
+			ExpiresAt: expiresAt.Unix(),
+		},
+	}
+
+	key := j.keys[j.activeKey]
+	token := jwt.NewWithClaims(key.method, claims)
+	token.Header["kid"] = j.activeKey
+	signed, err := token.SignedString(key.sign)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.Token{
+		Token:     signed,
+		Type:      "Bearer",
+		ExpiresAt: time.Unix(expiresAt.Unix(), 0),
+	}, nil
+}
+
+func (j *JWTAuth) keyFunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	if kid == "" {
+		kid = j.activeKey
+	}
+	key, ok := j.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown kid %q", kid)
+	}
+	// the alg header must match the key, otherwise an RS256 public key could be used as an HS256 secret
+	if token.Method.Alg() != key.method.Alg() {
+		return nil, fmt.Errorf("unexpected alg %q for kid %q", token.Method.Alg(), kid)
+	}
+	return key.verify, nil
+}
+
+func (j *JWTAuth) validate(c *Claims) error {
+	now := j.now().Unix()
+	leeway := int64(j.leeway / time.Second)
+
+	if c.ExpiresAt == 0 {
+		return fmt.Errorf("token has no exp")
+	}
+	if now > c.ExpiresAt+leeway {
+		return fmt.Errorf("token is expired")
+	}
+	if c.NotBefore != 0 && now < c.NotBefore-leeway {
+		return fmt.Errorf("token is not valid yet")
+	}
+	if c.IssuedAt != 0 && now < c.IssuedAt-leeway {
+		return fmt.Errorf("token is issued in the future")
+	}
+	if j.audience != "" && !c.VerifyAudience(j.audience, true) {
+		return fmt.Errorf("token audience %q is not accepted", c.Audience)
+	}
+	if j.issuer != "" && !c.VerifyIssuer(j.issuer, true) {
+		return fmt.Errorf("token issuer %q is not accepted", c.Issuer)
+	}
+	if c.Subject == "" {
+		return fmt.Errorf("token has no subject")
+	}
+	return nil
+}
+
+// bearerToken extract the token from "Bearer <token>", the legacy "Token <token>" scheme is accepted as well
+func bearerToken(authorization string) (string, error) {
+	fields := strings.Fields(authorization)
+	if len(fields) != 2 {
+		return "", models.ErrUnauthorized
+	}
+	scheme := strings.ToLower(fields[0])
+	if scheme != "bearer" && scheme != "token" {
+		return "", models.ErrUnauthorized
+	}
+	return fields[1], nil
+}
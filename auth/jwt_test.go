@@ -0,0 +1,204 @@
+package auth_test
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	jwt "github.com/dgrijalva/jwt-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/williamchand/my-wallet/auth"
+	"github.com/williamchand/my-wallet/models"
+)
+
+func hsConfig() auth.Config {
+	return auth.Config{
+		Issuer:    "my-wallet",
+		Audience:  "my-wallet-api",
+		ActiveKey: "new",
+		Keys: []auth.Key{
+			{ID: "new", Algorithm: "HS256", Secret: "new-secret"},
+			{ID: "old", Algorithm: "HS256", Secret: "old-secret"},
+		},
+	}
+}
+
+func validClaims() *auth.Claims {
+	now := time.Now()
+	return &auth.Claims{
+		Name: "William",
+		StandardClaims: jwt.StandardClaims{
+			Subject:   "customer-1",
+			Issuer:    "my-wallet",
+			Audience:  "my-wallet-api",
+			IssuedAt:  now.Unix(),
+			NotBefore: now.Unix(),
+			ExpiresAt: now.Add(time.Hour).Unix(),
+		},
+	}
+}
+
+func sign(t *testing.T, method jwt.SigningMethod, kid string, key interface{}, claims *auth.Claims) string {
+	token := jwt.NewWithClaims(method, claims)
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+	s, err := token.SignedString(key)
+	require.NoError(t, err)
+	return s
+}
+
+func TestVerifyHS256(t *testing.T) {
+	v, err := auth.NewJWTAuth(hsConfig())
+	require.NoError(t, err)
+
+	token := sign(t, jwt.SigningMethodHS256, "new", []byte("new-secret"), validClaims())
+	user, err := v.Verify("Bearer " + token)
+	require.NoError(t, err)
+	assert.Equal(t, "customer-1", user.CustomerID)
+	assert.Equal(t, "William", user.Name)
+	assert.Equal(t, "customer-1", user.WalletID)
+
+	// legacy scheme used by existing clients
+	_, err = v.Verify("Token " + token)
+	assert.NoError(t, err)
+}
+
+func TestVerifyKeyRotation(t *testing.T) {
+	v, err := auth.NewJWTAuth(hsConfig())
+	require.NoError(t, err)
+
+	token := sign(t, jwt.SigningMethodHS256, "old", []byte("old-secret"), validClaims())
+	_, err = v.Verify("Bearer " + token)
+	assert.NoError(t, err)
+
+	token = sign(t, jwt.SigningMethodHS256, "retired", []byte("old-secret"), validClaims())
+	_, err = v.Verify("Bearer " + token)
+	assert.Equal(t, models.ErrUnauthorized, err)
+}
+
+func TestVerifyRejected(t *testing.T) {
+	v, err := auth.NewJWTAuth(hsConfig())
+	require.NoError(t, err)
+
+	expired := validClaims()
+	expired.ExpiresAt = time.Now().Add(-time.Minute).Unix()
+
+	notBefore := validClaims()
+	notBefore.NotBefore = time.Now().Add(time.Hour).Unix()
+
+	audience := validClaims()
+	audience.Audience = "someone-else"
+
+	noSubject := validClaims()
+	noSubject.Subject = ""
+
+	tests := map[string]string{
+		"empty":         "",
+		"no scheme":     sign(t, jwt.SigningMethodHS256, "new", []byte("new-secret"), validClaims()),
+		"bad signature": "Bearer " + sign(t, jwt.SigningMethodHS256, "new", []byte("wrong"), validClaims()),
+		"expired":       "Bearer " + sign(t, jwt.SigningMethodHS256, "new", []byte("new-secret"), expired),
+		"not before":    "Bearer " + sign(t, jwt.SigningMethodHS256, "new", []byte("new-secret"), notBefore),
+		"audience":      "Bearer " + sign(t, jwt.SigningMethodHS256, "new", []byte("new-secret"), audience),
+		"no subject":    "Bearer " + sign(t, jwt.SigningMethodHS256, "new", []byte("new-secret"), noSubject),
+		"garbage":       "Bearer not.a.token",
+	}
+	for name, header := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := v.Verify(header)
+			assert.Equal(t, models.ErrUnauthorized, err)
+		})
+	}
+}
+
+func TestVerifyRS256(t *testing.T) {
+	priv, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	der, err := x509.MarshalPKIXPublicKey(&priv.PublicKey)
+	require.NoError(t, err)
+
+	f, err := ioutil.TempFile("", "wallet-rs256-*.pem")
+	require.NoError(t, err)
+	defer os.Remove(f.Name())
+	require.NoError(t, pem.Encode(f, &pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+	require.NoError(t, f.Close())
+
+	cfg := hsConfig()
+	cfg.Keys = append(cfg.Keys, auth.Key{ID: "rsa", Algorithm: "RS256", PublicKeyFile: f.Name()})
+	v, err := auth.NewJWTAuth(cfg)
+	require.NoError(t, err)
+
+	token := sign(t, jwt.SigningMethodRS256, "rsa", priv, validClaims())
+	user, err := v.Verify("Bearer " + token)
+	require.NoError(t, err)
+	assert.Equal(t, "customer-1", user.CustomerID)
+
+	// an HS256 token must not be accepted for an RS256 kid
+	token = sign(t, jwt.SigningMethodHS256, "rsa", []byte("new-secret"), validClaims())
+	_, err = v.Verify("Bearer " + token)
+	assert.Equal(t, models.ErrUnauthorized, err)
+}
+
+func TestIssue(t *testing.T) {
+	v, err := auth.NewJWTAuth(hsConfig())
+	require.NoError(t, err)
+
+	token, err := v.Issue(&models.User{ID: "customer-1", WalletID: "wallet-1"})
+	require.NoError(t, err)
+	assert.Equal(t, "Bearer", token.Type)
+	assert.True(t, token.ExpiresAt.After(time.Now()))
+
+	user, err := v.Verify(token.Type + " " + token.Token)
+	require.NoError(t, err)
+	assert.Equal(t, "customer-1", user.CustomerID)
+	assert.Equal(t, "wallet-1", user.WalletID)
+	assert.Equal(t, models.OwnerScopes, user.Scopes, "tokens without a scope claim belong to owners")
+
+	token, err = v.Issue(&models.User{ID: "support-1", Scopes: []string{models.ScopeAdminRead}})
+	require.NoError(t, err)
+	user, err = v.Verify(token.Type + " " + token.Token)
+	require.NoError(t, err)
+	assert.True(t, user.HasScope(models.ScopeAdminRead))
+	assert.False(t, user.HasScope(models.ScopeWalletWrite))
+}
+
+func TestIssueWithinSession(t *testing.T) {
+	cfg := hsConfig()
+	cfg.MaxSession = 86400
+	v, err := auth.NewJWTAuth(cfg)
+	require.NoError(t, err)
+
+	token, err := v.Issue(&models.User{ID: "customer-1", Scopes: models.OwnerScopes})
+	require.NoError(t, err)
+	user, err := v.Verify(token.Type + " " + token.Token)
+	require.NoError(t, err)
+	assert.WithinDuration(t, time.Now(), user.AuthTime, 2*time.Second, "a new session starts when its first token is issued")
+
+	authTime := time.Now().Add(-23*time.Hour - 30*time.Minute).Truncate(time.Second)
+	token, err = v.Issue(&models.User{ID: "customer-1", Scopes: models.OwnerScopes, AuthTime: authTime})
+	require.NoError(t, err)
+	assert.Equal(t, authTime.Add(24*time.Hour).Unix(), token.ExpiresAt.Unix(), "refreshed tokens do not outlive the session")
+	user, err = v.Verify(token.Type + " " + token.Token)
+	require.NoError(t, err)
+	assert.Equal(t, authTime.Unix(), user.AuthTime.Unix(), "refreshed tokens keep the start of the session")
+
+	_, err = v.Issue(&models.User{ID: "customer-1", Scopes: models.OwnerScopes, AuthTime: time.Now().Add(-25 * time.Hour)})
+	assert.Equal(t, models.ErrUnauthorized, err, "a session past max_session cannot be refreshed")
+}
+
+func TestVerifyAuthTimeOfOlderTokens(t *testing.T) {
+	v, err := auth.NewJWTAuth(hsConfig())
+	require.NoError(t, err)
+
+	claims := validClaims()
+	user, err := v.Verify("Bearer " + sign(t, jwt.SigningMethodHS256, "new", []byte("new-secret"), claims))
+	require.NoError(t, err)
+	assert.Equal(t, claims.IssuedAt, user.AuthTime.Unix(), "tokens without auth_time started their session when issued")
+}
@@ -0,0 +1,266 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/google/uuid"
+	validator "gopkg.in/go-playground/validator.v9"
+
+	"github.com/williamchand/my-wallet/models"
+	"github.com/williamchand/my-wallet/wallet"
+)
+
+const (
+	formatTable = "table"
+	formatJSON  = "json"
+	formatCSV   = "csv"
+
+	// exportPage is the page size export walks the history with
+	exportPage = 100
+)
+
+const usage = `commands:
+  show <wallet_id>
+  transactions [-type t] [-status s] [-limit n] [-cursor c] <wallet_id>
+  enable -reason r [-actor name] <wallet_id>
+  disable -reason r [-actor name] <wallet_id>
+  credit -amount n -reason r [-reference id] [-actor name] <wallet_id>
+  debit -amount n -reason r [-reference id] [-actor name] <wallet_id>
+  export [-format csv|json] <wallet_id>`
+
+// cli run the walletctl commands and print their result to out. The reads go to repo, the changes go through
+// the admin usecases of admin so they are recorded in the audit log under the operator.
+type cli struct {
+	repo   wallet.Repository
+	admin  wallet.Usecase
+	out    io.Writer
+	format string
+}
+
+func (c *cli) run(ctx context.Context, args []string) error {
+	if c.format != formatTable && c.format != formatJSON {
+		return fmt.Errorf("unknown output format %q", c.format)
+	}
+	if len(args) == 0 {
+		return errors.New(usage)
+	}
+
+	switch args[0] {
+	case "show":
+		return c.show(ctx, args[1:])
+	case "transactions":
+		return c.transactions(ctx, args[1:])
+	case "enable", "disable":
+		return c.setStatus(ctx, args[0], args[1:])
+	case "credit":
+		return c.adjust(ctx, models.TransactionTypeCredit, args[1:])
+	case "debit":
+		return c.adjust(ctx, models.TransactionTypeDebit, args[1:])
+	case "export":
+		return c.export(ctx, args[1:])
+	default:
+		return fmt.Errorf("unknown command %q\n%s", args[0], usage)
+	}
+}
+
+func (c *cli) show(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("show", flag.ContinueOnError)
+	id, err := parse(fs, args)
+	if err != nil {
+		return err
+	}
+
+	w, err := c.repo.LookupWallet(ctx, id)
+	if err != nil {
+		return err
+	}
+	return c.printWallet(w)
+}
+
+func (c *cli) transactions(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("transactions", flag.ContinueOnError)
+	filter := &models.TransactionFilter{}
+	fs.StringVar(&filter.Type, "type", "", "only transactions of this type")
+	fs.StringVar(&filter.Status, "status", "", "only transactions in this status")
+	fs.IntVar(&filter.Limit, "limit", 20, "page size")
+	fs.StringVar(&filter.Cursor, "cursor", "", "next_cursor of the previous page")
+	id, err := parse(fs, args)
+	if err != nil {
+		return err
+	}
+	if filter.Limit <= 0 {
+		return models.ErrBadParamInput
+	}
+
+	list, err := c.repo.FetchTransactions(ctx, filter, id)
+	if err != nil {
+		return err
+	}
+	if c.format == formatJSON {
+		return c.printJSON(list)
+	}
+	err = c.printTransactions(list.Transactions)
+	if err != nil {
+		return err
+	}
+	if list.NextCursor != "" {
+		_, err = fmt.Fprintf(c.out, "\nnext cursor: %s\n", list.NextCursor)
+	}
+	return err
+}
+
+func (c *cli) setStatus(ctx context.Context, command string, args []string) error {
+	fs := flag.NewFlagSet(command, flag.ContinueOnError)
+	reason := fs.String("reason", "", "why the status is changed, kept in the audit log")
+	actor := fs.String("actor", os.Getenv("USER"), "operator recorded in the audit log")
+	id, err := parse(fs, args)
+	if err != nil {
+		return err
+	}
+
+	var w *models.Wallet
+	if command == "enable" {
+		req := &models.ReqForceEnable{Reason: *reason}
+		if err = validator.New().Struct(req); err != nil {
+			return err
+		}
+		w, err = c.admin.ForceEnableWallet(ctx, req, id, operator(*actor))
+	} else {
+		req := &models.ReqForceDisable{Reason: *reason}
+		if err = validator.New().Struct(req); err != nil {
+			return err
+		}
+		w, err = c.admin.ForceDisableWallet(ctx, req, id, operator(*actor))
+	}
+	if err != nil {
+		return err
+	}
+	return c.printWallet(w)
+}
+
+func (c *cli) adjust(ctx context.Context, txType string, args []string) error {
+	fs := flag.NewFlagSet(txType, flag.ContinueOnError)
+	req := &models.ReqAdjustment{}
+	fs.Int64Var(&req.Amount, "amount", 0, "amount in minor units of the wallet currency")
+	fs.StringVar(&req.Reason, "reason", "", "why the wallet is adjusted, kept on the transaction")
+	fs.StringVar(&req.ReferenceID, "reference", uuid.New().String(), "reference_id, reuse one to retry safely")
+	fs.StringVar(&req.Actor, "actor", os.Getenv("USER"), "operator recorded as the creator of the transaction and in the audit log")
+	id, err := parse(fs, args)
+	if err != nil {
+		return err
+	}
+	err = validator.New().Struct(req)
+	if err != nil {
+		return err
+	}
+
+	res, err := c.admin.AdjustWallet(ctx, req, txType, id, operator(req.Actor))
+	if err != nil {
+		return err
+	}
+	if c.format == formatJSON {
+		return c.printJSON(res)
+	}
+	return c.printTransactions([]*models.Transaction{{
+		ReferenceID: res.ReferenceID,
+		ID:          res.ID,
+		Type:        res.Type,
+		Amount:      res.Amount,
+		Currency:    res.Currency,
+		Status:      res.Status,
+		Reason:      res.Reason,
+		CreatedBy:   res.AdjustedBy,
+		CreatedAt:   res.AdjustedAt,
+	}})
+}
+
+func (c *cli) export(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	format := fs.String("format", formatCSV, "csv or json")
+	id, err := parse(fs, args)
+	if err != nil {
+		return err
+	}
+	if *format != formatCSV && *format != formatJSON {
+		return fmt.Errorf("unknown export format %q", *format)
+	}
+
+	var all []*models.Transaction
+	filter := &models.TransactionFilter{Limit: exportPage}
+	for {
+		list, err := c.repo.FetchTransactions(ctx, filter, id)
+		if err != nil {
+			return err
+		}
+		all = append(all, list.Transactions...)
+		if list.NextCursor == "" {
+			break
+		}
+		filter.Cursor = list.NextCursor
+	}
+
+	if *format == formatJSON {
+		return c.printJSON(all)
+	}
+	w := csv.NewWriter(c.out)
+	_ = w.Write([]string{"reference_id", "wallet_id", "type", "amount", "currency", "status", "created_by", "created_at", "quote_id", "reason"})
+	for _, t := range all {
+		_ = w.Write([]string{t.ReferenceID, t.ID, t.Type, strconv.FormatInt(t.Amount, 10), t.Currency, t.Status,
+			t.CreatedBy, t.CreatedAt.Format(time.RFC3339), t.QuoteID, t.Reason})
+	}
+	w.Flush()
+	return w.Error()
+}
+
+// operator return the principal of the operator running walletctl
+func operator(actor string) *models.Principal {
+	return &models.Principal{CustomerID: actor, Scopes: []string{models.ScopeAdminRead, models.ScopeAdminAdjust}}
+}
+
+// parse the flags of a command followed by the wallet id it works on
+func parse(fs *flag.FlagSet, args []string) (string, error) {
+	err := fs.Parse(args)
+	if err != nil {
+		return "", err
+	}
+	if fs.NArg() != 1 {
+		return "", fmt.Errorf("%s takes exactly one wallet_id\n%s", fs.Name(), usage)
+	}
+	return fs.Arg(0), nil
+}
+
+func (c *cli) printWallet(w *models.Wallet) error {
+	if c.format == formatJSON {
+		return c.printJSON(w)
+	}
+	tw := tabwriter.NewWriter(c.out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "WALLET_ID\tOWNED_BY\tSTATUS\tBALANCE\tCURRENCY\tUPDATED_AT")
+	fmt.Fprintf(tw, "%s\t%s\t%s\t%d\t%s\t%s\n", w.ID, w.OwnedBy, w.Status, w.Balance, w.Currency, w.UpdatedAt.Format(time.RFC3339))
+	return tw.Flush()
+}
+
+func (c *cli) printTransactions(list []*models.Transaction) error {
+	tw := tabwriter.NewWriter(c.out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "REFERENCE_ID\tTYPE\tAMOUNT\tCURRENCY\tSTATUS\tCREATED_BY\tCREATED_AT\tREASON")
+	for _, t := range list {
+		fmt.Fprintf(tw, "%s\t%s\t%d\t%s\t%s\t%s\t%s\t%s\n", t.ReferenceID, t.Type, t.Amount, t.Currency, t.Status,
+			t.CreatedBy, t.CreatedAt.Format(time.RFC3339), t.Reason)
+	}
+	return tw.Flush()
+}
+
+func (c *cli) printJSON(v interface{}) error {
+	enc := json.NewEncoder(c.out)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
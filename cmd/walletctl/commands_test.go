@@ -0,0 +1,96 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/williamchand/my-wallet/models"
+	"github.com/williamchand/my-wallet/wallet/repository"
+	"github.com/williamchand/my-wallet/wallet/usecase"
+)
+
+func newCLI(t *testing.T, format string) (*cli, *bytes.Buffer, string) {
+	r := repository.NewMemoryWalletRepository()
+	w, err := r.InitWallet(context.Background(), "customer-1", models.DefaultCurrency)
+	require.NoError(t, err)
+	for i := 0; i < 3; i++ {
+		_, err = r.AddWallet(context.Background(), &models.ReqTransaction{ReferenceID: uuid.New().String(), Amount: 100}, nil, w.ID)
+		require.NoError(t, err)
+	}
+	out := new(bytes.Buffer)
+	return &cli{repo: r, admin: usecase.NewWalletUsecase(r, nil, nil, nil, nil, time.Second), out: out, format: format}, out, w.ID
+}
+
+func TestAdjust(t *testing.T) {
+	c, out, id := newCLI(t, formatJSON)
+	ctx := context.Background()
+
+	err := c.run(ctx, []string{"credit", "-amount", "50", "-actor", "ops-1", id})
+	assert.Error(t, err, "a reason is mandatory")
+	err = c.run(ctx, []string{"debit", "-amount", "50", "-reason", "duplicate deposit", "-actor", "ops-1", id})
+	require.NoError(t, err)
+	var adjustment models.Adjustment
+	require.NoError(t, json.Unmarshal(out.Bytes(), &adjustment))
+	assert.Equal(t, models.TransactionTypeDebit, adjustment.Type)
+	assert.Equal(t, "ops-1", adjustment.AdjustedBy)
+
+	out.Reset()
+	assert.Error(t, c.run(ctx, []string{"disable", "-actor", "ops-1", id}), "a reason is mandatory")
+	require.NoError(t, c.run(ctx, []string{"disable", "-reason", "fraud", "-actor", "ops-1", id}))
+	var w models.Wallet
+	require.NoError(t, json.Unmarshal(out.Bytes(), &w))
+	assert.Equal(t, "disabled", w.Status)
+	assert.Equal(t, int64(250), w.Balance)
+
+	out.Reset()
+	require.NoError(t, c.run(ctx, []string{"enable", "-reason", "customer verified", "-actor", "ops-2", id}))
+	require.NoError(t, json.Unmarshal(out.Bytes(), &w))
+	assert.Equal(t, "enabled", w.Status)
+
+	entries, err := c.repo.FetchAuditEntries(ctx, id, 10)
+	require.NoError(t, err)
+	require.Len(t, entries, 3, "every change is audited")
+	assert.Equal(t, models.AuditForceEnableWallet, entries[0].Action)
+	assert.Equal(t, "ops-2", entries[0].Actor)
+	assert.Equal(t, models.AuditForceDisableWallet, entries[1].Action)
+	assert.Equal(t, "fraud", entries[1].Detail)
+	assert.Equal(t, models.AuditAdjustWallet, entries[2].Action)
+	assert.Equal(t, "ops-1", entries[2].Actor)
+}
+
+func TestShowAndTransactions(t *testing.T) {
+	c, out, id := newCLI(t, formatTable)
+	ctx := context.Background()
+
+	require.NoError(t, c.run(ctx, []string{"show", id}))
+	assert.Contains(t, out.String(), "WALLET_ID")
+	assert.Contains(t, out.String(), id)
+
+	out.Reset()
+	require.NoError(t, c.run(ctx, []string{"transactions", "-limit", "2", id}))
+	assert.Equal(t, 2, bytes.Count(out.Bytes(), []byte(models.TransactionTypeDeposit)), "one page of two rows")
+	assert.Contains(t, out.String(), "next cursor:")
+
+	assert.Error(t, c.run(ctx, []string{"show"}))
+	assert.Error(t, c.run(ctx, []string{"show", uuid.New().String()}))
+	assert.Error(t, c.run(ctx, []string{"drop", id}))
+}
+
+func TestExport(t *testing.T) {
+	c, out, id := newCLI(t, formatTable)
+
+	require.NoError(t, c.run(context.Background(), []string{"export", id}))
+	records, err := csv.NewReader(out).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, records, 4)
+	assert.Equal(t, "reference_id", records[0][0])
+	assert.Equal(t, models.TransactionTypeDeposit, records[1][2])
+}
@@ -0,0 +1,66 @@
+// walletctl is the operator tool to inspect and fix wallets without going through the API.
+//
+//	walletctl [-config config.json] [-o table|json] <command> [flags] <wallet_id>
+//
+// Commands:
+//
+//	show          print the wallet, whatever its status
+//	transactions  list the transactions of the wallet, newest first
+//	enable        enable the wallet, -reason is mandatory
+//	disable       disable the wallet, -reason is mandatory
+//	credit        add funds to the wallet, -amount and -reason are mandatory
+//	debit         take funds from the wallet, -amount and -reason are mandatory
+//	export        write every transaction of the wallet as CSV, or JSON with -format json
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/spf13/viper"
+
+	"github.com/williamchand/my-wallet/config"
+	_walletRepo "github.com/williamchand/my-wallet/wallet/repository"
+	_walletUcase "github.com/williamchand/my-wallet/wallet/usecase"
+)
+
+func main() {
+	configPath := flag.String("config", "config.json", "path of the configuration file of the API server")
+	format := flag.String("o", formatTable, "output format, table or json")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: walletctl [-config config.json] [-o table|json] <command> [flags] <wallet_id>\n\n%s\n", usage)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	err := config.Load(*configPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if viper.GetString(`database.driver`) == "memory" {
+		log.Fatal("walletctl: the memory driver keeps nothing to inspect, point database to MySQL")
+	}
+	dbConn, err := config.OpenDatabase()
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer dbConn.Close()
+
+	repo := _walletRepo.NewMysqlWalletRepository(dbConn)
+	// the admin usecases audit the changes, they need no issuer, quoter, limits nor fees
+	timeoutContext := time.Duration(viper.GetInt("context.timeout")) * time.Second
+	c := &cli{
+		repo:   repo,
+		admin:  _walletUcase.NewWalletUsecase(repo, nil, nil, nil, nil, timeoutContext),
+		out:    os.Stdout,
+		format: *format,
+	}
+	if err = c.run(context.Background(), flag.Args()); err != nil {
+		fmt.Fprintln(os.Stderr, "walletctl:", err)
+		os.Exit(1)
+	}
+}
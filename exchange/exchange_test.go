@@ -0,0 +1,148 @@
+package exchange_test
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/williamchand/my-wallet/exchange"
+	"github.com/williamchand/my-wallet/models"
+)
+
+func TestStaticProvider(t *testing.T) {
+	// keys come lower-cased out of viper
+	p, err := exchange.NewStaticProvider(map[string]string{"usd/idr": "15500", "USD/SGD": "1.35", "SGD/USD": "0.74"})
+	require.NoError(t, err)
+
+	rate, err := p.Rate(context.TODO(), "USD", "IDR")
+	require.NoError(t, err)
+	assert.Equal(t, "15500", rate.RatString())
+	rate, err = p.Rate(context.TODO(), "IDR", "USD")
+	require.NoError(t, err)
+	assert.Equal(t, "1/15500", rate.RatString())
+	// a listed pair wins over the inverse of the other way
+	rate, err = p.Rate(context.TODO(), "SGD", "USD")
+	require.NoError(t, err)
+	assert.Equal(t, "37/50", rate.RatString())
+
+	_, err = p.Rate(context.TODO(), "SGD", "IDR")
+	assert.Equal(t, models.ErrRateUnavailable, err)
+
+	for _, rates := range []map[string]string{
+		{"USD/USD": "1"},
+		{"USD/XXX": "1"},
+		{"USD-IDR": "15500"},
+		{"USD/IDR": "-1"},
+		{"USD/IDR": "abc"},
+	} {
+		_, err = exchange.NewStaticProvider(rates)
+		assert.Error(t, err, rates)
+	}
+}
+
+func TestFileProvider(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rates")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "rates.json")
+	require.NoError(t, ioutil.WriteFile(path, []byte(`{"USD/IDR": "15500"}`), 0600))
+
+	p, err := exchange.NewRateProvider(exchange.Config{Provider: "file", File: path})
+	require.NoError(t, err)
+	rate, err := p.Rate(context.TODO(), "USD", "IDR")
+	require.NoError(t, err)
+	assert.Equal(t, "15500", rate.RatString())
+
+	// edits are picked up without a restart
+	require.NoError(t, ioutil.WriteFile(path, []byte(`{"USD/IDR": "15600"}`), 0600))
+	rate, err = p.Rate(context.TODO(), "USD", "IDR")
+	require.NoError(t, err)
+	assert.Equal(t, "15600", rate.RatString())
+
+	_, err = exchange.NewRateProvider(exchange.Config{Provider: "file"})
+	assert.Error(t, err)
+	_, err = exchange.NewRateProvider(exchange.Config{Provider: "carrier-pigeon"})
+	assert.Error(t, err)
+}
+
+func TestQuote(t *testing.T) {
+	p, err := exchange.NewStaticProvider(map[string]string{"USD/IDR": "15500.125"})
+	require.NoError(t, err)
+
+	tests := map[string]struct {
+		cfg       exchange.Config
+		amount    models.Money
+		to        string
+		rate      string
+		converted int64
+		fee       int64
+	}{
+		"no spread": {
+			cfg:    exchange.Config{},
+			amount: models.Money{Amount: 1000, Currency: "USD"}, to: "IDR",
+			rate: "15500.125", converted: 15500125, fee: 0,
+		},
+		"spread rounded down": {
+			cfg:    exchange.Config{SpreadBps: 50},
+			amount: models.Money{Amount: 1, Currency: "USD"}, to: "IDR",
+			// 15500.125 less 0.5% is 15422.624375
+			rate: "15500.125", converted: 15422, fee: 78,
+		},
+		"spread rounded half up": {
+			cfg:    exchange.Config{SpreadBps: 50, Rounding: exchange.RoundHalfUp},
+			amount: models.Money{Amount: 1, Currency: "USD"}, to: "IDR",
+			rate: "15500.125", converted: 15423, fee: 77,
+		},
+		"half even": {
+			cfg:    exchange.Config{Rounding: exchange.RoundHalfEven},
+			amount: models.Money{Amount: 2, Currency: "USD"}, to: "IDR",
+			// 31000.25 rounds to the even 31000
+			rate: "15500.125", converted: 31000, fee: 0,
+		},
+		"inverse rate": {
+			cfg:    exchange.Config{},
+			amount: models.Money{Amount: 1550012500, Currency: "IDR"}, to: "USD",
+			// 1/15500.125 quoted with 10 decimals
+			rate: "0.0000645156", converted: 99999, fee: 0,
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			q, err := exchange.NewQuoter(p, tc.cfg)
+			require.NoError(t, err)
+			quote, err := q.Quote(context.TODO(), tc.amount, tc.to)
+			require.NoError(t, err)
+			assert.Equal(t, tc.rate, quote.Rate)
+			assert.Equal(t, tc.converted, quote.ConvertedAmount)
+			assert.Equal(t, tc.fee, quote.Fee)
+			assert.Equal(t, tc.cfg.SpreadBps, quote.SpreadBps)
+			assert.WithinDuration(t, time.Now().Add(30*time.Second), quote.ExpiresAt, time.Second)
+		})
+	}
+}
+
+func TestQuoteRejects(t *testing.T) {
+	p, err := exchange.NewStaticProvider(map[string]string{"USD/IDR": "15500"})
+	require.NoError(t, err)
+	q, err := exchange.NewQuoter(p, exchange.Config{SpreadBps: 50})
+	require.NoError(t, err)
+
+	_, err = q.Quote(context.TODO(), models.Money{Amount: 100, Currency: "USD"}, "USD")
+	assert.Equal(t, models.ErrBadParamInput, err)
+	_, err = q.Quote(context.TODO(), models.Money{Amount: 100, Currency: "USD"}, "SGD")
+	assert.Equal(t, models.ErrRateUnavailable, err)
+	// 1 IDR minor unit buys less than a cent
+	_, err = q.Quote(context.TODO(), models.Money{Amount: 1, Currency: "IDR"}, "USD")
+	assert.Equal(t, models.ErrBadParamInput, err)
+
+	_, err = exchange.NewQuoter(p, exchange.Config{SpreadBps: 10000})
+	assert.Error(t, err)
+	_, err = exchange.NewQuoter(p, exchange.Config{Rounding: "up"})
+	assert.Error(t, err)
+}
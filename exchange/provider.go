@@ -0,0 +1,119 @@
+package exchange
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"strings"
+
+	"github.com/williamchand/my-wallet/models"
+)
+
+// FXRateProvider represent the source of exchange rates
+type FXRateProvider interface {
+	// Rate return how many major units of to one major unit of from buys
+	Rate(ctx context.Context, from string, to string) (*big.Rat, error)
+}
+
+// Config represent the fx section of config.json
+type Config struct {
+	Provider  string            `mapstructure:"provider"`
+	File      string            `mapstructure:"file"`
+	Rates     map[string]string `mapstructure:"rates"`
+	SpreadBps int64             `mapstructure:"spread_bps"`
+	Rounding  string            `mapstructure:"rounding"`
+	QuoteTTL  int               `mapstructure:"quote_ttl"`
+}
+
+// NewRateProvider will create the FXRateProvider selected by cfg.Provider, "static" when empty
+func NewRateProvider(cfg Config) (FXRateProvider, error) {
+	switch cfg.Provider {
+	case "", "static":
+		return NewStaticProvider(cfg.Rates)
+	case "file":
+		if cfg.File == "" {
+			return nil, fmt.Errorf("fx: the file provider needs fx.file")
+		}
+		return NewFileProvider(cfg.File), nil
+	default:
+		return nil, fmt.Errorf("fx: unknown provider %q", cfg.Provider)
+	}
+}
+
+type staticProvider struct {
+	rates map[string]*big.Rat
+}
+
+// NewStaticProvider will create an FXRateProvider over a fixed table of decimal rates keyed by "FROM/TO",
+// e.g. {"USD/IDR": "15500"}. The inverse of a pair is derived unless the table lists it too.
+func NewStaticProvider(rates map[string]string) (FXRateProvider, error) {
+	table, err := parseRates(rates)
+	if err != nil {
+		return nil, err
+	}
+	return &staticProvider{table}, nil
+}
+
+func (s *staticProvider) Rate(ctx context.Context, from string, to string) (*big.Rat, error) {
+	return lookup(s.rates, from, to)
+}
+
+type fileProvider struct {
+	path string
+}
+
+// NewFileProvider will create an FXRateProvider reading a JSON table shaped like the static one from path.
+// The file is read on every quote so it can be edited while the service runs, it is meant for local use.
+func NewFileProvider(path string) FXRateProvider {
+	return &fileProvider{path}
+}
+
+func (f *fileProvider) Rate(ctx context.Context, from string, to string) (*big.Rat, error) {
+	b, err := ioutil.ReadFile(f.path)
+	if err != nil {
+		return nil, err
+	}
+	var rates map[string]string
+	if err = json.Unmarshal(b, &rates); err != nil {
+		return nil, fmt.Errorf("fx: %s: %v", f.path, err)
+	}
+	table, err := parseRates(rates)
+	if err != nil {
+		return nil, err
+	}
+	return lookup(table, from, to)
+}
+
+func parseRates(rates map[string]string) (map[string]*big.Rat, error) {
+	table := make(map[string]*big.Rat, len(rates)*2)
+	inverse := make(map[string]*big.Rat, len(rates))
+	for pair, value := range rates {
+		// viper lower-cases map keys
+		codes := strings.Split(strings.ToUpper(pair), "/")
+		if len(codes) != 2 || !models.ValidCurrency(codes[0]) || !models.ValidCurrency(codes[1]) || codes[0] == codes[1] {
+			return nil, fmt.Errorf("fx: invalid currency pair %q", pair)
+		}
+		rate, ok := new(big.Rat).SetString(value)
+		if !ok || rate.Sign() <= 0 {
+			return nil, fmt.Errorf("fx: invalid rate %q for %s", value, pair)
+		}
+		table[codes[0]+"/"+codes[1]] = rate
+		inverse[codes[1]+"/"+codes[0]] = new(big.Rat).Inv(rate)
+	}
+	for pair, rate := range inverse {
+		if _, ok := table[pair]; !ok {
+			table[pair] = rate
+		}
+	}
+	return table, nil
+}
+
+func lookup(table map[string]*big.Rat, from string, to string) (*big.Rat, error) {
+	rate, ok := table[from+"/"+to]
+	if !ok {
+		return nil, models.ErrRateUnavailable
+	}
+	return new(big.Rat).Set(rate), nil
+}
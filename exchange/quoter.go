@@ -0,0 +1,128 @@
+package exchange
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/williamchand/my-wallet/models"
+)
+
+// Rounding rules of the converted amount, which rarely comes out as a whole number of minor units
+const (
+	RoundDown     = "down"
+	RoundHalfUp   = "half_up"
+	RoundHalfEven = "half_even"
+)
+
+const (
+	// rateDigits is the number of decimals a rate is quoted with, the amounts are computed from the quoted rate
+	rateDigits = 10
+
+	defaultQuoteTTL = 30 * time.Second
+	maxSpreadBps    = 10000
+)
+
+// Quoter represent the contract to price a conversion between two currencies
+type Quoter interface {
+	Quote(ctx context.Context, amount models.Money, to string) (*models.Quote, error)
+}
+
+type quoter struct {
+	rates     FXRateProvider
+	spreadBps int64
+	rounding  string
+	ttl       time.Duration
+}
+
+// NewQuoter will create a Quoter taking cfg.SpreadBps off the rates of the provider,
+// its quotes are valid for cfg.QuoteTTL seconds
+func NewQuoter(rates FXRateProvider, cfg Config) (Quoter, error) {
+	q := &quoter{
+		rates:     rates,
+		spreadBps: cfg.SpreadBps,
+		rounding:  cfg.Rounding,
+		ttl:       time.Duration(cfg.QuoteTTL) * time.Second,
+	}
+	if q.spreadBps < 0 || q.spreadBps >= maxSpreadBps {
+		return nil, fmt.Errorf("fx: spread_bps %d is out of range", q.spreadBps)
+	}
+	switch q.rounding {
+	case "":
+		q.rounding = RoundDown
+	case RoundDown, RoundHalfUp, RoundHalfEven:
+	default:
+		return nil, fmt.Errorf("fx: unknown rounding %q", q.rounding)
+	}
+	if q.ttl <= 0 {
+		q.ttl = defaultQuoteTTL
+	}
+	return q, nil
+}
+
+func (q *quoter) Quote(ctx context.Context, amount models.Money, to string) (*models.Quote, error) {
+	if !models.ValidCurrency(to) || to == amount.Currency {
+		return nil, models.ErrBadParamInput
+	}
+	rate, err := q.rates.Rate(ctx, amount.Currency, to)
+	if err != nil {
+		return nil, err
+	}
+	quoted := formatRate(rate)
+	rate.SetString(quoted)
+
+	// amount is in minor units of its currency and the result is in minor units of to
+	gross := new(big.Rat).Mul(new(big.Rat).SetInt64(amount.Amount), rate)
+	gross.Mul(gross, pow10(models.Exponent(to)))
+	gross.Quo(gross, pow10(models.Exponent(amount.Currency)))
+	net := new(big.Rat).Mul(gross, big.NewRat(maxSpreadBps-q.spreadBps, maxSpreadBps))
+
+	grossAmount, ok := round(gross, q.rounding)
+	if !ok {
+		return nil, models.ErrBadParamInput
+	}
+	netAmount, _ := round(net, q.rounding)
+	if netAmount <= 0 {
+		// too small to buy a single minor unit
+		return nil, models.ErrBadParamInput
+	}
+
+	now := time.Now()
+	return &models.Quote{
+		Amount:          amount.Amount,
+		Currency:        amount.Currency,
+		ConvertedAmount: netAmount,
+		ToCurrency:      to,
+		Rate:            quoted,
+		SpreadBps:       q.spreadBps,
+		Fee:             grossAmount - netAmount,
+		Rounding:        q.rounding,
+		ExpiresAt:       now.Add(q.ttl),
+		CreatedAt:       now,
+	}, nil
+}
+
+// formatRate write rate with rateDigits decimals at most, without trailing zeros
+func formatRate(rate *big.Rat) string {
+	s := rate.FloatString(rateDigits)
+	s = strings.TrimRight(s, "0")
+	return strings.TrimSuffix(s, ".")
+}
+
+func pow10(exp int) *big.Rat {
+	return new(big.Rat).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(exp)), nil))
+}
+
+// round turn a non negative r into a whole number following rule, false when it does not fit an int64
+func round(r *big.Rat, rule string) (int64, bool) {
+	q, m := new(big.Int).QuoRem(r.Num(), r.Denom(), new(big.Int))
+	half := m.Mul(m, big.NewInt(2)).Cmp(r.Denom())
+	switch {
+	case rule == RoundHalfUp && half >= 0,
+		rule == RoundHalfEven && (half > 0 || half == 0 && q.Bit(0) == 1):
+		q.Add(q, big.NewInt(1))
+	}
+	return q.Int64(), q.IsInt64()
+}
@@ -0,0 +1,152 @@
+package fees
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/williamchand/my-wallet/models"
+)
+
+// Operations a fee can be charged on
+const (
+	OperationWithdrawal = "withdrawal"
+	OperationTransfer   = "transfer"
+)
+
+// Kinds of fee rule
+const (
+	KindFlat       = "flat"
+	KindPercentage = "percentage"
+	KindTiered     = "tiered"
+)
+
+const bpsDenominator = 10000
+
+// Tier represent one band of a tiered rule, it prices amounts up to UpTo, or any amount left when UpTo is zero
+type Tier struct {
+	UpTo int64 `mapstructure:"up_to"`
+	Flat int64 `mapstructure:"flat"`
+	Bps  int64 `mapstructure:"bps"`
+}
+
+// Rule represent the fee of an operation in one currency, in minor units. A flat rule charges Flat, a
+// percentage rule Bps basis points of the amount plus Flat, and a tiered rule the Flat and Bps of the
+// first tier the amount fits in. The result is then kept between Min and Max, when they are set.
+type Rule struct {
+	Kind  string `mapstructure:"kind"`
+	Flat  int64  `mapstructure:"flat"`
+	Bps   int64  `mapstructure:"bps"`
+	Tiers []Tier `mapstructure:"tiers"`
+	Min   int64  `mapstructure:"min"`
+	Max   int64  `mapstructure:"max"`
+}
+
+// Config represent the fees section of config.json, rules are keyed by operation and then currency
+type Config struct {
+	Schedule map[string]map[string]Rule `mapstructure:"schedule"`
+}
+
+// Schedule represent the contract to price the fee of an operation
+type Schedule interface {
+	// Fee return the fee charged on top of amount, zero when no rule covers the operation and currency
+	Fee(operation string, amount models.Money) (int64, error)
+}
+
+type schedule struct {
+	rules map[string]Rule
+}
+
+// NewSchedule will create a Schedule over the rules of cfg, it refuses rules it could not apply
+func NewSchedule(cfg Config) (Schedule, error) {
+	s := &schedule{rules: make(map[string]Rule)}
+	for operation, currencies := range cfg.Schedule {
+		if operation != OperationWithdrawal && operation != OperationTransfer {
+			return nil, fmt.Errorf("fees: unknown operation %q", operation)
+		}
+		for code, rule := range currencies {
+			// viper lower-cases map keys
+			code = strings.ToUpper(code)
+			if !models.ValidCurrency(code) {
+				return nil, fmt.Errorf("fees: unknown currency %q", code)
+			}
+			if err := rule.validate(); err != nil {
+				return nil, fmt.Errorf("fees: %s %s: %v", operation, code, err)
+			}
+			s.rules[operation+"/"+code] = rule
+		}
+	}
+	return s, nil
+}
+
+func (s *schedule) Fee(operation string, amount models.Money) (int64, error) {
+	if amount.Amount <= 0 {
+		return 0, models.ErrBadParamInput
+	}
+	rule, ok := s.rules[operation+"/"+amount.Currency]
+	if !ok {
+		return 0, nil
+	}
+	return rule.fee(amount.Amount), nil
+}
+
+func (r Rule) validate() error {
+	if r.Flat < 0 || r.Bps < 0 || r.Min < 0 || r.Max < 0 {
+		return fmt.Errorf("amounts can not be negative")
+	}
+	if r.Max > 0 && r.Min > r.Max {
+		return fmt.Errorf("min %d is over max %d", r.Min, r.Max)
+	}
+	switch r.Kind {
+	case KindFlat:
+		if r.Bps != 0 || len(r.Tiers) > 0 {
+			return fmt.Errorf("a flat fee only takes flat")
+		}
+	case KindPercentage:
+		if r.Bps == 0 || len(r.Tiers) > 0 {
+			return fmt.Errorf("a percentage fee needs bps and no tiers")
+		}
+	case KindTiered:
+		if len(r.Tiers) == 0 || r.Flat != 0 || r.Bps != 0 {
+			return fmt.Errorf("a tiered fee only takes tiers")
+		}
+		for i, t := range r.Tiers {
+			last := i == len(r.Tiers)-1
+			if t.Flat < 0 || t.Bps < 0 {
+				return fmt.Errorf("amounts can not be negative")
+			}
+			if last != (t.UpTo == 0) || (i > 0 && !last && t.UpTo <= r.Tiers[i-1].UpTo) {
+				return fmt.Errorf("tiers must go up and only the last one can leave up_to out")
+			}
+		}
+	default:
+		return fmt.Errorf("unknown kind %q", r.Kind)
+	}
+	return nil
+}
+
+func (r Rule) fee(amount int64) int64 {
+	flat, bps := r.Flat, r.Bps
+	if r.Kind == KindTiered {
+		for _, t := range r.Tiers {
+			if t.UpTo == 0 || amount <= t.UpTo {
+				flat, bps = t.Flat, t.Bps
+				break
+			}
+		}
+	}
+
+	fee := flat + percentage(amount, bps)
+	if fee < r.Min {
+		fee = r.Min
+	}
+	if r.Max > 0 && fee > r.Max {
+		fee = r.Max
+	}
+	return fee
+}
+
+// percentage return bps basis points of amount rounded half up, split so that amount*bps can not overflow
+func percentage(amount int64, bps int64) int64 {
+	whole, rest := amount/bpsDenominator, amount%bpsDenominator
+	return whole*bps + (rest*bps+bpsDenominator/2)/bpsDenominator
+}
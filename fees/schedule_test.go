@@ -0,0 +1,86 @@
+package fees_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/williamchand/my-wallet/fees"
+	"github.com/williamchand/my-wallet/models"
+)
+
+func TestFee(t *testing.T) {
+	s, err := fees.NewSchedule(fees.Config{Schedule: map[string]map[string]fees.Rule{
+		fees.OperationWithdrawal: {
+			// keys come lower-cased out of viper
+			"idr": {Kind: fees.KindFlat, Flat: 2500},
+			"USD": {Kind: fees.KindPercentage, Bps: 150, Flat: 10, Min: 50, Max: 1000},
+		},
+		fees.OperationTransfer: {
+			"IDR": {Kind: fees.KindTiered, Max: 5000, Tiers: []fees.Tier{
+				{UpTo: 10000},
+				{UpTo: 100000, Flat: 1000},
+				{Bps: 50},
+			}},
+		},
+	}})
+	require.NoError(t, err)
+
+	tests := map[string]struct {
+		operation string
+		amount    models.Money
+		fee       int64
+	}{
+		"flat":                  {fees.OperationWithdrawal, models.Money{Amount: 1, Currency: "IDR"}, 2500},
+		"percentage":            {fees.OperationWithdrawal, models.Money{Amount: 10000, Currency: "USD"}, 160},
+		"percentage rounds":     {fees.OperationWithdrawal, models.Money{Amount: 10033, Currency: "USD"}, 160},
+		"percentage rounds up":  {fees.OperationWithdrawal, models.Money{Amount: 10034, Currency: "USD"}, 161},
+		"min":                   {fees.OperationWithdrawal, models.Money{Amount: 100, Currency: "USD"}, 50},
+		"capped":                {fees.OperationWithdrawal, models.Money{Amount: 1000000, Currency: "USD"}, 1000},
+		"first tier":            {fees.OperationTransfer, models.Money{Amount: 10000, Currency: "IDR"}, 0},
+		"second tier":           {fees.OperationTransfer, models.Money{Amount: 10001, Currency: "IDR"}, 1000},
+		"last tier":             {fees.OperationTransfer, models.Money{Amount: 200000, Currency: "IDR"}, 1000},
+		"last tier capped":      {fees.OperationTransfer, models.Money{Amount: 2000000, Currency: "IDR"}, 5000},
+		"no rule for currency":  {fees.OperationTransfer, models.Money{Amount: 10000, Currency: "USD"}, 0},
+		"large amounts":         {fees.OperationWithdrawal, models.Money{Amount: 1 << 62, Currency: "USD"}, 1000},
+		"no rule for operation": {"capture", models.Money{Amount: 10000, Currency: "IDR"}, 0},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			fee, err := s.Fee(tc.operation, tc.amount)
+			require.NoError(t, err)
+			assert.Equal(t, tc.fee, fee)
+		})
+	}
+
+	_, err = s.Fee(fees.OperationWithdrawal, models.Money{Amount: 0, Currency: "IDR"})
+	assert.Equal(t, models.ErrBadParamInput, err)
+}
+
+func TestNewScheduleRejects(t *testing.T) {
+	for name, rule := range map[string]fees.Rule{
+		"unknown kind":         {Kind: "free"},
+		"flat with bps":        {Kind: fees.KindFlat, Flat: 1, Bps: 1},
+		"percentage no bps":    {Kind: fees.KindPercentage, Flat: 1},
+		"negative":             {Kind: fees.KindFlat, Flat: -1},
+		"min over max":         {Kind: fees.KindFlat, Flat: 1, Min: 10, Max: 5},
+		"tiers going down":     {Kind: fees.KindTiered, Tiers: []fees.Tier{{UpTo: 100}, {UpTo: 50}, {}}},
+		"tiers not open ended": {Kind: fees.KindTiered, Tiers: []fees.Tier{{UpTo: 100}}},
+		"tiered with flat":     {Kind: fees.KindTiered, Flat: 1, Tiers: []fees.Tier{{}}},
+	} {
+		_, err := fees.NewSchedule(fees.Config{Schedule: map[string]map[string]fees.Rule{
+			fees.OperationWithdrawal: {"IDR": rule},
+		}})
+		assert.Error(t, err, name)
+	}
+
+	_, err := fees.NewSchedule(fees.Config{Schedule: map[string]map[string]fees.Rule{
+		"deposit": {"IDR": {Kind: fees.KindFlat, Flat: 1}},
+	}})
+	assert.Error(t, err)
+	_, err = fees.NewSchedule(fees.Config{Schedule: map[string]map[string]fees.Rule{
+		fees.OperationWithdrawal: {"XXX": {Kind: fees.KindFlat, Flat: 1}},
+	}})
+	assert.Error(t, err)
+}
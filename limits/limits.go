@@ -0,0 +1,182 @@
+package limits
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/williamchand/my-wallet/models"
+)
+
+// Names of the limits, as reported in models.LimitError
+const (
+	LimitMaxAmount  = "max_amount"
+	LimitMaxBalance = "max_balance"
+	LimitDailyIn    = "daily_in"
+	LimitMonthlyIn  = "monthly_in"
+	LimitDailyOut   = "daily_out"
+	LimitMonthlyOut = "monthly_out"
+	LimitMaxCount   = "max_count"
+)
+
+// the transaction types moving money into and out of a wallet, reversals only correct them and are not counted
+var (
+	inflow = []string{
+		models.TransactionTypeDeposit,
+		models.TransactionTypeTransferIn,
+		models.TransactionTypeConvertIn,
+	}
+	outflow = []string{
+		models.TransactionTypeWithdrawal,
+		models.TransactionTypeTransferOut,
+		models.TransactionTypeCapture,
+		models.TransactionTypeConvertOut,
+	}
+)
+
+// Rule represent the limits of the wallets in one currency, in minor units. A zero value is not enforced.
+type Rule struct {
+	MaxAmount  int64 `mapstructure:"max_amount"`
+	MaxBalance int64 `mapstructure:"max_balance"`
+	DailyIn    int64 `mapstructure:"daily_in"`
+	MonthlyIn  int64 `mapstructure:"monthly_in"`
+	DailyOut   int64 `mapstructure:"daily_out"`
+	MonthlyOut int64 `mapstructure:"monthly_out"`
+	// MaxCount transactions, in both directions, are allowed within any CountWindow seconds
+	MaxCount    int64 `mapstructure:"max_count"`
+	CountWindow int   `mapstructure:"count_window"`
+}
+
+// Config represent the limits section of config.json, days and months start at midnight in TimeZone
+type Config struct {
+	TimeZone   string          `mapstructure:"time_zone"`
+	Currencies map[string]Rule `mapstructure:"currencies"`
+}
+
+// UsageReader represent the source of what a wallet already moved, wallet.Repository satisfies it. The repositories
+// check the limits with a UsageReader reading within the transaction holding the wallet lock.
+type UsageReader interface {
+	FetchUsage(ctx context.Context, id string, types []string, since time.Time) (*models.Usage, error)
+}
+
+// Checker represent the limits applied before money moves into or out of a wallet
+type Checker interface {
+	// Check return a *models.LimitError when moving amount with a transaction of txType breaks a limit of w,
+	// what w already moved is read from usage
+	Check(ctx context.Context, usage UsageReader, w *models.FetchWallet, txType string, amount int64) error
+}
+
+type checker struct {
+	rules map[string]Rule
+	loc   *time.Location
+}
+
+// NewChecker will create a Checker enforcing the rules of cfg, wallets in a currency without rule are not limited
+func NewChecker(cfg Config) (Checker, error) {
+	c := &checker{
+		rules: make(map[string]Rule, len(cfg.Currencies)),
+		loc:   time.UTC,
+	}
+	if cfg.TimeZone != "" {
+		loc, err := time.LoadLocation(cfg.TimeZone)
+		if err != nil {
+			return nil, fmt.Errorf("limits: %v", err)
+		}
+		c.loc = loc
+	}
+	for code, rule := range cfg.Currencies {
+		// viper lower-cases map keys
+		code = strings.ToUpper(code)
+		if !models.ValidCurrency(code) {
+			return nil, fmt.Errorf("limits: unknown currency %q", code)
+		}
+		if rule.MaxCount > 0 && rule.CountWindow <= 0 {
+			return nil, fmt.Errorf("limits: %s max_count needs a count_window", code)
+		}
+		c.rules[code] = rule
+	}
+	return c, nil
+}
+
+func (c *checker) Check(ctx context.Context, usage UsageReader, w *models.FetchWallet, txType string, amount int64) error {
+	rule, ok := c.rules[w.Currency]
+	if !ok {
+		return nil
+	}
+
+	var types []string
+	var daily, monthly int64
+	var dailyName, monthlyName string
+	switch {
+	case contains(inflow, txType):
+		types, daily, monthly, dailyName, monthlyName = inflow, rule.DailyIn, rule.MonthlyIn, LimitDailyIn, LimitMonthlyIn
+	case contains(outflow, txType):
+		types, daily, monthly, dailyName, monthlyName = outflow, rule.DailyOut, rule.MonthlyOut, LimitDailyOut, LimitMonthlyOut
+	default:
+		return nil
+	}
+
+	if rule.MaxAmount > 0 && amount > rule.MaxAmount {
+		return exceeded(LimitMaxAmount, rule.MaxAmount, amount, w.Currency, time.Time{})
+	}
+	if rule.MaxBalance > 0 && contains(inflow, txType) && w.Balance+amount > rule.MaxBalance {
+		return exceeded(LimitMaxBalance, rule.MaxBalance, w.Balance, w.Currency, time.Time{})
+	}
+
+	now := time.Now().In(c.loc)
+	day := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, c.loc)
+	month := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, c.loc)
+	windows := []struct {
+		name    string
+		max     int64
+		since   time.Time
+		resetAt time.Time
+	}{
+		{dailyName, daily, day, day.AddDate(0, 0, 1)},
+		{monthlyName, monthly, month, month.AddDate(0, 1, 0)},
+	}
+	for _, win := range windows {
+		if win.max <= 0 {
+			continue
+		}
+		used, err := usage.FetchUsage(ctx, w.ID, types, win.since)
+		if err != nil {
+			return err
+		}
+		if used.Amount+amount > win.max {
+			return exceeded(win.name, win.max, used.Amount, w.Currency, win.resetAt)
+		}
+	}
+
+	if rule.MaxCount > 0 {
+		window := time.Duration(rule.CountWindow) * time.Second
+		used, err := usage.FetchUsage(ctx, w.ID, append(append([]string{}, inflow...), outflow...), now.Add(-window))
+		if err != nil {
+			return err
+		}
+		if used.Count >= rule.MaxCount {
+			// the window frees a slot once its oldest transaction falls out of it
+			return exceeded(LimitMaxCount, rule.MaxCount, used.Count, w.Currency, used.First.Add(window))
+		}
+	}
+
+	return nil
+}
+
+func exceeded(name string, max int64, used int64, currency string, resetsAt time.Time) *models.LimitError {
+	e := &models.LimitError{Limit: name, Max: max, Used: used, Currency: currency}
+	if !resetsAt.IsZero() {
+		e.ResetsAt = &resetsAt
+	}
+	return e
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,174 @@
+package limits_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/williamchand/my-wallet/limits"
+	"github.com/williamchand/my-wallet/models"
+)
+
+// usageStub tells the windows apart by their start: the count window is the only one asking for both
+// directions, and the month is the window starting on the 1st more than a day ago
+type usageStub struct {
+	daily   models.Usage
+	monthly models.Usage
+	recent  models.Usage
+	calls   []time.Time
+}
+
+func (u *usageStub) FetchUsage(ctx context.Context, id string, types []string, since time.Time) (*models.Usage, error) {
+	u.calls = append(u.calls, since)
+	now := time.Now()
+	switch {
+	case now.Sub(since) < time.Hour && len(types) > 4:
+		return &u.recent, nil
+	case since.Day() == 1 && now.Sub(since) > 24*time.Hour:
+		return &u.monthly, nil
+	default:
+		return &u.daily, nil
+	}
+}
+
+func TestCheck(t *testing.T) {
+	cfg := limits.Config{
+		TimeZone: "Asia/Jakarta",
+		Currencies: map[string]limits.Rule{
+			// keys come lower-cased out of viper
+			"idr": {
+				MaxAmount:   1000,
+				MaxBalance:  5000,
+				DailyIn:     3000,
+				DailyOut:    2000,
+				MonthlyOut:  4000,
+				MaxCount:    5,
+				CountWindow: 60,
+			},
+		},
+	}
+	loc, err := time.LoadLocation("Asia/Jakarta")
+	require.NoError(t, err)
+	now := time.Now().In(loc)
+	tomorrow := time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, loc)
+	recent := now.Add(-30 * time.Second)
+
+	tests := map[string]struct {
+		wallet   models.FetchWallet
+		txType   string
+		amount   int64
+		usage    usageStub
+		limit    string
+		used     int64
+		resetsAt *time.Time
+	}{
+		"within limits": {
+			wallet: models.FetchWallet{Currency: "IDR", Balance: 100},
+			txType: models.TransactionTypeWithdrawal, amount: 500,
+			usage: usageStub{daily: models.Usage{Amount: 1500}},
+		},
+		"other currency": {
+			wallet: models.FetchWallet{Currency: "USD"},
+			txType: models.TransactionTypeDeposit, amount: 1000000,
+		},
+		"max amount": {
+			wallet: models.FetchWallet{Currency: "IDR"},
+			txType: models.TransactionTypeDeposit, amount: 1001,
+			limit: limits.LimitMaxAmount, used: 1001,
+		},
+		"max balance": {
+			wallet: models.FetchWallet{Currency: "IDR", Balance: 4500},
+			txType: models.TransactionTypeDeposit, amount: 600,
+			limit: limits.LimitMaxBalance, used: 4500,
+		},
+		"max balance is not checked on the way out": {
+			wallet: models.FetchWallet{Currency: "IDR", Balance: 6000},
+			txType: models.TransactionTypeWithdrawal, amount: 600,
+		},
+		"daily in": {
+			wallet: models.FetchWallet{Currency: "IDR"},
+			txType: models.TransactionTypeDeposit, amount: 600,
+			usage: usageStub{daily: models.Usage{Amount: 2500}},
+			limit: limits.LimitDailyIn, used: 2500, resetsAt: &tomorrow,
+		},
+		"daily out": {
+			wallet: models.FetchWallet{Currency: "IDR", Balance: 10000},
+			txType: models.TransactionTypeWithdrawal, amount: 600,
+			usage: usageStub{daily: models.Usage{Amount: 1500}},
+			limit: limits.LimitDailyOut, used: 1500, resetsAt: &tomorrow,
+		},
+		"max count": {
+			wallet: models.FetchWallet{Currency: "IDR"},
+			txType: models.TransactionTypeDeposit, amount: 1,
+			usage: usageStub{recent: models.Usage{Count: 5, First: recent}},
+			limit: limits.LimitMaxCount, used: 5, resetsAt: timePtr(recent.Add(time.Minute)),
+		},
+		"reversals are not limited": {
+			wallet: models.FetchWallet{Currency: "IDR"},
+			txType: models.TransactionTypeReversal, amount: 100000,
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			c, err := limits.NewChecker(cfg)
+			require.NoError(t, err)
+			err = c.Check(context.TODO(), &tc.usage, &tc.wallet, tc.txType, tc.amount)
+			if tc.limit == "" {
+				assert.NoError(t, err)
+				return
+			}
+			le, ok := err.(*models.LimitError)
+			require.True(t, ok, "%v", err)
+			assert.Equal(t, models.ErrLimitExceeded.Error(), le.Error())
+			assert.Equal(t, tc.limit, le.Limit)
+			assert.Equal(t, tc.used, le.Used)
+			assert.Equal(t, tc.wallet.Currency, le.Currency)
+			if tc.resetsAt == nil {
+				assert.Nil(t, le.ResetsAt)
+			} else {
+				require.NotNil(t, le.ResetsAt)
+				assert.True(t, tc.resetsAt.Equal(*le.ResetsAt), "%v != %v", tc.resetsAt, le.ResetsAt)
+			}
+		})
+	}
+}
+
+func TestMonthlyLimit(t *testing.T) {
+	usage := &usageStub{}
+	c, err := limits.NewChecker(limits.Config{Currencies: map[string]limits.Rule{"IDR": {MonthlyOut: 4000}}})
+	require.NoError(t, err)
+
+	err = c.Check(context.TODO(), usage, &models.FetchWallet{Currency: "IDR"}, models.TransactionTypeTransferOut, 100)
+	require.NoError(t, err)
+	// without a time zone months start in UTC
+	now := time.Now().UTC()
+	month := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	require.Len(t, usage.calls, 1)
+	assert.True(t, month.Equal(usage.calls[0]))
+
+	usage.daily = models.Usage{Amount: 3950}
+	usage.monthly = usage.daily
+	err = c.Check(context.TODO(), usage, &models.FetchWallet{Currency: "IDR"}, models.TransactionTypeTransferOut, 100)
+	le, ok := err.(*models.LimitError)
+	require.True(t, ok, "%v", err)
+	assert.Equal(t, limits.LimitMonthlyOut, le.Limit)
+	assert.True(t, month.AddDate(0, 1, 0).Equal(*le.ResetsAt))
+}
+
+func TestNewCheckerRejects(t *testing.T) {
+	for name, cfg := range map[string]limits.Config{
+		"time zone":          {TimeZone: "Mars/Olympus"},
+		"currency":           {Currencies: map[string]limits.Rule{"XXX": {MaxAmount: 1}}},
+		"count needs window": {Currencies: map[string]limits.Rule{"IDR": {MaxCount: 1}}},
+	} {
+		_, err := limits.NewChecker(cfg)
+		assert.Error(t, err, name)
+	}
+}
+
+func timePtr(t time.Time) *time.Time {
+	return &t
+}
@@ -1,71 +1,195 @@
 package main
 
 import (
-	"database/sql"
-	"fmt"
+	"context"
 	"log"
-	"net/url"
+	"net"
 	"os"
 	"time"
 
-	_ "github.com/go-sql-driver/mysql"
 	"github.com/labstack/echo"
+	"github.com/sirupsen/logrus"
 	"github.com/spf13/viper"
+	"google.golang.org/grpc"
 
+	"github.com/williamchand/my-wallet/auth"
+	"github.com/williamchand/my-wallet/config"
+	"github.com/williamchand/my-wallet/exchange"
+	"github.com/williamchand/my-wallet/fees"
+	"github.com/williamchand/my-wallet/limits"
 	"github.com/williamchand/my-wallet/middleware"
+	"github.com/williamchand/my-wallet/outbox"
+	"github.com/williamchand/my-wallet/requestid"
+	"github.com/williamchand/my-wallet/wallet"
+	_walletGrpcDeliver "github.com/williamchand/my-wallet/wallet/delivery/grpc"
 	_walletHttpDeliver "github.com/williamchand/my-wallet/wallet/delivery/http"
 	_walletRepo "github.com/williamchand/my-wallet/wallet/repository"
 	_walletUcase "github.com/williamchand/my-wallet/wallet/usecase"
+	"github.com/williamchand/my-wallet/webhook"
+	_webhookHttpDeliver "github.com/williamchand/my-wallet/webhook/delivery/http"
+	"github.com/williamchand/my-wallet/webhook/dispatcher"
+	_webhookRepo "github.com/williamchand/my-wallet/webhook/repository"
+	_webhookUcase "github.com/williamchand/my-wallet/webhook/usecase"
 )
 
 func init() {
-	viper.SetConfigFile(`config.json`)
-	err := viper.ReadInConfig()
+	err := config.Load(`config.json`)
 	if err != nil {
 		panic(err)
 	}
-
-	if viper.GetBool(`debug`) {
-		fmt.Println("Service RUN on DEBUG mode")
-	}
 }
 
 func main() {
-	dbHost := viper.GetString(`database.host`)
-	dbPort := viper.GetString(`database.port`)
-	dbUser := viper.GetString(`database.user`)
-	dbPass := viper.GetString(`database.pass`)
-	dbName := viper.GetString(`database.name`)
-	connection := fmt.Sprintf("%s:%s@tcp(%s:%s)/%s", dbUser, dbPass, dbHost, dbPort, dbName)
-	val := url.Values{}
-	val.Add("parseTime", "1")
-	val.Add("loc", "Asia/Jakarta")
-	dsn := fmt.Sprintf("%s?%s", connection, val.Encode())
-	dbConn, err := sql.Open(`mysql`, dsn)
-	if err != nil && viper.GetBool("debug") {
-		fmt.Println(err)
+	var ar wallet.Repository
+	var or outbox.Repository
+	var wr webhook.Repository
+	switch viper.GetString(`database.driver`) {
+	case "memory":
+		// nothing is persisted, meant for local runs without MySQL
+		ar = _walletRepo.NewMemoryWalletRepository()
+		var err error
+		or, err = _walletRepo.NewMemoryOutboxRepository(ar)
+		if err != nil {
+			log.Fatal(err)
+		}
+		wr = _webhookRepo.NewMemoryWebhookRepository()
+	default:
+		dbConn, err := config.OpenDatabase()
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer func() {
+			err := dbConn.Close()
+			if err != nil {
+				log.Fatal(err)
+			}
+		}()
+		ar = _walletRepo.NewMysqlWalletRepository(dbConn)
+		or = _walletRepo.NewMysqlOutboxRepository(dbConn)
+		wr = _webhookRepo.NewMysqlWebhookRepository(dbConn)
+	}
+
+	var authConfig auth.Config
+	err := viper.UnmarshalKey("auth", &authConfig)
+	if err != nil {
+		log.Fatal(err)
 	}
-	err = dbConn.Ping()
+	jwtAuth, err := auth.NewJWTAuth(authConfig)
 	if err != nil {
 		log.Fatal(err)
-		os.Exit(1)
 	}
 
-	defer func() {
-		err := dbConn.Close()
-		if err != nil {
-			log.Fatal(err)
-		}
-	}()
+	var corsConfig middleware.CORSConfig
+	err = viper.UnmarshalKey("cors", &corsConfig)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// the entries logged while serving a request carry its X-Request-ID
+	logrus.AddHook(requestid.Hook{})
+	accessLog := logrus.New()
+	accessLog.SetOutput(os.Stdout)
+	accessLog.SetFormatter(&logrus.JSONFormatter{})
 
 	e := echo.New()
-	middL := middleware.InitMiddleware()
+	e.Use(middleware.RequestID)
+	e.Use(middleware.AccessLog(accessLog))
+	// a wallet is initialized before its owner holds a token
+	middL, err := middleware.InitMiddleware(jwtAuth, corsConfig, "/api/v1/init")
+	if err != nil {
+		log.Fatal(err)
+	}
+	var rateLimitConfig middleware.RateLimitConfig
+	err = viper.UnmarshalKey("rate_limit", &rateLimitConfig)
+	if err != nil {
+		log.Fatal(err)
+	}
+	// shared by the HTTP and gRPC servers
+	rateLimiter, err := middleware.NewLimiter(middleware.NewMemoryStore(), rateLimitConfig)
+	if err != nil {
+		log.Fatal(err)
+	}
 	e.Use(middL.CORS)
-	ar := _walletRepo.NewMysqlWalletRepository(dbConn)
+	e.Use(rateLimiter.IP)
+	e.Use(middL.Auth)
+	e.Use(rateLimiter.Client)
+
+	var fxConfig exchange.Config
+	err = viper.UnmarshalKey("fx", &fxConfig)
+	if err != nil {
+		log.Fatal(err)
+	}
+	rates, err := exchange.NewRateProvider(fxConfig)
+	if err != nil {
+		log.Fatal(err)
+	}
+	quoter, err := exchange.NewQuoter(rates, fxConfig)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	var limitsConfig limits.Config
+	err = viper.UnmarshalKey("limits", &limitsConfig)
+	if err != nil {
+		log.Fatal(err)
+	}
+	limiter, err := limits.NewChecker(limitsConfig)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	var feesConfig fees.Config
+	err = viper.UnmarshalKey("fees", &feesConfig)
+	if err != nil {
+		log.Fatal(err)
+	}
+	schedule, err := fees.NewSchedule(feesConfig)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	var webhooksConfig dispatcher.Config
+	err = viper.UnmarshalKey("webhooks", &webhooksConfig)
+	if err != nil {
+		log.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go dispatcher.NewDispatcher(wr, webhooksConfig).Run(ctx)
 
 	timeoutContext := time.Duration(viper.GetInt("context.timeout")) * time.Second
-	au := _walletUcase.NewWalletUsecase(ar, timeoutContext)
+	wu := _webhookUcase.NewWebhookUsecase(wr, timeoutContext)
+	_webhookHttpDeliver.NewWebhookHandler(e, wu)
+
+	var outboxConfig outbox.Config
+	err = viper.UnmarshalKey("outbox", &outboxConfig)
+	if err != nil {
+		log.Fatal(err)
+	}
+	publisher, err := outbox.NewPublisher(outboxConfig, wu)
+	if err != nil {
+		log.Fatal(err)
+	}
+	go outbox.NewRelay(or, publisher, outboxConfig).Run(ctx)
+
+	au := _walletUcase.NewWalletUsecase(ar, jwtAuth, quoter, limiter, schedule, timeoutContext)
 	_walletHttpDeliver.NewWalletHandler(e, au)
+	_walletHttpDeliver.NewAdminHandler(e, au)
+
+	lis, err := net.Listen("tcp", viper.GetString("server.grpc_address"))
+	if err != nil {
+		log.Fatal(err)
+	}
+	s := grpc.NewServer(grpc.UnaryInterceptor(_walletGrpcDeliver.ChainUnaryInterceptors(
+		_walletGrpcDeliver.NewRequestIDInterceptor(),
+		_walletGrpcDeliver.NewIPRateLimitInterceptor(rateLimiter),
+		_walletGrpcDeliver.NewAuthInterceptor(jwtAuth),
+		_walletGrpcDeliver.NewRateLimitInterceptor(rateLimiter),
+	)))
+	_walletGrpcDeliver.NewWalletServer(s, au)
+	go func() {
+		log.Fatal(s.Serve(lis))
+	}()
 
 	log.Fatal(e.Start(viper.GetString("server.address")))
 }
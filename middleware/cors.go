@@ -0,0 +1,129 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo"
+)
+
+// CORSConfig represent the cors section of config.json
+type CORSConfig struct {
+	// AllowOrigins are the origins allowed to call the API, "*" allows any
+	AllowOrigins     []string `mapstructure:"allow_origins"`
+	AllowMethods     []string `mapstructure:"allow_methods"`
+	AllowHeaders     []string `mapstructure:"allow_headers"`
+	ExposeHeaders    []string `mapstructure:"expose_headers"`
+	AllowCredentials bool     `mapstructure:"allow_credentials"`
+	// MaxAge is how many seconds browsers may cache a preflight response
+	MaxAge int `mapstructure:"max_age"`
+}
+
+var (
+	defaultAllowMethods = []string{echo.GET, echo.POST, echo.PUT, echo.PATCH, echo.DELETE}
+	defaultAllowHeaders = []string{echo.HeaderAuthorization, echo.HeaderContentType, echo.HeaderAccept}
+)
+
+// corsPolicy is CORSConfig with its headers ready to be written
+type corsPolicy struct {
+	origins       map[string]bool
+	anyOrigin     bool
+	credentials   bool
+	allowMethods  string
+	allowHeaders  string
+	exposeHeaders string
+	maxAge        string
+}
+
+// newCORSPolicy refuse to allow any origin with credentials, as it would let every site make credentialed calls
+func newCORSPolicy(cfg CORSConfig) (*corsPolicy, error) {
+	p := &corsPolicy{
+		origins:       make(map[string]bool, len(cfg.AllowOrigins)),
+		credentials:   cfg.AllowCredentials,
+		allowMethods:  strings.Join(orDefault(cfg.AllowMethods, defaultAllowMethods), ","),
+		allowHeaders:  strings.Join(orDefault(cfg.AllowHeaders, defaultAllowHeaders), ","),
+		exposeHeaders: strings.Join(cfg.ExposeHeaders, ","),
+	}
+	for _, origin := range cfg.AllowOrigins {
+		if origin == "*" {
+			p.anyOrigin = true
+			continue
+		}
+		p.origins[strings.TrimSuffix(origin, "/")] = true
+	}
+	if p.anyOrigin && p.credentials {
+		return nil, errors.New(`cors: allow_origins "*" can not be combined with allow_credentials`)
+	}
+	if cfg.MaxAge > 0 {
+		p.maxAge = strconv.Itoa(cfg.MaxAge)
+	}
+	return p, nil
+}
+
+func orDefault(values, def []string) []string {
+	if len(values) == 0 {
+		return def
+	}
+	return values
+}
+
+// allowOrigin return the Access-Control-Allow-Origin to answer origin with, empty when it is not allowed
+func (p *corsPolicy) allowOrigin(origin string) string {
+	switch {
+	case p.origins[origin]:
+		return origin
+	case p.anyOrigin:
+		return "*"
+	}
+	return ""
+}
+
+// CORS will handle the CORS middleware: the origins in the allow-list get the CORS headers and their preflight
+// requests are answered here, before Auth, as browsers send them without the Authorization header. The preflight
+// of any other origin is refused with 403, their other requests are served without the headers so browsers block them.
+func (m *GoMiddleware) CORS(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		req := c.Request()
+		header := c.Response().Header()
+		origin := req.Header.Get(echo.HeaderOrigin)
+		preflight := req.Method == echo.OPTIONS && req.Header.Get(echo.HeaderAccessControlRequestMethod) != ""
+
+		header.Add(echo.HeaderVary, echo.HeaderOrigin)
+		if origin == "" {
+			return next(c)
+		}
+		allowed := m.cors.allowOrigin(origin)
+
+		if !preflight {
+			if allowed == "" {
+				return next(c)
+			}
+			header.Set(echo.HeaderAccessControlAllowOrigin, allowed)
+			if m.cors.credentials {
+				header.Set(echo.HeaderAccessControlAllowCredentials, "true")
+			}
+			if m.cors.exposeHeaders != "" {
+				header.Set(echo.HeaderAccessControlExposeHeaders, m.cors.exposeHeaders)
+			}
+			return next(c)
+		}
+
+		header.Add(echo.HeaderVary, echo.HeaderAccessControlRequestMethod)
+		header.Add(echo.HeaderVary, echo.HeaderAccessControlRequestHeaders)
+		if allowed == "" {
+			return c.NoContent(http.StatusForbidden)
+		}
+		header.Set(echo.HeaderAccessControlAllowOrigin, allowed)
+		header.Set(echo.HeaderAccessControlAllowMethods, m.cors.allowMethods)
+		header.Set(echo.HeaderAccessControlAllowHeaders, m.cors.allowHeaders)
+		if m.cors.credentials {
+			header.Set(echo.HeaderAccessControlAllowCredentials, "true")
+		}
+		if m.cors.maxAge != "" {
+			header.Set(echo.HeaderAccessControlMaxAge, m.cors.maxAge)
+		}
+		return c.NoContent(http.StatusNoContent)
+	}
+}
@@ -1,21 +1,103 @@
 package middleware
 
-import "github.com/labstack/echo"
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/labstack/echo"
+	"github.com/sirupsen/logrus"
+
+	"github.com/williamchand/my-wallet/auth"
+	"github.com/williamchand/my-wallet/models"
+)
 
 // GoMiddleware represent the data-struct for middleware
 type GoMiddleware struct {
-	// another stuff , may be needed by middleware
+	verifier auth.Verifier
+	// public are the routes served without authentication
+	public map[string]bool
+	cors   *corsPolicy
 }
 
-// CORS will handle the CORS middleware
-func (m *GoMiddleware) CORS(next echo.HandlerFunc) echo.HandlerFunc {
+// Auth will authenticate the Authorization header once and put the Principal in the request context,
+// see auth.FromContext. The public routes are let through without it, but a header sent to them is still checked.
+func (m *GoMiddleware) Auth(next echo.HandlerFunc) echo.HandlerFunc {
 	return func(c echo.Context) error {
-		c.Response().Header().Set("Access-Control-Allow-Origin", "*")
+		if m.public[c.Path()] && c.Request().Header.Get(echo.HeaderAuthorization) == "" {
+			return next(c)
+		}
+
+		principal, err := m.verifier.Verify(c.Request().Header.Get(echo.HeaderAuthorization))
+		if err != nil {
+			return fail(c, err)
+		}
+		req := c.Request()
+		c.SetRequest(req.WithContext(auth.NewContext(req.Context(), principal)))
 		return next(c)
 	}
 }
 
-// InitMiddleware intialize the middleware
-func InitMiddleware() *GoMiddleware {
-	return &GoMiddleware{}
+// RequireScope will create the route middleware refusing with 403 the principals whose token does not grant scope,
+// it runs after Auth
+func RequireScope(scope string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			principal := auth.FromContext(c.Request().Context())
+			if principal == nil {
+				return fail(c, models.ErrUnauthorized)
+			}
+			if !principal.HasScope(scope) {
+				return fail(c, models.ErrForbidden)
+			}
+			return next(c)
+		}
+	}
+}
+
+// InitMiddleware intialize the middleware with the CORS policy of cors, the routes in public are served without
+// authentication. It fails when cors is not a valid policy.
+func InitMiddleware(v auth.Verifier, cors CORSConfig, public ...string) (*GoMiddleware, error) {
+	policy, err := newCORSPolicy(cors)
+	if err != nil {
+		return nil, err
+	}
+	m := &GoMiddleware{
+		verifier: v,
+		public:   make(map[string]bool, len(public)),
+		cors:     policy,
+	}
+	for _, path := range public {
+		m.public[path] = true
+	}
+	return m, nil
+}
+
+// response is the error envelope the handlers answer with
+type response struct {
+	Status string        `json:"status"`
+	Data   responseError `json:"data"`
+}
+
+type responseError struct {
+	Error string `json:"error"`
+	Code  string `json:"code"`
+}
+
+// fail write err in the error envelope, or as an RFC 7807 problem when the client accepts application/problem+json
+func fail(c echo.Context, err error) error {
+	e := models.ErrorOf(err)
+	logrus.WithContext(c.Request().Context()).Error(err)
+	c.Set(ErrorCodeKey, e.Code)
+
+	if strings.Contains(c.Request().Header.Get(echo.HeaderAccept), models.MIMEProblemJSON) {
+		b, jerr := json.Marshal(models.NewProblem(e, c.Request().URL.Path))
+		if jerr != nil {
+			return jerr
+		}
+		return c.Blob(e.Status, models.MIMEProblemJSON, b)
+	}
+	return c.JSON(e.Status, response{Status: "fail", Data: responseError{
+		Error: e.Message,
+		Code:  e.Code,
+	}})
 }
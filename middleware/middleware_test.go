@@ -1,29 +1,449 @@
 package middleware_test
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
 	"net/http"
 	test "net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/labstack/echo"
+	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	"github.com/williamchand/my-wallet/auth"
 	"github.com/williamchand/my-wallet/middleware"
+	"github.com/williamchand/my-wallet/models"
+	"github.com/williamchand/my-wallet/requestid"
 )
 
+// verifierStub accept "Bearer valid", the token of an owner, and "Bearer support", the token of a support agent
+type verifierStub struct{}
+
+func (verifierStub) Verify(authorization string) (*models.Principal, error) {
+	switch authorization {
+	case "Bearer valid":
+		return &models.Principal{CustomerID: "customer-1", WalletID: "wallet-1", Scopes: models.OwnerScopes}, nil
+	case "Bearer support":
+		return &models.Principal{CustomerID: "support-1", Scopes: []string{models.ScopeAdminRead}}, nil
+	}
+	return nil, models.ErrUnauthorized
+}
+
+var corsConfig = middleware.CORSConfig{
+	AllowOrigins:     []string{"https://app.example.com"},
+	AllowMethods:     []string{echo.GET, echo.POST, echo.PATCH},
+	AllowHeaders:     []string{echo.HeaderAuthorization, echo.HeaderContentType},
+	ExposeHeaders:    []string{"X-Request-ID"},
+	AllowCredentials: true,
+	MaxAge:           600,
+}
+
 func TestCORS(t *testing.T) {
 	e := echo.New()
-	req := test.NewRequest(echo.GET, "/", nil)
+	m, err := middleware.InitMiddleware(verifierStub{}, corsConfig, "/api/v1/init")
+	require.NoError(t, err)
+	e.Use(m.CORS)
+	e.Use(m.Auth)
+	e.PATCH("/api/v1/wallet", func(c echo.Context) error {
+		return c.NoContent(http.StatusOK)
+	})
+
+	serve := func(method, origin string, header map[string]string) *test.ResponseRecorder {
+		req := test.NewRequest(method, "/api/v1/wallet", nil)
+		if origin != "" {
+			req.Header.Set(echo.HeaderOrigin, origin)
+		}
+		for k, v := range header {
+			req.Header.Set(k, v)
+		}
+		res := test.NewRecorder()
+		e.ServeHTTP(res, req)
+		return res
+	}
+	preflight := map[string]string{
+		echo.HeaderAccessControlRequestMethod:  echo.PATCH,
+		echo.HeaderAccessControlRequestHeaders: echo.HeaderAuthorization,
+	}
+
+	res := serve(echo.OPTIONS, "https://app.example.com", preflight)
+	assert.Equal(t, http.StatusNoContent, res.Code, "preflight is answered before Auth")
+	assert.Equal(t, "https://app.example.com", res.Header().Get(echo.HeaderAccessControlAllowOrigin))
+	assert.Equal(t, "GET,POST,PATCH", res.Header().Get(echo.HeaderAccessControlAllowMethods))
+	assert.Equal(t, "Authorization,Content-Type", res.Header().Get(echo.HeaderAccessControlAllowHeaders))
+	assert.Equal(t, "true", res.Header().Get(echo.HeaderAccessControlAllowCredentials))
+	assert.Equal(t, "600", res.Header().Get(echo.HeaderAccessControlMaxAge))
+
+	res = serve(echo.OPTIONS, "https://evil.example.com", preflight)
+	assert.Equal(t, http.StatusForbidden, res.Code)
+	assert.Empty(t, res.Header().Get(echo.HeaderAccessControlAllowOrigin))
+
+	authorized := map[string]string{echo.HeaderAuthorization: "Bearer valid"}
+	res = serve(echo.PATCH, "https://app.example.com", authorized)
+	assert.Equal(t, http.StatusOK, res.Code)
+	assert.Equal(t, "https://app.example.com", res.Header().Get(echo.HeaderAccessControlAllowOrigin))
+	assert.Equal(t, "true", res.Header().Get(echo.HeaderAccessControlAllowCredentials))
+	assert.Equal(t, "X-Request-ID", res.Header().Get(echo.HeaderAccessControlExposeHeaders))
+	assert.Empty(t, res.Header().Get(echo.HeaderAccessControlAllowMethods), "only preflight lists the methods")
+
+	res = serve(echo.PATCH, "https://evil.example.com", authorized)
+	assert.Equal(t, http.StatusOK, res.Code, "the browser is left to block the response")
+	assert.Empty(t, res.Header().Get(echo.HeaderAccessControlAllowOrigin))
+
+	res = serve(echo.PATCH, "", authorized)
+	assert.Equal(t, http.StatusOK, res.Code, "requests without Origin are not cross-origin")
+	assert.Empty(t, res.Header().Get(echo.HeaderAccessControlAllowOrigin))
+	assert.Equal(t, echo.HeaderOrigin, res.Header().Get(echo.HeaderVary))
+
+	res = serve(echo.OPTIONS, "https://app.example.com", nil)
+	assert.Equal(t, http.StatusUnauthorized, res.Code, "a plain OPTIONS is not a preflight")
+}
+
+func TestCORSAnyOrigin(t *testing.T) {
+	handler := func(c echo.Context) error {
+		return c.NoContent(http.StatusOK)
+	}
+	m, err := middleware.InitMiddleware(verifierStub{}, middleware.CORSConfig{AllowOrigins: []string{"*"}})
+	require.NoError(t, err)
+	req := test.NewRequest(echo.OPTIONS, "/", nil)
+	req.Header.Set(echo.HeaderOrigin, "https://app.example.com")
+	req.Header.Set(echo.HeaderAccessControlRequestMethod, echo.GET)
 	res := test.NewRecorder()
-	c := e.NewContext(req, res)
-	m := middleware.InitMiddleware()
+	c := echo.New().NewContext(req, res)
+
+	err = m.CORS(handler)(c)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusNoContent, res.Code)
+	assert.Equal(t, "*", res.Header().Get(echo.HeaderAccessControlAllowOrigin))
+	assert.Empty(t, res.Header().Get(echo.HeaderAccessControlAllowCredentials))
+	assert.Equal(t, "GET,POST,PUT,PATCH,DELETE", res.Header().Get(echo.HeaderAccessControlAllowMethods), "defaults")
+	assert.Equal(t, "Authorization,Content-Type,Accept", res.Header().Get(echo.HeaderAccessControlAllowHeaders), "defaults")
+	assert.Empty(t, res.Header().Get(echo.HeaderAccessControlMaxAge))
+
+	_, err = middleware.InitMiddleware(verifierStub{}, middleware.CORSConfig{AllowOrigins: []string{"*"}, AllowCredentials: true})
+	assert.Error(t, err, "any origin can not be allowed with credentials")
+}
+
+func TestAuth(t *testing.T) {
+	e := echo.New()
+	m, err := middleware.InitMiddleware(verifierStub{}, corsConfig, "/api/v1/init")
+	require.NoError(t, err)
+	e.Use(m.Auth)
+	handler := func(c echo.Context) error {
+		p := auth.FromContext(c.Request().Context())
+		if p == nil {
+			return c.String(http.StatusOK, "anonymous")
+		}
+		return c.String(http.StatusOK, p.WalletID)
+	}
+	e.GET("/api/v1/wallet", handler)
+	e.POST("/api/v1/init", handler)
+
+	serve := func(method, path, authorization string) *test.ResponseRecorder {
+		req := test.NewRequest(method, path, nil)
+		if authorization != "" {
+			req.Header.Set(echo.HeaderAuthorization, authorization)
+		}
+		res := test.NewRecorder()
+		e.ServeHTTP(res, req)
+		return res
+	}
+
+	res := serve(echo.GET, "/api/v1/wallet", "Bearer valid")
+	assert.Equal(t, http.StatusOK, res.Code)
+	assert.Equal(t, "wallet-1", res.Body.String())
+
+	for _, authorization := range []string{"", "Bearer expired"} {
+		res = serve(echo.GET, "/api/v1/wallet", authorization)
+		assert.Equal(t, http.StatusUnauthorized, res.Code)
+		assert.True(t, strings.Contains(res.Body.String(), models.CodeUnauthorized), res.Body.String())
+	}
+
+	res = serve(echo.POST, "/api/v1/init", "")
+	assert.Equal(t, http.StatusOK, res.Code, "public routes are exempt")
+	assert.Equal(t, "anonymous", res.Body.String())
+
+	res = serve(echo.POST, "/api/v1/init", "Bearer valid")
+	assert.Equal(t, http.StatusOK, res.Code)
+	assert.Equal(t, "wallet-1", res.Body.String(), "a token sent to a public route is checked")
+	res = serve(echo.POST, "/api/v1/init", "Bearer expired")
+	assert.Equal(t, http.StatusUnauthorized, res.Code)
+}
+
+func TestRequireScope(t *testing.T) {
+	e := echo.New()
+	m, err := middleware.InitMiddleware(verifierStub{}, corsConfig, "/api/v1/init")
+	require.NoError(t, err)
+	e.Use(m.Auth)
+	handler := func(c echo.Context) error {
+		return c.NoContent(http.StatusOK)
+	}
+	e.GET("/api/v1/wallet", handler, middleware.RequireScope(models.ScopeWalletRead))
+	e.GET("/api/v1/admin/wallets", handler, middleware.RequireScope(models.ScopeAdminRead))
+	e.POST("/api/v1/init", handler, middleware.RequireScope(models.ScopeWalletRead))
+	e.POST("/api/v1/token/refresh", handler, middleware.RequireScope(models.ScopeTokenRefresh))
+
+	serve := func(method, path, authorization string) *test.ResponseRecorder {
+		req := test.NewRequest(method, path, nil)
+		if authorization != "" {
+			req.Header.Set(echo.HeaderAuthorization, authorization)
+		}
+		res := test.NewRecorder()
+		e.ServeHTTP(res, req)
+		return res
+	}
+
+	res := serve(echo.GET, "/api/v1/wallet", "Bearer valid")
+	assert.Equal(t, http.StatusOK, res.Code)
+
+	res = serve(echo.GET, "/api/v1/admin/wallets", "Bearer valid")
+	assert.Equal(t, http.StatusForbidden, res.Code)
+	assert.True(t, strings.Contains(res.Body.String(), models.CodeForbidden), res.Body.String())
+
+	res = serve(echo.GET, "/api/v1/admin/wallets", "Bearer support")
+	assert.Equal(t, http.StatusOK, res.Code)
+
+	res = serve(echo.POST, "/api/v1/token/refresh", "Bearer valid")
+	assert.Equal(t, http.StatusOK, res.Code)
+
+	res = serve(echo.POST, "/api/v1/token/refresh", "Bearer support")
+	assert.Equal(t, http.StatusForbidden, res.Code, "tokens minted without token:refresh cannot be refreshed")
+
+	res = serve(echo.POST, "/api/v1/init", "")
+	assert.Equal(t, http.StatusUnauthorized, res.Code, "no principal on a public route")
+}
+
+func TestRateLimiter(t *testing.T) {
+	e := echo.New()
+	m, err := middleware.InitMiddleware(verifierStub{}, corsConfig, "/api/v1/init")
+	require.NoError(t, err)
+	l, err := middleware.NewLimiter(middleware.NewMemoryStore(), middleware.RateLimitConfig{
+		Default: middleware.RateLimit{Limit: 3, Window: 60},
+		Routes: []middleware.RouteRateLimit{
+			{Method: echo.POST, Path: "/api/v1/wallet/withdrawals", RateLimit: middleware.RateLimit{Limit: 1, Window: 60}},
+		},
+	})
+	require.NoError(t, err)
+	e.Use(m.Auth)
+	e.Use(l.Client)
+	handler := func(c echo.Context) error {
+		return c.NoContent(http.StatusOK)
+	}
+	e.GET("/api/v1/wallet", handler)
+	e.POST("/api/v1/wallet/withdrawals", handler)
+	e.POST("/api/v1/init", handler)
+
+	serve := func(method, path, authorization, ip string) *test.ResponseRecorder {
+		req := test.NewRequest(method, path, nil)
+		if authorization != "" {
+			req.Header.Set(echo.HeaderAuthorization, authorization)
+		}
+		req.RemoteAddr = ip + ":40000"
+		res := test.NewRecorder()
+		e.ServeHTTP(res, req)
+		return res
+	}
+
+	res := serve(echo.POST, "/api/v1/wallet/withdrawals", "Bearer valid", "10.0.0.1")
+	assert.Equal(t, http.StatusOK, res.Code)
+	assert.Equal(t, "1", res.Header().Get(middleware.HeaderRateLimitLimit))
+	assert.Equal(t, "0", res.Header().Get(middleware.HeaderRateLimitRemaining))
+	assert.Equal(t, "60", res.Header().Get(middleware.HeaderRateLimitReset))
 
-	h := m.CORS(echo.HandlerFunc(func(c echo.Context) error {
+	res = serve(echo.POST, "/api/v1/wallet/withdrawals", "Bearer valid", "10.0.0.2")
+	assert.Equal(t, http.StatusTooManyRequests, res.Code, "the wallet is limited whatever its IP")
+	assert.Equal(t, "60", res.Header().Get(middleware.HeaderRetryAfter))
+	assert.True(t, strings.Contains(res.Body.String(), `"status":"fail"`), res.Body.String())
+	assert.True(t, strings.Contains(res.Body.String(), models.CodeRateLimited), res.Body.String())
+
+	for i := 3; i > 0; i-- {
+		res = serve(echo.GET, "/api/v1/wallet", "Bearer valid", "10.0.0.1")
+		assert.Equal(t, http.StatusOK, res.Code, "the default bucket is not the one of the route")
+		assert.Equal(t, "3", res.Header().Get(middleware.HeaderRateLimitLimit))
+	}
+	res = serve(echo.GET, "/api/v1/wallet", "Bearer valid", "10.0.0.1")
+	assert.Equal(t, http.StatusTooManyRequests, res.Code)
+	assert.Equal(t, "20", res.Header().Get(middleware.HeaderRetryAfter), "one request is earned back every 20s")
+
+	for _, ip := range []string{"10.0.0.1", "10.0.0.1", "10.0.0.1", "10.0.0.2"} {
+		res = serve(echo.POST, "/api/v1/init", "", ip)
+		assert.Equal(t, http.StatusOK, res.Code, "public routes are limited per IP")
+	}
+	res = serve(echo.POST, "/api/v1/init", "", "10.0.0.1")
+	assert.Equal(t, http.StatusTooManyRequests, res.Code)
+}
+
+func TestIPRateLimit(t *testing.T) {
+	e := echo.New()
+	m, err := middleware.InitMiddleware(verifierStub{}, corsConfig)
+	require.NoError(t, err)
+	l, err := middleware.NewLimiter(middleware.NewMemoryStore(), middleware.RateLimitConfig{
+		IP:      middleware.RateLimit{Limit: 2, Window: 60},
+		Default: middleware.RateLimit{Limit: 10, Window: 60},
+	})
+	require.NoError(t, err)
+	e.Use(l.IP)
+	e.Use(m.Auth)
+	e.Use(l.Client)
+	e.GET("/api/v1/wallet", func(c echo.Context) error {
 		return c.NoContent(http.StatusOK)
-	}))
+	})
+
+	serve := func(authorization, ip string) *test.ResponseRecorder {
+		req := test.NewRequest(echo.GET, "/api/v1/wallet", nil)
+		req.Header.Set(echo.HeaderAuthorization, authorization)
+		req.RemoteAddr = ip + ":40000"
+		res := test.NewRecorder()
+		e.ServeHTTP(res, req)
+		return res
+	}
+
+	assert.Equal(t, http.StatusUnauthorized, serve("Bearer invalid", "10.0.0.1").Code)
+	assert.Equal(t, http.StatusOK, serve("Bearer valid", "10.0.0.1").Code)
+	res := serve("Bearer invalid", "10.0.0.1")
+	assert.Equal(t, http.StatusTooManyRequests, res.Code, "the requests with an invalid token count against the IP")
+	assert.Equal(t, "30", res.Header().Get(middleware.HeaderRetryAfter))
+	assert.Equal(t, http.StatusTooManyRequests, serve("Bearer valid", "10.0.0.1").Code)
+	assert.Equal(t, http.StatusOK, serve("Bearer valid", "10.0.0.2").Code)
+}
+
+func TestRealIP(t *testing.T) {
+	_, err := middleware.NewLimiter(middleware.NewMemoryStore(), middleware.RateLimitConfig{TrustedProxies: []string{"10.0.0.0/33"}})
+	assert.Error(t, err)
+	l, err := middleware.NewLimiter(middleware.NewMemoryStore(), middleware.RateLimitConfig{
+		TrustedProxies: []string{"10.0.0.0/8", "192.168.1.1"},
+	})
+	require.NoError(t, err)
+
+	realIP := func(remoteAddr, forwardedFor, realIP string) string {
+		req := test.NewRequest(echo.GET, "/api/v1/wallet", nil)
+		req.RemoteAddr = remoteAddr
+		if forwardedFor != "" {
+			req.Header.Set(echo.HeaderXForwardedFor, forwardedFor)
+		}
+		if realIP != "" {
+			req.Header.Set(echo.HeaderXRealIP, realIP)
+		}
+		return l.RealIP(req)
+	}
+
+	assert.Equal(t, "203.0.113.9", realIP("203.0.113.9:40000", "198.51.100.1", "198.51.100.1"),
+		"the headers of a client are not believed")
+	assert.Equal(t, "198.51.100.1", realIP("10.1.2.3:40000", "", "198.51.100.1"))
+	assert.Equal(t, "203.0.113.9", realIP("10.1.2.3:40000", "198.51.100.1, 203.0.113.9, 192.168.1.1", ""),
+		"the addresses a client prepends to X-Forwarded-For are skipped")
+	assert.Equal(t, "10.1.2.3", realIP("10.1.2.3:40000", "", ""))
+}
+
+func TestMemoryStore(t *testing.T) {
+	ctx := context.Background()
+	s := middleware.NewMemoryStore()
+	limit := middleware.RateLimit{Limit: 2, Window: 10}
+	start := time.Now()
 
-	err := h(c)
+	for remaining := 1; remaining >= 0; remaining-- {
+		q, err := s.Take(ctx, "key", limit, start)
+		require.NoError(t, err)
+		assert.True(t, q.Allowed)
+		assert.Equal(t, remaining, q.Remaining)
+	}
+	q, err := s.Take(ctx, "key", limit, start.Add(time.Second))
 	require.NoError(t, err)
-	assert.Equal(t, "*", res.Header().Get("Access-Control-Allow-Origin"))
+	assert.False(t, q.Allowed)
+	assert.Equal(t, 4*time.Second, q.RetryAfter)
+	assert.Equal(t, 9*time.Second, q.Reset)
+
+	q, err = s.Take(ctx, "other", limit, start.Add(time.Second))
+	require.NoError(t, err)
+	assert.True(t, q.Allowed, "buckets are per key")
+
+	q, err = s.Take(ctx, "key", limit, start.Add(5*time.Second))
+	require.NoError(t, err)
+	assert.True(t, q.Allowed, "a token is earned back every 5s")
+	assert.Equal(t, 0, q.Remaining)
+
+	q, err = s.Take(ctx, "key", limit, start.Add(time.Hour))
+	require.NoError(t, err)
+	assert.True(t, q.Allowed)
+	assert.Equal(t, 1, q.Remaining, "the bucket does not fill past its limit")
+}
+
+func TestRequestID(t *testing.T) {
+	e := echo.New()
+	e.Use(middleware.RequestID)
+	e.GET("/", func(c echo.Context) error {
+		return c.String(http.StatusOK, requestid.FromContext(c.Request().Context()))
+	})
+
+	serve := func(id string) *test.ResponseRecorder {
+		req := test.NewRequest(echo.GET, "/", nil)
+		req.Header.Set(echo.HeaderXRequestID, id)
+		res := test.NewRecorder()
+		e.ServeHTTP(res, req)
+		return res
+	}
+
+	res := serve("req-1")
+	assert.Equal(t, "req-1", res.Header().Get(echo.HeaderXRequestID))
+	assert.Equal(t, "req-1", res.Body.String(), "the handlers get it in the context")
+
+	for _, id := range []string{"", "req\nlevel=info", strings.Repeat("a", 129)} {
+		res = serve(id)
+		generated := res.Header().Get(echo.HeaderXRequestID)
+		assert.Len(t, generated, 36, "%q is replaced", id)
+		assert.Equal(t, generated, res.Body.String())
+	}
+}
+
+func TestAccessLog(t *testing.T) {
+	var out bytes.Buffer
+	log := logrus.New()
+	log.SetOutput(&out)
+	log.SetFormatter(&logrus.JSONFormatter{})
+
+	e := echo.New()
+	m, err := middleware.InitMiddleware(verifierStub{}, corsConfig)
+	require.NoError(t, err)
+	e.Use(middleware.RequestID)
+	e.Use(middleware.AccessLog(log))
+	e.Use(m.Auth)
+	handler := func(c echo.Context) error {
+		return c.NoContent(http.StatusOK)
+	}
+	e.GET("/api/v1/wallet/holds/:hold_id", handler)
+	e.GET("/api/v1/admin/wallets", handler, middleware.RequireScope(models.ScopeAdminRead))
+
+	serve := func(path, authorization string) map[string]interface{} {
+		out.Reset()
+		req := test.NewRequest(echo.GET, path, nil)
+		req.Header.Set(echo.HeaderXRequestID, "req-1")
+		req.Header.Set(echo.HeaderAuthorization, authorization)
+		e.ServeHTTP(test.NewRecorder(), req)
+		var entry map[string]interface{}
+		require.NoError(t, json.Unmarshal(out.Bytes(), &entry), out.String())
+		return entry
+	}
+
+	entry := serve("/api/v1/wallet/holds/hold-1", "Bearer valid")
+	assert.Equal(t, "GET", entry["method"])
+	assert.Equal(t, "/api/v1/wallet/holds/:hold_id", entry["route"])
+	assert.Equal(t, "/api/v1/wallet/holds/hold-1", entry["path"])
+	assert.Equal(t, float64(http.StatusOK), entry["status"])
+	assert.Equal(t, "req-1", entry[requestid.Field])
+	assert.Equal(t, "wallet-1", entry["wallet_id"])
+	assert.Contains(t, entry, "latency_ms")
+	assert.NotContains(t, entry, "error_code")
+
+	entry = serve("/api/v1/admin/wallets", "Bearer valid")
+	assert.Equal(t, float64(http.StatusForbidden), entry["status"])
+	assert.Equal(t, models.CodeForbidden, entry["error_code"])
+
+	entry = serve("/api/v1/wallet/holds/hold-1", "Bearer expired")
+	assert.Equal(t, float64(http.StatusUnauthorized), entry["status"], "requests refused by Auth are logged")
+	assert.Equal(t, models.CodeUnauthorized, entry["error_code"])
+	assert.NotContains(t, entry, "wallet_id")
 }
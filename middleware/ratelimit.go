@@ -0,0 +1,304 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo"
+	"github.com/sirupsen/logrus"
+
+	"github.com/williamchand/my-wallet/auth"
+	"github.com/williamchand/my-wallet/models"
+)
+
+// Headers of the rate limit, following the IETF RateLimit header fields draft
+const (
+	HeaderRateLimitLimit     = "RateLimit-Limit"
+	HeaderRateLimitRemaining = "RateLimit-Remaining"
+	HeaderRateLimitReset     = "RateLimit-Reset"
+	HeaderRetryAfter         = "Retry-After"
+)
+
+// RateLimit represent a token bucket holding Limit requests, refilled evenly over Window seconds.
+// A zero Limit is not enforced.
+type RateLimit struct {
+	Limit  int `mapstructure:"limit"`
+	Window int `mapstructure:"window"`
+}
+
+// RouteRateLimit represent the RateLimit of one route, Path is the route as registered e.g. /api/v1/wallet/holds/:hold_id
+type RouteRateLimit struct {
+	Method    string `mapstructure:"method"`
+	Path      string `mapstructure:"path"`
+	RateLimit `mapstructure:",squash"`
+}
+
+// RateLimitConfig represent the rate_limit section of config.json. IP is the bucket of every request from an IP,
+// counted before the token is checked. Past it, each route in Routes has a bucket of its own per client, the other
+// routes share the Default bucket of the client.
+// The IP of a request is the address it comes from, unless that is one of the TrustedProxies (IPs or CIDRs):
+// only then are X-Forwarded-For and X-Real-IP believed.
+type RateLimitConfig struct {
+	IP             RateLimit        `mapstructure:"ip"`
+	Default        RateLimit        `mapstructure:"default"`
+	Routes         []RouteRateLimit `mapstructure:"routes"`
+	TrustedProxies []string         `mapstructure:"trusted_proxies"`
+}
+
+// Quota represent what is left of a bucket once a request is counted against it
+type Quota struct {
+	Allowed   bool
+	Remaining int
+	// Reset is how long until the bucket is full again
+	Reset time.Duration
+	// RetryAfter is how long until the next request is allowed, when this one is not
+	RetryAfter time.Duration
+}
+
+// Store represent the buckets of the rate limiter, shared by the API servers when it is not in memory
+type Store interface {
+	// Take count one request at now against the bucket of key, filled according to limit
+	Take(ctx context.Context, key string, limit RateLimit, now time.Time) (*Quota, error)
+}
+
+// Limiter count the requests of the clients against the buckets of a RateLimitConfig kept in a Store. The HTTP
+// middlewares and the gRPC interceptors share it, so a client has the same buckets whichever API it calls.
+type Limiter struct {
+	store   Store
+	ip      RateLimit
+	def     RateLimit
+	routes  map[string]RateLimit
+	proxies []*net.IPNet
+}
+
+// NewLimiter will create the Limiter of cfg keeping its buckets in store
+func NewLimiter(store Store, cfg RateLimitConfig) (*Limiter, error) {
+	routes := make(map[string]RateLimit, len(cfg.Routes))
+	for _, r := range cfg.Routes {
+		routes[r.Method+" "+r.Path] = r.RateLimit
+	}
+	proxies := make([]*net.IPNet, 0, len(cfg.TrustedProxies))
+	for _, p := range cfg.TrustedProxies {
+		if !strings.Contains(p, "/") {
+			if ip := net.ParseIP(p); ip != nil && ip.To4() != nil {
+				p += "/32"
+			} else {
+				p += "/128"
+			}
+		}
+		_, network, err := net.ParseCIDR(p)
+		if err != nil {
+			return nil, fmt.Errorf("rate_limit: trusted proxy %q: %v", p, err)
+		}
+		proxies = append(proxies, network)
+	}
+	return &Limiter{
+		store:   store,
+		ip:      cfg.IP,
+		def:     cfg.Default,
+		routes:  routes,
+		proxies: proxies,
+	}, nil
+}
+
+// TakeIP count a request from ip against its IP bucket. The Quota is nil when the IP limit is not enforced.
+func (l *Limiter) TakeIP(ctx context.Context, ip string) (RateLimit, *Quota, error) {
+	return l.take(ctx, "ip|"+ip, l.ip)
+}
+
+// Take count a request of principal, or of ip when it is anonymous, to route, "<METHOD> <path>" as in
+// RateLimitConfig.Routes. The Quota is nil when the bucket of route is not enforced.
+func (l *Limiter) Take(ctx context.Context, route string, principal *models.Principal, ip string) (RateLimit, *Quota, error) {
+	limit, ok := l.routes[route]
+	if !ok {
+		route, limit = "*", l.def
+	}
+	return l.take(ctx, route+"|"+clientKey(principal, ip), limit)
+}
+
+func (l *Limiter) take(ctx context.Context, key string, limit RateLimit) (RateLimit, *Quota, error) {
+	if limit.Limit <= 0 || limit.Window <= 0 {
+		return limit, nil, nil
+	}
+	q, err := l.store.Take(ctx, key, limit, time.Now())
+	return limit, q, err
+}
+
+// IP will handle the middleware limiting the requests of each IP, it runs before Auth so the requests with a
+// missing or invalid token are counted too
+func (l *Limiter) IP(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		limit, q, err := l.TakeIP(c.Request().Context(), l.RealIP(c.Request()))
+		return limited(c, next, limit, q, err)
+	}
+}
+
+// Client will handle the middleware limiting the requests of each client on each route, the clients are told by
+// the wallet or customer of their Principal, or by their IP when the route is public, so it runs after Auth.
+// A request over the limit is refused with 429, and the store failing lets the request through.
+func (l *Limiter) Client(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		req := c.Request()
+		limit, q, err := l.Take(req.Context(), req.Method+" "+c.Path(), auth.FromContext(req.Context()), l.RealIP(req))
+		return limited(c, next, limit, q, err)
+	}
+}
+
+// RealIP return the IP req comes from. The forwarding headers are only believed when it comes from a trusted proxy,
+// X-Forwarded-For is then read from the right, the client being the first address that is not a trusted proxy.
+func (l *Limiter) RealIP(req *http.Request) string {
+	ip := req.RemoteAddr
+	if host, _, err := net.SplitHostPort(ip); err == nil {
+		ip = host
+	}
+	if !l.trusted(ip) {
+		return ip
+	}
+	if forwarded := req.Header[echo.HeaderXForwardedFor]; len(forwarded) > 0 {
+		hops := strings.Split(strings.Join(forwarded, ","), ",")
+		for i := len(hops) - 1; i >= 0; i-- {
+			hop := strings.TrimSpace(hops[i])
+			if i == 0 || !l.trusted(hop) {
+				return hop
+			}
+		}
+	}
+	if real := strings.TrimSpace(req.Header.Get(echo.HeaderXRealIP)); real != "" {
+		return real
+	}
+	return ip
+}
+
+// trusted tell whether ip is one of the trusted proxies
+func (l *Limiter) trusted(ip string) bool {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return false
+	}
+	for _, p := range l.proxies {
+		if p.Contains(parsed) {
+			return true
+		}
+	}
+	return false
+}
+
+// limited write the RateLimit headers of q and serve the request with next, unless q refuses it
+func limited(c echo.Context, next echo.HandlerFunc, limit RateLimit, q *Quota, err error) error {
+	if err != nil {
+		logrus.WithContext(c.Request().Context()).Error(err)
+		return next(c)
+	}
+	if q == nil {
+		return next(c)
+	}
+	header := c.Response().Header()
+	header.Set(HeaderRateLimitLimit, strconv.Itoa(limit.Limit))
+	header.Set(HeaderRateLimitRemaining, strconv.Itoa(q.Remaining))
+	header.Set(HeaderRateLimitReset, Seconds(q.Reset))
+	if !q.Allowed {
+		header.Set(HeaderRetryAfter, Seconds(q.RetryAfter))
+		return fail(c, models.ErrRateLimited)
+	}
+	return next(c)
+}
+
+func clientKey(principal *models.Principal, ip string) string {
+	switch {
+	case principal == nil:
+		return "ip:" + ip
+	case principal.WalletID != "":
+		return "wallet:" + principal.WalletID
+	default:
+		return "customer:" + principal.CustomerID
+	}
+}
+
+// Seconds format d as whole seconds for the RateLimit headers, rounded up so clients do not retry too early
+func Seconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
+
+// sweepInterval is how often the memory store forgets the buckets that are full again
+const sweepInterval = time.Minute
+
+type bucket struct {
+	tokens   float64
+	capacity float64
+	// rate is the tokens added per second
+	rate float64
+	last time.Time
+}
+
+// refill add the tokens earned since the last request
+func (b *bucket) refill(now time.Time) {
+	if elapsed := now.Sub(b.last).Seconds(); elapsed > 0 {
+		b.tokens = math.Min(b.capacity, b.tokens+elapsed*b.rate)
+		b.last = now
+	}
+}
+
+type memoryStore struct {
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	swept   time.Time
+}
+
+// NewMemoryStore will create a Store keeping the buckets in memory, they are not shared between servers
+func NewMemoryStore() Store {
+	return &memoryStore{
+		buckets: make(map[string]*bucket),
+	}
+}
+
+func (s *memoryStore) Take(ctx context.Context, key string, limit RateLimit, now time.Time) (*Quota, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if now.Sub(s.swept) > sweepInterval {
+		s.sweep(now)
+	}
+
+	capacity := float64(limit.Limit)
+	rate := capacity / float64(limit.Window)
+	b, ok := s.buckets[key]
+	if !ok || b.capacity != capacity || b.rate != rate {
+		b = &bucket{tokens: capacity, capacity: capacity, rate: rate, last: now}
+		s.buckets[key] = b
+	}
+	b.refill(now)
+
+	q := new(Quota)
+	if b.tokens >= 1 {
+		b.tokens--
+		q.Allowed = true
+	} else {
+		q.RetryAfter = toDuration((1 - b.tokens) / rate)
+	}
+	q.Remaining = int(b.tokens)
+	q.Reset = toDuration((capacity - b.tokens) / rate)
+
+	return q, nil
+}
+
+// sweep drop the buckets that refilled, they are created full again on the next request
+func (s *memoryStore) sweep(now time.Time) {
+	for key, b := range s.buckets {
+		b.refill(now)
+		if b.tokens >= b.capacity {
+			delete(s.buckets, key)
+		}
+	}
+	s.swept = now
+}
+
+func toDuration(secs float64) time.Duration {
+	return time.Duration(secs * float64(time.Second))
+}
@@ -0,0 +1,65 @@
+package middleware
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo"
+	"github.com/sirupsen/logrus"
+
+	"github.com/williamchand/my-wallet/auth"
+	"github.com/williamchand/my-wallet/requestid"
+)
+
+// ErrorCodeKey is the key of the echo context holding the code of the error a request failed with, for AccessLog
+const ErrorCodeKey = "error_code"
+
+// RequestID will take the X-Request-ID of the request, or generate one when it is missing or malformed, echo it in
+// the response and put it in the request context so the logs of the usecases and repositories carry it,
+// see requestid.Hook
+func RequestID(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		req := c.Request()
+		id := req.Header.Get(echo.HeaderXRequestID)
+		if !requestid.Valid(id) {
+			id = uuid.New().String()
+		}
+		c.Response().Header().Set(echo.HeaderXRequestID, id)
+		c.SetRequest(req.WithContext(requestid.NewContext(req.Context(), id)))
+		return next(c)
+	}
+}
+
+// AccessLog will create the middleware writing one entry per request to log, with its method, route, status,
+// latency, request ID, and the wallet and error code when there are. It runs after RequestID and before the other
+// middlewares, so the requests they refuse are logged too.
+func AccessLog(log *logrus.Logger) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			start := time.Now()
+			if err := next(c); err != nil {
+				c.Error(err)
+			}
+
+			req := c.Request()
+			fields := logrus.Fields{
+				"method":     req.Method,
+				"route":      c.Path(),
+				"path":       req.URL.Path,
+				"status":     c.Response().Status,
+				"latency_ms": float64(time.Since(start)) / float64(time.Millisecond),
+			}
+			if id := requestid.FromContext(req.Context()); id != "" {
+				fields[requestid.Field] = id
+			}
+			if principal := auth.FromContext(req.Context()); principal != nil && principal.WalletID != "" {
+				fields["wallet_id"] = principal.WalletID
+			}
+			if code, ok := c.Get(ErrorCodeKey).(string); ok {
+				fields["error_code"] = code
+			}
+			log.WithFields(fields).Info("access")
+			return nil
+		}
+	}
+}
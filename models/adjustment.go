@@ -0,0 +1,25 @@
+package models
+
+import "time"
+
+// ReqAdjustment represent a manual credit or debit an operator posts on a wallet,
+// Reason is mandatory and kept on the transaction along with the operator in Actor
+type ReqAdjustment struct {
+	ReferenceID string `json:"reference_id" validate:"required,excludes=#"`
+	Amount      int64  `json:"amount" validate:"required,gt=0"`
+	Reason      string `json:"reason" validate:"required,max=255"`
+	Actor       string `json:"actor" validate:"required,max=100"`
+}
+
+// Adjustment represent a manual credit or debit of a wallet
+type Adjustment struct {
+	ReferenceID string    `json:"reference_id"`
+	ID          string    `json:"wallet_id"`
+	Type        string    `json:"type"`
+	Amount      int64     `json:"amount"`
+	Currency    string    `json:"currency"`
+	Status      string    `json:"status"`
+	Reason      string    `json:"reason"`
+	AdjustedBy  string    `json:"adjusted_by"`
+	AdjustedAt  time.Time `json:"adjusted_at"`
+}
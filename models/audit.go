@@ -0,0 +1,36 @@
+package models
+
+import "time"
+
+// Actions recorded in the audit log, one for each admin endpoint and walletctl command changing a wallet
+const (
+	AuditLookupWallet       = "wallet.lookup"
+	AuditLookupWallets      = "wallet.lookup_by_owner"
+	AuditFetchTransactions  = "wallet.transactions"
+	AuditFetchAuditEntries  = "wallet.audit"
+	AuditForceDisableWallet = "wallet.force_disable"
+	AuditForceEnableWallet  = "wallet.force_enable"
+	AuditAdjustWallet       = "wallet.adjust"
+	AuditReverseTransaction = "transaction.reverse"
+)
+
+// AuditEntry represent an action of the support staff, Actor is who took it and WalletID the wallet it was
+// taken on, if any. Detail holds what else identifies the action, such as the owner looked up or a reason.
+type AuditEntry struct {
+	ID        int64     `json:"id"`
+	Actor     string    `json:"actor"`
+	Action    string    `json:"action"`
+	WalletID  string    `json:"wallet_id,omitempty"`
+	Detail    string    `json:"detail,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ReqForceDisable represent the body of an admin disabling a wallet, the reason is kept in the audit log
+type ReqForceDisable struct {
+	Reason string `json:"reason" validate:"required,max=255"`
+}
+
+// ReqForceEnable represent an operator enabling a wallet back, the reason is kept in the audit log
+type ReqForceEnable struct {
+	Reason string `json:"reason" validate:"required,max=255"`
+}
@@ -1,18 +1,156 @@
 package models
 
-import "errors"
+import (
+	"errors"
+	"net/http"
+)
+
+// Error codes returned to clients, they stay the same when the messages change
+const (
+	CodeInternal           = "INTERNAL_ERROR"
+	CodeNotFound           = "NOT_FOUND"
+	CodeConflict           = "CONFLICT"
+	CodeDuplicateReference = "DUPLICATE_REFERENCE"
+	CodeInvalidParameter   = "INVALID_PARAMETER"
+	CodeInvalidBody        = "INVALID_BODY"
+	CodeWalletEnabled      = "WALLET_ALREADY_ENABLED"
+	CodeWalletDisabled     = "WALLET_DISABLED"
+	CodeUnauthorized       = "UNAUTHORIZED"
+	CodeForbidden          = "FORBIDDEN"
+	CodeCurrencyMismatch   = "CURRENCY_MISMATCH"
+	CodeRateUnavailable    = "RATE_UNAVAILABLE"
+	CodeQuoteExpired       = "QUOTE_EXPIRED"
+	CodeLimitExceeded      = "LIMIT_EXCEEDED"
+	CodeInsufficientFunds  = "INSUFFICIENT_FUNDS"
+	CodeRateLimited        = "RATE_LIMITED"
+)
 
 var (
 	// ErrInternalServerError will throw if any the Internal Server Error happen
-	ErrInternalServerError = errors.New("Internal Server Error")
+	ErrInternalServerError = NewError(CodeInternal, http.StatusInternalServerError, "Internal Server Error")
 	// ErrNotFound will throw if the requested item is not exists
-	ErrNotFound = errors.New("Your requested Item is not found")
+	ErrNotFound = NewError(CodeNotFound, http.StatusNotFound, "Your requested Item is not found")
 	// ErrConflict will throw if the current action already exists
-	ErrConflict = errors.New("Your Item already exist")
+	ErrConflict = NewError(CodeConflict, http.StatusConflict, "Your Item already exist")
+	// ErrDuplicateReference will throw if a reference_id is reused for a different request
+	ErrDuplicateReference = NewError(CodeDuplicateReference, http.StatusConflict, "reference_id is already used by another request")
 	// ErrBadParamInput will throw if the given request-body or params is not valid
-	ErrBadParamInput = errors.New("Given Param is not valid")
+	ErrBadParamInput = NewError(CodeInvalidParameter, http.StatusBadRequest, "Given Param is not valid")
 	// ErrAlreadyEnabled will throw if the wallet is enabled
-	ErrAlreadyEnabled = errors.New("Already enabled")
+	ErrAlreadyEnabled = NewError(CodeWalletEnabled, http.StatusBadRequest, "Already enabled")
 	// ErrDisabled will throw if the wallet is disabled
-	ErrDisabled = errors.New("Disabled")
+	ErrDisabled = NewError(CodeWalletDisabled, http.StatusNotFound, "Disabled")
+	// ErrUnauthorized will throw if the Authorization header is missing or holds an invalid token
+	ErrUnauthorized = NewError(CodeUnauthorized, http.StatusUnauthorized, "Unauthorized")
+	// ErrForbidden will throw if the token is valid but does not grant the scope the action needs
+	ErrForbidden = NewError(CodeForbidden, http.StatusForbidden, "Forbidden")
+	// ErrUnbalancedJournal will throw if the debits and credits posted for a transaction do not cancel out
+	ErrUnbalancedJournal = NewError(CodeInternal, http.StatusInternalServerError, "Unbalanced journal")
+	// ErrCurrencyMismatch will throw if an operation mixes amounts or wallets of different currencies
+	ErrCurrencyMismatch = NewError(CodeCurrencyMismatch, http.StatusUnprocessableEntity, "Currency mismatch")
+	// ErrRateUnavailable will throw if no exchange rate is known between the two currencies
+	ErrRateUnavailable = NewError(CodeRateUnavailable, http.StatusUnprocessableEntity, "Exchange rate unavailable")
+	// ErrQuoteExpired will throw if a conversion uses a quote past its expiry
+	ErrQuoteExpired = NewError(CodeQuoteExpired, http.StatusUnprocessableEntity, "Quote expired")
+	// ErrLimitExceeded will throw if a transaction goes over one of the wallet limits, see LimitError
+	ErrLimitExceeded = NewError(CodeLimitExceeded, http.StatusUnprocessableEntity, "Limit exceeded")
+	// ErrInsufficientFunds will throw if the available balance of the wallet does not cover a transaction, see FundsError
+	ErrInsufficientFunds = NewError(CodeInsufficientFunds, http.StatusUnprocessableEntity, "Insufficient funds")
+	// ErrRateLimited will throw if the client sent more requests than its rate limit allows
+	ErrRateLimited = NewError(CodeRateLimited, http.StatusTooManyRequests, "Too many requests")
 )
+
+// Error represent a domain error. Code is stable for clients to match on, Status is the HTTP status it maps to
+// and Message is safe to return to them. Err is the cause, it is logged but never returned to clients.
+type Error struct {
+	Code    string
+	Status  int
+	Message string
+	Err     error
+}
+
+// NewError will create an Error without a cause
+func NewError(code string, status int, message string) *Error {
+	return &Error{Code: code, Status: status, Message: message}
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return e.Message + ": " + e.Err.Error()
+	}
+	return e.Message
+}
+
+// Unwrap return the cause of e
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Is tell whether target is e or the Error e was copied from by Wrap, so errors.Is matches the wrapped sentinels
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Code == e.Code && t.Message == e.Message
+}
+
+// Wrap return a copy of e caused by err
+func (e *Error) Wrap(err error) *Error {
+	wrapped := *e
+	wrapped.Err = err
+	return &wrapped
+}
+
+// ErrorOf return err as an Error: an Error as it is, a LimitError as ErrLimitExceeded, a FundsError as
+// ErrInsufficientFunds, and anything else as ErrInternalServerError caused by err, so the text of driver
+// errors does not reach clients. They are found in the chain of err, so errors wrapped with %w map the same.
+func ErrorOf(err error) *Error {
+	if err == nil {
+		return nil
+	}
+	var e *Error
+	if errors.As(err, &e) {
+		return e
+	}
+	var limit *LimitError
+	if errors.As(err, &limit) {
+		return ErrLimitExceeded.Wrap(limit)
+	}
+	var funds *FundsError
+	if errors.As(err, &funds) {
+		return ErrInsufficientFunds.Wrap(funds)
+	}
+	return ErrInternalServerError.Wrap(err)
+}
+
+// MIMEProblemJSON is the media type of Problem
+const MIMEProblemJSON = "application/problem+json"
+
+// problemTypePrefix prefix the code of an Error to make the type of its Problem
+const problemTypePrefix = "urn:my-wallet:error:"
+
+// Problem represent an Error in the RFC 7807 problem details format, Code, Limit and Funds are extension members
+type Problem struct {
+	Type     string      `json:"type"`
+	Title    string      `json:"title"`
+	Status   int         `json:"status"`
+	Instance string      `json:"instance,omitempty"`
+	Code     string      `json:"code"`
+	Limit    *LimitError `json:"limit,omitempty"`
+	Funds    *FundsError `json:"funds,omitempty"`
+}
+
+// NewProblem will create the Problem of e raised at instance, with the LimitError or FundsError behind e if any
+func NewProblem(e *Error, instance string) *Problem {
+	var limit *LimitError
+	var funds *FundsError
+	errors.As(e.Err, &limit)
+	errors.As(e.Err, &funds)
+	return &Problem{
+		Type:     problemTypePrefix + e.Code,
+		Title:    e.Message,
+		Status:   e.Status,
+		Instance: instance,
+		Code:     e.Code,
+		Limit:    limit,
+		Funds:    funds,
+	}
+}
@@ -0,0 +1,18 @@
+package models
+
+// ReqFeeQuote represent the body of a fee dry run, an empty Currency means the wallet's own one
+type ReqFeeQuote struct {
+	Operation string `json:"operation" validate:"required,oneof=withdrawal transfer"`
+	Amount    int64  `json:"amount" validate:"required,gt=0"`
+	Currency  string `json:"currency" validate:"omitempty,len=3"`
+}
+
+// FeeQuote represent what an operation would cost without running it, GrossAmount is what leaves the
+// wallet, NetAmount what reaches the other side and Fee the difference
+type FeeQuote struct {
+	Operation   string `json:"operation"`
+	GrossAmount int64  `json:"gross_amount"`
+	Fee         int64  `json:"fee"`
+	NetAmount   int64  `json:"net_amount"`
+	Currency    string `json:"currency"`
+}
@@ -0,0 +1,17 @@
+package models
+
+// ReasonInsufficientFunds is the reason recorded on a transaction refused for a lack of funds
+const ReasonInsufficientFunds = "insufficient funds"
+
+// FundsError represent a transaction refused because the available balance of the wallet does not cover it.
+// It reads as ErrInsufficientFunds and tells how much is available and how much the transaction needed,
+// fee included, both in minor units of Currency.
+type FundsError struct {
+	Available int64  `json:"available"`
+	Required  int64  `json:"required"`
+	Currency  string `json:"currency"`
+}
+
+func (e *FundsError) Error() string {
+	return ErrInsufficientFunds.Error()
+}
@@ -0,0 +1,60 @@
+package models
+
+import "time"
+
+// Quote represent an exchange rate locked until ExpiresAt for converting Amount out of a wallet.
+// Rate is the provider rate in major units of ToCurrency per major unit of Currency, the spread
+// taken on top of it is reported as Fee in minor units of ToCurrency.
+type Quote struct {
+	ID              string    `json:"quote_id"`
+	WalletID        string    `json:"wallet_id"`
+	Amount          int64     `json:"amount"`
+	Currency        string    `json:"currency"`
+	ConvertedAmount int64     `json:"converted_amount"`
+	ToCurrency      string    `json:"to_currency"`
+	Rate            string    `json:"rate"`
+	SpreadBps       int64     `json:"spread_bps"`
+	Fee             int64     `json:"fee"`
+	Rounding        string    `json:"rounding"`
+	ReferenceID     string    `json:"reference_id,omitempty"`
+	ExpiresAt       time.Time `json:"expires_at"`
+	CreatedBy       string    `json:"created_by"`
+	CreatedAt       time.Time `json:"created_at"`
+}
+
+// Expired tell whether the quote can no longer be used at now
+func (q *Quote) Expired(now time.Time) bool {
+	return !now.Before(q.ExpiresAt)
+}
+
+// ReqQuote represent the body of a quote, Amount is in minor units of the wallet currency
+type ReqQuote struct {
+	Amount     int64  `json:"amount" validate:"required,gt=0"`
+	Currency   string `json:"currency" validate:"omitempty,len=3"`
+	ToCurrency string `json:"to_currency" validate:"required,len=3"`
+}
+
+// ReqConversion represent the body of a conversion at a quoted rate
+type ReqConversion struct {
+	ReferenceID string `json:"reference_id" validate:"required,excludes=#"`
+	QuoteID     string `json:"quote_id" validate:"required"`
+}
+
+// Conversion represent money moved between two wallets of the same customer in different currencies
+type Conversion struct {
+	ReferenceID     string    `json:"reference_id"`
+	QuoteID         string    `json:"quote_id"`
+	From            string    `json:"from_wallet_id"`
+	To              string    `json:"to_wallet_id"`
+	Amount          int64     `json:"amount"`
+	Currency        string    `json:"currency"`
+	ConvertedAmount int64     `json:"converted_amount"`
+	ToCurrency      string    `json:"to_currency"`
+	Rate            string    `json:"rate"`
+	SpreadBps       int64     `json:"spread_bps"`
+	Fee             int64     `json:"fee"`
+	Rounding        string    `json:"rounding"`
+	Status          string    `json:"status"`
+	ConvertedBy     string    `json:"converted_by"`
+	ConvertedAt     time.Time `json:"converted_at"`
+}
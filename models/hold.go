@@ -0,0 +1,47 @@
+package models
+
+import "time"
+
+// Hold statuses, an active hold past its expiry is reported and treated as expired
+const (
+	HoldStatusActive   = "active"
+	HoldStatusCaptured = "captured"
+	HoldStatusVoided   = "voided"
+	HoldStatusExpired  = "expired"
+)
+
+// Hold represent funds reserved on a wallet until they are captured, voided or the hold expires
+type Hold struct {
+	ID          string    `json:"hold_id"`
+	ReferenceID string    `json:"reference_id"`
+	WalletID    string    `json:"wallet_id"`
+	Amount      int64     `json:"amount"`
+	Currency    string    `json:"currency"`
+	Captured    int64     `json:"captured_amount"`
+	Status      string    `json:"status"`
+	ExpiresAt   time.Time `json:"expires_at"`
+	CreatedBy   string    `json:"created_by"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// Expire report an active hold past its expiry as expired
+func (h *Hold) Expire(now time.Time) {
+	if h.Status == HoldStatusActive && !now.Before(h.ExpiresAt) {
+		h.Status = HoldStatusExpired
+		h.UpdatedAt = h.ExpiresAt
+	}
+}
+
+// ReqHold represent the body of a new hold, ExpiresIn is in seconds
+type ReqHold struct {
+	ReferenceID string `json:"reference_id" validate:"required,excludes=#"`
+	Amount      int64  `json:"amount" validate:"required,gt=0"`
+	Currency    string `json:"currency" validate:"omitempty,len=3"`
+	ExpiresIn   int64  `json:"expires_in" validate:"omitempty,gt=0"`
+}
+
+// ReqCapture represent the body of a capture, a zero Amount captures the whole hold
+type ReqCapture struct {
+	Amount int64 `json:"amount" validate:"omitempty,gt=0"`
+}
@@ -0,0 +1,97 @@
+package models
+
+import "time"
+
+// System accounts of the ledger. Wallet balances are what the company owes its
+// customers, so a wallet account grows with credits and shrinks with debits.
+const (
+	AccountCashIn  = "system:cash_in"
+	AccountCashOut = "system:cash_out"
+	AccountFees    = "system:fees"
+	// AccountFX is the currency position of the company, it takes one currency in and pays the other out on conversions
+	AccountFX = "system:fx"
+	// AccountAdjustments balances the manual credits and debits operators post on wallets
+	AccountAdjustments = "system:adjustments"
+	// AccountOpening balances the opening journals of the wallets funded before the ledger, posted by wallet.sql
+	AccountOpening = "system:opening"
+
+	walletAccountPrefix = "wallet:"
+)
+
+// WalletAccount return the ledger account of the given wallet
+func WalletAccount(walletID string) string {
+	return walletAccountPrefix + walletID
+}
+
+// WalletOfAccount return the wallet id of a wallet account, and false for system accounts
+func WalletOfAccount(account string) (string, bool) {
+	if len(account) <= len(walletAccountPrefix) || account[:len(walletAccountPrefix)] != walletAccountPrefix {
+		return "", false
+	}
+	return account[len(walletAccountPrefix):], true
+}
+
+// LedgerEntry represent one debit or credit line of the journal
+type LedgerEntry struct {
+	TransactionID int64     `json:"-"`
+	Account       string    `json:"account"`
+	Debit         int64     `json:"debit"`
+	Credit        int64     `json:"credit"`
+	Currency      string    `json:"currency"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// Journal represent the entries posted for one transaction
+type Journal []*LedgerEntry
+
+// NewJournal will move amount from the debited account to the credited one
+func NewJournal(debit, credit string, amount Money) Journal {
+	return Journal{
+		{Account: debit, Debit: amount.Amount, Currency: amount.Currency},
+		{Account: credit, Credit: amount.Amount, Currency: amount.Currency},
+	}
+}
+
+// Balanced tell whether the debits and credits of the journal cancel out in every currency
+func (j Journal) Balanced() bool {
+	sum := make(map[string]int64)
+	for _, e := range j {
+		sum[e.Currency] += e.Debit - e.Credit
+	}
+	for _, s := range sum {
+		if s != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// AccountBalance represent the debit and credit totals of one ledger account in one currency
+type AccountBalance struct {
+	Account  string `json:"account"`
+	Currency string `json:"currency"`
+	Debit    int64  `json:"debit"`
+	Credit   int64  `json:"credit"`
+}
+
+// CurrencyTotal represent the debit and credit totals of the whole ledger in one currency
+type CurrencyTotal struct {
+	Currency string `json:"currency"`
+	Debit    int64  `json:"debit"`
+	Credit   int64  `json:"credit"`
+}
+
+// WalletMismatch represent a wallet whose stored balance differs from its ledger account
+type WalletMismatch struct {
+	WalletID      string `json:"wallet_id"`
+	Balance       int64  `json:"balance"`
+	LedgerBalance int64  `json:"ledger_balance"`
+}
+
+// TrialBalance represent the result of checking the whole ledger
+type TrialBalance struct {
+	Accounts   []*AccountBalance `json:"accounts"`
+	Totals     []*CurrencyTotal  `json:"totals"`
+	Mismatches []*WalletMismatch `json:"mismatches"`
+	Balanced   bool              `json:"balanced"`
+}
@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+// Usage represent the transactions of a wallet within a window, First is the oldest of them
+type Usage struct {
+	Count  int64
+	Amount int64
+	First  time.Time
+}
+
+// LimitError represent a transaction refused by a wallet limit. It reads as ErrLimitExceeded and tells
+// which limit was hit, how much of it is used and, for limits over a window, when it resets.
+type LimitError struct {
+	Limit    string     `json:"limit"`
+	Max      int64      `json:"max"`
+	Used     int64      `json:"used"`
+	Currency string     `json:"currency"`
+	ResetsAt *time.Time `json:"resets_at,omitempty"`
+}
+
+func (e *LimitError) Error() string {
+	return ErrLimitExceeded.Error()
+}
@@ -0,0 +1,37 @@
+package models
+
+// DefaultCurrency is used wherever a request does not name a currency
+const DefaultCurrency = "IDR"
+
+// currencies map the supported ISO 4217 codes to their exponent, the number of minor unit digits
+var currencies = map[string]int{
+	"IDR": 2,
+	"USD": 2,
+	"SGD": 2,
+}
+
+// ValidCurrency tell whether code is a supported ISO 4217 currency code
+func ValidCurrency(code string) bool {
+	_, ok := currencies[code]
+	return ok
+}
+
+// Exponent return the number of minor unit digits of the currency
+func Exponent(code string) int {
+	return currencies[code]
+}
+
+// Money represent an amount in minor units of a currency, e.g. 1050 USD is $10.50
+type Money struct {
+	Amount   int64  `json:"amount"`
+	Currency string `json:"currency"`
+}
+
+// MatchCurrency return the currency an operation on a wallet of walletCurrency runs in,
+// an empty requested currency means the wallet's own one
+func MatchCurrency(requested string, walletCurrency string) (string, error) {
+	if requested != "" && requested != walletCurrency {
+		return "", ErrCurrencyMismatch
+	}
+	return walletCurrency, nil
+}
@@ -0,0 +1,28 @@
+package models
+
+import "time"
+
+// Outbox statuses, an event is dead once it ran out of attempts
+const (
+	OutboxStatusPending = "pending"
+	OutboxStatusSent    = "sent"
+	OutboxStatusDead    = "dead"
+)
+
+// OutboxEntry represent an event recorded together with the change it is about, waiting to be published.
+// Seq follows the order the events were recorded in.
+type OutboxEntry struct {
+	Seq           int64
+	Event         *Event
+	Status        string
+	Attempts      int
+	NextAttemptAt time.Time
+	LastError     string
+}
+
+// WalletStatus represent the data of the wallet.enabled and wallet.disabled events
+type WalletStatus struct {
+	ID        string    `json:"wallet_id"`
+	Status    string    `json:"status"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
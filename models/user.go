@@ -1,11 +1,69 @@
 package models
 
-// User represent the user model
+import "time"
+
+// Scopes granted to tokens, wallet owners operate their own wallet and the support staff any wallet through the admin API
+const (
+	ScopeWalletRead  = "wallet:read"
+	ScopeWalletWrite = "wallet:write"
+	ScopeAdminRead   = "admin:read"
+	ScopeAdminAdjust = "admin:adjust"
+	// ScopeTokenRefresh lets a token be exchanged for a new one, within the session it was issued in
+	ScopeTokenRefresh = "token:refresh"
+)
+
+// OwnerScopes are the scopes of the tokens issued to wallet owners
+var OwnerScopes = []string{ScopeWalletRead, ScopeWalletWrite, ScopeTokenRefresh}
+
+// User represent the user model, AuthTime is when its session started and is zero for a new one
 type User struct {
-	ID   string `json:"id"`
-	Name string `json:"name"`
+	ID       string    `json:"id"`
+	Name     string    `json:"name"`
+	WalletID string    `json:"wallet_id"`
+	Scopes   []string  `json:"scopes"`
+	AuthTime time.Time `json:"-"`
+}
+
+// Principal represent the authenticated caller of a request, the customer CustomerID operating the wallet WalletID
+// with the Scopes granted to its token. AuthTime is when the first token of its session was issued.
+type Principal struct {
+	CustomerID string    `json:"customer_id"`
+	Name       string    `json:"name"`
+	WalletID   string    `json:"wallet_id"`
+	Scopes     []string  `json:"scopes"`
+	AuthTime   time.Time `json:"-"`
+}
+
+// User return the user the token of p was issued to, to issue it a new one in the same session
+func (p *Principal) User() *User {
+	return &User{ID: p.CustomerID, Name: p.Name, WalletID: p.WalletID, Scopes: p.Scopes, AuthTime: p.AuthTime}
 }
 
+// HasScope tell whether the token of p grants scope
+func (p *Principal) HasScope(scope string) bool {
+	for _, s := range p.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// Customer represent the body of a wallet initialization, a customer holds one wallet per currency
 type Customer struct {
-	ID string `json:"customer_id"`
+	ID       string `json:"customer_id"`
+	Currency string `json:"currency"`
+}
+
+// Token represent the signed credential returned to the wallet owner
+type Token struct {
+	Token     string    `json:"token"`
+	Type      string    `json:"token_type"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// Account represent an initialized wallet together with the token to operate it
+type Account struct {
+	Wallet *FetchWallet `json:"wallet"`
+	Token  *Token       `json:"token"`
 }
@@ -9,16 +9,21 @@ type Wallet struct {
 	ID        string    `json:"wallet_id"`
 	Status    string    `json:"status"`
 	Balance   int64     `json:"balance"`
+	Currency  string    `json:"currency"`
 	OwnedBy   string    `json:"owned_by"`
 	UpdatedAt time.Time `json:"updated_at"`
 }
 
+// FetchWallet represent an enabled wallet, Balance is the ledger balance
+// and AvailableBalance what is left of it once the active holds are reserved
 type FetchWallet struct {
-	ID        string    `json:"id"`
-	OwnedBy   string    `json:"owned_by"`
-	Status    string    `json:"status"`
-	EnabledAt time.Time `json:"enabled_at"`
-	Balance   int64     `json:"balance"`
+	ID               string    `json:"id"`
+	OwnedBy          string    `json:"owned_by"`
+	Status           string    `json:"status"`
+	EnabledAt        time.Time `json:"enabled_at"`
+	Balance          int64     `json:"balance"`
+	AvailableBalance int64     `json:"available_balance"`
+	Currency         string    `json:"currency"`
 }
 
 type WalletDisabled struct {
@@ -27,37 +32,139 @@ type WalletDisabled struct {
 	Status     string    `json:"status"`
 	DisabledAt time.Time `json:"disabled_at"`
 	Balance    int64     `json:"balance"`
+	Currency   string    `json:"currency"`
 }
 
+// Transaction types and statuses stored in the transaction table
+const (
+	TransactionTypeDeposit     = "deposit"
+	TransactionTypeWithdrawal  = "withdrawal"
+	TransactionTypeTransferOut = "transfer_out"
+	TransactionTypeTransferIn  = "transfer_in"
+	TransactionTypeCapture     = "capture"
+	TransactionTypeReversal    = "reversal"
+	TransactionTypeConvertOut  = "conversion_out"
+	TransactionTypeConvertIn   = "conversion_in"
+	TransactionTypeFee         = "fee"
+	TransactionTypeCredit      = "manual_credit"
+	TransactionTypeDebit       = "manual_debit"
+	// TransactionTypeOpening carries the balance a wallet had before the ledger, see AccountOpening
+	TransactionTypeOpening = "opening"
+
+	TransactionStatusSuccess           = "success"
+	TransactionStatusFailed            = "failed"
+	TransactionStatusReversed          = "reversed"
+	TransactionStatusPartiallyReversed = "partially_reversed"
+)
+
 type Transaction struct {
+	RowID       int64     `json:"-"`
+	ParentID    int64     `json:"-"`
 	ReferenceID string    `json:"reference_id"`
 	ID          string    `json:"wallet_id"`
-	Type        bool      `json:"type"`
+	Type        string    `json:"type"`
 	Amount      int64     `json:"amount"`
+	Currency    string    `json:"currency"`
 	Status      string    `json:"status"`
+	QuoteID     string    `json:"quote_id,omitempty"`
+	Reason      string    `json:"reason,omitempty"`
 	CreatedBy   string    `json:"created_by"`
 	CreatedAt   time.Time `json:"created_at"`
 }
 
+// Money return the amount of the transaction along with its currency
+func (t *Transaction) Money() Money {
+	return Money{Amount: t.Amount, Currency: t.Currency}
+}
+
 type TransactionDeposit struct {
 	ReferenceID string    `json:"reference_id"`
 	ID          string    `json:"id"`
 	Amount      int64     `json:"amount"`
+	Currency    string    `json:"currency"`
 	Status      string    `json:"status"`
 	DepositBy   string    `json:"deposited_by"`
 	DepositAt   time.Time `json:"deposited_at"`
 }
 
+// TransactionWithdraw represent a withdrawal, the wallet is debited GrossAmount: the NetAmount paid out and the Fee
 type TransactionWithdraw struct {
 	ReferenceID string    `json:"reference_id"`
 	ID          string    `json:"id"`
 	Amount      int64     `json:"amount"`
+	GrossAmount int64     `json:"gross_amount"`
+	Fee         int64     `json:"fee"`
+	NetAmount   int64     `json:"net_amount"`
+	Currency    string    `json:"currency"`
 	Status      string    `json:"status"`
 	WithdrawnBy string    `json:"withdrawn_by"`
 	WithdrawnAt time.Time `json:"withdrawn_at"`
 }
 
+// ReqTransaction represent the body of a deposit or a withdrawal, Amount is in minor units of Currency
+// and an empty Currency means the wallet's own one
 type ReqTransaction struct {
-	ReferenceID string `json:"reference_id" validate:"required"`
-	Amount      int64  `json:"amount" validate:"required"`
+	ReferenceID string `json:"reference_id" validate:"required,excludes=#"`
+	Amount      int64  `json:"amount" validate:"required,gt=0"`
+	Currency    string `json:"currency" validate:"omitempty,len=3"`
+}
+
+// ReqTransfer represent the body of a transfer to another wallet
+type ReqTransfer struct {
+	ReferenceID string `json:"reference_id" validate:"required,excludes=#"`
+	WalletID    string `json:"wallet_id" validate:"required"`
+	Amount      int64  `json:"amount" validate:"required,gt=0"`
+	Currency    string `json:"currency" validate:"omitempty,len=3"`
+}
+
+// Transfer represent a wallet to wallet transfer, the sender is debited GrossAmount and the receiver credited NetAmount
+type Transfer struct {
+	ReferenceID   string    `json:"reference_id"`
+	From          string    `json:"from_wallet_id"`
+	To            string    `json:"to_wallet_id"`
+	Amount        int64     `json:"amount"`
+	GrossAmount   int64     `json:"gross_amount"`
+	Fee           int64     `json:"fee"`
+	NetAmount     int64     `json:"net_amount"`
+	Currency      string    `json:"currency"`
+	Status        string    `json:"status"`
+	TransferredBy string    `json:"transferred_by"`
+	TransferredAt time.Time `json:"transferred_at"`
+}
+
+// ReqReversal represent the body of a reversal, a zero Amount reverses whatever is left of the original
+type ReqReversal struct {
+	ReferenceID string `json:"reference_id" validate:"required,excludes=#"`
+	Amount      int64  `json:"amount" validate:"omitempty,gt=0"`
+}
+
+// Reversal represent a compensating transaction and the status it left the original in
+type Reversal struct {
+	ReferenceID         string    `json:"reference_id"`
+	OriginalReferenceID string    `json:"original_reference_id"`
+	ID                  string    `json:"wallet_id"`
+	Amount              int64     `json:"amount"`
+	Currency            string    `json:"currency"`
+	Status              string    `json:"status"`
+	OriginalStatus      string    `json:"original_status"`
+	ReversedBy          string    `json:"reversed_by"`
+	ReversedAt          time.Time `json:"reversed_at"`
+}
+
+// TransactionFilter represent the query of the transaction history, zero values are not filtered on
+type TransactionFilter struct {
+	Type      string
+	Status    string
+	MinAmount int64
+	MaxAmount int64
+	From      time.Time
+	To        time.Time
+	Cursor    string
+	Limit     int
+}
+
+// TransactionList represent one page of the transaction history
+type TransactionList struct {
+	Transactions []*Transaction `json:"transactions"`
+	NextCursor   string         `json:"next_cursor,omitempty"`
 }
@@ -0,0 +1,139 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Event types a webhook subscription can filter on, EventAll matches every one of them
+const (
+	EventWalletEnabled       = "wallet.enabled"
+	EventWalletDisabled      = "wallet.disabled"
+	EventDepositSucceeded    = "deposit.succeeded"
+	EventWithdrawalSucceeded = "withdrawal.succeeded"
+	EventWithdrawalFailed    = "withdrawal.failed"
+	EventTransferSucceeded   = "transfer.succeeded"
+	EventTransferFailed      = "transfer.failed"
+	EventTransferReceived    = "transfer.received"
+	EventReversalSucceeded   = "reversal.succeeded"
+	EventHoldCreated         = "hold.created"
+	EventHoldCaptured        = "hold.captured"
+	EventHoldVoided          = "hold.voided"
+	EventConversionSucceeded = "conversion.succeeded"
+	EventBalanceAdjusted     = "balance.adjusted"
+
+	EventAll = "*"
+)
+
+var eventTypes = map[string]bool{
+	EventWalletEnabled:       true,
+	EventWalletDisabled:      true,
+	EventDepositSucceeded:    true,
+	EventWithdrawalSucceeded: true,
+	EventWithdrawalFailed:    true,
+	EventTransferSucceeded:   true,
+	EventTransferFailed:      true,
+	EventTransferReceived:    true,
+	EventReversalSucceeded:   true,
+	EventHoldCreated:         true,
+	EventHoldCaptured:        true,
+	EventHoldVoided:          true,
+	EventConversionSucceeded: true,
+	EventBalanceAdjusted:     true,
+	EventAll:                 true,
+}
+
+// ValidEventType tell whether t can be used in the event filter of a subscription
+func ValidEventType(t string) bool {
+	return eventTypes[t]
+}
+
+// Event represent something that happened to a wallet, Data is the resource it happened to
+type Event struct {
+	ID        string      `json:"id"`
+	Type      string      `json:"type"`
+	WalletID  string      `json:"wallet_id"`
+	Data      interface{} `json:"data"`
+	CreatedAt time.Time   `json:"created_at"`
+}
+
+// NewEvent will create an Event of the wallet happening now
+func NewEvent(eventType string, walletID string, data interface{}) *Event {
+	return &Event{
+		ID:        uuid.New().String(),
+		Type:      eventType,
+		WalletID:  walletID,
+		Data:      data,
+		CreatedAt: time.Now(),
+	}
+}
+
+// Subscription statuses
+const (
+	SubscriptionStatusActive  = "active"
+	SubscriptionStatusDeleted = "deleted"
+)
+
+// Subscription represent a URL receiving the events of a wallet. Secret signs the payloads, it is only
+// shown when the subscription is created.
+type Subscription struct {
+	ID        string    `json:"subscription_id"`
+	WalletID  string    `json:"wallet_id"`
+	URL       string    `json:"url"`
+	Events    []string  `json:"events"`
+	Secret    string    `json:"secret,omitempty"`
+	Status    string    `json:"status"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Matches tell whether the subscription wants events of eventType
+func (s *Subscription) Matches(eventType string) bool {
+	for _, e := range s.Events {
+		if e == EventAll || e == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// ReqSubscription represent the body of a webhook registration
+type ReqSubscription struct {
+	URL    string   `json:"url" validate:"required,url"`
+	Events []string `json:"events" validate:"required,min=1,dive,required"`
+}
+
+// Delivery statuses, a delivery is dead once it ran out of attempts
+const (
+	DeliveryStatusPending   = "pending"
+	DeliveryStatusDelivered = "delivered"
+	DeliveryStatusDead      = "dead"
+)
+
+// Delivery represent one event sent to one subscription, with the log of its attempts.
+// URL and Secret are those of the subscription, loaded to send it.
+type Delivery struct {
+	ID             string             `json:"delivery_id"`
+	SubscriptionID string             `json:"subscription_id"`
+	EventID        string             `json:"event_id"`
+	EventType      string             `json:"event_type"`
+	Payload        string             `json:"payload"`
+	Status         string             `json:"status"`
+	Attempts       int                `json:"attempts"`
+	NextAttemptAt  time.Time          `json:"next_attempt_at"`
+	LastError      string             `json:"last_error,omitempty"`
+	CreatedAt      time.Time          `json:"created_at"`
+	UpdatedAt      time.Time          `json:"updated_at"`
+	Log            []*DeliveryAttempt `json:"log"`
+	URL            string             `json:"-"`
+	Secret         string             `json:"-"`
+}
+
+// DeliveryAttempt represent one try to send a delivery, StatusCode is zero when no response came back
+type DeliveryAttempt struct {
+	Attempt    int       `json:"attempt"`
+	StatusCode int       `json:"status_code,omitempty"`
+	Error      string    `json:"error,omitempty"`
+	DurationMs int64     `json:"duration_ms"`
+	CreatedAt  time.Time `json:"created_at"`
+}
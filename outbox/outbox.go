@@ -0,0 +1,19 @@
+package outbox
+
+import (
+	"context"
+
+	"github.com/williamchand/my-wallet/models"
+)
+
+// Repository represent the relay's side of the outbox, the wallet repository writes the events
+type Repository interface {
+	// FetchPendingEvents return up to limit pending events recorded after afterSeq, in Seq order
+	FetchPendingEvents(ctx context.Context, afterSeq int64, limit int) ([]*models.OutboxEntry, error)
+	UpdateEvent(ctx context.Context, e *models.OutboxEntry) error
+}
+
+// EventPublisher represent a sink the relay hands the events over to
+type EventPublisher interface {
+	Publish(ctx context.Context, event *models.Event) error
+}
@@ -0,0 +1,105 @@
+package outbox
+
+import (
+	"context"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/williamchand/my-wallet/models"
+	"github.com/williamchand/my-wallet/retry"
+)
+
+const batchSize = 100
+
+// Config represent the outbox section of config.json, durations are in seconds
+type Config struct {
+	Sinks        []string `mapstructure:"sinks"`
+	File         string   `mapstructure:"file"`
+	URL          string   `mapstructure:"url"`
+	Timeout      int      `mapstructure:"timeout"`
+	retry.Policy `mapstructure:",squash"`
+}
+
+// defaultPolicy holds the defaults of the zero values of Config.Policy
+var defaultPolicy = retry.Policy{MaxAttempts: 10, Backoff: 5, MaxBackoff: 10 * 60, Interval: 1}
+
+// Relay publish the pending events of the outbox. The events of a wallet are published in the order they
+// were recorded: while one waits for a retry the later ones wait too, unless it goes dead after MaxAttempts.
+// Only one relay should run against a database, the ordering relies on it.
+type Relay struct {
+	repo      Repository
+	publisher EventPublisher
+	policy    retry.Policy
+}
+
+// NewRelay will create a Relay publishing the events of repo, zero values of cfg take their defaults
+func NewRelay(repo Repository, publisher EventPublisher, cfg Config) *Relay {
+	return &Relay{
+		repo:      repo,
+		publisher: publisher,
+		policy:    cfg.Policy.WithDefaults(defaultPolicy),
+	}
+}
+
+// Run relay the pending events every interval until ctx is done
+func (r *Relay) Run(ctx context.Context) {
+	r.policy.Run(ctx, r.RelayPending)
+}
+
+// RelayPending publish every pending event that may go out at now and return how many were published
+func (r *Relay) RelayPending(ctx context.Context, now time.Time) (int, error) {
+	// wallets with an earlier event still pending, their later events have to wait
+	blocked := make(map[string]bool)
+	sent := 0
+	var after int64
+	for {
+		list, err := r.repo.FetchPendingEvents(ctx, after, batchSize)
+		if err != nil {
+			return sent, err
+		}
+		for _, e := range list {
+			after = e.Seq
+			walletID := e.Event.WalletID
+			if blocked[walletID] {
+				continue
+			}
+			if e.NextAttemptAt.After(now) {
+				blocked[walletID] = true
+				continue
+			}
+
+			ok, err := r.publish(ctx, e, now)
+			if err != nil {
+				return sent, err
+			}
+			if ok {
+				sent++
+			} else if e.Status == models.OutboxStatusPending {
+				blocked[walletID] = true
+			}
+		}
+		if len(list) < batchSize {
+			return sent, nil
+		}
+	}
+}
+
+func (r *Relay) publish(ctx context.Context, e *models.OutboxEntry, now time.Time) (bool, error) {
+	pubErr := r.publisher.Publish(ctx, e.Event)
+	e.Attempts++
+	switch {
+	case pubErr == nil:
+		e.Status = models.OutboxStatusSent
+		e.LastError = ""
+	case e.Attempts >= r.policy.MaxAttempts:
+		logrus.Errorf("outbox: event %s dropped after %d attempts: %v", e.Event.ID, e.Attempts, pubErr)
+		e.Status = models.OutboxStatusDead
+		e.LastError = pubErr.Error()
+	default:
+		e.LastError = pubErr.Error()
+		e.NextAttemptAt = now.Add(r.policy.BackoffAfter(e.Attempts))
+	}
+
+	return pubErr == nil, r.repo.UpdateEvent(ctx, e)
+}
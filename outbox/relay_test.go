@@ -0,0 +1,133 @@
+package outbox_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/williamchand/my-wallet/models"
+	"github.com/williamchand/my-wallet/outbox"
+	"github.com/williamchand/my-wallet/retry"
+	"github.com/williamchand/my-wallet/wallet"
+	"github.com/williamchand/my-wallet/wallet/repository"
+)
+
+// publisherStub records what it published and fails while down holds the wallet of the event
+type publisherStub struct {
+	mu        sync.Mutex
+	down      map[string]bool
+	published []*models.Event
+}
+
+func (p *publisherStub) Publish(ctx context.Context, event *models.Event) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.down[event.WalletID] {
+		return errors.New("sink down")
+	}
+	p.published = append(p.published, event)
+	return nil
+}
+
+func (p *publisherStub) types(walletID string) []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	res := make([]string, 0)
+	for _, e := range p.published {
+		if e.WalletID == walletID {
+			res = append(res, e.Type)
+		}
+	}
+	return res
+}
+
+func deposit(t *testing.T, r wallet.Repository, walletID string, amount int64) {
+	_, err := r.AddWallet(context.Background(), &models.ReqTransaction{ReferenceID: uuid.New().String(), Amount: amount}, nil, walletID)
+	require.NoError(t, err)
+}
+
+func TestRelayPending(t *testing.T) {
+	ctx := context.Background()
+	r := repository.NewMemoryWalletRepository()
+	a, err := r.InitWallet(ctx, "customer-a", models.DefaultCurrency)
+	require.NoError(t, err)
+	b, err := r.InitWallet(ctx, "customer-b", models.DefaultCurrency)
+	require.NoError(t, err)
+	deposit(t, r, a.ID, 100)
+	deposit(t, r, b.ID, 100)
+	_, err = r.TransferWallet(ctx, &models.ReqTransfer{ReferenceID: uuid.New().String(), WalletID: b.ID, Amount: 10}, 0, nil, a.ID)
+	require.NoError(t, err)
+
+	o, err := repository.NewMemoryOutboxRepository(r)
+	require.NoError(t, err)
+	pub := &publisherStub{down: map[string]bool{b.ID: true}}
+	relay := outbox.NewRelay(o, pub, outbox.Config{Policy: retry.Policy{Backoff: 10, MaxBackoff: 15, MaxAttempts: 5}})
+	now := time.Now().Add(time.Second)
+
+	n, err := relay.RelayPending(ctx, now)
+	require.NoError(t, err)
+	assert.Equal(t, 2, n)
+	assert.Equal(t, []string{models.EventDepositSucceeded, models.EventTransferSucceeded}, pub.types(a.ID))
+	assert.Empty(t, pub.types(b.ID))
+
+	// b's deposit waits for its retry and the transfer it received waits behind it
+	pub.down[b.ID] = false
+	deposit(t, r, a.ID, 5)
+	n, err = relay.RelayPending(ctx, now.Add(9*time.Second))
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	assert.Empty(t, pub.types(b.ID))
+
+	n, err = relay.RelayPending(ctx, now.Add(10*time.Second))
+	require.NoError(t, err)
+	assert.Equal(t, 2, n)
+	assert.Equal(t, []string{models.EventDepositSucceeded, models.EventTransferReceived}, pub.types(b.ID))
+
+	n, err = relay.RelayPending(ctx, now.Add(time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, 0, n, "a published event is not published again")
+}
+
+func TestRelayBackoffAndDeadEvents(t *testing.T) {
+	ctx := context.Background()
+	r := repository.NewMemoryWalletRepository()
+	w, err := r.InitWallet(ctx, "customer-a", models.DefaultCurrency)
+	require.NoError(t, err)
+	deposit(t, r, w.ID, 100)
+	deposit(t, r, w.ID, 50)
+
+	o, err := repository.NewMemoryOutboxRepository(r)
+	require.NoError(t, err)
+	pub := &publisherStub{down: map[string]bool{w.ID: true}}
+	relay := outbox.NewRelay(o, pub, outbox.Config{Policy: retry.Policy{Backoff: 10, MaxBackoff: 15, MaxAttempts: 3}})
+	now := time.Now().Add(time.Second)
+
+	// retried after 10s, then 15s as 20s is over the max backoff
+	for _, at := range []time.Duration{0, 10 * time.Second, 25 * time.Second} {
+		_, err = relay.RelayPending(ctx, now.Add(at))
+		require.NoError(t, err)
+	}
+	pending, err := o.FetchPendingEvents(ctx, 0, 10)
+	require.NoError(t, err)
+	require.Len(t, pending, 1, "the first deposit is dead after 3 attempts")
+	// the second deposit waited behind it, then got its first attempt
+	assert.Equal(t, 1, pending[0].Attempts)
+
+	pub.down[w.ID] = false
+	n, err := relay.RelayPending(ctx, now.Add(35*time.Second))
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	require.Len(t, pub.published, 1)
+	assert.Equal(t, pending[0].Event.ID, pub.published[0].ID)
+}
+
+func TestMemoryOutboxOfOtherRepository(t *testing.T) {
+	_, err := repository.NewMemoryOutboxRepository(repository.NewMysqlWalletRepository(nil))
+	assert.Error(t, err)
+}
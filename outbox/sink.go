@@ -0,0 +1,167 @@
+package outbox
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/williamchand/my-wallet/models"
+)
+
+// Sinks an event can be published to
+const (
+	SinkWebhook = "webhook"
+	SinkLog     = "log"
+	SinkFile    = "file"
+	SinkHTTP    = "http"
+)
+
+// NewPublisher will create the EventPublisher of the sinks listed in cfg, webhooks is the webhook sink
+func NewPublisher(cfg Config, webhooks EventPublisher) (EventPublisher, error) {
+	if len(cfg.Sinks) == 0 {
+		cfg.Sinks = []string{SinkWebhook}
+	}
+	timeout := time.Duration(cfg.Timeout) * time.Second
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+
+	publishers := make(Publishers, 0, len(cfg.Sinks))
+	for _, sink := range cfg.Sinks {
+		switch sink {
+		case SinkWebhook:
+			publishers = append(publishers, webhooks)
+		case SinkLog:
+			publishers = append(publishers, LogPublisher{})
+		case SinkFile:
+			if cfg.File == "" {
+				return nil, fmt.Errorf("outbox: the file sink needs a file")
+			}
+			publishers = append(publishers, NewFilePublisher(cfg.File))
+		case SinkHTTP:
+			if cfg.URL == "" {
+				return nil, fmt.Errorf("outbox: the http sink needs a url")
+			}
+			publishers = append(publishers, NewHTTPPublisher(cfg.URL, timeout))
+		default:
+			return nil, fmt.Errorf("outbox: unknown sink %q", sink)
+		}
+	}
+	if len(publishers) == 1 {
+		return publishers[0], nil
+	}
+
+	return publishers, nil
+}
+
+// Publishers hand an event over to each of its publishers in turn. When one fails the event is retried
+// on all of them, so the sinks have to tell repeated events apart by their id: the webhook sink queues an
+// event once per subscription.
+type Publishers []EventPublisher
+
+// Publish publish event to every publisher, it stops at the first failure
+func (p Publishers) Publish(ctx context.Context, event *models.Event) error {
+	for _, publisher := range p {
+		if err := publisher.Publish(ctx, event); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// LogPublisher write the events to the application log
+type LogPublisher struct{}
+
+// Publish log event
+func (LogPublisher) Publish(ctx context.Context, event *models.Event) error {
+	data, err := json.Marshal(event.Data)
+	if err != nil {
+		return err
+	}
+	logrus.WithFields(logrus.Fields{
+		"event_id":  event.ID,
+		"type":      event.Type,
+		"wallet_id": event.WalletID,
+	}).Info(string(data))
+	return nil
+}
+
+// FilePublisher append the events to a file, one JSON object per line
+type FilePublisher struct {
+	mu   sync.Mutex
+	path string
+}
+
+// NewFilePublisher will create a FilePublisher appending to path, the file is created when missing
+func NewFilePublisher(path string) *FilePublisher {
+	return &FilePublisher{path: path}
+}
+
+// Publish append event to the file
+func (f *FilePublisher) Publish(ctx context.Context, event *models.Event) error {
+	line, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	file, err := os.OpenFile(f.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err = file.Write(append(line, '\n')); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// HTTPPublisher POST the events as JSON to a URL, any 2xx answer counts as published
+type HTTPPublisher struct {
+	url    string
+	client *http.Client
+}
+
+// NewHTTPPublisher will create a HTTPPublisher posting to url
+func NewHTTPPublisher(url string, timeout time.Duration) *HTTPPublisher {
+	return &HTTPPublisher{
+		url:    url,
+		client: &http.Client{Timeout: timeout},
+	}
+}
+
+// Publish post event to the URL
+func (h *HTTPPublisher) Publish(ctx context.Context, event *models.Event) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, h.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/json")
+
+	res, err := h.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	_, _ = io.Copy(ioutil.Discard, io.LimitReader(res.Body, 64<<10))
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return fmt.Errorf("outbox: unexpected status %d", res.StatusCode)
+	}
+	return nil
+}
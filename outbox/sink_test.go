@@ -0,0 +1,76 @@
+package outbox_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/williamchand/my-wallet/models"
+	"github.com/williamchand/my-wallet/outbox"
+)
+
+func TestFilePublisher(t *testing.T) {
+	dir, err := ioutil.TempDir("", "outbox")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "events.jsonl")
+
+	p, err := outbox.NewPublisher(outbox.Config{Sinks: []string{outbox.SinkFile}, File: path}, nil)
+	require.NoError(t, err)
+	first := models.NewEvent(models.EventDepositSucceeded, "wallet-1", map[string]int64{"amount": 10})
+	second := models.NewEvent(models.EventWalletDisabled, "wallet-1", nil)
+	require.NoError(t, p.Publish(context.Background(), first))
+	require.NoError(t, p.Publish(context.Background(), second))
+
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	defer f.Close()
+	var ids []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var e models.Event
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &e))
+		ids = append(ids, e.ID)
+	}
+	assert.Equal(t, []string{first.ID, second.ID}, ids)
+}
+
+func TestHTTPPublisher(t *testing.T) {
+	status := http.StatusAccepted
+	var received models.Event
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&received))
+		w.WriteHeader(status)
+	}))
+	defer srv.Close()
+
+	p, err := outbox.NewPublisher(outbox.Config{Sinks: []string{outbox.SinkHTTP}, URL: srv.URL}, nil)
+	require.NoError(t, err)
+	event := models.NewEvent(models.EventDepositSucceeded, "wallet-1", nil)
+	require.NoError(t, p.Publish(context.Background(), event))
+	assert.Equal(t, event.ID, received.ID)
+
+	status = http.StatusInternalServerError
+	assert.Error(t, p.Publish(context.Background(), event))
+}
+
+func TestNewPublisherRejects(t *testing.T) {
+	for name, cfg := range map[string]outbox.Config{
+		"unknown sink":     {Sinks: []string{"kafka"}},
+		"file without one": {Sinks: []string{outbox.SinkFile}},
+		"http without url": {Sinks: []string{outbox.SinkHTTP}},
+	} {
+		_, err := outbox.NewPublisher(cfg, nil)
+		assert.Error(t, err, name)
+	}
+}
@@ -0,0 +1,64 @@
+// Package requestid carry the ID of the request being served in its context, so every log entry it causes can be
+// traced back to it
+package requestid
+
+import (
+	"context"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Field is the logrus field holding the request ID
+const Field = "request_id"
+
+// maxLength bound the request IDs accepted from clients
+const maxLength = 128
+
+type requestIDKey struct{}
+
+// Valid tell whether the request ID sent by a client can be used, it has to be made of letters, digits and -_.: only,
+// so it can not forge log lines
+func Valid(id string) bool {
+	if id == "" || len(id) > maxLength {
+		return false
+	}
+	for _, r := range id {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		case r == '-', r == '_', r == '.', r == ':':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// NewContext return a copy of ctx carrying the request ID id
+func NewContext(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// FromContext return the request ID of ctx, empty when ctx is not serving a request
+func FromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// Hook represent the logrus hook adding the request ID to the entries logged with logrus.WithContext
+type Hook struct{}
+
+// Levels return every level, the request ID is added to all of them
+func (Hook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+// Fire add the request ID of the context of entry, if any
+func (Hook) Fire(entry *logrus.Entry) error {
+	if entry.Context == nil {
+		return nil
+	}
+	if id := FromContext(entry.Context); id != "" {
+		entry.Data[Field] = id
+	}
+	return nil
+}
@@ -0,0 +1,43 @@
+package requestid_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/williamchand/my-wallet/requestid"
+)
+
+func TestHook(t *testing.T) {
+	var out bytes.Buffer
+	log := logrus.New()
+	log.SetOutput(&out)
+	log.SetFormatter(&logrus.JSONFormatter{})
+	log.AddHook(requestid.Hook{})
+
+	ctx := requestid.NewContext(context.Background(), "req-1")
+	assert.Equal(t, "req-1", requestid.FromContext(ctx))
+	assert.Empty(t, requestid.FromContext(context.Background()))
+
+	log.WithContext(ctx).Error("boom")
+	var entry map[string]interface{}
+	require.NoError(t, json.Unmarshal(out.Bytes(), &entry))
+	assert.Equal(t, "req-1", entry[requestid.Field])
+
+	out.Reset()
+	log.WithContext(context.Background()).Error("boom")
+	entry = nil
+	require.NoError(t, json.Unmarshal(out.Bytes(), &entry))
+	assert.NotContains(t, entry, requestid.Field)
+
+	out.Reset()
+	log.Error("boom")
+	entry = nil
+	require.NoError(t, json.Unmarshal(out.Bytes(), &entry))
+	assert.NotContains(t, entry, requestid.Field, "entries without context")
+}
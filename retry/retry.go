@@ -0,0 +1,70 @@
+// Package retry hold the polling and backoff shared by the background workers, the outbox relay and the webhook
+// dispatcher
+package retry
+
+import (
+	"context"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Policy represent how a background worker polls and retries, as set in config.json, durations are in seconds.
+// A failed attempt is retried after Backoff seconds, doubled on every further failure up to MaxBackoff, and it is
+// given up once it failed MaxAttempts times.
+type Policy struct {
+	MaxAttempts int `mapstructure:"max_attempts"`
+	Backoff     int `mapstructure:"backoff"`
+	MaxBackoff  int `mapstructure:"max_backoff"`
+	Interval    int `mapstructure:"interval"`
+}
+
+// WithDefaults return p with its zero values taken from def
+func (p Policy) WithDefaults(def Policy) Policy {
+	if p.MaxAttempts <= 0 {
+		p.MaxAttempts = def.MaxAttempts
+	}
+	if p.Backoff <= 0 {
+		p.Backoff = def.Backoff
+	}
+	if p.MaxBackoff <= 0 {
+		p.MaxBackoff = def.MaxBackoff
+	}
+	if p.Interval <= 0 {
+		p.Interval = def.Interval
+	}
+	return p
+}
+
+// BackoffAfter return how long to wait after the given number of failed attempts
+func (p Policy) BackoffAfter(attempts int) time.Duration {
+	wait := seconds(p.Backoff)
+	max := seconds(p.MaxBackoff)
+	for i := 1; i < attempts && wait < max; i++ {
+		wait *= 2
+	}
+	if wait > max {
+		wait = max
+	}
+	return wait
+}
+
+// Run call tick with the current time every Interval until ctx is done, its errors are logged
+func (p Policy) Run(ctx context.Context, tick func(ctx context.Context, now time.Time) (int, error)) {
+	ticker := time.NewTicker(seconds(p.Interval))
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := tick(ctx, time.Now()); err != nil {
+				logrus.Error(err)
+			}
+		}
+	}
+}
+
+func seconds(n int) time.Duration {
+	return time.Duration(n) * time.Second
+}
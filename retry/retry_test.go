@@ -0,0 +1,30 @@
+package retry_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/williamchand/my-wallet/retry"
+)
+
+func TestWithDefaults(t *testing.T) {
+	def := retry.Policy{MaxAttempts: 8, Backoff: 30, MaxBackoff: 600, Interval: 5}
+	assert.Equal(t, def, retry.Policy{}.WithDefaults(def))
+	assert.Equal(t, retry.Policy{MaxAttempts: 3, Backoff: 30, MaxBackoff: 600, Interval: 1},
+		retry.Policy{MaxAttempts: 3, Interval: 1}.WithDefaults(def))
+}
+
+func TestBackoffAfter(t *testing.T) {
+	p := retry.Policy{Backoff: 10, MaxBackoff: 60}
+	for attempts, want := range map[int]time.Duration{
+		1:  10 * time.Second,
+		2:  20 * time.Second,
+		3:  40 * time.Second,
+		4:  60 * time.Second,
+		20: 60 * time.Second,
+	} {
+		assert.Equal(t, want, p.BackoffAfter(attempts), "after %d attempts", attempts)
+	}
+}
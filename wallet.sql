@@ -32,10 +32,164 @@ CREATE TABLE `transaction` (
   `id` int(64) NOT NULL,
   `reference_id` varchar(100) CHARACTER SET utf8 COLLATE utf8_unicode_ci NOT NULL,
   `wallet_id` varchar(150) CHARACTER SET utf8 COLLATE utf8_unicode_ci NOT NULL,
-  `type` tinyint(1) NOT NULL,
-  `amount` int(64) NOT NULL,
+  `type` varchar(20) COLLATE utf8_unicode_ci NOT NULL,
+  `amount` bigint(20) NOT NULL,
+  `currency` char(3) COLLATE utf8_unicode_ci NOT NULL DEFAULT 'IDR',
   `status` varchar(20) COLLATE utf8_unicode_ci NOT NULL,
   `created_by` varchar(100) COLLATE utf8_unicode_ci NOT NULL,
+  `created_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `parent_id` int(64) DEFAULT NULL,
+  `quote_id` varchar(150) COLLATE utf8_unicode_ci DEFAULT NULL,
+  `reason` varchar(255) COLLATE utf8_unicode_ci DEFAULT NULL
+) ENGINE=InnoDB DEFAULT CHARSET=utf8 COLLATE=utf8_unicode_ci;
+
+-- --------------------------------------------------------
+
+--
+-- Struktur dari tabel `hold`
+--
+
+CREATE TABLE `hold` (
+  `id` int(64) NOT NULL,
+  `hold_id` varchar(150) COLLATE utf8_unicode_ci NOT NULL,
+  `reference_id` varchar(100) COLLATE utf8_unicode_ci NOT NULL,
+  `wallet_id` varchar(150) COLLATE utf8_unicode_ci NOT NULL,
+  `amount` bigint(20) NOT NULL,
+  `currency` char(3) COLLATE utf8_unicode_ci NOT NULL DEFAULT 'IDR',
+  `captured_amount` bigint(20) NOT NULL DEFAULT '0',
+  `status` varchar(20) COLLATE utf8_unicode_ci NOT NULL,
+  `expires_at` datetime NOT NULL,
+  `created_by` varchar(100) COLLATE utf8_unicode_ci NOT NULL,
+  `created_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `updated_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP
+) ENGINE=InnoDB DEFAULT CHARSET=utf8 COLLATE=utf8_unicode_ci;
+
+-- --------------------------------------------------------
+
+--
+-- Struktur dari tabel `ledger_entry`
+--
+
+CREATE TABLE `ledger_entry` (
+  `id` int(64) NOT NULL,
+  `transaction_id` int(64) NOT NULL,
+  `account` varchar(170) COLLATE utf8_unicode_ci NOT NULL,
+  `debit` bigint(20) NOT NULL DEFAULT '0',
+  `credit` bigint(20) NOT NULL DEFAULT '0',
+  `currency` char(3) COLLATE utf8_unicode_ci NOT NULL DEFAULT 'IDR',
+  `created_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP
+) ENGINE=InnoDB DEFAULT CHARSET=utf8 COLLATE=utf8_unicode_ci;
+
+-- --------------------------------------------------------
+
+--
+-- Struktur dari tabel `fx_quote`
+--
+
+CREATE TABLE `fx_quote` (
+  `id` int(64) NOT NULL,
+  `quote_id` varchar(150) COLLATE utf8_unicode_ci NOT NULL,
+  `wallet_id` varchar(150) COLLATE utf8_unicode_ci NOT NULL,
+  `amount` bigint(20) NOT NULL,
+  `currency` char(3) COLLATE utf8_unicode_ci NOT NULL,
+  `converted_amount` bigint(20) NOT NULL,
+  `to_currency` char(3) COLLATE utf8_unicode_ci NOT NULL,
+  `rate` varchar(40) COLLATE utf8_unicode_ci NOT NULL,
+  `spread_bps` int(11) NOT NULL DEFAULT '0',
+  `fee` bigint(20) NOT NULL DEFAULT '0',
+  `rounding` varchar(20) COLLATE utf8_unicode_ci NOT NULL,
+  `reference_id` varchar(100) COLLATE utf8_unicode_ci DEFAULT NULL,
+  `expires_at` datetime NOT NULL,
+  `created_by` varchar(100) COLLATE utf8_unicode_ci NOT NULL,
+  `created_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP
+) ENGINE=InnoDB DEFAULT CHARSET=utf8 COLLATE=utf8_unicode_ci;
+
+-- --------------------------------------------------------
+
+--
+-- Struktur dari tabel `outbox`
+--
+
+CREATE TABLE `outbox` (
+  `id` int(64) NOT NULL,
+  `event_id` varchar(150) COLLATE utf8_unicode_ci NOT NULL,
+  `event_type` varchar(50) COLLATE utf8_unicode_ci NOT NULL,
+  `wallet_id` varchar(150) COLLATE utf8_unicode_ci NOT NULL,
+  `payload` text COLLATE utf8_unicode_ci NOT NULL,
+  `status` varchar(20) COLLATE utf8_unicode_ci NOT NULL,
+  `attempts` int(11) NOT NULL DEFAULT '0',
+  `next_attempt_at` datetime NOT NULL,
+  `last_error` varchar(1000) COLLATE utf8_unicode_ci DEFAULT NULL,
+  `created_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `updated_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP
+) ENGINE=InnoDB DEFAULT CHARSET=utf8 COLLATE=utf8_unicode_ci;
+
+-- --------------------------------------------------------
+
+--
+-- Struktur dari tabel `webhook_subscription`
+--
+
+CREATE TABLE `webhook_subscription` (
+  `id` int(64) NOT NULL,
+  `subscription_id` varchar(150) COLLATE utf8_unicode_ci NOT NULL,
+  `wallet_id` varchar(150) COLLATE utf8_unicode_ci NOT NULL,
+  `url` varchar(2048) COLLATE utf8_unicode_ci NOT NULL,
+  `events` varchar(1000) COLLATE utf8_unicode_ci NOT NULL,
+  `secret` varchar(100) COLLATE utf8_unicode_ci NOT NULL,
+  `status` varchar(20) COLLATE utf8_unicode_ci NOT NULL,
+  `created_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP
+) ENGINE=InnoDB DEFAULT CHARSET=utf8 COLLATE=utf8_unicode_ci;
+
+-- --------------------------------------------------------
+
+--
+-- Struktur dari tabel `webhook_delivery`
+--
+
+CREATE TABLE `webhook_delivery` (
+  `id` int(64) NOT NULL,
+  `delivery_id` varchar(150) COLLATE utf8_unicode_ci NOT NULL,
+  `subscription_id` varchar(150) COLLATE utf8_unicode_ci NOT NULL,
+  `event_id` varchar(150) COLLATE utf8_unicode_ci NOT NULL,
+  `event_type` varchar(50) COLLATE utf8_unicode_ci NOT NULL,
+  `payload` text COLLATE utf8_unicode_ci NOT NULL,
+  `status` varchar(20) COLLATE utf8_unicode_ci NOT NULL,
+  `attempts` int(11) NOT NULL DEFAULT '0',
+  `next_attempt_at` datetime NOT NULL,
+  `last_error` varchar(1000) COLLATE utf8_unicode_ci DEFAULT NULL,
+  `created_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `updated_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP
+) ENGINE=InnoDB DEFAULT CHARSET=utf8 COLLATE=utf8_unicode_ci;
+
+-- --------------------------------------------------------
+
+--
+-- Struktur dari tabel `webhook_attempt`
+--
+
+CREATE TABLE `webhook_attempt` (
+  `id` int(64) NOT NULL,
+  `delivery_id` varchar(150) COLLATE utf8_unicode_ci NOT NULL,
+  `attempt` int(11) NOT NULL,
+  `status_code` int(11) NOT NULL DEFAULT '0',
+  `error` varchar(1000) COLLATE utf8_unicode_ci DEFAULT NULL,
+  `duration_ms` bigint(20) NOT NULL DEFAULT '0',
+  `created_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP
+) ENGINE=InnoDB DEFAULT CHARSET=utf8 COLLATE=utf8_unicode_ci;
+
+-- --------------------------------------------------------
+
+--
+-- Struktur dari tabel `audit_log`
+--
+
+CREATE TABLE `audit_log` (
+  `id` int(64) NOT NULL,
+  `actor` varchar(100) COLLATE utf8_unicode_ci NOT NULL,
+  `action` varchar(50) COLLATE utf8_unicode_ci NOT NULL,
+  `wallet_id` varchar(150) COLLATE utf8_unicode_ci DEFAULT NULL,
+  `detail` varchar(255) COLLATE utf8_unicode_ci DEFAULT NULL,
   `created_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8 COLLATE=utf8_unicode_ci;
 
@@ -49,7 +203,8 @@ CREATE TABLE `wallet` (
   `id` int(64) NOT NULL,
   `wallet_id` varchar(150) COLLATE utf8_unicode_ci NOT NULL,
   `status` varchar(20) COLLATE utf8_unicode_ci NOT NULL,
-  `balance` int(64) NOT NULL DEFAULT '0',
+  `balance` bigint(20) NOT NULL DEFAULT '0',
+  `currency` char(3) COLLATE utf8_unicode_ci NOT NULL DEFAULT 'IDR',
   `owned_by` varchar(100) COLLATE utf8_unicode_ci NOT NULL,
   `updated_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8 COLLATE=utf8_unicode_ci;
@@ -64,14 +219,83 @@ CREATE TABLE `wallet` (
 ALTER TABLE `transaction`
   ADD PRIMARY KEY (`id`) USING BTREE,
   ADD UNIQUE KEY `reference_id` (`reference_id`),
-  ADD KEY `transaction_bind_1` (`wallet_id`);
+  ADD KEY `transaction_bind_1` (`wallet_id`),
+  ADD KEY `parent_id` (`parent_id`),
+  ADD KEY `quote_id` (`quote_id`);
+
+--
+-- Indeks untuk tabel `hold`
+--
+ALTER TABLE `hold`
+  ADD PRIMARY KEY (`id`),
+  ADD UNIQUE KEY `hold_id` (`hold_id`),
+  ADD UNIQUE KEY `reference_id` (`reference_id`),
+  ADD KEY `hold_bind_1` (`wallet_id`,`status`);
+
+--
+-- Indeks untuk tabel `ledger_entry`
+--
+ALTER TABLE `ledger_entry`
+  ADD PRIMARY KEY (`id`),
+  ADD KEY `ledger_entry_bind_1` (`transaction_id`),
+  ADD KEY `account` (`account`);
+
+--
+-- Indeks untuk tabel `fx_quote`
+--
+ALTER TABLE `fx_quote`
+  ADD PRIMARY KEY (`id`),
+  ADD UNIQUE KEY `quote_id` (`quote_id`),
+  ADD UNIQUE KEY `reference_id` (`reference_id`),
+  ADD KEY `fx_quote_bind_1` (`wallet_id`);
+
+--
+-- Indeks untuk tabel `outbox`
+--
+ALTER TABLE `outbox`
+  ADD PRIMARY KEY (`id`),
+  ADD UNIQUE KEY `event_id` (`event_id`),
+  ADD KEY `pending` (`status`,`id`);
+
+--
+-- Indeks untuk tabel `webhook_subscription`
+--
+ALTER TABLE `webhook_subscription`
+  ADD PRIMARY KEY (`id`),
+  ADD UNIQUE KEY `subscription_id` (`subscription_id`),
+  ADD KEY `webhook_subscription_bind_1` (`wallet_id`,`status`);
+
+--
+-- Indeks untuk tabel `webhook_delivery`
+--
+ALTER TABLE `webhook_delivery`
+  ADD PRIMARY KEY (`id`),
+  ADD UNIQUE KEY `delivery_id` (`delivery_id`),
+  ADD UNIQUE KEY `event` (`subscription_id`,`event_id`),
+  ADD KEY `webhook_delivery_bind_1` (`subscription_id`),
+  ADD KEY `due` (`status`,`next_attempt_at`);
+
+--
+-- Indeks untuk tabel `webhook_attempt`
+--
+ALTER TABLE `webhook_attempt`
+  ADD PRIMARY KEY (`id`),
+  ADD KEY `webhook_attempt_bind_1` (`delivery_id`);
+
+--
+-- Indeks untuk tabel `audit_log`
+--
+ALTER TABLE `audit_log`
+  ADD PRIMARY KEY (`id`),
+  ADD KEY `audit_log_bind_1` (`wallet_id`);
 
 --
 -- Indeks untuk tabel `wallet`
 --
 ALTER TABLE `wallet`
   ADD PRIMARY KEY (`id`),
-  ADD UNIQUE KEY `wallet_id` (`wallet_id`);
+  ADD UNIQUE KEY `wallet_id` (`wallet_id`),
+  ADD UNIQUE KEY `owned_by` (`owned_by`,`currency`);
 
 --
 -- AUTO_INCREMENT untuk tabel yang dibuang
@@ -83,6 +307,54 @@ ALTER TABLE `wallet`
 ALTER TABLE `transaction`
   MODIFY `id` int(64) NOT NULL AUTO_INCREMENT;
 
+--
+-- AUTO_INCREMENT untuk tabel `hold`
+--
+ALTER TABLE `hold`
+  MODIFY `id` int(64) NOT NULL AUTO_INCREMENT;
+
+--
+-- AUTO_INCREMENT untuk tabel `ledger_entry`
+--
+ALTER TABLE `ledger_entry`
+  MODIFY `id` int(64) NOT NULL AUTO_INCREMENT;
+
+--
+-- AUTO_INCREMENT untuk tabel `fx_quote`
+--
+ALTER TABLE `fx_quote`
+  MODIFY `id` int(64) NOT NULL AUTO_INCREMENT;
+
+--
+-- AUTO_INCREMENT untuk tabel `outbox`
+--
+ALTER TABLE `outbox`
+  MODIFY `id` int(64) NOT NULL AUTO_INCREMENT;
+
+--
+-- AUTO_INCREMENT untuk tabel `webhook_subscription`
+--
+ALTER TABLE `webhook_subscription`
+  MODIFY `id` int(64) NOT NULL AUTO_INCREMENT;
+
+--
+-- AUTO_INCREMENT untuk tabel `webhook_delivery`
+--
+ALTER TABLE `webhook_delivery`
+  MODIFY `id` int(64) NOT NULL AUTO_INCREMENT;
+
+--
+-- AUTO_INCREMENT untuk tabel `webhook_attempt`
+--
+ALTER TABLE `webhook_attempt`
+  MODIFY `id` int(64) NOT NULL AUTO_INCREMENT;
+
+--
+-- AUTO_INCREMENT untuk tabel `audit_log`
+--
+ALTER TABLE `audit_log`
+  MODIFY `id` int(64) NOT NULL AUTO_INCREMENT;
+
 --
 -- AUTO_INCREMENT untuk tabel `wallet`
 --
@@ -98,6 +370,42 @@ ALTER TABLE `wallet`
 --
 ALTER TABLE `transaction`
   ADD CONSTRAINT `transaction_bind_1` FOREIGN KEY (`wallet_id`) REFERENCES `wallet` (`wallet_id`) ON DELETE RESTRICT ON UPDATE RESTRICT;
+
+--
+-- Ketidakleluasaan untuk tabel `hold`
+--
+ALTER TABLE `hold`
+  ADD CONSTRAINT `hold_bind_1` FOREIGN KEY (`wallet_id`) REFERENCES `wallet` (`wallet_id`) ON DELETE RESTRICT ON UPDATE RESTRICT;
+
+--
+-- Ketidakleluasaan untuk tabel `ledger_entry`
+--
+ALTER TABLE `ledger_entry`
+  ADD CONSTRAINT `ledger_entry_bind_1` FOREIGN KEY (`transaction_id`) REFERENCES `transaction` (`id`) ON DELETE RESTRICT ON UPDATE RESTRICT;
+
+--
+-- Ketidakleluasaan untuk tabel `fx_quote`
+--
+ALTER TABLE `fx_quote`
+  ADD CONSTRAINT `fx_quote_bind_1` FOREIGN KEY (`wallet_id`) REFERENCES `wallet` (`wallet_id`) ON DELETE RESTRICT ON UPDATE RESTRICT;
+
+--
+-- Ketidakleluasaan untuk tabel `webhook_subscription`
+--
+ALTER TABLE `webhook_subscription`
+  ADD CONSTRAINT `webhook_subscription_bind_1` FOREIGN KEY (`wallet_id`) REFERENCES `wallet` (`wallet_id`) ON DELETE RESTRICT ON UPDATE RESTRICT;
+
+--
+-- Ketidakleluasaan untuk tabel `webhook_delivery`
+--
+ALTER TABLE `webhook_delivery`
+  ADD CONSTRAINT `webhook_delivery_bind_1` FOREIGN KEY (`subscription_id`) REFERENCES `webhook_subscription` (`subscription_id`) ON DELETE RESTRICT ON UPDATE RESTRICT;
+
+--
+-- Ketidakleluasaan untuk tabel `webhook_attempt`
+--
+ALTER TABLE `webhook_attempt`
+  ADD CONSTRAINT `webhook_attempt_bind_1` FOREIGN KEY (`delivery_id`) REFERENCES `webhook_delivery` (`delivery_id`) ON DELETE RESTRICT ON UPDATE RESTRICT;
 COMMIT;
 
 /*!40101 SET CHARACTER_SET_CLIENT=@OLD_CHARACTER_SET_CLIENT */;
@@ -0,0 +1,101 @@
+package grpc
+
+import (
+	"context"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+
+	"github.com/williamchand/my-wallet/auth"
+	"github.com/williamchand/my-wallet/middleware"
+	"github.com/williamchand/my-wallet/models"
+)
+
+// methodRoutes are the HTTP routes each method shares its rate limit bucket with, so a client does not get a second
+// quota by calling the other API
+var methodRoutes = map[string]string{
+	"/wallet.WalletService/InitWallet":    "POST /api/v1/init",
+	"/wallet.WalletService/FetchWallet":   "GET /api/v1/wallet",
+	"/wallet.WalletService/EnableWallet":  "POST /api/v1/wallet",
+	"/wallet.WalletService/Deposit":       "POST /api/v1/wallet/deposits",
+	"/wallet.WalletService/Withdraw":      "POST /api/v1/wallet/withdrawals",
+	"/wallet.WalletService/DisableWallet": "PATCH /api/v1/wallet",
+}
+
+// NewIPRateLimitInterceptor will create the interceptor limiting the calls of each peer IP like Limiter.IP,
+// it runs before NewAuthInterceptor so the calls with a missing or invalid token are counted too
+func NewIPRateLimitInterceptor(l *middleware.Limiter) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		limit, q, err := l.TakeIP(ctx, peerIP(ctx))
+		return limited(ctx, req, handler, limit, q, err)
+	}
+}
+
+// NewRateLimitInterceptor will create the interceptor limiting the calls of each client like Limiter.Client, on the
+// bucket of the HTTP route of the method. It runs after NewAuthInterceptor, which tells the client.
+func NewRateLimitInterceptor(l *middleware.Limiter) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		route, ok := methodRoutes[info.FullMethod]
+		if !ok {
+			route = "GRPC " + info.FullMethod
+		}
+		limit, q, err := l.Take(ctx, route, auth.FromContext(ctx), peerIP(ctx))
+		return limited(ctx, req, handler, limit, q, err)
+	}
+}
+
+// ChainUnaryInterceptors will create the interceptor running interceptors in order, the first one outermost
+func ChainUnaryInterceptors(interceptors ...grpc.UnaryServerInterceptor) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		next := handler
+		for i := len(interceptors) - 1; i >= 0; i-- {
+			interceptor, inner := interceptors[i], next
+			next = func(ctx context.Context, req interface{}) (interface{}, error) {
+				return interceptor(ctx, req, info, inner)
+			}
+		}
+		return next(ctx, req)
+	}
+}
+
+// limited send the RateLimit headers of q as metadata and serve the call with handler, unless q refuses it
+func limited(ctx context.Context, req interface{}, handler grpc.UnaryHandler, limit middleware.RateLimit, q *middleware.Quota, err error) (interface{}, error) {
+	if err != nil {
+		logrus.WithContext(ctx).Error(err)
+		return handler(ctx, req)
+	}
+	if q == nil {
+		return handler(ctx, req)
+	}
+	md := metadata.Pairs(
+		strings.ToLower(middleware.HeaderRateLimitLimit), strconv.Itoa(limit.Limit),
+		strings.ToLower(middleware.HeaderRateLimitRemaining), strconv.Itoa(q.Remaining),
+		strings.ToLower(middleware.HeaderRateLimitReset), middleware.Seconds(q.Reset),
+	)
+	if !q.Allowed {
+		md.Set(strings.ToLower(middleware.HeaderRetryAfter), middleware.Seconds(q.RetryAfter))
+	}
+	_ = grpc.SetHeader(ctx, md)
+	if !q.Allowed {
+		return nil, getStatus(ctx, models.ErrRateLimited)
+	}
+	return handler(ctx, req)
+}
+
+// peerIP return the IP the call comes from, or the whole address when it has no port
+func peerIP(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return ""
+	}
+	addr := p.Addr.String()
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		return host
+	}
+	return addr
+}
@@ -0,0 +1,318 @@
+package grpc
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes"
+	"github.com/golang/protobuf/ptypes/timestamp"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	validator "gopkg.in/go-playground/validator.v9"
+
+	"github.com/williamchand/my-wallet/auth"
+	"github.com/williamchand/my-wallet/models"
+	"github.com/williamchand/my-wallet/requestid"
+	"github.com/williamchand/my-wallet/wallet"
+	"github.com/williamchand/my-wallet/wallet/delivery/grpc/walletpb"
+)
+
+// WalletServer represent the grpc handler for wallet
+type WalletServer struct {
+	AUsecase wallet.Usecase
+}
+
+// NewWalletServer will register the WalletService on the given server
+func NewWalletServer(s *grpc.Server, us wallet.Usecase) {
+	walletpb.RegisterWalletServiceServer(s, &WalletServer{
+		AUsecase: us,
+	})
+}
+
+// EnableWallet will enable the wallet of the caller
+func (a *WalletServer) EnableWallet(ctx context.Context, req *walletpb.EnableWalletRequest) (*walletpb.WalletResponse, error) {
+	res, err := a.AUsecase.EnableWallet(ctx, auth.FromContext(ctx))
+	if err != nil {
+		return nil, getStatus(ctx, err)
+	}
+
+	return &walletpb.WalletResponse{Wallet: toWallet(res)}, nil
+}
+
+// FetchWallet will fetch the wallet of the caller
+func (a *WalletServer) FetchWallet(ctx context.Context, req *walletpb.FetchWalletRequest) (*walletpb.WalletResponse, error) {
+	res, err := a.AUsecase.FetchWallet(ctx, auth.FromContext(ctx))
+	if err != nil {
+		return nil, getStatus(ctx, err)
+	}
+
+	return &walletpb.WalletResponse{Wallet: toWallet(res)}, nil
+}
+
+// Deposit will deposit the wallet by given request
+func (a *WalletServer) Deposit(ctx context.Context, req *walletpb.TransactionRequest) (*walletpb.DepositResponse, error) {
+	deposit := models.ReqTransaction{
+		ReferenceID: req.GetReferenceId(),
+		Amount:      req.GetAmount(),
+		Currency:    req.GetCurrency(),
+	}
+	if ok, err := isRequestValid(&deposit); !ok {
+		return nil, getStatus(ctx, models.NewError(models.CodeInvalidParameter, http.StatusBadRequest, err.Error()))
+	}
+
+	res, err := a.AUsecase.AddWallet(ctx, &deposit, auth.FromContext(ctx))
+	if err != nil {
+		return nil, getStatus(ctx, err)
+	}
+
+	return &walletpb.DepositResponse{
+		ReferenceId: res.ReferenceID,
+		Id:          res.ID,
+		Amount:      res.Amount,
+		Currency:    res.Currency,
+		Status:      res.Status,
+		DepositedBy: res.DepositBy,
+		DepositedAt: toTimestamp(res.DepositAt),
+	}, nil
+}
+
+// Withdraw will withdraw the wallet by given request
+func (a *WalletServer) Withdraw(ctx context.Context, req *walletpb.TransactionRequest) (*walletpb.WithdrawalResponse, error) {
+	withdrawal := models.ReqTransaction{
+		ReferenceID: req.GetReferenceId(),
+		Amount:      req.GetAmount(),
+		Currency:    req.GetCurrency(),
+	}
+	if ok, err := isRequestValid(&withdrawal); !ok {
+		return nil, getStatus(ctx, models.NewError(models.CodeInvalidParameter, http.StatusBadRequest, err.Error()))
+	}
+
+	res, err := a.AUsecase.WithdrawWallet(ctx, &withdrawal, auth.FromContext(ctx))
+	if err != nil {
+		return nil, getStatus(ctx, err)
+	}
+
+	return &walletpb.WithdrawalResponse{
+		ReferenceId: res.ReferenceID,
+		Id:          res.ID,
+		Amount:      res.Amount,
+		GrossAmount: res.GrossAmount,
+		Fee:         res.Fee,
+		NetAmount:   res.NetAmount,
+		Currency:    res.Currency,
+		Status:      res.Status,
+		WithdrawnBy: res.WithdrawnBy,
+		WithdrawnAt: toTimestamp(res.WithdrawnAt),
+	}, nil
+}
+
+// DisableWallet will disable the wallet of the caller, is_disabled has to be set
+func (a *WalletServer) DisableWallet(ctx context.Context, req *walletpb.DisableWalletRequest) (*walletpb.WalletDisabledResponse, error) {
+	if !req.GetIsDisabled() {
+		return nil, getStatus(ctx, models.ErrBadParamInput)
+	}
+
+	res, err := a.AUsecase.DisableWallet(ctx, req.GetIsDisabled(), auth.FromContext(ctx))
+	if err != nil {
+		return nil, getStatus(ctx, err)
+	}
+
+	return &walletpb.WalletDisabledResponse{
+		Id:         res.ID,
+		OwnedBy:    res.OwnedBy,
+		Status:     res.Status,
+		DisabledAt: toTimestamp(res.DisabledAt),
+		Balance:    res.Balance,
+		Currency:   res.Currency,
+	}, nil
+}
+
+// InitWallet will init the wallet of the customer in the request
+func (a *WalletServer) InitWallet(ctx context.Context, req *walletpb.InitWalletRequest) (*walletpb.AccountResponse, error) {
+	if req.GetCustomerId() == "" {
+		return nil, getStatus(ctx, models.ErrBadParamInput)
+	}
+
+	res, err := a.AUsecase.InitWallet(ctx, req.GetCustomerId(), req.GetCurrency(), auth.FromContext(ctx))
+	if err != nil {
+		return nil, getStatus(ctx, err)
+	}
+
+	return &walletpb.AccountResponse{
+		Wallet: toWallet(res.Wallet),
+		Token: &walletpb.Token{
+			Token:     res.Token.Token,
+			TokenType: res.Token.Type,
+			ExpiresAt: toTimestamp(res.Token.ExpiresAt),
+		},
+	}, nil
+}
+
+// requestIDKey is the metadata key of the request ID, the gRPC counterpart of the X-Request-ID header
+const requestIDKey = "x-request-id"
+
+// NewRequestIDInterceptor will create the interceptor taking the "x-request-id" metadata of a call, or generating one
+// when it is missing or malformed like middleware.RequestID, sending it back in the header metadata and putting it in
+// the call context so the logs of the interceptors, usecases and repositories carry it. It goes first in the chain.
+func NewRequestIDInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		var id string
+		if md, ok := metadata.FromIncomingContext(ctx); ok {
+			if values := md.Get(requestIDKey); len(values) > 0 {
+				id = values[0]
+			}
+		}
+		if !requestid.Valid(id) {
+			id = uuid.New().String()
+		}
+		if err := grpc.SetHeader(ctx, metadata.Pairs(requestIDKey, id)); err != nil {
+			logrus.WithContext(ctx).Error(err)
+		}
+		return handler(requestid.NewContext(ctx, id), req)
+	}
+}
+
+// publicMethods are the methods served without authentication
+var publicMethods = map[string]bool{
+	"/wallet.WalletService/InitWallet": true,
+}
+
+// methodScopes are the scopes the token has to grant for each method, a method missing here is refused
+var methodScopes = map[string]string{
+	"/wallet.WalletService/FetchWallet":   models.ScopeWalletRead,
+	"/wallet.WalletService/EnableWallet":  models.ScopeWalletWrite,
+	"/wallet.WalletService/Deposit":       models.ScopeWalletWrite,
+	"/wallet.WalletService/Withdraw":      models.ScopeWalletWrite,
+	"/wallet.WalletService/DisableWallet": models.ScopeWalletWrite,
+}
+
+// NewAuthInterceptor will create the interceptor authenticating the "authorization" metadata of a call once,
+// the gRPC counterpart of the Authorization header, and putting the Principal in its context.
+// The Principal has to be granted the scope of the method in methodScopes, the public methods are served without
+// the metadata but check it when it is sent.
+func NewAuthInterceptor(v auth.Verifier) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		var authorization string
+		if md, ok := metadata.FromIncomingContext(ctx); ok {
+			if values := md.Get("authorization"); len(values) > 0 {
+				authorization = values[0]
+			}
+		}
+		public := publicMethods[info.FullMethod]
+		if public && authorization == "" {
+			return handler(ctx, req)
+		}
+		principal, err := v.Verify(authorization)
+		if err != nil {
+			return nil, getStatus(ctx, err)
+		}
+		if public {
+			return handler(auth.NewContext(ctx, principal), req)
+		}
+		if scope, ok := methodScopes[info.FullMethod]; !ok || !principal.HasScope(scope) {
+			return nil, getStatus(ctx, models.ErrForbidden)
+		}
+		return handler(auth.NewContext(ctx, principal), req)
+	}
+}
+
+func toWallet(w *models.FetchWallet) *walletpb.Wallet {
+	return &walletpb.Wallet{
+		Id:               w.ID,
+		OwnedBy:          w.OwnedBy,
+		Status:           w.Status,
+		EnabledAt:        toTimestamp(w.EnabledAt),
+		Balance:          w.Balance,
+		AvailableBalance: w.AvailableBalance,
+		Currency:         w.Currency,
+	}
+}
+
+func toTimestamp(t time.Time) *timestamp.Timestamp {
+	if t.IsZero() {
+		return nil
+	}
+	ts, err := ptypes.TimestampProto(t)
+	if err != nil {
+		logrus.Error(err)
+		return nil
+	}
+	return ts
+}
+
+func isRequestValid(m interface{}) (bool, error) {
+	validate := validator.New()
+	err := validate.Struct(m)
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// getStatus map err to the gRPC status matching the HTTP status of its code, the code goes in the details
+// along with the LimitError or FundsError if there is one
+func getStatus(ctx context.Context, err error) error {
+	if err == nil {
+		return nil
+	}
+	logrus.WithContext(ctx).Error(err)
+	e := models.ErrorOf(err)
+
+	var code codes.Code
+	switch e.Status {
+	case http.StatusNotFound:
+		code = codes.NotFound
+	case http.StatusBadRequest:
+		code = codes.InvalidArgument
+	case http.StatusConflict:
+		code = codes.AlreadyExists
+	case http.StatusUnauthorized:
+		code = codes.Unauthenticated
+	case http.StatusForbidden:
+		code = codes.PermissionDenied
+	case http.StatusUnprocessableEntity:
+		code = codes.FailedPrecondition
+	case http.StatusTooManyRequests:
+		code = codes.ResourceExhausted
+	default:
+		code = codes.Internal
+	}
+
+	st := status.New(code, e.Message)
+	details := []proto.Message{&walletpb.ErrorDetail{Code: e.Code}}
+	switch cause := e.Err.(type) {
+	case *models.LimitError:
+		details = append(details, &walletpb.LimitError{
+			Limit:    cause.Limit,
+			Max:      cause.Max,
+			Used:     cause.Used,
+			Currency: cause.Currency,
+			ResetsAt: toResetsAt(cause.ResetsAt),
+		})
+	case *models.FundsError:
+		details = append(details, &walletpb.FundsError{
+			Available: cause.Available,
+			Required:  cause.Required,
+			Currency:  cause.Currency,
+		})
+	}
+	detailed, derr := st.WithDetails(details...)
+	if derr != nil {
+		logrus.Error(derr)
+		return st.Err()
+	}
+	return detailed.Err()
+}
+
+func toResetsAt(t *time.Time) *timestamp.Timestamp {
+	if t == nil {
+		return nil
+	}
+	return toTimestamp(*t)
+}
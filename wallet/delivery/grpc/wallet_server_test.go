@@ -0,0 +1,282 @@
+package grpc_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/williamchand/my-wallet/middleware"
+	"github.com/williamchand/my-wallet/models"
+	"github.com/williamchand/my-wallet/requestid"
+	"github.com/williamchand/my-wallet/wallet"
+	_walletGrpcDeliver "github.com/williamchand/my-wallet/wallet/delivery/grpc"
+	"github.com/williamchand/my-wallet/wallet/delivery/grpc/walletpb"
+)
+
+const (
+	token         = "Token valid"
+	readOnlyToken = "Token read-only"
+)
+
+// verifierStub accept token, granted the owner scopes, and readOnlyToken, granted wallet:read only
+type verifierStub struct{}
+
+func (verifierStub) Verify(authorization string) (*models.Principal, error) {
+	switch authorization {
+	case token:
+		return &models.Principal{CustomerID: "customer-1", WalletID: "wallet-1", Scopes: models.OwnerScopes}, nil
+	case readOnlyToken:
+		return &models.Principal{CustomerID: "customer-1", WalletID: "wallet-1", Scopes: []string{models.ScopeWalletRead}}, nil
+	}
+	return nil, models.ErrUnauthorized
+}
+
+// usecaseStub serve the wallet of the holder of token, the methods the service does not call are left to the embedded nil
+type usecaseStub struct {
+	wallet.Usecase
+	balance int64
+	err     error
+	// principal is the one of the last InitWallet call
+	principal *models.Principal
+}
+
+func (u *usecaseStub) FetchWallet(ctx context.Context, principal *models.Principal) (*models.FetchWallet, error) {
+	return &models.FetchWallet{ID: principal.WalletID, OwnedBy: principal.CustomerID, Status: "enabled", Balance: u.balance, Currency: "IDR"}, nil
+}
+
+func (u *usecaseStub) AddWallet(ctx context.Context, req *models.ReqTransaction, principal *models.Principal) (*models.TransactionDeposit, error) {
+	if u.err != nil {
+		return nil, u.err
+	}
+	u.balance += req.Amount
+	return &models.TransactionDeposit{ReferenceID: req.ReferenceID, ID: "wallet-1", Amount: req.Amount, Currency: "IDR",
+		Status: models.TransactionStatusSuccess, DepositBy: "customer-1", DepositAt: time.Now()}, nil
+}
+
+func (u *usecaseStub) InitWallet(ctx context.Context, customerID string, currency string, principal *models.Principal) (*models.Account, error) {
+	u.principal = principal
+	return &models.Account{
+		Wallet: &models.FetchWallet{ID: "wallet-1", OwnedBy: customerID, Status: "disabled", Currency: currency},
+		Token:  &models.Token{Token: "valid", Type: "Token", ExpiresAt: time.Now().Add(time.Hour)},
+	}, nil
+}
+
+func dial(t *testing.T, us wallet.Usecase) (walletpb.WalletServiceClient, func()) {
+	return dialWith(t, us, _walletGrpcDeliver.NewAuthInterceptor(verifierStub{}))
+}
+
+func dialWith(t *testing.T, us wallet.Usecase, interceptor grpc.UnaryServerInterceptor) (walletpb.WalletServiceClient, func()) {
+	lis := bufconn.Listen(1024 * 1024)
+	s := grpc.NewServer(grpc.UnaryInterceptor(interceptor))
+	_walletGrpcDeliver.NewWalletServer(s, us)
+	go func() {
+		_ = s.Serve(lis)
+	}()
+
+	conn, err := grpc.DialContext(context.Background(), "bufnet", grpc.WithInsecure(),
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.Dial()
+		}))
+	require.NoError(t, err)
+	return walletpb.NewWalletServiceClient(conn), func() {
+		_ = conn.Close()
+		s.Stop()
+	}
+}
+
+func withToken(authorization string) context.Context {
+	return metadata.AppendToOutgoingContext(context.Background(), "authorization", authorization)
+}
+
+func TestDepositAndFetch(t *testing.T) {
+	client, stop := dial(t, &usecaseStub{})
+	defer stop()
+
+	deposit, err := client.Deposit(withToken(token), &walletpb.TransactionRequest{ReferenceId: "ref-1", Amount: 100})
+	require.NoError(t, err)
+	assert.Equal(t, "ref-1", deposit.ReferenceId)
+	assert.Equal(t, int64(100), deposit.Amount)
+	assert.NotNil(t, deposit.DepositedAt)
+
+	res, err := client.FetchWallet(withToken(token), &walletpb.FetchWalletRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, "wallet-1", res.Wallet.Id)
+	assert.Equal(t, int64(100), res.Wallet.Balance)
+}
+
+func TestInitWallet(t *testing.T) {
+	us := &usecaseStub{}
+	client, stop := dial(t, us)
+	defer stop()
+
+	res, err := client.InitWallet(context.Background(), &walletpb.InitWalletRequest{CustomerId: "customer-1", Currency: "IDR"})
+	require.NoError(t, err)
+	assert.Equal(t, "customer-1", res.Wallet.OwnedBy)
+	assert.Equal(t, "valid", res.Token.Token)
+	assert.Nil(t, us.principal)
+
+	_, err = client.InitWallet(withToken(token), &walletpb.InitWalletRequest{CustomerId: "customer-1", Currency: "USD"})
+	require.NoError(t, err)
+	if assert.NotNil(t, us.principal, "a token sent to a public method is passed on") {
+		assert.Equal(t, "customer-1", us.principal.CustomerID)
+	}
+	_, err = client.InitWallet(withToken("Token expired"), &walletpb.InitWalletRequest{CustomerId: "customer-1", Currency: "USD"})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	_, err = client.InitWallet(context.Background(), &walletpb.InitWalletRequest{})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestErrorCodes(t *testing.T) {
+	us := &usecaseStub{}
+	client, stop := dial(t, us)
+	defer stop()
+	valid := &walletpb.TransactionRequest{ReferenceId: "ref-1", Amount: 100}
+
+	_, err := client.FetchWallet(context.Background(), &walletpb.FetchWalletRequest{})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err), "no authorization metadata")
+
+	_, err = client.FetchWallet(withToken(readOnlyToken), &walletpb.FetchWalletRequest{})
+	assert.NoError(t, err)
+	_, err = client.Deposit(withToken(readOnlyToken), valid)
+	assert.Equal(t, codes.PermissionDenied, status.Code(err), "wallet:write not granted")
+
+	_, err = client.Deposit(withToken(token), &walletpb.TransactionRequest{ReferenceId: "ref-1"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err), "missing amount")
+
+	_, err = client.DisableWallet(withToken(token), &walletpb.DisableWalletRequest{})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err), "is_disabled unset")
+
+	for want, e := range map[codes.Code]error{
+		codes.NotFound:           models.ErrDisabled,
+		codes.AlreadyExists:      models.ErrConflict,
+		codes.FailedPrecondition: models.ErrCurrencyMismatch,
+		codes.Internal:           models.ErrInternalServerError,
+	} {
+		us.err = e
+		_, err = client.Deposit(withToken(token), valid)
+		assert.Equal(t, want, status.Code(err), e.Error())
+	}
+
+	us.err = fmt.Errorf("deposit: %w", models.ErrDisabled)
+	_, err = client.Deposit(withToken(token), valid)
+	assert.Equal(t, codes.NotFound, status.Code(err), "a wrapped Error maps as the Error")
+	us.err = fmt.Errorf("deposit: %w", &models.LimitError{Limit: "daily_deposit", Max: 1000, Used: 950, Currency: "IDR"})
+	_, err = client.Deposit(withToken(token), valid)
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+
+	us.err = errors.New("dial tcp 10.0.0.1:3306: connection refused")
+	_, err = client.Deposit(withToken(token), valid)
+	assert.Equal(t, codes.Internal, status.Code(err))
+	assert.Equal(t, models.ErrInternalServerError.Message, status.Convert(err).Message(), "the cause is not returned")
+
+	us.err = &models.LimitError{Limit: "daily_deposit", Max: 1000, Used: 950, Currency: "IDR"}
+	_, err = client.Deposit(withToken(token), valid)
+	st := status.Convert(err)
+	assert.Equal(t, codes.FailedPrecondition, st.Code())
+	require.Len(t, st.Details(), 2)
+	detail, ok := st.Details()[0].(*walletpb.ErrorDetail)
+	require.True(t, ok)
+	assert.Equal(t, models.CodeLimitExceeded, detail.Code)
+	limit, ok := st.Details()[1].(*walletpb.LimitError)
+	require.True(t, ok)
+	assert.Equal(t, "daily_deposit", limit.Limit)
+	assert.Equal(t, int64(950), limit.Used)
+
+	us.err = &models.FundsError{Available: 40, Required: 100, Currency: "IDR"}
+	_, err = client.Deposit(withToken(token), valid)
+	st = status.Convert(err)
+	assert.Equal(t, codes.FailedPrecondition, st.Code())
+	require.Len(t, st.Details(), 2)
+	funds, ok := st.Details()[1].(*walletpb.FundsError)
+	require.True(t, ok)
+	assert.Equal(t, int64(40), funds.Available)
+}
+
+func TestRateLimit(t *testing.T) {
+	l, err := middleware.NewLimiter(middleware.NewMemoryStore(), middleware.RateLimitConfig{
+		IP:      middleware.RateLimit{Limit: 4, Window: 60},
+		Default: middleware.RateLimit{Limit: 10, Window: 60},
+		Routes: []middleware.RouteRateLimit{
+			{Method: "POST", Path: "/api/v1/wallet/deposits", RateLimit: middleware.RateLimit{Limit: 1, Window: 60}},
+		},
+	})
+	require.NoError(t, err)
+	client, stop := dialWith(t, &usecaseStub{}, _walletGrpcDeliver.ChainUnaryInterceptors(
+		_walletGrpcDeliver.NewIPRateLimitInterceptor(l),
+		_walletGrpcDeliver.NewAuthInterceptor(verifierStub{}),
+		_walletGrpcDeliver.NewRateLimitInterceptor(l),
+	))
+	defer stop()
+	valid := &walletpb.TransactionRequest{ReferenceId: "ref-1", Amount: 100}
+
+	var header metadata.MD
+	_, err = client.Deposit(withToken(token), valid, grpc.Header(&header))
+	require.NoError(t, err)
+	assert.Contains(t, header.Get("ratelimit-limit"), "1")
+
+	_, err = client.Deposit(withToken(token), valid, grpc.Header(&header))
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err), "Deposit shares the bucket of its HTTP route")
+	assert.Equal(t, []string{"60"}, header.Get("retry-after"))
+	_, err = client.FetchWallet(withToken(token), &walletpb.FetchWalletRequest{})
+	assert.NoError(t, err, "the other methods use the default bucket")
+
+	_, err = client.FetchWallet(withToken("Token invalid"), &walletpb.FetchWalletRequest{})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	_, err = client.FetchWallet(withToken("Token invalid"), &walletpb.FetchWalletRequest{})
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err), "the calls with an invalid token count against the IP")
+}
+
+func TestRequestID(t *testing.T) {
+	var out bytes.Buffer
+	log := logrus.StandardLogger()
+	output, formatter := log.Out, log.Formatter
+	hooks := log.ReplaceHooks(logrus.LevelHooks{})
+	log.AddHook(requestid.Hook{})
+	log.SetOutput(&out)
+	log.SetFormatter(&logrus.JSONFormatter{})
+	defer func() {
+		log.SetOutput(output)
+		log.SetFormatter(formatter)
+		log.ReplaceHooks(hooks)
+	}()
+
+	client, stop := dialWith(t, &usecaseStub{}, _walletGrpcDeliver.ChainUnaryInterceptors(
+		_walletGrpcDeliver.NewRequestIDInterceptor(),
+		_walletGrpcDeliver.NewAuthInterceptor(verifierStub{}),
+	))
+	defer stop()
+
+	var header metadata.MD
+	ctx := metadata.AppendToOutgoingContext(withToken("Token invalid"), "x-request-id", "req-1")
+	_, err := client.FetchWallet(ctx, &walletpb.FetchWalletRequest{}, grpc.Header(&header))
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	assert.Equal(t, []string{"req-1"}, header.Get("x-request-id"))
+	var entry map[string]interface{}
+	require.NoError(t, json.Unmarshal(out.Bytes(), &entry))
+	assert.Equal(t, "req-1", entry[requestid.Field], "the errors of a call are logged with its request ID")
+
+	_, err = client.FetchWallet(withToken(token), &walletpb.FetchWalletRequest{}, grpc.Header(&header))
+	require.NoError(t, err)
+	require.Len(t, header.Get("x-request-id"), 1)
+	assert.NotEmpty(t, header.Get("x-request-id")[0], "a call without a request ID gets one")
+
+	ctx = metadata.AppendToOutgoingContext(withToken(token), "x-request-id", "req-1 level=info")
+	_, err = client.FetchWallet(ctx, &walletpb.FetchWalletRequest{}, grpc.Header(&header))
+	require.NoError(t, err)
+	assert.NotEqual(t, []string{"req-1 level=info"}, header.Get("x-request-id"), "malformed request IDs are replaced")
+}
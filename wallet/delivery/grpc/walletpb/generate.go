@@ -0,0 +1,4 @@
+// Package walletpb holds the gRPC contract of the wallet service, wallet.pb.go is generated from wallet.proto
+package walletpb
+
+//go:generate protoc --go_out=plugins=grpc,paths=source_relative:. wallet.proto
@@ -0,0 +1,1244 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// source: wallet.proto
+
+package walletpb
+
+import (
+	context "context"
+	fmt "fmt"
+	proto "github.com/golang/protobuf/proto"
+	timestamp "github.com/golang/protobuf/ptypes/timestamp"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+	math "math"
+)
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ = proto.Marshal
+var _ = fmt.Errorf
+var _ = math.Inf
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the proto package it is being compiled against.
+// A compilation error at this line likely means your copy of the
+// proto package needs to be updated.
+const _ = proto.ProtoPackageIsVersion3 // please upgrade the proto package
+
+type EnableWalletRequest struct {
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *EnableWalletRequest) Reset()         { *m = EnableWalletRequest{} }
+func (m *EnableWalletRequest) String() string { return proto.CompactTextString(m) }
+func (*EnableWalletRequest) ProtoMessage()    {}
+func (*EnableWalletRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_b88fd140af4deb6f, []int{0}
+}
+
+func (m *EnableWalletRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_EnableWalletRequest.Unmarshal(m, b)
+}
+func (m *EnableWalletRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_EnableWalletRequest.Marshal(b, m, deterministic)
+}
+func (m *EnableWalletRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_EnableWalletRequest.Merge(m, src)
+}
+func (m *EnableWalletRequest) XXX_Size() int {
+	return xxx_messageInfo_EnableWalletRequest.Size(m)
+}
+func (m *EnableWalletRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_EnableWalletRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_EnableWalletRequest proto.InternalMessageInfo
+
+type FetchWalletRequest struct {
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *FetchWalletRequest) Reset()         { *m = FetchWalletRequest{} }
+func (m *FetchWalletRequest) String() string { return proto.CompactTextString(m) }
+func (*FetchWalletRequest) ProtoMessage()    {}
+func (*FetchWalletRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_b88fd140af4deb6f, []int{1}
+}
+
+func (m *FetchWalletRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_FetchWalletRequest.Unmarshal(m, b)
+}
+func (m *FetchWalletRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_FetchWalletRequest.Marshal(b, m, deterministic)
+}
+func (m *FetchWalletRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_FetchWalletRequest.Merge(m, src)
+}
+func (m *FetchWalletRequest) XXX_Size() int {
+	return xxx_messageInfo_FetchWalletRequest.Size(m)
+}
+func (m *FetchWalletRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_FetchWalletRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_FetchWalletRequest proto.InternalMessageInfo
+
+// TransactionRequest is the body of a deposit or a withdrawal, amount is in minor units of currency
+// and an empty currency means the wallet's own one
+type TransactionRequest struct {
+	ReferenceId          string   `protobuf:"bytes,1,opt,name=reference_id,json=referenceId,proto3" json:"reference_id,omitempty"`
+	Amount               int64    `protobuf:"varint,2,opt,name=amount,proto3" json:"amount,omitempty"`
+	Currency             string   `protobuf:"bytes,3,opt,name=currency,proto3" json:"currency,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *TransactionRequest) Reset()         { *m = TransactionRequest{} }
+func (m *TransactionRequest) String() string { return proto.CompactTextString(m) }
+func (*TransactionRequest) ProtoMessage()    {}
+func (*TransactionRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_b88fd140af4deb6f, []int{2}
+}
+
+func (m *TransactionRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_TransactionRequest.Unmarshal(m, b)
+}
+func (m *TransactionRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_TransactionRequest.Marshal(b, m, deterministic)
+}
+func (m *TransactionRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_TransactionRequest.Merge(m, src)
+}
+func (m *TransactionRequest) XXX_Size() int {
+	return xxx_messageInfo_TransactionRequest.Size(m)
+}
+func (m *TransactionRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_TransactionRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_TransactionRequest proto.InternalMessageInfo
+
+func (m *TransactionRequest) GetReferenceId() string {
+	if m != nil {
+		return m.ReferenceId
+	}
+	return ""
+}
+
+func (m *TransactionRequest) GetAmount() int64 {
+	if m != nil {
+		return m.Amount
+	}
+	return 0
+}
+
+func (m *TransactionRequest) GetCurrency() string {
+	if m != nil {
+		return m.Currency
+	}
+	return ""
+}
+
+type DisableWalletRequest struct {
+	IsDisabled           bool     `protobuf:"varint,1,opt,name=is_disabled,json=isDisabled,proto3" json:"is_disabled,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *DisableWalletRequest) Reset()         { *m = DisableWalletRequest{} }
+func (m *DisableWalletRequest) String() string { return proto.CompactTextString(m) }
+func (*DisableWalletRequest) ProtoMessage()    {}
+func (*DisableWalletRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_b88fd140af4deb6f, []int{3}
+}
+
+func (m *DisableWalletRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_DisableWalletRequest.Unmarshal(m, b)
+}
+func (m *DisableWalletRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_DisableWalletRequest.Marshal(b, m, deterministic)
+}
+func (m *DisableWalletRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_DisableWalletRequest.Merge(m, src)
+}
+func (m *DisableWalletRequest) XXX_Size() int {
+	return xxx_messageInfo_DisableWalletRequest.Size(m)
+}
+func (m *DisableWalletRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_DisableWalletRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_DisableWalletRequest proto.InternalMessageInfo
+
+func (m *DisableWalletRequest) GetIsDisabled() bool {
+	if m != nil {
+		return m.IsDisabled
+	}
+	return false
+}
+
+type InitWalletRequest struct {
+	CustomerId           string   `protobuf:"bytes,1,opt,name=customer_id,json=customerId,proto3" json:"customer_id,omitempty"`
+	Currency             string   `protobuf:"bytes,2,opt,name=currency,proto3" json:"currency,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *InitWalletRequest) Reset()         { *m = InitWalletRequest{} }
+func (m *InitWalletRequest) String() string { return proto.CompactTextString(m) }
+func (*InitWalletRequest) ProtoMessage()    {}
+func (*InitWalletRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_b88fd140af4deb6f, []int{4}
+}
+
+func (m *InitWalletRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_InitWalletRequest.Unmarshal(m, b)
+}
+func (m *InitWalletRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_InitWalletRequest.Marshal(b, m, deterministic)
+}
+func (m *InitWalletRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_InitWalletRequest.Merge(m, src)
+}
+func (m *InitWalletRequest) XXX_Size() int {
+	return xxx_messageInfo_InitWalletRequest.Size(m)
+}
+func (m *InitWalletRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_InitWalletRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_InitWalletRequest proto.InternalMessageInfo
+
+func (m *InitWalletRequest) GetCustomerId() string {
+	if m != nil {
+		return m.CustomerId
+	}
+	return ""
+}
+
+func (m *InitWalletRequest) GetCurrency() string {
+	if m != nil {
+		return m.Currency
+	}
+	return ""
+}
+
+type Wallet struct {
+	Id                   string               `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	OwnedBy              string               `protobuf:"bytes,2,opt,name=owned_by,json=ownedBy,proto3" json:"owned_by,omitempty"`
+	Status               string               `protobuf:"bytes,3,opt,name=status,proto3" json:"status,omitempty"`
+	EnabledAt            *timestamp.Timestamp `protobuf:"bytes,4,opt,name=enabled_at,json=enabledAt,proto3" json:"enabled_at,omitempty"`
+	Balance              int64                `protobuf:"varint,5,opt,name=balance,proto3" json:"balance,omitempty"`
+	AvailableBalance     int64                `protobuf:"varint,6,opt,name=available_balance,json=availableBalance,proto3" json:"available_balance,omitempty"`
+	Currency             string               `protobuf:"bytes,7,opt,name=currency,proto3" json:"currency,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}             `json:"-"`
+	XXX_unrecognized     []byte               `json:"-"`
+	XXX_sizecache        int32                `json:"-"`
+}
+
+func (m *Wallet) Reset()         { *m = Wallet{} }
+func (m *Wallet) String() string { return proto.CompactTextString(m) }
+func (*Wallet) ProtoMessage()    {}
+func (*Wallet) Descriptor() ([]byte, []int) {
+	return fileDescriptor_b88fd140af4deb6f, []int{5}
+}
+
+func (m *Wallet) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Wallet.Unmarshal(m, b)
+}
+func (m *Wallet) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Wallet.Marshal(b, m, deterministic)
+}
+func (m *Wallet) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Wallet.Merge(m, src)
+}
+func (m *Wallet) XXX_Size() int {
+	return xxx_messageInfo_Wallet.Size(m)
+}
+func (m *Wallet) XXX_DiscardUnknown() {
+	xxx_messageInfo_Wallet.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Wallet proto.InternalMessageInfo
+
+func (m *Wallet) GetId() string {
+	if m != nil {
+		return m.Id
+	}
+	return ""
+}
+
+func (m *Wallet) GetOwnedBy() string {
+	if m != nil {
+		return m.OwnedBy
+	}
+	return ""
+}
+
+func (m *Wallet) GetStatus() string {
+	if m != nil {
+		return m.Status
+	}
+	return ""
+}
+
+func (m *Wallet) GetEnabledAt() *timestamp.Timestamp {
+	if m != nil {
+		return m.EnabledAt
+	}
+	return nil
+}
+
+func (m *Wallet) GetBalance() int64 {
+	if m != nil {
+		return m.Balance
+	}
+	return 0
+}
+
+func (m *Wallet) GetAvailableBalance() int64 {
+	if m != nil {
+		return m.AvailableBalance
+	}
+	return 0
+}
+
+func (m *Wallet) GetCurrency() string {
+	if m != nil {
+		return m.Currency
+	}
+	return ""
+}
+
+type WalletResponse struct {
+	Wallet               *Wallet  `protobuf:"bytes,1,opt,name=wallet,proto3" json:"wallet,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *WalletResponse) Reset()         { *m = WalletResponse{} }
+func (m *WalletResponse) String() string { return proto.CompactTextString(m) }
+func (*WalletResponse) ProtoMessage()    {}
+func (*WalletResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_b88fd140af4deb6f, []int{6}
+}
+
+func (m *WalletResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_WalletResponse.Unmarshal(m, b)
+}
+func (m *WalletResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_WalletResponse.Marshal(b, m, deterministic)
+}
+func (m *WalletResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_WalletResponse.Merge(m, src)
+}
+func (m *WalletResponse) XXX_Size() int {
+	return xxx_messageInfo_WalletResponse.Size(m)
+}
+func (m *WalletResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_WalletResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_WalletResponse proto.InternalMessageInfo
+
+func (m *WalletResponse) GetWallet() *Wallet {
+	if m != nil {
+		return m.Wallet
+	}
+	return nil
+}
+
+type DepositResponse struct {
+	ReferenceId          string               `protobuf:"bytes,1,opt,name=reference_id,json=referenceId,proto3" json:"reference_id,omitempty"`
+	Id                   string               `protobuf:"bytes,2,opt,name=id,proto3" json:"id,omitempty"`
+	Amount               int64                `protobuf:"varint,3,opt,name=amount,proto3" json:"amount,omitempty"`
+	Currency             string               `protobuf:"bytes,4,opt,name=currency,proto3" json:"currency,omitempty"`
+	Status               string               `protobuf:"bytes,5,opt,name=status,proto3" json:"status,omitempty"`
+	DepositedBy          string               `protobuf:"bytes,6,opt,name=deposited_by,json=depositedBy,proto3" json:"deposited_by,omitempty"`
+	DepositedAt          *timestamp.Timestamp `protobuf:"bytes,7,opt,name=deposited_at,json=depositedAt,proto3" json:"deposited_at,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}             `json:"-"`
+	XXX_unrecognized     []byte               `json:"-"`
+	XXX_sizecache        int32                `json:"-"`
+}
+
+func (m *DepositResponse) Reset()         { *m = DepositResponse{} }
+func (m *DepositResponse) String() string { return proto.CompactTextString(m) }
+func (*DepositResponse) ProtoMessage()    {}
+func (*DepositResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_b88fd140af4deb6f, []int{7}
+}
+
+func (m *DepositResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_DepositResponse.Unmarshal(m, b)
+}
+func (m *DepositResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_DepositResponse.Marshal(b, m, deterministic)
+}
+func (m *DepositResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_DepositResponse.Merge(m, src)
+}
+func (m *DepositResponse) XXX_Size() int {
+	return xxx_messageInfo_DepositResponse.Size(m)
+}
+func (m *DepositResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_DepositResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_DepositResponse proto.InternalMessageInfo
+
+func (m *DepositResponse) GetReferenceId() string {
+	if m != nil {
+		return m.ReferenceId
+	}
+	return ""
+}
+
+func (m *DepositResponse) GetId() string {
+	if m != nil {
+		return m.Id
+	}
+	return ""
+}
+
+func (m *DepositResponse) GetAmount() int64 {
+	if m != nil {
+		return m.Amount
+	}
+	return 0
+}
+
+func (m *DepositResponse) GetCurrency() string {
+	if m != nil {
+		return m.Currency
+	}
+	return ""
+}
+
+func (m *DepositResponse) GetStatus() string {
+	if m != nil {
+		return m.Status
+	}
+	return ""
+}
+
+func (m *DepositResponse) GetDepositedBy() string {
+	if m != nil {
+		return m.DepositedBy
+	}
+	return ""
+}
+
+func (m *DepositResponse) GetDepositedAt() *timestamp.Timestamp {
+	if m != nil {
+		return m.DepositedAt
+	}
+	return nil
+}
+
+type WithdrawalResponse struct {
+	ReferenceId          string               `protobuf:"bytes,1,opt,name=reference_id,json=referenceId,proto3" json:"reference_id,omitempty"`
+	Id                   string               `protobuf:"bytes,2,opt,name=id,proto3" json:"id,omitempty"`
+	Amount               int64                `protobuf:"varint,3,opt,name=amount,proto3" json:"amount,omitempty"`
+	GrossAmount          int64                `protobuf:"varint,4,opt,name=gross_amount,json=grossAmount,proto3" json:"gross_amount,omitempty"`
+	Fee                  int64                `protobuf:"varint,5,opt,name=fee,proto3" json:"fee,omitempty"`
+	NetAmount            int64                `protobuf:"varint,6,opt,name=net_amount,json=netAmount,proto3" json:"net_amount,omitempty"`
+	Currency             string               `protobuf:"bytes,7,opt,name=currency,proto3" json:"currency,omitempty"`
+	Status               string               `protobuf:"bytes,8,opt,name=status,proto3" json:"status,omitempty"`
+	WithdrawnBy          string               `protobuf:"bytes,9,opt,name=withdrawn_by,json=withdrawnBy,proto3" json:"withdrawn_by,omitempty"`
+	WithdrawnAt          *timestamp.Timestamp `protobuf:"bytes,10,opt,name=withdrawn_at,json=withdrawnAt,proto3" json:"withdrawn_at,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}             `json:"-"`
+	XXX_unrecognized     []byte               `json:"-"`
+	XXX_sizecache        int32                `json:"-"`
+}
+
+func (m *WithdrawalResponse) Reset()         { *m = WithdrawalResponse{} }
+func (m *WithdrawalResponse) String() string { return proto.CompactTextString(m) }
+func (*WithdrawalResponse) ProtoMessage()    {}
+func (*WithdrawalResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_b88fd140af4deb6f, []int{8}
+}
+
+func (m *WithdrawalResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_WithdrawalResponse.Unmarshal(m, b)
+}
+func (m *WithdrawalResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_WithdrawalResponse.Marshal(b, m, deterministic)
+}
+func (m *WithdrawalResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_WithdrawalResponse.Merge(m, src)
+}
+func (m *WithdrawalResponse) XXX_Size() int {
+	return xxx_messageInfo_WithdrawalResponse.Size(m)
+}
+func (m *WithdrawalResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_WithdrawalResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_WithdrawalResponse proto.InternalMessageInfo
+
+func (m *WithdrawalResponse) GetReferenceId() string {
+	if m != nil {
+		return m.ReferenceId
+	}
+	return ""
+}
+
+func (m *WithdrawalResponse) GetId() string {
+	if m != nil {
+		return m.Id
+	}
+	return ""
+}
+
+func (m *WithdrawalResponse) GetAmount() int64 {
+	if m != nil {
+		return m.Amount
+	}
+	return 0
+}
+
+func (m *WithdrawalResponse) GetGrossAmount() int64 {
+	if m != nil {
+		return m.GrossAmount
+	}
+	return 0
+}
+
+func (m *WithdrawalResponse) GetFee() int64 {
+	if m != nil {
+		return m.Fee
+	}
+	return 0
+}
+
+func (m *WithdrawalResponse) GetNetAmount() int64 {
+	if m != nil {
+		return m.NetAmount
+	}
+	return 0
+}
+
+func (m *WithdrawalResponse) GetCurrency() string {
+	if m != nil {
+		return m.Currency
+	}
+	return ""
+}
+
+func (m *WithdrawalResponse) GetStatus() string {
+	if m != nil {
+		return m.Status
+	}
+	return ""
+}
+
+func (m *WithdrawalResponse) GetWithdrawnBy() string {
+	if m != nil {
+		return m.WithdrawnBy
+	}
+	return ""
+}
+
+func (m *WithdrawalResponse) GetWithdrawnAt() *timestamp.Timestamp {
+	if m != nil {
+		return m.WithdrawnAt
+	}
+	return nil
+}
+
+type WalletDisabledResponse struct {
+	Id                   string               `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	OwnedBy              string               `protobuf:"bytes,2,opt,name=owned_by,json=ownedBy,proto3" json:"owned_by,omitempty"`
+	Status               string               `protobuf:"bytes,3,opt,name=status,proto3" json:"status,omitempty"`
+	DisabledAt           *timestamp.Timestamp `protobuf:"bytes,4,opt,name=disabled_at,json=disabledAt,proto3" json:"disabled_at,omitempty"`
+	Balance              int64                `protobuf:"varint,5,opt,name=balance,proto3" json:"balance,omitempty"`
+	Currency             string               `protobuf:"bytes,6,opt,name=currency,proto3" json:"currency,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}             `json:"-"`
+	XXX_unrecognized     []byte               `json:"-"`
+	XXX_sizecache        int32                `json:"-"`
+}
+
+func (m *WalletDisabledResponse) Reset()         { *m = WalletDisabledResponse{} }
+func (m *WalletDisabledResponse) String() string { return proto.CompactTextString(m) }
+func (*WalletDisabledResponse) ProtoMessage()    {}
+func (*WalletDisabledResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_b88fd140af4deb6f, []int{9}
+}
+
+func (m *WalletDisabledResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_WalletDisabledResponse.Unmarshal(m, b)
+}
+func (m *WalletDisabledResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_WalletDisabledResponse.Marshal(b, m, deterministic)
+}
+func (m *WalletDisabledResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_WalletDisabledResponse.Merge(m, src)
+}
+func (m *WalletDisabledResponse) XXX_Size() int {
+	return xxx_messageInfo_WalletDisabledResponse.Size(m)
+}
+func (m *WalletDisabledResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_WalletDisabledResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_WalletDisabledResponse proto.InternalMessageInfo
+
+func (m *WalletDisabledResponse) GetId() string {
+	if m != nil {
+		return m.Id
+	}
+	return ""
+}
+
+func (m *WalletDisabledResponse) GetOwnedBy() string {
+	if m != nil {
+		return m.OwnedBy
+	}
+	return ""
+}
+
+func (m *WalletDisabledResponse) GetStatus() string {
+	if m != nil {
+		return m.Status
+	}
+	return ""
+}
+
+func (m *WalletDisabledResponse) GetDisabledAt() *timestamp.Timestamp {
+	if m != nil {
+		return m.DisabledAt
+	}
+	return nil
+}
+
+func (m *WalletDisabledResponse) GetBalance() int64 {
+	if m != nil {
+		return m.Balance
+	}
+	return 0
+}
+
+func (m *WalletDisabledResponse) GetCurrency() string {
+	if m != nil {
+		return m.Currency
+	}
+	return ""
+}
+
+type Token struct {
+	Token                string               `protobuf:"bytes,1,opt,name=token,proto3" json:"token,omitempty"`
+	TokenType            string               `protobuf:"bytes,2,opt,name=token_type,json=tokenType,proto3" json:"token_type,omitempty"`
+	ExpiresAt            *timestamp.Timestamp `protobuf:"bytes,3,opt,name=expires_at,json=expiresAt,proto3" json:"expires_at,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}             `json:"-"`
+	XXX_unrecognized     []byte               `json:"-"`
+	XXX_sizecache        int32                `json:"-"`
+}
+
+func (m *Token) Reset()         { *m = Token{} }
+func (m *Token) String() string { return proto.CompactTextString(m) }
+func (*Token) ProtoMessage()    {}
+func (*Token) Descriptor() ([]byte, []int) {
+	return fileDescriptor_b88fd140af4deb6f, []int{10}
+}
+
+func (m *Token) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Token.Unmarshal(m, b)
+}
+func (m *Token) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Token.Marshal(b, m, deterministic)
+}
+func (m *Token) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Token.Merge(m, src)
+}
+func (m *Token) XXX_Size() int {
+	return xxx_messageInfo_Token.Size(m)
+}
+func (m *Token) XXX_DiscardUnknown() {
+	xxx_messageInfo_Token.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Token proto.InternalMessageInfo
+
+func (m *Token) GetToken() string {
+	if m != nil {
+		return m.Token
+	}
+	return ""
+}
+
+func (m *Token) GetTokenType() string {
+	if m != nil {
+		return m.TokenType
+	}
+	return ""
+}
+
+func (m *Token) GetExpiresAt() *timestamp.Timestamp {
+	if m != nil {
+		return m.ExpiresAt
+	}
+	return nil
+}
+
+type AccountResponse struct {
+	Wallet               *Wallet  `protobuf:"bytes,1,opt,name=wallet,proto3" json:"wallet,omitempty"`
+	Token                *Token   `protobuf:"bytes,2,opt,name=token,proto3" json:"token,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *AccountResponse) Reset()         { *m = AccountResponse{} }
+func (m *AccountResponse) String() string { return proto.CompactTextString(m) }
+func (*AccountResponse) ProtoMessage()    {}
+func (*AccountResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_b88fd140af4deb6f, []int{11}
+}
+
+func (m *AccountResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_AccountResponse.Unmarshal(m, b)
+}
+func (m *AccountResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_AccountResponse.Marshal(b, m, deterministic)
+}
+func (m *AccountResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_AccountResponse.Merge(m, src)
+}
+func (m *AccountResponse) XXX_Size() int {
+	return xxx_messageInfo_AccountResponse.Size(m)
+}
+func (m *AccountResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_AccountResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_AccountResponse proto.InternalMessageInfo
+
+func (m *AccountResponse) GetWallet() *Wallet {
+	if m != nil {
+		return m.Wallet
+	}
+	return nil
+}
+
+func (m *AccountResponse) GetToken() *Token {
+	if m != nil {
+		return m.Token
+	}
+	return nil
+}
+
+// LimitError is attached to the status details of a FailedPrecondition when a wallet limit is exceeded
+type LimitError struct {
+	Limit                string               `protobuf:"bytes,1,opt,name=limit,proto3" json:"limit,omitempty"`
+	Max                  int64                `protobuf:"varint,2,opt,name=max,proto3" json:"max,omitempty"`
+	Used                 int64                `protobuf:"varint,3,opt,name=used,proto3" json:"used,omitempty"`
+	Currency             string               `protobuf:"bytes,4,opt,name=currency,proto3" json:"currency,omitempty"`
+	ResetsAt             *timestamp.Timestamp `protobuf:"bytes,5,opt,name=resets_at,json=resetsAt,proto3" json:"resets_at,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}             `json:"-"`
+	XXX_unrecognized     []byte               `json:"-"`
+	XXX_sizecache        int32                `json:"-"`
+}
+
+func (m *LimitError) Reset()         { *m = LimitError{} }
+func (m *LimitError) String() string { return proto.CompactTextString(m) }
+func (*LimitError) ProtoMessage()    {}
+func (*LimitError) Descriptor() ([]byte, []int) {
+	return fileDescriptor_b88fd140af4deb6f, []int{12}
+}
+
+func (m *LimitError) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_LimitError.Unmarshal(m, b)
+}
+func (m *LimitError) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_LimitError.Marshal(b, m, deterministic)
+}
+func (m *LimitError) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_LimitError.Merge(m, src)
+}
+func (m *LimitError) XXX_Size() int {
+	return xxx_messageInfo_LimitError.Size(m)
+}
+func (m *LimitError) XXX_DiscardUnknown() {
+	xxx_messageInfo_LimitError.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_LimitError proto.InternalMessageInfo
+
+func (m *LimitError) GetLimit() string {
+	if m != nil {
+		return m.Limit
+	}
+	return ""
+}
+
+func (m *LimitError) GetMax() int64 {
+	if m != nil {
+		return m.Max
+	}
+	return 0
+}
+
+func (m *LimitError) GetUsed() int64 {
+	if m != nil {
+		return m.Used
+	}
+	return 0
+}
+
+func (m *LimitError) GetCurrency() string {
+	if m != nil {
+		return m.Currency
+	}
+	return ""
+}
+
+func (m *LimitError) GetResetsAt() *timestamp.Timestamp {
+	if m != nil {
+		return m.ResetsAt
+	}
+	return nil
+}
+
+// ErrorDetail is attached to the status details of every error, code is the stable code the HTTP API returns
+type ErrorDetail struct {
+	Code                 string   `protobuf:"bytes,1,opt,name=code,proto3" json:"code,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ErrorDetail) Reset()         { *m = ErrorDetail{} }
+func (m *ErrorDetail) String() string { return proto.CompactTextString(m) }
+func (*ErrorDetail) ProtoMessage()    {}
+func (*ErrorDetail) Descriptor() ([]byte, []int) {
+	return fileDescriptor_b88fd140af4deb6f, []int{13}
+}
+
+func (m *ErrorDetail) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ErrorDetail.Unmarshal(m, b)
+}
+func (m *ErrorDetail) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ErrorDetail.Marshal(b, m, deterministic)
+}
+func (m *ErrorDetail) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ErrorDetail.Merge(m, src)
+}
+func (m *ErrorDetail) XXX_Size() int {
+	return xxx_messageInfo_ErrorDetail.Size(m)
+}
+func (m *ErrorDetail) XXX_DiscardUnknown() {
+	xxx_messageInfo_ErrorDetail.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ErrorDetail proto.InternalMessageInfo
+
+func (m *ErrorDetail) GetCode() string {
+	if m != nil {
+		return m.Code
+	}
+	return ""
+}
+
+// FundsError is attached to the status details of a FailedPrecondition when the available balance does not cover a withdrawal
+type FundsError struct {
+	Available            int64    `protobuf:"varint,1,opt,name=available,proto3" json:"available,omitempty"`
+	Required             int64    `protobuf:"varint,2,opt,name=required,proto3" json:"required,omitempty"`
+	Currency             string   `protobuf:"bytes,3,opt,name=currency,proto3" json:"currency,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *FundsError) Reset()         { *m = FundsError{} }
+func (m *FundsError) String() string { return proto.CompactTextString(m) }
+func (*FundsError) ProtoMessage()    {}
+func (*FundsError) Descriptor() ([]byte, []int) {
+	return fileDescriptor_b88fd140af4deb6f, []int{14}
+}
+
+func (m *FundsError) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_FundsError.Unmarshal(m, b)
+}
+func (m *FundsError) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_FundsError.Marshal(b, m, deterministic)
+}
+func (m *FundsError) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_FundsError.Merge(m, src)
+}
+func (m *FundsError) XXX_Size() int {
+	return xxx_messageInfo_FundsError.Size(m)
+}
+func (m *FundsError) XXX_DiscardUnknown() {
+	xxx_messageInfo_FundsError.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_FundsError proto.InternalMessageInfo
+
+func (m *FundsError) GetAvailable() int64 {
+	if m != nil {
+		return m.Available
+	}
+	return 0
+}
+
+func (m *FundsError) GetRequired() int64 {
+	if m != nil {
+		return m.Required
+	}
+	return 0
+}
+
+func (m *FundsError) GetCurrency() string {
+	if m != nil {
+		return m.Currency
+	}
+	return ""
+}
+
+func init() {
+	proto.RegisterType((*EnableWalletRequest)(nil), "wallet.EnableWalletRequest")
+	proto.RegisterType((*FetchWalletRequest)(nil), "wallet.FetchWalletRequest")
+	proto.RegisterType((*TransactionRequest)(nil), "wallet.TransactionRequest")
+	proto.RegisterType((*DisableWalletRequest)(nil), "wallet.DisableWalletRequest")
+	proto.RegisterType((*InitWalletRequest)(nil), "wallet.InitWalletRequest")
+	proto.RegisterType((*Wallet)(nil), "wallet.Wallet")
+	proto.RegisterType((*WalletResponse)(nil), "wallet.WalletResponse")
+	proto.RegisterType((*DepositResponse)(nil), "wallet.DepositResponse")
+	proto.RegisterType((*WithdrawalResponse)(nil), "wallet.WithdrawalResponse")
+	proto.RegisterType((*WalletDisabledResponse)(nil), "wallet.WalletDisabledResponse")
+	proto.RegisterType((*Token)(nil), "wallet.Token")
+	proto.RegisterType((*AccountResponse)(nil), "wallet.AccountResponse")
+	proto.RegisterType((*LimitError)(nil), "wallet.LimitError")
+	proto.RegisterType((*ErrorDetail)(nil), "wallet.ErrorDetail")
+	proto.RegisterType((*FundsError)(nil), "wallet.FundsError")
+}
+
+func init() { proto.RegisterFile("wallet.proto", fileDescriptor_b88fd140af4deb6f) }
+
+var fileDescriptor_b88fd140af4deb6f = []byte{
+	// 886 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xb4, 0x56, 0xdb, 0x6e, 0x23, 0x45,
+	0x10, 0x95, 0xed, 0xf8, 0x56, 0x76, 0xb2, 0xbb, 0x4d, 0x08, 0x5e, 0xb3, 0xb0, 0xc9, 0x20, 0xa1,
+	0x48, 0x08, 0x5b, 0x0a, 0x0f, 0x0b, 0x42, 0x5c, 0xc6, 0x64, 0x57, 0x8a, 0x04, 0x12, 0x32, 0x91,
+	0x56, 0xe2, 0x01, 0xab, 0x67, 0xba, 0xe2, 0xb4, 0x76, 0x6e, 0xdb, 0xdd, 0x13, 0xc7, 0x1f, 0xc3,
+	0x1f, 0xf0, 0x23, 0xbc, 0xf0, 0x1f, 0xbc, 0xf2, 0x05, 0x68, 0xfa, 0x32, 0xe3, 0x4b, 0x6e, 0x48,
+	0xec, 0x53, 0xba, 0xaa, 0x4f, 0x79, 0xea, 0x9c, 0xaa, 0xae, 0x0a, 0xf4, 0x17, 0x34, 0x8a, 0x50,
+	0x8d, 0x32, 0x91, 0xaa, 0x94, 0xb4, 0x8c, 0x35, 0x7c, 0x3e, 0x4f, 0xd3, 0x79, 0x84, 0x63, 0xed,
+	0x0d, 0xf2, 0x8b, 0xb1, 0xe2, 0x31, 0x4a, 0x45, 0xe3, 0xcc, 0x00, 0xbd, 0xf7, 0xe1, 0xbd, 0x97,
+	0x09, 0x0d, 0x22, 0x7c, 0xad, 0x03, 0xa6, 0xf8, 0x36, 0x47, 0xa9, 0xbc, 0x7d, 0x20, 0xaf, 0x50,
+	0x85, 0x97, 0xeb, 0xde, 0x37, 0x40, 0xce, 0x05, 0x4d, 0x24, 0x0d, 0x15, 0x4f, 0x13, 0xeb, 0x25,
+	0x47, 0xd0, 0x17, 0x78, 0x81, 0x02, 0x93, 0x10, 0x67, 0x9c, 0x0d, 0x6a, 0x87, 0xb5, 0xe3, 0xee,
+	0xb4, 0x57, 0xfa, 0xce, 0x18, 0x39, 0x80, 0x16, 0x8d, 0xd3, 0x3c, 0x51, 0x83, 0xfa, 0x61, 0xed,
+	0xb8, 0x31, 0xb5, 0x16, 0x19, 0x42, 0x27, 0xcc, 0x45, 0x81, 0x5a, 0x0e, 0x1a, 0x3a, 0xac, 0xb4,
+	0xbd, 0x17, 0xb0, 0x7f, 0xca, 0xe5, 0x56, 0x6a, 0xe4, 0x39, 0xf4, 0xb8, 0x9c, 0x31, 0x73, 0x65,
+	0xbe, 0xd6, 0x99, 0x02, 0x97, 0x16, 0xcc, 0xbc, 0x9f, 0xe1, 0xc9, 0x59, 0xc2, 0xd5, 0x56, 0x54,
+	0x98, 0x4b, 0x95, 0xc6, 0x28, 0xaa, 0x1c, 0xc1, 0xb9, 0xce, 0xd8, 0x5a, 0x2a, 0xf5, 0x8d, 0x54,
+	0xfe, 0xae, 0x41, 0xcb, 0xfc, 0x1c, 0xd9, 0x83, 0x7a, 0x19, 0x5e, 0xe7, 0x8c, 0x3c, 0x85, 0x4e,
+	0xba, 0x48, 0x90, 0xcd, 0x02, 0x17, 0xd6, 0xd6, 0xf6, 0x64, 0x59, 0x90, 0x96, 0x8a, 0xaa, 0x5c,
+	0x5a, 0x6a, 0xd6, 0x22, 0x5f, 0x01, 0xa0, 0x96, 0x9c, 0xcd, 0xa8, 0x1a, 0xec, 0x1c, 0xd6, 0x8e,
+	0x7b, 0x27, 0xc3, 0x91, 0x29, 0xd4, 0xc8, 0x15, 0x6a, 0x74, 0xee, 0x0a, 0x35, 0xed, 0x5a, 0xb4,
+	0xaf, 0xc8, 0x00, 0xda, 0x01, 0x8d, 0x68, 0x12, 0xe2, 0xa0, 0xa9, 0x85, 0x74, 0x26, 0xf9, 0x0c,
+	0x9e, 0xd0, 0x2b, 0xca, 0xa3, 0x02, 0x39, 0x73, 0x98, 0x96, 0xc6, 0x3c, 0x2e, 0x2f, 0x26, 0x16,
+	0xbc, 0xca, 0xb5, 0xbd, 0xc1, 0xf5, 0x4b, 0xd8, 0x73, 0xca, 0xc9, 0x2c, 0x4d, 0x24, 0x92, 0x4f,
+	0xc1, 0x76, 0x93, 0xa6, 0xdd, 0x3b, 0xd9, 0x1b, 0x19, 0x73, 0x64, 0x71, 0xf6, 0xd6, 0xfb, 0xa7,
+	0x06, 0x8f, 0x4e, 0x31, 0x4b, 0x25, 0xaf, 0x62, 0x1f, 0xd0, 0x1b, 0x46, 0xd1, 0x7a, 0xa9, 0x68,
+	0xd5, 0x2b, 0x8d, 0x5b, 0x7b, 0x65, 0x67, 0x3d, 0xe9, 0x15, 0xa9, 0x9b, 0x6b, 0x52, 0x1f, 0x41,
+	0x9f, 0x99, 0x8c, 0x4c, 0x85, 0x5a, 0xe6, 0xf3, 0xa5, 0x6f, 0xb2, 0x24, 0xdf, 0xac, 0x42, 0xa8,
+	0x1a, 0xb4, 0xef, 0xad, 0x47, 0x15, 0xee, 0x2b, 0xef, 0xcf, 0x3a, 0x90, 0xd7, 0x5c, 0x5d, 0x32,
+	0x41, 0x17, 0x34, 0x7a, 0x17, 0xbc, 0x8f, 0xa0, 0x3f, 0x17, 0xa9, 0x94, 0x33, 0x7b, 0xbb, 0xa3,
+	0x6f, 0x7b, 0xda, 0xe7, 0x1b, 0xc8, 0x63, 0x68, 0x5c, 0xa0, 0x6b, 0x89, 0xe2, 0x48, 0x3e, 0x02,
+	0x48, 0x50, 0xb9, 0x10, 0xd3, 0x07, 0xdd, 0x04, 0x95, 0xbf, 0xad, 0x65, 0xfb, 0x56, 0x2d, 0x3b,
+	0x9b, 0x5a, 0x2e, 0x2c, 0xd1, 0xa4, 0xd0, 0xb2, 0x6b, 0x28, 0x95, 0x3e, 0xa3, 0x65, 0x05, 0xa1,
+	0x6a, 0x00, 0xf7, 0x6b, 0x59, 0xe2, 0x7d, 0xe5, 0xfd, 0x55, 0x83, 0x03, 0xd3, 0x53, 0xee, 0x2d,
+	0x97, 0x7a, 0xfe, 0x0f, 0xcf, 0xee, 0x6b, 0xe8, 0xb9, 0xa1, 0xf1, 0xb0, 0x77, 0x07, 0x0e, 0x7e,
+	0xe7, 0xc3, 0x5b, 0x95, 0xb2, 0xb5, 0xf1, 0x96, 0x16, 0xd0, 0x3c, 0x4f, 0xdf, 0x60, 0x42, 0xf6,
+	0xa1, 0xa9, 0x8a, 0x83, 0x65, 0x60, 0x8c, 0xa2, 0x48, 0xfa, 0x30, 0x53, 0xcb, 0x0c, 0x2d, 0x8d,
+	0xae, 0xf6, 0x9c, 0x2f, 0x33, 0xd4, 0x73, 0xe2, 0x3a, 0xe3, 0x02, 0x65, 0x91, 0x6f, 0xe3, 0x01,
+	0x73, 0xc2, 0xa0, 0x7d, 0xe5, 0xfd, 0x06, 0x8f, 0xfc, 0x30, 0x2c, 0x4a, 0xfd, 0x5f, 0x5f, 0x31,
+	0xf9, 0xc4, 0xa5, 0x5a, 0xd7, 0xb0, 0x5d, 0x07, 0xd3, 0x44, 0x6c, 0xe6, 0xde, 0xef, 0x35, 0x80,
+	0x1f, 0x79, 0xcc, 0xd5, 0x4b, 0x21, 0x52, 0x51, 0xd0, 0x8b, 0x0a, 0xcb, 0xd1, 0xd3, 0x46, 0xd1,
+	0x95, 0x31, 0xbd, 0xb6, 0x13, 0xbf, 0x38, 0x12, 0x02, 0x3b, 0xb9, 0x44, 0x66, 0x1b, 0x5c, 0x9f,
+	0xef, 0x7c, 0xd6, 0x2f, 0xa0, 0x2b, 0x50, 0xa2, 0xd2, 0x02, 0x34, 0xef, 0x15, 0xa0, 0x63, 0xc0,
+	0xbe, 0xf2, 0x8e, 0xa0, 0xa7, 0x33, 0x3b, 0x45, 0x45, 0x79, 0x54, 0x7c, 0x37, 0x4c, 0x19, 0xda,
+	0xf4, 0xf4, 0xd9, 0x0b, 0x00, 0x5e, 0xe5, 0x09, 0x93, 0x86, 0xc1, 0x33, 0xe8, 0x96, 0x53, 0x52,
+	0xc3, 0x1a, 0xd3, 0xca, 0x51, 0xe4, 0x28, 0xf0, 0x6d, 0xce, 0x05, 0x32, 0x4b, 0xa7, 0xb4, 0xef,
+	0x5a, 0x61, 0x27, 0x7f, 0x34, 0x60, 0xd7, 0xc8, 0xfb, 0x0b, 0x8a, 0x2b, 0x1e, 0x22, 0xf9, 0x01,
+	0xfa, 0xab, 0xeb, 0x96, 0x7c, 0xe8, 0xe4, 0xbd, 0x61, 0x09, 0x0f, 0x0f, 0x36, 0x4a, 0xe4, 0x4a,
+	0xe9, 0x43, 0x6f, 0x65, 0x39, 0x93, 0xa1, 0x83, 0x6d, 0x6f, 0xec, 0x5b, 0x7f, 0xe2, 0x5b, 0x68,
+	0xdb, 0x51, 0x5d, 0x85, 0x6f, 0xaf, 0xf6, 0xe1, 0x07, 0xee, 0x6e, 0x73, 0xae, 0x4f, 0xa0, 0xe3,
+	0xa6, 0xde, 0x9d, 0x3f, 0x50, 0xde, 0xdd, 0x30, 0x23, 0x7f, 0x82, 0xdd, 0xb5, 0x05, 0x4f, 0x9e,
+	0x95, 0x5f, 0xbb, 0x61, 0xef, 0x0f, 0x3f, 0x5e, 0xa7, 0xb2, 0x35, 0x22, 0xbe, 0x07, 0xa8, 0xd6,
+	0x3e, 0x79, 0xea, 0xd0, 0x5b, 0xff, 0x0a, 0x54, 0xa4, 0x36, 0x9e, 0xc8, 0xc4, 0xff, 0xf5, 0xbb,
+	0x39, 0x57, 0x97, 0x79, 0x30, 0x0a, 0xd3, 0x78, 0xbc, 0xe0, 0x51, 0xc4, 0x69, 0x1c, 0x5e, 0xd2,
+	0x84, 0x8d, 0xe3, 0xe5, 0xe7, 0x26, 0x68, 0x6c, 0xff, 0x30, 0x8c, 0xf8, 0x15, 0x8a, 0xe5, 0x78,
+	0x2e, 0xb2, 0xd0, 0x3a, 0xb3, 0x20, 0x68, 0xe9, 0xb6, 0xfc, 0xe2, 0xdf, 0x01, 0x00, 0xcf, 0x64,
+	0xc0, 0xa1, 0x8e, 0x09, 0x00, 0x00,
+}
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ context.Context
+var _ grpc.ClientConnInterface
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+const _ = grpc.SupportPackageIsVersion6
+
+// WalletServiceClient is the client API for WalletService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://godoc.org/google.golang.org/grpc#ClientConn.NewStream.
+type WalletServiceClient interface {
+	EnableWallet(ctx context.Context, in *EnableWalletRequest, opts ...grpc.CallOption) (*WalletResponse, error)
+	FetchWallet(ctx context.Context, in *FetchWalletRequest, opts ...grpc.CallOption) (*WalletResponse, error)
+	Deposit(ctx context.Context, in *TransactionRequest, opts ...grpc.CallOption) (*DepositResponse, error)
+	Withdraw(ctx context.Context, in *TransactionRequest, opts ...grpc.CallOption) (*WithdrawalResponse, error)
+	DisableWallet(ctx context.Context, in *DisableWalletRequest, opts ...grpc.CallOption) (*WalletDisabledResponse, error)
+	InitWallet(ctx context.Context, in *InitWalletRequest, opts ...grpc.CallOption) (*AccountResponse, error)
+}
+
+type walletServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewWalletServiceClient(cc grpc.ClientConnInterface) WalletServiceClient {
+	return &walletServiceClient{cc}
+}
+
+func (c *walletServiceClient) EnableWallet(ctx context.Context, in *EnableWalletRequest, opts ...grpc.CallOption) (*WalletResponse, error) {
+	out := new(WalletResponse)
+	err := c.cc.Invoke(ctx, "/wallet.WalletService/EnableWallet", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *walletServiceClient) FetchWallet(ctx context.Context, in *FetchWalletRequest, opts ...grpc.CallOption) (*WalletResponse, error) {
+	out := new(WalletResponse)
+	err := c.cc.Invoke(ctx, "/wallet.WalletService/FetchWallet", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *walletServiceClient) Deposit(ctx context.Context, in *TransactionRequest, opts ...grpc.CallOption) (*DepositResponse, error) {
+	out := new(DepositResponse)
+	err := c.cc.Invoke(ctx, "/wallet.WalletService/Deposit", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *walletServiceClient) Withdraw(ctx context.Context, in *TransactionRequest, opts ...grpc.CallOption) (*WithdrawalResponse, error) {
+	out := new(WithdrawalResponse)
+	err := c.cc.Invoke(ctx, "/wallet.WalletService/Withdraw", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *walletServiceClient) DisableWallet(ctx context.Context, in *DisableWalletRequest, opts ...grpc.CallOption) (*WalletDisabledResponse, error) {
+	out := new(WalletDisabledResponse)
+	err := c.cc.Invoke(ctx, "/wallet.WalletService/DisableWallet", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *walletServiceClient) InitWallet(ctx context.Context, in *InitWalletRequest, opts ...grpc.CallOption) (*AccountResponse, error) {
+	out := new(AccountResponse)
+	err := c.cc.Invoke(ctx, "/wallet.WalletService/InitWallet", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// WalletServiceServer is the server API for WalletService service.
+type WalletServiceServer interface {
+	EnableWallet(context.Context, *EnableWalletRequest) (*WalletResponse, error)
+	FetchWallet(context.Context, *FetchWalletRequest) (*WalletResponse, error)
+	Deposit(context.Context, *TransactionRequest) (*DepositResponse, error)
+	Withdraw(context.Context, *TransactionRequest) (*WithdrawalResponse, error)
+	DisableWallet(context.Context, *DisableWalletRequest) (*WalletDisabledResponse, error)
+	InitWallet(context.Context, *InitWalletRequest) (*AccountResponse, error)
+}
+
+// UnimplementedWalletServiceServer can be embedded to have forward compatible implementations.
+type UnimplementedWalletServiceServer struct {
+}
+
+func (*UnimplementedWalletServiceServer) EnableWallet(ctx context.Context, req *EnableWalletRequest) (*WalletResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method EnableWallet not implemented")
+}
+func (*UnimplementedWalletServiceServer) FetchWallet(ctx context.Context, req *FetchWalletRequest) (*WalletResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method FetchWallet not implemented")
+}
+func (*UnimplementedWalletServiceServer) Deposit(ctx context.Context, req *TransactionRequest) (*DepositResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Deposit not implemented")
+}
+func (*UnimplementedWalletServiceServer) Withdraw(ctx context.Context, req *TransactionRequest) (*WithdrawalResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Withdraw not implemented")
+}
+func (*UnimplementedWalletServiceServer) DisableWallet(ctx context.Context, req *DisableWalletRequest) (*WalletDisabledResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DisableWallet not implemented")
+}
+func (*UnimplementedWalletServiceServer) InitWallet(ctx context.Context, req *InitWalletRequest) (*AccountResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method InitWallet not implemented")
+}
+
+func RegisterWalletServiceServer(s *grpc.Server, srv WalletServiceServer) {
+	s.RegisterService(&_WalletService_serviceDesc, srv)
+}
+
+func _WalletService_EnableWallet_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(EnableWalletRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(WalletServiceServer).EnableWallet(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/wallet.WalletService/EnableWallet",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(WalletServiceServer).EnableWallet(ctx, req.(*EnableWalletRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _WalletService_FetchWallet_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(FetchWalletRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(WalletServiceServer).FetchWallet(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/wallet.WalletService/FetchWallet",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(WalletServiceServer).FetchWallet(ctx, req.(*FetchWalletRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _WalletService_Deposit_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(TransactionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(WalletServiceServer).Deposit(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/wallet.WalletService/Deposit",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(WalletServiceServer).Deposit(ctx, req.(*TransactionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _WalletService_Withdraw_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(TransactionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(WalletServiceServer).Withdraw(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/wallet.WalletService/Withdraw",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(WalletServiceServer).Withdraw(ctx, req.(*TransactionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _WalletService_DisableWallet_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DisableWalletRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(WalletServiceServer).DisableWallet(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/wallet.WalletService/DisableWallet",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(WalletServiceServer).DisableWallet(ctx, req.(*DisableWalletRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _WalletService_InitWallet_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(InitWalletRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(WalletServiceServer).InitWallet(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/wallet.WalletService/InitWallet",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(WalletServiceServer).InitWallet(ctx, req.(*InitWalletRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+var _WalletService_serviceDesc = grpc.ServiceDesc{
+	ServiceName: "wallet.WalletService",
+	HandlerType: (*WalletServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "EnableWallet",
+			Handler:    _WalletService_EnableWallet_Handler,
+		},
+		{
+			MethodName: "FetchWallet",
+			Handler:    _WalletService_FetchWallet_Handler,
+		},
+		{
+			MethodName: "Deposit",
+			Handler:    _WalletService_Deposit_Handler,
+		},
+		{
+			MethodName: "Withdraw",
+			Handler:    _WalletService_Withdraw_Handler,
+		},
+		{
+			MethodName: "DisableWallet",
+			Handler:    _WalletService_DisableWallet_Handler,
+		},
+		{
+			MethodName: "InitWallet",
+			Handler:    _WalletService_InitWallet_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "wallet.proto",
+}
@@ -0,0 +1,166 @@
+package http
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/labstack/echo"
+
+	"github.com/williamchand/my-wallet/auth"
+	"github.com/williamchand/my-wallet/middleware"
+	"github.com/williamchand/my-wallet/models"
+	"github.com/williamchand/my-wallet/wallet"
+)
+
+// AdminHandler  represent the httphandler the support staff operate any wallet with
+type AdminHandler struct {
+	AUsecase wallet.Usecase
+}
+
+// NewAdminHandler will initialize the admin/ resources endpoint
+func NewAdminHandler(e *echo.Echo, us wallet.Usecase) {
+	handler := &AdminHandler{
+		AUsecase: us,
+	}
+	read := middleware.RequireScope(models.ScopeAdminRead)
+	adjust := middleware.RequireScope(models.ScopeAdminAdjust)
+	g := e.Group("/api/v1/admin")
+	g.GET("/wallets", handler.LookupWallets, read)
+	g.GET("/wallets/:wallet_id", handler.LookupWallet, read)
+	g.GET("/wallets/:wallet_id/transactions", handler.FetchTransactions, read)
+	g.GET("/wallets/:wallet_id/audit", handler.FetchAuditEntries, read)
+	g.POST("/wallets/:wallet_id/disable", handler.DisableWallet, adjust)
+	g.POST("/wallets/:wallet_id/transactions/:reference_id/reversal", handler.ReverseTransaction, adjust)
+}
+
+// LookupWallet will fetch any wallet by the wallet_id in the path, disabled or not
+func (a *AdminHandler) LookupWallet(c echo.Context) error {
+	principal := auth.FromContext(c.Request().Context())
+	ctx := c.Request().Context()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	res, err := a.AUsecase.LookupWallet(ctx, c.Param("wallet_id"), principal)
+
+	if err != nil {
+		return fail(c, err)
+	}
+	return c.JSON(http.StatusOK, Response{Status: "success", ResponseData: ResponseWallet{
+		Wallet: res,
+	}})
+}
+
+// LookupWallets will fetch the wallets of the customer in the owned_by query param
+func (a *AdminHandler) LookupWallets(c echo.Context) error {
+	principal := auth.FromContext(c.Request().Context())
+	ownedBy := c.QueryParam("owned_by")
+	if ownedBy == "" {
+		return fail(c, models.ErrBadParamInput)
+	}
+	ctx := c.Request().Context()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	res, err := a.AUsecase.LookupWallets(ctx, ownedBy, principal)
+
+	if err != nil {
+		return fail(c, err)
+	}
+	return c.JSON(http.StatusOK, Response{Status: "success", ResponseData: ResponseWallets{
+		Wallets: res,
+	}})
+}
+
+// FetchTransactions will list the transactions of the wallet in the path, with the filters of the owner's history
+func (a *AdminHandler) FetchTransactions(c echo.Context) error {
+	principal := auth.FromContext(c.Request().Context())
+	filter, err := parseTransactionFilter(c)
+	if err != nil {
+		return fail(c, err)
+	}
+	ctx := c.Request().Context()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	res, err := a.AUsecase.FetchWalletTransactions(ctx, filter, c.Param("wallet_id"), principal)
+
+	if err != nil {
+		return fail(c, err)
+	}
+	return c.JSON(http.StatusOK, Response{Status: "success", ResponseData: res})
+}
+
+// FetchAuditEntries will list the admin actions taken on the wallet in the path, newest first
+func (a *AdminHandler) FetchAuditEntries(c echo.Context) error {
+	principal := auth.FromContext(c.Request().Context())
+	ctx := c.Request().Context()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	res, err := a.AUsecase.FetchAuditEntries(ctx, c.Param("wallet_id"), principal)
+
+	if err != nil {
+		return fail(c, err)
+	}
+	return c.JSON(http.StatusOK, Response{Status: "success", ResponseData: ResponseAuditEntries{
+		AuditEntries: res,
+	}})
+}
+
+// DisableWallet will disable the wallet in the path whoever owns it, the reason in the body is audited
+func (a *AdminHandler) DisableWallet(c echo.Context) error {
+	principal := auth.FromContext(c.Request().Context())
+	var req models.ReqForceDisable
+	err := c.Bind(&req)
+	if err != nil {
+		return fail(c, invalidBody(err))
+	}
+
+	if ok, err := isRequestValid(&req); !ok {
+		return fail(c, invalidParam(err))
+	}
+	ctx := c.Request().Context()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	res, err := a.AUsecase.ForceDisableWallet(ctx, &req, c.Param("wallet_id"), principal)
+
+	if err != nil {
+		return fail(c, err)
+	}
+	return c.JSON(http.StatusOK, Response{Status: "success", ResponseData: ResponseWallet{
+		Wallet: res,
+	}})
+}
+
+// ReverseTransaction will give back all or part of the transaction in the path with a compensating transaction
+func (a *AdminHandler) ReverseTransaction(c echo.Context) error {
+	principal := auth.FromContext(c.Request().Context())
+	var reversal models.ReqReversal
+	err := c.Bind(&reversal)
+	if err != nil {
+		return fail(c, invalidBody(err))
+	}
+
+	if ok, err := isRequestValid(&reversal); !ok {
+		return fail(c, invalidParam(err))
+	}
+	ctx := c.Request().Context()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	res, err := a.AUsecase.ReverseTransaction(ctx, &reversal, c.Param("wallet_id"), c.Param("reference_id"), principal)
+
+	if err != nil {
+		return fail(c, err)
+	}
+	return c.JSON(http.StatusOK, Response{Status: "success", ResponseData: ResponseReversal{
+		Reversal: res,
+	}})
+}
@@ -3,13 +3,18 @@ package http
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/labstack/echo"
 	"github.com/sirupsen/logrus"
 	validator "gopkg.in/go-playground/validator.v9"
 
+	"github.com/williamchand/my-wallet/auth"
+	"github.com/williamchand/my-wallet/middleware"
 	"github.com/williamchand/my-wallet/models"
 	"github.com/williamchand/my-wallet/wallet"
 )
@@ -28,8 +33,38 @@ type ResponseDeposit struct {
 type ResponseWithdrawal struct {
 	Withdrawal interface{} `json:"withdrawal"`
 }
+type ResponseToken struct {
+	Token interface{} `json:"token"`
+}
+type ResponseTransfer struct {
+	Transfer interface{} `json:"transfer"`
+}
+type ResponseReversal struct {
+	Reversal interface{} `json:"reversal"`
+}
+type ResponseHold struct {
+	Hold interface{} `json:"hold"`
+}
+type ResponseQuote struct {
+	Quote interface{} `json:"quote"`
+}
+type ResponseConversion struct {
+	Conversion interface{} `json:"conversion"`
+}
+type ResponseWallets struct {
+	Wallets interface{} `json:"wallets"`
+}
+type ResponseAuditEntries struct {
+	AuditEntries interface{} `json:"audit_entries"`
+}
+type ResponseFee struct {
+	Fee interface{} `json:"fee"`
+}
 type ResponseError struct {
-	Error interface{} `json:"error"`
+	Error interface{}        `json:"error"`
+	Code  string             `json:"code"`
+	Limit *models.LimitError `json:"limit,omitempty"`
+	Funds *models.FundsError `json:"funds,omitempty"`
 }
 
 // WalletHandler  represent the httphandler for wallet
@@ -42,27 +77,37 @@ func NewWalletHandler(e *echo.Echo, us wallet.Usecase) {
 	handler := &WalletHandler{
 		AUsecase: us,
 	}
-	e.POST("/api/v1/wallet", handler.EnableWallet)
-	e.GET("/api/v1/wallet", handler.FetchWallet)
-	e.POST("/api/v1/wallet/deposits", handler.AddWallet)
-	e.POST("/api/v1/wallet/withdrawals", handler.WithdrawWallet)
-	e.PATCH("/api/v1/wallet", handler.DisableWallet)
+	read := middleware.RequireScope(models.ScopeWalletRead)
+	write := middleware.RequireScope(models.ScopeWalletWrite)
+	e.POST("/api/v1/wallet", handler.EnableWallet, write)
+	e.GET("/api/v1/wallet", handler.FetchWallet, read)
+	e.POST("/api/v1/wallet/deposits", handler.AddWallet, write)
+	e.POST("/api/v1/wallet/withdrawals", handler.WithdrawWallet, write)
+	e.POST("/api/v1/wallet/transfers", handler.TransferWallet, write)
+	e.GET("/api/v1/wallet/transactions", handler.FetchTransactions, read)
+	e.POST("/api/v1/wallet/holds", handler.CreateHold, write)
+	e.GET("/api/v1/wallet/holds/:hold_id", handler.FetchHold, read)
+	e.POST("/api/v1/wallet/holds/:hold_id/capture", handler.CaptureHold, write)
+	e.POST("/api/v1/wallet/holds/:hold_id/void", handler.VoidHold, write)
+	e.POST("/api/v1/wallet/fx/quotes", handler.CreateQuote, write)
+	e.POST("/api/v1/wallet/conversions", handler.ConvertWallet, write)
+	e.POST("/api/v1/wallet/fees/quote", handler.QuoteFee, read)
+	e.PATCH("/api/v1/wallet", handler.DisableWallet, write)
 	e.POST("/api/v1/init", handler.InitWallet)
+	e.POST("/api/v1/token/refresh", handler.RefreshToken, middleware.RequireScope(models.ScopeTokenRefresh))
 }
 
 // EnableWallet will enable wallet by given param
 func (a *WalletHandler) EnableWallet(c echo.Context) error {
-	authorization := c.Request().Header.Get("Authorization")
+	principal := auth.FromContext(c.Request().Context())
 	ctx := c.Request().Context()
 	if ctx == nil {
 		ctx = context.Background()
 	}
-	res, err := a.AUsecase.EnableWallet(ctx, authorization)
+	res, err := a.AUsecase.EnableWallet(ctx, principal)
 
 	if err != nil {
-		return c.JSON(getStatusCode(err), Response{Status: "fail", ResponseData: ResponseError{
-			Error: err.Error(),
-		}})
+		return fail(c, err)
 	}
 
 	return c.JSON(http.StatusOK, Response{Status: "success", ResponseData: ResponseWallet{
@@ -72,17 +117,15 @@ func (a *WalletHandler) EnableWallet(c echo.Context) error {
 
 // FetchWallet will fetch the wallet based on given params
 func (a *WalletHandler) FetchWallet(c echo.Context) error {
-	authorization := c.Request().Header.Get("Authorization")
+	principal := auth.FromContext(c.Request().Context())
 	ctx := c.Request().Context()
 	if ctx == nil {
 		ctx = context.Background()
 	}
-	res, err := a.AUsecase.FetchWallet(ctx, authorization)
+	res, err := a.AUsecase.FetchWallet(ctx, principal)
 
 	if err != nil {
-		return c.JSON(getStatusCode(err), Response{Status: "fail", ResponseData: ResponseError{
-			Error: err.Error(),
-		}})
+		return fail(c, err)
 	}
 
 	return c.JSON(http.StatusOK, Response{Status: "success", ResponseData: ResponseWallet{
@@ -92,31 +135,25 @@ func (a *WalletHandler) FetchWallet(c echo.Context) error {
 
 // AddWallet will deposit the wallet by given request body
 func (a *WalletHandler) AddWallet(c echo.Context) error {
-	authorization := c.Request().Header.Get("Authorization")
+	principal := auth.FromContext(c.Request().Context())
 	var wallet models.ReqTransaction
 	err := c.Bind(&wallet)
 	if err != nil {
-		return c.JSON(http.StatusUnprocessableEntity, Response{Status: "fail", ResponseData: ResponseError{
-			Error: err.Error(),
-		}})
+		return fail(c, invalidBody(err))
 	}
 
 	if ok, err := isRequestValid(&wallet); !ok {
-		return c.JSON(http.StatusBadRequest, Response{Status: "fail", ResponseData: ResponseError{
-			Error: err.Error(),
-		}})
+		return fail(c, invalidParam(err))
 	}
 	ctx := c.Request().Context()
 	if ctx == nil {
 		ctx = context.Background()
 	}
 
-	res, err := a.AUsecase.AddWallet(ctx, &wallet, authorization)
+	res, err := a.AUsecase.AddWallet(ctx, &wallet, principal)
 
 	if err != nil {
-		return c.JSON(getStatusCode(err), Response{Status: "fail", ResponseData: ResponseError{
-			Error: err.Error(),
-		}})
+		return fail(c, err)
 	}
 	return c.JSON(http.StatusOK, Response{Status: "success", ResponseData: ResponseDeposit{
 		Deposit: res,
@@ -125,50 +162,261 @@ func (a *WalletHandler) AddWallet(c echo.Context) error {
 
 // WithdrawWallet will withdraw the wallet by given request body
 func (a *WalletHandler) WithdrawWallet(c echo.Context) error {
-	authorization := c.Request().Header.Get("Authorization")
+	principal := auth.FromContext(c.Request().Context())
 	var wallet models.ReqTransaction
 	err := c.Bind(&wallet)
 	if err != nil {
-		return c.JSON(http.StatusUnprocessableEntity, Response{Status: "fail", ResponseData: ResponseError{
-			Error: err.Error(),
-		}})
+		return fail(c, invalidBody(err))
 	}
 
 	if ok, err := isRequestValid(&wallet); !ok {
-		return c.JSON(http.StatusBadRequest, Response{Status: "fail", ResponseData: ResponseError{
-			Error: err.Error(),
-		}})
+		return fail(c, invalidParam(err))
 	}
 	ctx := c.Request().Context()
 	if ctx == nil {
 		ctx = context.Background()
 	}
 
-	res, err := a.AUsecase.WithdrawWallet(ctx, &wallet, authorization)
+	res, err := a.AUsecase.WithdrawWallet(ctx, &wallet, principal)
 
 	if err != nil {
-		return c.JSON(getStatusCode(err), Response{Status: "fail", ResponseData: ResponseError{
-			Error: err.Error(),
-		}})
+		return fail(c, err)
 	}
 	return c.JSON(http.StatusOK, Response{Status: "success", ResponseData: ResponseWithdrawal{
 		Withdrawal: res,
 	}})
 }
 
+// TransferWallet will move money from the caller's wallet to the wallet in the request body
+func (a *WalletHandler) TransferWallet(c echo.Context) error {
+	principal := auth.FromContext(c.Request().Context())
+	var transfer models.ReqTransfer
+	err := c.Bind(&transfer)
+	if err != nil {
+		return fail(c, invalidBody(err))
+	}
+
+	if ok, err := isRequestValid(&transfer); !ok {
+		return fail(c, invalidParam(err))
+	}
+	ctx := c.Request().Context()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	res, err := a.AUsecase.TransferWallet(ctx, &transfer, principal)
+
+	if err != nil {
+		return fail(c, err)
+	}
+	return c.JSON(http.StatusOK, Response{Status: "success", ResponseData: ResponseTransfer{
+		Transfer: res,
+	}})
+}
+
+// FetchTransactions will list the wallet's transactions filtered by the query params
+func (a *WalletHandler) FetchTransactions(c echo.Context) error {
+	principal := auth.FromContext(c.Request().Context())
+	filter, err := parseTransactionFilter(c)
+	if err != nil {
+		return fail(c, err)
+	}
+
+	ctx := c.Request().Context()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	res, err := a.AUsecase.FetchTransactions(ctx, filter, principal)
+
+	if err != nil {
+		return fail(c, err)
+	}
+	return c.JSON(http.StatusOK, Response{Status: "success", ResponseData: res})
+}
+
+// CreateHold will reserve funds of the wallet by given request body
+func (a *WalletHandler) CreateHold(c echo.Context) error {
+	principal := auth.FromContext(c.Request().Context())
+	var hold models.ReqHold
+	err := c.Bind(&hold)
+	if err != nil {
+		return fail(c, invalidBody(err))
+	}
+
+	if ok, err := isRequestValid(&hold); !ok {
+		return fail(c, invalidParam(err))
+	}
+	ctx := c.Request().Context()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	res, err := a.AUsecase.CreateHold(ctx, &hold, principal)
+
+	if err != nil {
+		return fail(c, err)
+	}
+	return c.JSON(http.StatusOK, Response{Status: "success", ResponseData: ResponseHold{
+		Hold: res,
+	}})
+}
+
+// FetchHold will fetch the hold based on given params
+func (a *WalletHandler) FetchHold(c echo.Context) error {
+	principal := auth.FromContext(c.Request().Context())
+	ctx := c.Request().Context()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	res, err := a.AUsecase.FetchHold(ctx, c.Param("hold_id"), principal)
+
+	if err != nil {
+		return fail(c, err)
+	}
+	return c.JSON(http.StatusOK, Response{Status: "success", ResponseData: ResponseHold{
+		Hold: res,
+	}})
+}
+
+// CaptureHold will debit the held funds, the request body is optional and holds the amount of a partial capture
+func (a *WalletHandler) CaptureHold(c echo.Context) error {
+	principal := auth.FromContext(c.Request().Context())
+	var capture models.ReqCapture
+	if c.Request().ContentLength != 0 {
+		err := c.Bind(&capture)
+		if err != nil {
+			return fail(c, invalidBody(err))
+		}
+	}
+
+	if ok, err := isRequestValid(&capture); !ok {
+		return fail(c, invalidParam(err))
+	}
+	ctx := c.Request().Context()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	res, err := a.AUsecase.CaptureHold(ctx, &capture, c.Param("hold_id"), principal)
+
+	if err != nil {
+		return fail(c, err)
+	}
+	return c.JSON(http.StatusOK, Response{Status: "success", ResponseData: ResponseHold{
+		Hold: res,
+	}})
+}
+
+// VoidHold will release the held funds
+func (a *WalletHandler) VoidHold(c echo.Context) error {
+	principal := auth.FromContext(c.Request().Context())
+	ctx := c.Request().Context()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	res, err := a.AUsecase.VoidHold(ctx, c.Param("hold_id"), principal)
+
+	if err != nil {
+		return fail(c, err)
+	}
+	return c.JSON(http.StatusOK, Response{Status: "success", ResponseData: ResponseHold{
+		Hold: res,
+	}})
+}
+
+// CreateQuote will lock an exchange rate for converting out of the wallet by given request body
+func (a *WalletHandler) CreateQuote(c echo.Context) error {
+	principal := auth.FromContext(c.Request().Context())
+	var quote models.ReqQuote
+	err := c.Bind(&quote)
+	if err != nil {
+		return fail(c, invalidBody(err))
+	}
+
+	if ok, err := isRequestValid(&quote); !ok {
+		return fail(c, invalidParam(err))
+	}
+	ctx := c.Request().Context()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	res, err := a.AUsecase.CreateQuote(ctx, &quote, principal)
+
+	if err != nil {
+		return fail(c, err)
+	}
+	return c.JSON(http.StatusOK, Response{Status: "success", ResponseData: ResponseQuote{
+		Quote: res,
+	}})
+}
+
+// ConvertWallet will convert money into another wallet of the customer at a quoted rate by given request body
+func (a *WalletHandler) ConvertWallet(c echo.Context) error {
+	principal := auth.FromContext(c.Request().Context())
+	var conversion models.ReqConversion
+	err := c.Bind(&conversion)
+	if err != nil {
+		return fail(c, invalidBody(err))
+	}
+
+	if ok, err := isRequestValid(&conversion); !ok {
+		return fail(c, invalidParam(err))
+	}
+	ctx := c.Request().Context()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	res, err := a.AUsecase.ConvertWallet(ctx, &conversion, principal)
+
+	if err != nil {
+		return fail(c, err)
+	}
+	return c.JSON(http.StatusOK, Response{Status: "success", ResponseData: ResponseConversion{
+		Conversion: res,
+	}})
+}
+
+// QuoteFee will tell the fee of a withdrawal or a transfer by given request body, without running it
+func (a *WalletHandler) QuoteFee(c echo.Context) error {
+	principal := auth.FromContext(c.Request().Context())
+	var quote models.ReqFeeQuote
+	err := c.Bind(&quote)
+	if err != nil {
+		return fail(c, invalidBody(err))
+	}
+
+	if ok, err := isRequestValid(&quote); !ok {
+		return fail(c, invalidParam(err))
+	}
+	ctx := c.Request().Context()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	res, err := a.AUsecase.QuoteFee(ctx, &quote, principal)
+
+	if err != nil {
+		return fail(c, err)
+	}
+	return c.JSON(http.StatusOK, Response{Status: "success", ResponseData: ResponseFee{
+		Fee: res,
+	}})
+}
+
 // DisableWallet will disable wallet by given param
 func (a *WalletHandler) DisableWallet(c echo.Context) error {
-	authorization := c.Request().Header.Get("Authorization")
+	principal := auth.FromContext(c.Request().Context())
 	isDisabled, err := strconv.ParseBool(c.FormValue("is_disabled"))
 	if err != nil {
-		return c.JSON(http.StatusUnprocessableEntity, Response{Status: "fail", ResponseData: ResponseError{
-			Error: err.Error(),
-		}})
+		return fail(c, invalidBody(err))
 	}
 	if isDisabled != true {
-		return c.JSON(http.StatusBadRequest, Response{Status: "fail", ResponseData: ResponseError{
-			Error: err.Error(),
-		}})
+		return fail(c, models.ErrBadParamInput)
 	}
 
 	ctx := c.Request().Context()
@@ -176,12 +424,10 @@ func (a *WalletHandler) DisableWallet(c echo.Context) error {
 		ctx = context.Background()
 	}
 
-	res, err := a.AUsecase.DisableWallet(ctx, isDisabled, authorization)
+	res, err := a.AUsecase.DisableWallet(ctx, isDisabled, principal)
 
 	if err != nil {
-		return c.JSON(getStatusCode(err), Response{Status: "fail", ResponseData: ResponseError{
-			Error: err.Error(),
-		}})
+		return fail(c, err)
 	}
 	return c.JSON(http.StatusOK, Response{Status: "success", ResponseData: ResponseWallet{
 		Wallet: res,
@@ -192,22 +438,19 @@ func (a *WalletHandler) DisableWallet(c echo.Context) error {
 func (a *WalletHandler) InitWallet(c echo.Context) error {
 	contentType := c.Request().Header.Get("Content-Type")
 	if contentType != "application/json" {
-		return c.JSON(http.StatusUnsupportedMediaType, Response{Status: "fail", ResponseData: ResponseError{
-			Error: "Content-Type header is not application/json",
-		}})
+		return fail(c, models.NewError(models.CodeInvalidBody, http.StatusUnsupportedMediaType, "Content-Type header is not application/json"))
 	}
 
 	if c.Request().Body == nil {
-		return c.JSON(http.StatusBadRequest, Response{Status: "fail", ResponseData: ResponseError{
-			Error: "Please send Body",
-		}})
+		return fail(c, models.NewError(models.CodeInvalidBody, http.StatusBadRequest, "Please send Body"))
 	}
 	customer := new(models.Customer)
 	err := json.NewDecoder(c.Request().Body).Decode(&customer)
 	if err != nil {
-		return c.JSON(http.StatusBadRequest, Response{Status: "fail", ResponseData: ResponseError{
-			Error: err.Error(),
-		}})
+		return fail(c, invalidParam(err))
+	}
+	if customer.ID == "" {
+		return fail(c, models.ErrBadParamInput)
 	}
 
 	ctx := c.Request().Context()
@@ -215,19 +458,85 @@ func (a *WalletHandler) InitWallet(c echo.Context) error {
 		ctx = context.Background()
 	}
 
-	res, err := a.AUsecase.InitWallet(ctx, customer.ID)
+	res, err := a.AUsecase.InitWallet(ctx, customer.ID, customer.Currency, auth.FromContext(ctx))
 
 	if err != nil {
-		return c.JSON(getStatusCode(err), Response{Status: "fail", ResponseData: ResponseError{
-			Error: err.Error(),
-		}})
+		return fail(c, err)
 	}
-	return c.JSON(http.StatusOK, Response{Status: "success", ResponseData: ResponseWallet{
-		Wallet: res,
+	return c.JSON(http.StatusOK, Response{Status: "success", ResponseData: res})
+}
+
+// RefreshToken will exchange a still valid token for a new one of the same session
+func (a *WalletHandler) RefreshToken(c echo.Context) error {
+	principal := auth.FromContext(c.Request().Context())
+	ctx := c.Request().Context()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	res, err := a.AUsecase.RefreshToken(ctx, principal)
+
+	if err != nil {
+		return fail(c, err)
+	}
+	return c.JSON(http.StatusOK, Response{Status: "success", ResponseData: ResponseToken{
+		Token: res,
 	}})
 }
 
-func isRequestValid(m *models.ReqTransaction) (bool, error) {
+func parseTransactionFilter(c echo.Context) (*models.TransactionFilter, error) {
+	filter := &models.TransactionFilter{
+		Type:   c.QueryParam("type"),
+		Status: c.QueryParam("status"),
+		Cursor: c.QueryParam("cursor"),
+	}
+
+	var err error
+	if v := c.QueryParam("min_amount"); v != "" {
+		if filter.MinAmount, err = strconv.ParseInt(v, 10, 64); err != nil {
+			return nil, models.ErrBadParamInput
+		}
+	}
+	if v := c.QueryParam("max_amount"); v != "" {
+		if filter.MaxAmount, err = strconv.ParseInt(v, 10, 64); err != nil {
+			return nil, models.ErrBadParamInput
+		}
+	}
+	if v := c.QueryParam("from"); v != "" {
+		if filter.From, err = time.Parse(time.RFC3339, v); err != nil {
+			return nil, models.ErrBadParamInput
+		}
+	}
+	if v := c.QueryParam("to"); v != "" {
+		if filter.To, err = time.Parse(time.RFC3339, v); err != nil {
+			return nil, models.ErrBadParamInput
+		}
+	}
+	if v := c.QueryParam("limit"); v != "" {
+		if filter.Limit, err = strconv.Atoi(v); err != nil {
+			return nil, models.ErrBadParamInput
+		}
+	}
+
+	switch filter.Type {
+	case "", models.TransactionTypeDeposit, models.TransactionTypeWithdrawal,
+		models.TransactionTypeTransferOut, models.TransactionTypeTransferIn, models.TransactionTypeCapture,
+		models.TransactionTypeReversal, models.TransactionTypeConvertOut, models.TransactionTypeConvertIn,
+		models.TransactionTypeFee, models.TransactionTypeCredit, models.TransactionTypeDebit, models.TransactionTypeOpening:
+	default:
+		return nil, models.ErrBadParamInput
+	}
+	if filter.MaxAmount > 0 && filter.MinAmount > filter.MaxAmount {
+		return nil, models.ErrBadParamInput
+	}
+	if !filter.From.IsZero() && !filter.To.IsZero() && filter.From.After(filter.To) {
+		return nil, models.ErrBadParamInput
+	}
+
+	return filter, nil
+}
+
+func isRequestValid(m interface{}) (bool, error) {
 	validate := validator.New()
 	err := validate.Struct(m)
 	if err != nil {
@@ -236,23 +545,38 @@ func isRequestValid(m *models.ReqTransaction) (bool, error) {
 	return true, nil
 }
 
-func getStatusCode(err error) int {
-	if err == nil {
-		return http.StatusOK
-	}
-	logrus.Error(err)
-	switch err {
-	case models.ErrInternalServerError:
-		return http.StatusInternalServerError
-	case models.ErrNotFound:
-		return http.StatusNotFound
-	case models.ErrConflict:
-		return http.StatusConflict
-	case models.ErrAlreadyEnabled:
-		return http.StatusBadRequest
-	case models.ErrDisabled:
-		return http.StatusNotFound
-	default:
-		return http.StatusInternalServerError
+// invalidParam report a request that does not pass validation, the validator message names the field
+func invalidParam(err error) error {
+	return models.NewError(models.CodeInvalidParameter, http.StatusBadRequest, err.Error())
+}
+
+// invalidBody report a body that can not be bound to the request
+func invalidBody(err error) error {
+	return models.NewError(models.CodeInvalidBody, http.StatusUnprocessableEntity, err.Error())
+}
+
+// fail write err in the error envelope, or as an RFC 7807 problem when the client accepts application/problem+json.
+// Only the code and the safe message of err are returned, its cause is logged.
+func fail(c echo.Context, err error) error {
+	e := models.ErrorOf(err)
+	logrus.WithContext(c.Request().Context()).Error(err)
+	c.Set(middleware.ErrorCodeKey, e.Code)
+	var limit *models.LimitError
+	var funds *models.FundsError
+	errors.As(e.Err, &limit)
+	errors.As(e.Err, &funds)
+
+	if strings.Contains(c.Request().Header.Get(echo.HeaderAccept), models.MIMEProblemJSON) {
+		b, jerr := json.Marshal(models.NewProblem(e, c.Request().URL.Path))
+		if jerr != nil {
+			return jerr
+		}
+		return c.Blob(e.Status, models.MIMEProblemJSON, b)
 	}
+	return c.JSON(e.Status, Response{Status: "fail", ResponseData: ResponseError{
+		Error: e.Message,
+		Code:  e.Code,
+		Limit: limit,
+		Funds: funds,
+	}})
 }
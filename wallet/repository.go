@@ -2,16 +2,39 @@ package wallet
 
 import (
 	"context"
+	"time"
 
+	"github.com/williamchand/my-wallet/limits"
 	"github.com/williamchand/my-wallet/models"
 )
 
-// Repository represent the wallet's repository contract
+// Repository represent the wallet's repository contract. The methods moving money take the limits.Checker they
+// apply once the wallets are locked and the request is known not to be a replay, a nil Checker checks nothing.
 type Repository interface {
 	EnableWallet(ctx context.Context, id string) (*models.FetchWallet, error)
 	FetchWallet(ctx context.Context, id string) (*models.FetchWallet, error)
-	AddWallet(ctx context.Context, req *models.ReqTransaction, id string) (*models.TransactionDeposit, error)
-	WithdrawWallet(ctx context.Context, req *models.ReqTransaction, id string) (*models.TransactionWithdraw, error)
+	LookupWallet(ctx context.Context, id string) (*models.Wallet, error)
+	FetchWalletsByOwner(ctx context.Context, ownedBy string) ([]*models.Wallet, error)
+	AddWallet(ctx context.Context, req *models.ReqTransaction, limit limits.Checker, id string) (*models.TransactionDeposit, error)
+	WithdrawWallet(ctx context.Context, req *models.ReqTransaction, fee int64, limit limits.Checker, id string) (*models.TransactionWithdraw, error)
+	TransferWallet(ctx context.Context, req *models.ReqTransfer, fee int64, limit limits.Checker, id string) (*models.Transfer, error)
+	FetchTransactions(ctx context.Context, filter *models.TransactionFilter, id string) (*models.TransactionList, error)
+	ReverseTransaction(ctx context.Context, req *models.ReqReversal, referenceID string, id string, entry *models.AuditEntry) (*models.Reversal, error)
+	AdjustWallet(ctx context.Context, req *models.ReqAdjustment, txType string, id string, entry *models.AuditEntry) (*models.Adjustment, error)
 	DisableWallet(ctx context.Context, isDisabled bool, id string) (*models.WalletDisabled, error)
-	InitWallet(ctx context.Context, customer_id string) (*models.FetchWallet, error)
+	ForceDisableWallet(ctx context.Context, id string, entry *models.AuditEntry) (*models.Wallet, error)
+	ForceEnableWallet(ctx context.Context, id string, entry *models.AuditEntry) (*models.Wallet, error)
+	InitWallet(ctx context.Context, customer_id string, currency string) (*models.FetchWallet, error)
+	CreateHold(ctx context.Context, req *models.ReqHold, expiresAt time.Time, id string) (*models.Hold, error)
+	FetchHold(ctx context.Context, holdID string, id string) (*models.Hold, error)
+	CaptureHold(ctx context.Context, req *models.ReqCapture, holdID string, limit limits.Checker, id string) (*models.Hold, error)
+	VoidHold(ctx context.Context, holdID string, id string) (*models.Hold, error)
+	CreateQuote(ctx context.Context, quote *models.Quote, id string) (*models.Quote, error)
+	FetchQuote(ctx context.Context, quoteID string, id string) (*models.Quote, error)
+	ConvertWallet(ctx context.Context, req *models.ReqConversion, limit limits.Checker, id string) (*models.Conversion, error)
+	FetchUsage(ctx context.Context, id string, types []string, since time.Time) (*models.Usage, error)
+	FetchAccountBalances(ctx context.Context) ([]*models.AccountBalance, error)
+	FetchWalletBalances(ctx context.Context) (map[string]int64, error)
+	InsertAuditEntry(ctx context.Context, entry *models.AuditEntry) error
+	FetchAuditEntries(ctx context.Context, walletID string, limit int) ([]*models.AuditEntry, error)
 }
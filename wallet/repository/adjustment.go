@@ -0,0 +1,32 @@
+package repository
+
+import (
+	"github.com/williamchand/my-wallet/models"
+)
+
+// adjustmentJournal return the journal of a manual credit or debit of the wallet walletID,
+// the other side is the adjustments account
+func adjustmentJournal(txType string, walletID string, amount models.Money) (models.Journal, error) {
+	switch txType {
+	case models.TransactionTypeCredit:
+		return models.NewJournal(models.AccountAdjustments, models.WalletAccount(walletID), amount), nil
+	case models.TransactionTypeDebit:
+		return models.NewJournal(models.WalletAccount(walletID), models.AccountAdjustments, amount), nil
+	default:
+		return nil, models.ErrBadParamInput
+	}
+}
+
+func toAdjustment(t *models.Transaction) *models.Adjustment {
+	return &models.Adjustment{
+		ReferenceID: t.ReferenceID,
+		ID:          t.ID,
+		Type:        t.Type,
+		Amount:      t.Amount,
+		Currency:    t.Currency,
+		Status:      t.Status,
+		Reason:      t.Reason,
+		AdjustedBy:  t.CreatedBy,
+		AdjustedAt:  t.CreatedAt,
+	}
+}
@@ -0,0 +1,36 @@
+package repository
+
+import (
+	"github.com/williamchand/my-wallet/models"
+)
+
+// conversionJournals return the journal of each leg of a conversion at quote. Every leg balances in its own
+// currency through the fx position account, and the spread kept out of the converted amount is fee income.
+func conversionJournals(quote *models.Quote, from string, to string) (models.Journal, models.Journal) {
+	out := models.NewJournal(models.WalletAccount(from), models.AccountFX, models.Money{Amount: quote.Amount, Currency: quote.Currency})
+	in := models.NewJournal(models.AccountFX, models.WalletAccount(to), models.Money{Amount: quote.ConvertedAmount, Currency: quote.ToCurrency})
+	if quote.Fee > 0 {
+		in = append(in, models.NewJournal(models.AccountFX, models.AccountFees, models.Money{Amount: quote.Fee, Currency: quote.ToCurrency})...)
+	}
+	return out, in
+}
+
+func toConversion(out *models.Transaction, quote *models.Quote, to string) *models.Conversion {
+	return &models.Conversion{
+		ReferenceID:     out.ReferenceID,
+		QuoteID:         quote.ID,
+		From:            out.ID,
+		To:              to,
+		Amount:          out.Amount,
+		Currency:        out.Currency,
+		ConvertedAmount: quote.ConvertedAmount,
+		ToCurrency:      quote.ToCurrency,
+		Rate:            quote.Rate,
+		SpreadBps:       quote.SpreadBps,
+		Fee:             quote.Fee,
+		Rounding:        quote.Rounding,
+		Status:          out.Status,
+		ConvertedBy:     out.CreatedBy,
+		ConvertedAt:     out.CreatedAt,
+	}
+}
@@ -0,0 +1,44 @@
+package repository
+
+import (
+	"encoding/base64"
+	"strconv"
+	"strings"
+
+	"github.com/williamchand/my-wallet/models"
+)
+
+const cursorPrefix = "tx:"
+
+// The history is ordered by the transaction row id, newest first. A cursor only
+// carries the id of the last row returned, so rows inserted while a client pages
+// through the history never shift the following pages.
+
+func encodeCursor(rowID int64) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(cursorPrefix + strconv.FormatInt(rowID, 10)))
+}
+
+func decodeCursor(cursor string) (int64, error) {
+	if cursor == "" {
+		return 0, nil
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil || !strings.HasPrefix(string(raw), cursorPrefix) {
+		return 0, models.ErrBadParamInput
+	}
+	rowID, err := strconv.ParseInt(strings.TrimPrefix(string(raw), cursorPrefix), 10, 64)
+	if err != nil || rowID <= 0 {
+		return 0, models.ErrBadParamInput
+	}
+	return rowID, nil
+}
+
+// newTransactionList cut the limit+1 rows fetched down to one page
+func newTransactionList(list []*models.Transaction, limit int) *models.TransactionList {
+	res := &models.TransactionList{Transactions: list}
+	if len(list) > limit {
+		res.Transactions = list[:limit]
+		res.NextCursor = encodeCursor(list[limit-1].RowID)
+	}
+	return res
+}
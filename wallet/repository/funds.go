@@ -0,0 +1,14 @@
+package repository
+
+import (
+	"github.com/williamchand/my-wallet/models"
+)
+
+// insufficientFunds return the FundsError of w for a transaction that needed required while held of its balance is reserved
+func insufficientFunds(w *models.Wallet, held int64, required int64) *models.FundsError {
+	return &models.FundsError{
+		Available: w.Balance - held,
+		Required:  required,
+		Currency:  w.Currency,
+	}
+}
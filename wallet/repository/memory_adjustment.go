@@ -0,0 +1,62 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/williamchand/my-wallet/models"
+)
+
+func (m *memoryWalletRepository) AdjustWallet(ctx context.Context, req *models.ReqAdjustment, txType string, id string, entry *models.AuditEntry) (*models.Adjustment, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	w, err := m.enabledWallet(id)
+	if err != nil {
+		return nil, err
+	}
+	journal, err := adjustmentJournal(txType, w.ID, models.Money{Amount: req.Amount, Currency: w.Currency})
+	if err != nil {
+		return nil, err
+	}
+
+	t, err := m.replay(req.ReferenceID, w.ID, txType, req.Amount)
+	if err != nil {
+		return nil, err
+	}
+	if t == nil {
+		if txType == models.TransactionTypeDebit {
+			// a debit can not take the funds reserved by holds
+			if held := m.heldAmount(w.ID, time.Now()); w.Balance-held < req.Amount {
+				return nil, insufficientFunds(w, held, req.Amount)
+			}
+		}
+		err = m.atomically(func() error {
+			if txType == models.TransactionTypeDebit {
+				w.Balance -= req.Amount
+			} else {
+				w.Balance += req.Amount
+			}
+			t, err = m.insertTransaction(&models.Transaction{
+				ReferenceID: req.ReferenceID,
+				ID:          w.ID,
+				Type:        txType,
+				Amount:      req.Amount,
+				Currency:    w.Currency,
+				Status:      models.TransactionStatusSuccess,
+				Reason:      req.Reason,
+				CreatedBy:   req.Actor,
+			})
+			if err != nil {
+				return err
+			}
+			return m.postJournal(t, journal)
+		}, w)
+		if err != nil {
+			return nil, err
+		}
+	}
+	m.insertAuditEntry(entry)
+
+	return toAdjustment(t), nil
+}
@@ -0,0 +1,81 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/williamchand/my-wallet/models"
+)
+
+func (m *memoryWalletRepository) InsertAuditEntry(ctx context.Context, entry *models.AuditEntry) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.insertAuditEntry(entry)
+
+	return nil
+}
+
+// insertAuditEntry record entry, m.mu is held
+func (m *memoryWalletRepository) insertAuditEntry(entry *models.AuditEntry) {
+	entry.ID = int64(len(m.audit) + 1)
+	entry.CreatedAt = now()
+	res := *entry
+	m.audit = append(m.audit, &res)
+}
+
+func (m *memoryWalletRepository) ForceDisableWallet(ctx context.Context, id string, entry *models.AuditEntry) (*models.Wallet, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	w, ok := m.wallets[id]
+	if !ok {
+		return nil, models.ErrNotFound
+	}
+	if w.Status == "enabled" {
+		w.Status = "disabled"
+		w.UpdatedAt = now()
+		if err := m.recordEvent(models.EventWalletDisabled, w.ID, &models.WalletStatus{ID: w.ID, Status: w.Status, UpdatedAt: w.UpdatedAt}); err != nil {
+			return nil, err
+		}
+	}
+	m.insertAuditEntry(entry)
+	res := *w
+
+	return &res, nil
+}
+
+func (m *memoryWalletRepository) ForceEnableWallet(ctx context.Context, id string, entry *models.AuditEntry) (*models.Wallet, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	w, ok := m.wallets[id]
+	if !ok {
+		return nil, models.ErrNotFound
+	}
+	if w.Status == "disabled" {
+		w.Status = "enabled"
+		w.UpdatedAt = now()
+		if err := m.recordEvent(models.EventWalletEnabled, w.ID, &models.WalletStatus{ID: w.ID, Status: w.Status, UpdatedAt: w.UpdatedAt}); err != nil {
+			return nil, err
+		}
+	}
+	m.insertAuditEntry(entry)
+	res := *w
+
+	return &res, nil
+}
+
+func (m *memoryWalletRepository) FetchAuditEntries(ctx context.Context, walletID string, limit int) ([]*models.AuditEntry, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	list := make([]*models.AuditEntry, 0)
+	for i := len(m.audit) - 1; i >= 0 && len(list) < limit; i-- {
+		if m.audit[i].WalletID == walletID {
+			res := *m.audit[i]
+			list = append(list, &res)
+		}
+	}
+
+	return list, nil
+}
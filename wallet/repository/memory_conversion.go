@@ -0,0 +1,140 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/williamchand/my-wallet/limits"
+	"github.com/williamchand/my-wallet/models"
+)
+
+func (m *memoryWalletRepository) CreateQuote(ctx context.Context, quote *models.Quote, id string) (*models.Quote, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	w, err := m.enabledWallet(id)
+	if err != nil {
+		return nil, err
+	}
+	if quote.Currency != w.Currency {
+		return nil, models.ErrCurrencyMismatch
+	}
+	if _, err = m.ownedWallet(w.OwnedBy, quote.ToCurrency); err != nil {
+		return nil, err
+	}
+
+	q := *quote
+	q.ID = uuid.New().String()
+	q.WalletID = w.ID
+	q.CreatedBy = w.OwnedBy
+	q.ExpiresAt = q.ExpiresAt.Truncate(time.Second)
+	q.CreatedAt = now()
+	m.quotes[q.ID] = &q
+
+	res := q
+	return &res, nil
+}
+
+func (m *memoryWalletRepository) FetchQuote(ctx context.Context, quoteID string, id string) (*models.Quote, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	quote, ok := m.quotes[quoteID]
+	if !ok || quote.WalletID != id {
+		return nil, models.ErrNotFound
+	}
+	res := *quote
+
+	return &res, nil
+}
+
+func (m *memoryWalletRepository) ConvertWallet(ctx context.Context, req *models.ReqConversion, limit limits.Checker, id string) (*models.Conversion, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	quote, ok := m.quotes[req.QuoteID]
+	if !ok || quote.WalletID != id {
+		return nil, models.ErrNotFound
+	}
+	source, err := m.enabledWallet(id)
+	if err != nil {
+		return nil, err
+	}
+	target, err := m.ownedWallet(quote.CreatedBy, quote.ToCurrency)
+	if err != nil {
+		return nil, err
+	}
+
+	if prev, ok := m.references[req.ReferenceID]; ok {
+		if quote.ReferenceID != req.ReferenceID || prev.ID != id || prev.Type != models.TransactionTypeConvertOut || prev.Amount != quote.Amount {
+			return nil, models.ErrDuplicateReference
+		}
+		return toConversion(prev, quote, target.ID), nil
+	}
+	if quote.ReferenceID != "" {
+		return nil, models.ErrConflict
+	}
+	if quote.Expired(time.Now()) {
+		return nil, models.ErrQuoteExpired
+	}
+	if err = m.checkLimits(ctx, limit, source, models.TransactionTypeConvertOut, quote.Amount); err != nil {
+		return nil, err
+	}
+	if err = m.checkLimits(ctx, limit, target, models.TransactionTypeConvertIn, quote.ConvertedAmount); err != nil {
+		return nil, err
+	}
+	if held := m.heldAmount(source.ID, time.Now()); source.Balance-held < quote.Amount {
+		return nil, insufficientFunds(source, held, quote.Amount)
+	}
+
+	var out *models.Transaction
+	err = m.atomically(func() error {
+		source.Balance -= quote.Amount
+		target.Balance += quote.ConvertedAmount
+		outJournal, inJournal := conversionJournals(quote, source.ID, target.ID)
+		out, err = m.insertTransaction(&models.Transaction{
+			ReferenceID: req.ReferenceID,
+			ID:          source.ID,
+			Type:        models.TransactionTypeConvertOut,
+			Amount:      quote.Amount,
+			Currency:    quote.Currency,
+			Status:      models.TransactionStatusSuccess,
+			QuoteID:     quote.ID,
+			CreatedBy:   source.OwnedBy,
+		})
+		if err != nil {
+			return err
+		}
+		if err := m.postJournal(out, outJournal); err != nil {
+			return err
+		}
+		in, err := m.insertTransaction(&models.Transaction{
+			ParentID:    out.RowID,
+			ReferenceID: linkedReference(req.ReferenceID, models.TransactionTypeConvertIn),
+			ID:          target.ID,
+			Type:        models.TransactionTypeConvertIn,
+			Amount:      quote.ConvertedAmount,
+			Currency:    quote.ToCurrency,
+			Status:      models.TransactionStatusSuccess,
+			QuoteID:     quote.ID,
+			CreatedBy:   source.OwnedBy,
+		})
+		if err != nil {
+			return err
+		}
+		return m.postJournal(in, inJournal)
+	}, source, target)
+	if err != nil {
+		return nil, err
+	}
+	quote.ReferenceID = req.ReferenceID
+
+	return toConversion(out, quote, target.ID), nil
+}
+
+// ownedWallet return the enabled wallet the customer holds in currency
+func (m *memoryWalletRepository) ownedWallet(customerID string, currency string) (*models.Wallet, error) {
+	return m.enabledWallet(m.owners[customerID+" "+currency])
+}
@@ -0,0 +1,186 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/williamchand/my-wallet/limits"
+	"github.com/williamchand/my-wallet/models"
+)
+
+func (m *memoryWalletRepository) CreateHold(ctx context.Context, req *models.ReqHold, expiresAt time.Time, id string) (*models.Hold, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	w, err := m.enabledWallet(id)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = models.MatchCurrency(req.Currency, w.Currency); err != nil {
+		return nil, err
+	}
+
+	if prev, ok := m.holdRefs[req.ReferenceID]; ok {
+		if prev.WalletID != w.ID || prev.Amount != req.Amount {
+			return nil, models.ErrDuplicateReference
+		}
+		return copyHold(prev), nil
+	}
+	if held := m.heldAmount(w.ID, time.Now()); w.Balance-held < req.Amount {
+		return nil, insufficientFunds(w, held, req.Amount)
+	}
+
+	h := &models.Hold{
+		ID:          uuid.New().String(),
+		ReferenceID: req.ReferenceID,
+		WalletID:    w.ID,
+		Amount:      req.Amount,
+		Currency:    w.Currency,
+		Status:      models.HoldStatusActive,
+		ExpiresAt:   expiresAt.Truncate(time.Second),
+		CreatedBy:   w.OwnedBy,
+		CreatedAt:   now(),
+		UpdatedAt:   now(),
+	}
+	m.holds[h.ID] = h
+	m.holdRefs[h.ReferenceID] = h
+	if err := m.recordEvent(models.EventHoldCreated, w.ID, copyHold(h)); err != nil {
+		return nil, err
+	}
+
+	return copyHold(h), nil
+}
+
+func (m *memoryWalletRepository) FetchHold(ctx context.Context, holdID string, id string) (*models.Hold, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	h, ok := m.holds[holdID]
+	if !ok || h.WalletID != id {
+		return nil, models.ErrNotFound
+	}
+
+	return copyHold(h), nil
+}
+
+func (m *memoryWalletRepository) CaptureHold(ctx context.Context, req *models.ReqCapture, holdID string, limit limits.Checker, id string) (*models.Hold, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	w, h, err := m.activeHold(holdID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	amount := req.Amount
+	if amount == 0 {
+		amount = h.Amount
+	}
+	switch h.Status {
+	case models.HoldStatusCaptured:
+		if req.Amount != 0 && req.Amount != h.Captured {
+			return nil, models.ErrConflict
+		}
+		return copyHold(h), nil
+	case models.HoldStatusActive:
+	default:
+		return nil, models.ErrConflict
+	}
+	if amount > h.Amount || w.Balance < amount {
+		return nil, models.ErrBadParamInput
+	}
+	if err = m.checkLimits(ctx, limit, w, models.TransactionTypeCapture, amount); err != nil {
+		return nil, err
+	}
+
+	captured := copyHold(h)
+	captured.Status = models.HoldStatusCaptured
+	captured.Captured = amount
+	captured.UpdatedAt = now()
+	err = m.atomically(func() error {
+		w.Balance -= amount
+		t, err := m.insertTransaction(&models.Transaction{
+			ReferenceID: linkedReference(h.ReferenceID, models.TransactionTypeCapture),
+			ID:          w.ID,
+			Type:        models.TransactionTypeCapture,
+			Amount:      amount,
+			Currency:    h.Currency,
+			Status:      models.TransactionStatusSuccess,
+			CreatedBy:   w.OwnedBy,
+		})
+		if err != nil {
+			return err
+		}
+		if err := m.postJournal(t, models.NewJournal(models.WalletAccount(w.ID), models.AccountCashOut, t.Money())); err != nil {
+			return err
+		}
+		return m.recordEvent(models.EventHoldCaptured, w.ID, captured)
+	}, w)
+	if err != nil {
+		return nil, err
+	}
+	h.Status = captured.Status
+	h.Captured = captured.Captured
+	h.UpdatedAt = captured.UpdatedAt
+
+	return copyHold(h), nil
+}
+
+func (m *memoryWalletRepository) VoidHold(ctx context.Context, holdID string, id string) (*models.Hold, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	_, h, err := m.activeHold(holdID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	switch h.Status {
+	case models.HoldStatusVoided:
+		return copyHold(h), nil
+	case models.HoldStatusActive:
+	default:
+		return nil, models.ErrConflict
+	}
+	h.Status = models.HoldStatusVoided
+	h.UpdatedAt = now()
+	if err := m.recordEvent(models.EventHoldVoided, h.WalletID, copyHold(h)); err != nil {
+		return nil, err
+	}
+
+	return copyHold(h), nil
+}
+
+// activeHold return the enabled wallet and its hold, settling the hold first when it has expired
+func (m *memoryWalletRepository) activeHold(holdID string, id string) (*models.Wallet, *models.Hold, error) {
+	w, err := m.enabledWallet(id)
+	if err != nil {
+		return nil, nil, err
+	}
+	h, ok := m.holds[holdID]
+	if !ok || h.WalletID != w.ID {
+		return nil, nil, models.ErrNotFound
+	}
+	h.Expire(time.Now())
+
+	return w, h, nil
+}
+
+// heldAmount sum the holds of the wallet that are still active at now
+func (m *memoryWalletRepository) heldAmount(id string, now time.Time) int64 {
+	var held int64
+	for _, h := range m.holds {
+		if h.WalletID == id && h.Status == models.HoldStatusActive && now.Before(h.ExpiresAt) {
+			held += h.Amount
+		}
+	}
+	return held
+}
+
+func copyHold(h *models.Hold) *models.Hold {
+	c := *h
+	c.Expire(time.Now())
+	return &c
+}
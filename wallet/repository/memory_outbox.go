@@ -0,0 +1,77 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/williamchand/my-wallet/models"
+	"github.com/williamchand/my-wallet/outbox"
+	"github.com/williamchand/my-wallet/wallet"
+)
+
+// NewMemoryOutboxRepository will create the outbox.Repository reading the events recorded by r,
+// which has to come from NewMemoryWalletRepository
+func NewMemoryOutboxRepository(r wallet.Repository) (outbox.Repository, error) {
+	m, ok := r.(*memoryWalletRepository)
+	if !ok {
+		return nil, fmt.Errorf("outbox: %T is not a memory wallet repository", r)
+	}
+	return m, nil
+}
+
+// recordEvent add an event to the outbox, the caller holds the lock of the change it is about.
+// data is stored as JSON, like in the MySQL outbox.
+func (m *memoryWalletRepository) recordEvent(eventType string, walletID string, data interface{}) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	event := models.NewEvent(eventType, walletID, json.RawMessage(payload))
+	event.CreatedAt = now()
+	m.outbox = append(m.outbox, &models.OutboxEntry{
+		Seq:           int64(len(m.outbox) + 1),
+		Event:         event,
+		Status:        models.OutboxStatusPending,
+		NextAttemptAt: event.CreatedAt,
+	})
+	return nil
+}
+
+func (m *memoryWalletRepository) FetchPendingEvents(ctx context.Context, afterSeq int64, limit int) ([]*models.OutboxEntry, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	list := make([]*models.OutboxEntry, 0)
+	for _, e := range m.outbox {
+		if e.Seq <= afterSeq || e.Status != models.OutboxStatusPending {
+			continue
+		}
+		if len(list) == limit {
+			break
+		}
+		c := *e
+		event := *e.Event
+		c.Event = &event
+		list = append(list, &c)
+	}
+
+	return list, nil
+}
+
+func (m *memoryWalletRepository) UpdateEvent(ctx context.Context, e *models.OutboxEntry) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if e.Seq < 1 || e.Seq > int64(len(m.outbox)) {
+		return models.ErrNotFound
+	}
+	stored := m.outbox[e.Seq-1]
+	stored.Status = e.Status
+	stored.Attempts = e.Attempts
+	stored.NextAttemptAt = e.NextAttemptAt.Truncate(time.Second)
+	stored.LastError = e.LastError
+
+	return nil
+}
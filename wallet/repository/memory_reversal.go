@@ -0,0 +1,92 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/williamchand/my-wallet/models"
+)
+
+func (m *memoryWalletRepository) ReverseTransaction(ctx context.Context, req *models.ReqReversal, referenceID string, id string, entry *models.AuditEntry) (*models.Reversal, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// a disabled wallet can be reversed too
+	w, ok := m.wallets[id]
+	if !ok {
+		return nil, models.ErrNotFound
+	}
+	orig, ok := m.references[referenceID]
+	if !ok || orig.ID != w.ID {
+		return nil, models.ErrNotFound
+	}
+
+	if prev, ok := m.references[req.ReferenceID]; ok {
+		if prev.Type != models.TransactionTypeReversal || prev.ID != w.ID || prev.ParentID != orig.RowID ||
+			(req.Amount != 0 && req.Amount != prev.Amount) {
+			return nil, models.ErrDuplicateReference
+		}
+		m.insertAuditEntry(entry)
+		return toReversal(prev, orig), nil
+	}
+
+	var reversed int64
+	for _, t := range m.transactions {
+		if t.ParentID == orig.RowID && t.Type == models.TransactionTypeReversal && t.Status == models.TransactionStatusSuccess {
+			reversed += t.Amount
+		}
+	}
+	amount, status, journal, err := reversal(orig, reversed, req)
+	if err != nil {
+		return nil, err
+	}
+
+	if debitsWallet(orig.Type) {
+		if held := m.heldAmount(w.ID, time.Now()); w.Balance-held < amount {
+			return nil, insufficientFunds(w, held, amount)
+		}
+	}
+	var t *models.Transaction
+	err = m.atomically(func() error {
+		if debitsWallet(orig.Type) {
+			w.Balance -= amount
+		} else {
+			w.Balance += amount
+		}
+		t, err = m.insertTransaction(&models.Transaction{
+			ParentID:    orig.RowID,
+			ReferenceID: req.ReferenceID,
+			ID:          w.ID,
+			Type:        models.TransactionTypeReversal,
+			Amount:      amount,
+			Currency:    orig.Currency,
+			Status:      models.TransactionStatusSuccess,
+			CreatedBy:   w.OwnedBy,
+		})
+		if err != nil {
+			return err
+		}
+		return m.postJournal(t, journal)
+	}, w)
+	if err != nil {
+		return nil, err
+	}
+	orig.Status = status
+	m.insertAuditEntry(entry)
+
+	return toReversal(t, orig), nil
+}
+
+func toReversal(t *models.Transaction, orig *models.Transaction) *models.Reversal {
+	return &models.Reversal{
+		ReferenceID:         t.ReferenceID,
+		OriginalReferenceID: orig.ReferenceID,
+		ID:                  t.ID,
+		Amount:              t.Amount,
+		Currency:            t.Currency,
+		Status:              t.Status,
+		OriginalStatus:      orig.Status,
+		ReversedBy:          t.CreatedBy,
+		ReversedAt:          t.CreatedAt,
+	}
+}
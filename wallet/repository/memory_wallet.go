@@ -0,0 +1,611 @@
+package repository
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/williamchand/my-wallet/limits"
+	"github.com/williamchand/my-wallet/models"
+	"github.com/williamchand/my-wallet/wallet"
+)
+
+type memoryWalletRepository struct {
+	mu           sync.Mutex
+	wallets      map[string]*models.Wallet
+	owners       map[string]string
+	transactions []*models.Transaction
+	references   map[string]*models.Transaction
+	entries      []*models.LedgerEntry
+	holds        map[string]*models.Hold
+	holdRefs     map[string]*models.Hold
+	quotes       map[string]*models.Quote
+	outbox       []*models.OutboxEntry
+	audit        []*models.AuditEntry
+}
+
+// NewMemoryWalletRepository will create an in-memory object that represent the wallet.Repository interface,
+// it behaves like the MySQL repository and is meant for tests and local runs
+func NewMemoryWalletRepository() wallet.Repository {
+	return &memoryWalletRepository{
+		wallets:    make(map[string]*models.Wallet),
+		owners:     make(map[string]string),
+		references: make(map[string]*models.Transaction),
+		holds:      make(map[string]*models.Hold),
+		holdRefs:   make(map[string]*models.Hold),
+		quotes:     make(map[string]*models.Quote),
+	}
+}
+
+// now is truncated to seconds, like the DATETIME columns of the MySQL schema
+func now() time.Time {
+	return time.Now().Truncate(time.Second)
+}
+
+func (m *memoryWalletRepository) EnableWallet(ctx context.Context, id string) (*models.FetchWallet, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	w, ok := m.wallets[id]
+	if !ok || w.Status != "disabled" {
+		return nil, models.ErrAlreadyEnabled
+	}
+	w.Status = "enabled"
+	w.UpdatedAt = now()
+	if err := m.recordEvent(models.EventWalletEnabled, w.ID, &models.WalletStatus{ID: w.ID, Status: w.Status, UpdatedAt: w.UpdatedAt}); err != nil {
+		return nil, err
+	}
+
+	return m.toFetchWallet(w), nil
+}
+
+func (m *memoryWalletRepository) FetchWallet(ctx context.Context, id string) (*models.FetchWallet, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	w, err := m.enabledWallet(id)
+	if err != nil {
+		return nil, err
+	}
+
+	return m.toFetchWallet(w), nil
+}
+
+func (m *memoryWalletRepository) LookupWallet(ctx context.Context, id string) (*models.Wallet, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	w, ok := m.wallets[id]
+	if !ok {
+		return nil, models.ErrNotFound
+	}
+	res := *w
+
+	return &res, nil
+}
+
+func (m *memoryWalletRepository) FetchWalletsByOwner(ctx context.Context, ownedBy string) ([]*models.Wallet, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	list := make([]*models.Wallet, 0)
+	for _, w := range m.wallets {
+		if w.OwnedBy == ownedBy {
+			res := *w
+			list = append(list, &res)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Currency < list[j].Currency })
+
+	return list, nil
+}
+
+func (m *memoryWalletRepository) AddWallet(ctx context.Context, req *models.ReqTransaction, limit limits.Checker, id string) (*models.TransactionDeposit, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	w, err := m.enabledWallet(id)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = models.MatchCurrency(req.Currency, w.Currency); err != nil {
+		return nil, err
+	}
+
+	t, err := m.replay(req.ReferenceID, w.ID, models.TransactionTypeDeposit, req.Amount)
+	if err != nil {
+		return nil, err
+	}
+	if t == nil {
+		if err = m.checkLimits(ctx, limit, w, models.TransactionTypeDeposit, req.Amount); err != nil {
+			return nil, err
+		}
+		err = m.atomically(func() error {
+			w.Balance += req.Amount
+			t, err = m.insertTransaction(&models.Transaction{
+				ReferenceID: req.ReferenceID,
+				ID:          w.ID,
+				Type:        models.TransactionTypeDeposit,
+				Amount:      req.Amount,
+				Currency:    w.Currency,
+				Status:      models.TransactionStatusSuccess,
+				CreatedBy:   w.OwnedBy,
+			})
+			if err != nil {
+				return err
+			}
+			return m.postJournal(t, models.NewJournal(models.AccountCashIn, models.WalletAccount(w.ID), t.Money()))
+		}, w)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &models.TransactionDeposit{
+		ReferenceID: t.ReferenceID,
+		ID:          t.ID,
+		Amount:      t.Amount,
+		Currency:    t.Currency,
+		Status:      t.Status,
+		DepositBy:   t.CreatedBy,
+		DepositAt:   t.CreatedAt,
+	}, nil
+}
+
+func (m *memoryWalletRepository) WithdrawWallet(ctx context.Context, req *models.ReqTransaction, fee int64, limit limits.Checker, id string) (*models.TransactionWithdraw, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	w, err := m.enabledWallet(id)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = models.MatchCurrency(req.Currency, w.Currency); err != nil {
+		return nil, err
+	}
+
+	t, err := m.replay(req.ReferenceID, w.ID, models.TransactionTypeWithdrawal, req.Amount)
+	if err != nil {
+		return nil, err
+	}
+	if t == nil {
+		if err = m.checkLimits(ctx, limit, w, models.TransactionTypeWithdrawal, req.Amount); err != nil {
+			return nil, err
+		}
+		err = m.atomically(func() error {
+			status := models.TransactionStatusSuccess
+			var reason string
+			var journal models.Journal
+			if w.Balance-m.heldAmount(w.ID, time.Now()) < req.Amount+fee {
+				status = models.TransactionStatusFailed
+				reason = models.ReasonInsufficientFunds
+			} else {
+				w.Balance -= req.Amount + fee
+				journal = models.NewJournal(models.WalletAccount(w.ID), models.AccountCashOut, models.Money{Amount: req.Amount, Currency: w.Currency})
+			}
+			t, err = m.insertTransaction(&models.Transaction{
+				ReferenceID: req.ReferenceID,
+				ID:          w.ID,
+				Type:        models.TransactionTypeWithdrawal,
+				Amount:      req.Amount,
+				Currency:    w.Currency,
+				Status:      status,
+				Reason:      reason,
+				CreatedBy:   w.OwnedBy,
+			})
+			if err != nil {
+				return err
+			}
+			if err := m.postJournal(t, journal); err != nil {
+				return err
+			}
+			if status == models.TransactionStatusSuccess {
+				return m.insertFee(t, fee)
+			}
+			return nil
+		}, w)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if t.Status == models.TransactionStatusFailed {
+		held := m.heldAmount(w.ID, time.Now())
+		return nil, insufficientFunds(w, held, req.Amount+fee)
+	}
+
+	charged := m.feeOf(t)
+	return &models.TransactionWithdraw{
+		ReferenceID: t.ReferenceID,
+		ID:          t.ID,
+		Amount:      t.Amount,
+		GrossAmount: t.Amount + charged,
+		Fee:         charged,
+		NetAmount:   t.Amount,
+		Currency:    t.Currency,
+		Status:      t.Status,
+		WithdrawnBy: t.CreatedBy,
+		WithdrawnAt: t.CreatedAt,
+	}, nil
+}
+
+func (m *memoryWalletRepository) TransferWallet(ctx context.Context, req *models.ReqTransfer, fee int64, limit limits.Checker, id string) (*models.Transfer, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	sender, err := m.enabledWallet(id)
+	if err != nil {
+		return nil, err
+	}
+	receiver, err := m.enabledWallet(req.WalletID)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = models.MatchCurrency(req.Currency, sender.Currency); err != nil {
+		return nil, err
+	}
+	if receiver.Currency != sender.Currency {
+		return nil, models.ErrCurrencyMismatch
+	}
+
+	out, err := m.replay(req.ReferenceID, sender.ID, models.TransactionTypeTransferOut, req.Amount)
+	if err != nil {
+		return nil, err
+	}
+	if out != nil && out.Status == models.TransactionStatusSuccess {
+		in := m.references[linkedReference(req.ReferenceID, models.TransactionTypeTransferIn)]
+		if in == nil || in.ID != receiver.ID {
+			return nil, models.ErrDuplicateReference
+		}
+	}
+	if out == nil {
+		if err = m.checkLimits(ctx, limit, sender, models.TransactionTypeTransferOut, req.Amount); err != nil {
+			return nil, err
+		}
+		if err = m.checkLimits(ctx, limit, receiver, models.TransactionTypeTransferIn, req.Amount); err != nil {
+			return nil, err
+		}
+		out = &models.Transaction{
+			ReferenceID: req.ReferenceID,
+			ID:          sender.ID,
+			Type:        models.TransactionTypeTransferOut,
+			Amount:      req.Amount,
+			Currency:    sender.Currency,
+			Status:      models.TransactionStatusSuccess,
+			CreatedBy:   sender.OwnedBy,
+		}
+		err = m.atomically(func() error {
+			if sender.Balance-m.heldAmount(sender.ID, time.Now()) < req.Amount+fee {
+				out.Status = models.TransactionStatusFailed
+				out.Reason = models.ReasonInsufficientFunds
+				_, err := m.insertTransaction(out)
+				return err
+			}
+			sender.Balance -= req.Amount + fee
+			receiver.Balance += req.Amount
+			if _, err := m.insertTransaction(out); err != nil {
+				return err
+			}
+			if err := m.postJournal(out, models.NewJournal(models.WalletAccount(sender.ID), models.WalletAccount(receiver.ID), out.Money())); err != nil {
+				return err
+			}
+			if err := m.insertFee(out, fee); err != nil {
+				return err
+			}
+			_, err := m.insertTransaction(&models.Transaction{
+				ParentID:    out.RowID,
+				ReferenceID: linkedReference(req.ReferenceID, models.TransactionTypeTransferIn),
+				ID:          receiver.ID,
+				Type:        models.TransactionTypeTransferIn,
+				Amount:      req.Amount,
+				Currency:    receiver.Currency,
+				Status:      models.TransactionStatusSuccess,
+				CreatedBy:   sender.OwnedBy,
+			})
+			return err
+		}, sender, receiver)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if out.Status == models.TransactionStatusFailed {
+		held := m.heldAmount(sender.ID, time.Now())
+		return nil, insufficientFunds(sender, held, req.Amount+fee)
+	}
+
+	charged := m.feeOf(out)
+	return &models.Transfer{
+		ReferenceID:   out.ReferenceID,
+		From:          out.ID,
+		To:            receiver.ID,
+		Amount:        out.Amount,
+		GrossAmount:   out.Amount + charged,
+		Fee:           charged,
+		NetAmount:     out.Amount,
+		Currency:      out.Currency,
+		Status:        out.Status,
+		TransferredBy: out.CreatedBy,
+		TransferredAt: out.CreatedAt,
+	}, nil
+}
+
+func (m *memoryWalletRepository) FetchTransactions(ctx context.Context, filter *models.TransactionFilter, id string) (*models.TransactionList, error) {
+	after, err := decodeCursor(filter.Cursor)
+	if err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	list := make([]*models.Transaction, 0)
+	for i := len(m.transactions) - 1; i >= 0 && len(list) <= filter.Limit; i-- {
+		t := m.transactions[i]
+		switch {
+		case t.ID != id,
+			after > 0 && t.RowID >= after,
+			filter.Type != "" && t.Type != filter.Type,
+			filter.Status != "" && t.Status != filter.Status,
+			filter.MinAmount > 0 && t.Amount < filter.MinAmount,
+			filter.MaxAmount > 0 && t.Amount > filter.MaxAmount,
+			!filter.From.IsZero() && t.CreatedAt.Before(filter.From),
+			!filter.To.IsZero() && !t.CreatedAt.Before(filter.To):
+			continue
+		}
+		c := *t
+		list = append(list, &c)
+	}
+
+	return newTransactionList(list, filter.Limit), nil
+}
+
+func (m *memoryWalletRepository) FetchUsage(ctx context.Context, id string, types []string, since time.Time) (*models.Usage, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.usage(id, types, since), nil
+}
+
+func (m *memoryWalletRepository) usage(id string, types []string, since time.Time) *models.Usage {
+	usage := new(models.Usage)
+	for _, t := range m.transactions {
+		if t.ID != id || t.Status == models.TransactionStatusFailed || t.CreatedAt.Before(since) || !contains(types, t.Type) {
+			continue
+		}
+		if usage.Count == 0 || t.CreatedAt.Before(usage.First) {
+			usage.First = t.CreatedAt
+		}
+		usage.Count++
+		usage.Amount += t.Amount
+	}
+	return usage
+}
+
+// checkLimits apply limit to w, the caller holds mu so no other transaction of w is recorded meanwhile
+func (m *memoryWalletRepository) checkLimits(ctx context.Context, limit limits.Checker, w *models.Wallet, txType string, amount int64) error {
+	if limit == nil {
+		return nil
+	}
+	return limit.Check(ctx, lockedUsage{m}, m.toFetchWallet(w), txType, amount)
+}
+
+// lockedUsage is the limits.UsageReader of a caller holding mu
+type lockedUsage struct {
+	m *memoryWalletRepository
+}
+
+func (u lockedUsage) FetchUsage(ctx context.Context, id string, types []string, since time.Time) (*models.Usage, error) {
+	return u.m.usage(id, types, since), nil
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *memoryWalletRepository) FetchAccountBalances(ctx context.Context) ([]*models.AccountBalance, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	byAccount := make(map[string]*models.AccountBalance)
+	result := make([]*models.AccountBalance, 0)
+	for _, e := range m.entries {
+		key := e.Account + " " + e.Currency
+		a, ok := byAccount[key]
+		if !ok {
+			a = &models.AccountBalance{Account: e.Account, Currency: e.Currency}
+			byAccount[key] = a
+			result = append(result, a)
+		}
+		a.Debit += e.Debit
+		a.Credit += e.Credit
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Account != result[j].Account {
+			return result[i].Account < result[j].Account
+		}
+		return result[i].Currency < result[j].Currency
+	})
+
+	return result, nil
+}
+
+func (m *memoryWalletRepository) FetchWalletBalances(ctx context.Context) (map[string]int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	result := make(map[string]int64, len(m.wallets))
+	for id, w := range m.wallets {
+		result[id] = w.Balance
+	}
+
+	return result, nil
+}
+
+func (m *memoryWalletRepository) DisableWallet(ctx context.Context, isDisabled bool, id string) (*models.WalletDisabled, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	w, ok := m.wallets[id]
+	if !ok || w.Status != "enabled" {
+		return nil, models.ErrDisabled
+	}
+	w.Status = "disabled"
+	w.UpdatedAt = now()
+	if err := m.recordEvent(models.EventWalletDisabled, w.ID, &models.WalletStatus{ID: w.ID, Status: w.Status, UpdatedAt: w.UpdatedAt}); err != nil {
+		return nil, err
+	}
+
+	return &models.WalletDisabled{
+		ID:         w.ID,
+		OwnedBy:    w.OwnedBy,
+		Status:     w.Status,
+		DisabledAt: w.UpdatedAt,
+		Balance:    w.Balance,
+		Currency:   w.Currency,
+	}, nil
+}
+
+func (m *memoryWalletRepository) InitWallet(ctx context.Context, customer_id string, currency string) (*models.FetchWallet, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// a customer holds one wallet per currency
+	owner := customer_id + " " + currency
+	if _, ok := m.owners[owner]; ok {
+		return nil, models.ErrConflict
+	}
+	w := &models.Wallet{
+		ID:        uuid.New().String(),
+		Status:    "enabled",
+		Currency:  currency,
+		OwnedBy:   customer_id,
+		UpdatedAt: now(),
+	}
+	m.wallets[w.ID] = w
+	m.owners[owner] = w.ID
+
+	return m.toFetchWallet(w), nil
+}
+
+func (m *memoryWalletRepository) enabledWallet(id string) (*models.Wallet, error) {
+	w, ok := m.wallets[id]
+	if !ok || w.Status != "enabled" {
+		return nil, models.ErrDisabled
+	}
+	return w, nil
+}
+
+// replay return the transaction already recorded under referenceID, or nil when the reference is new
+func (m *memoryWalletRepository) replay(referenceID, walletID, txType string, amount int64) (*models.Transaction, error) {
+	t, ok := m.references[referenceID]
+	if !ok {
+		return nil, nil
+	}
+	if t.ID != walletID || t.Type != txType || t.Amount != amount {
+		return nil, models.ErrDuplicateReference
+	}
+	return t, nil
+}
+
+// atomically run write, which changes the balances of wallets. When it fails, the balances are put back and the
+// transactions, entries and events it recorded are dropped, as the MySQL repository rolls its transaction back.
+// The caller holds mu.
+func (m *memoryWalletRepository) atomically(write func() error, wallets ...*models.Wallet) error {
+	balances := make([]int64, len(wallets))
+	for i, w := range wallets {
+		balances[i] = w.Balance
+	}
+	transactions, entries, outbox := len(m.transactions), len(m.entries), len(m.outbox)
+
+	err := write()
+	if err == nil {
+		return nil
+	}
+	for i, w := range wallets {
+		w.Balance = balances[i]
+	}
+	for _, t := range m.transactions[transactions:] {
+		delete(m.references, t.ReferenceID)
+	}
+	m.transactions = m.transactions[:transactions]
+	m.entries = m.entries[:entries]
+	m.outbox = m.outbox[:outbox]
+	return err
+}
+
+func (m *memoryWalletRepository) insertTransaction(t *models.Transaction) (*models.Transaction, error) {
+	t.RowID = int64(len(m.transactions) + 1)
+	t.CreatedAt = now()
+	m.transactions = append(m.transactions, t)
+	m.references[t.ReferenceID] = t
+	if eventType := transactionEvent(t); eventType != "" {
+		data := *t
+		if err := m.recordEvent(eventType, t.ID, &data); err != nil {
+			return nil, err
+		}
+	}
+	return t, nil
+}
+
+// insertFee record the fee charged on top of parent and move it to the fees account,
+// nothing is recorded for a zero fee
+func (m *memoryWalletRepository) insertFee(parent *models.Transaction, fee int64) error {
+	if fee <= 0 {
+		return nil
+	}
+	t, err := m.insertTransaction(&models.Transaction{
+		ParentID:    parent.RowID,
+		ReferenceID: linkedReference(parent.ReferenceID, models.TransactionTypeFee),
+		ID:          parent.ID,
+		Type:        models.TransactionTypeFee,
+		Amount:      fee,
+		Currency:    parent.Currency,
+		Status:      models.TransactionStatusSuccess,
+		CreatedBy:   parent.CreatedBy,
+	})
+	if err != nil {
+		return err
+	}
+	return m.postJournal(t, models.NewJournal(models.WalletAccount(parent.ID), models.AccountFees, t.Money()))
+}
+
+// feeOf return the fee charged on top of t, zero when there was none
+func (m *memoryWalletRepository) feeOf(t *models.Transaction) int64 {
+	if fee, ok := m.references[linkedReference(t.ReferenceID, models.TransactionTypeFee)]; ok {
+		return fee.Amount
+	}
+	return 0
+}
+
+// postJournal write the ledger entries of t, an unbalanced journal is refused with ErrUnbalancedJournal
+func (m *memoryWalletRepository) postJournal(t *models.Transaction, journal models.Journal) error {
+	if !journal.Balanced() {
+		return models.ErrUnbalancedJournal
+	}
+	for _, e := range journal {
+		c := *e
+		c.TransactionID = t.RowID
+		c.CreatedAt = t.CreatedAt
+		m.entries = append(m.entries, &c)
+	}
+	return nil
+}
+
+func (m *memoryWalletRepository) toFetchWallet(w *models.Wallet) *models.FetchWallet {
+	return &models.FetchWallet{
+		ID:               w.ID,
+		OwnedBy:          w.OwnedBy,
+		Status:           w.Status,
+		EnabledAt:        w.UpdatedAt,
+		Balance:          w.Balance,
+		AvailableBalance: w.Balance - m.heldAmount(w.ID, time.Now()),
+		Currency:         w.Currency,
+	}
+}
@@ -0,0 +1,57 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/williamchand/my-wallet/models"
+)
+
+func TestMemoryRollback(t *testing.T) {
+	ctx := context.Background()
+	m := NewMemoryWalletRepository().(*memoryWalletRepository)
+	w, err := m.InitWallet(ctx, "customer-1", models.DefaultCurrency)
+	require.NoError(t, err)
+	_, err = m.AddWallet(ctx, &models.ReqTransaction{ReferenceID: "ref-1", Amount: 100}, nil, w.ID)
+	require.NoError(t, err)
+	transactions, entries, outbox := len(m.transactions), len(m.entries), len(m.outbox)
+
+	failure := errors.New("audit_log is unavailable")
+	m.mu.Lock()
+	stored := m.wallets[w.ID]
+	err = m.atomically(func() error {
+		stored.Balance -= 40
+		tx, err := m.insertTransaction(&models.Transaction{
+			ReferenceID: "ref-2",
+			ID:          w.ID,
+			Type:        models.TransactionTypeWithdrawal,
+			Amount:      40,
+			Currency:    w.Currency,
+			Status:      models.TransactionStatusSuccess,
+			CreatedBy:   w.OwnedBy,
+		})
+		require.NoError(t, err)
+		require.NoError(t, m.postJournal(tx, models.NewJournal(models.WalletAccount(w.ID), models.AccountCashOut, tx.Money())))
+		return failure
+	}, stored)
+	m.mu.Unlock()
+	assert.Equal(t, failure, err)
+
+	res, err := m.FetchWallet(ctx, w.ID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(100), res.Balance, "the balance is put back")
+	assert.Len(t, m.transactions, transactions)
+	assert.Len(t, m.entries, entries)
+	assert.Len(t, m.outbox, outbox, "the events of the write are not published")
+
+	wd, err := m.WithdrawWallet(ctx, &models.ReqTransaction{ReferenceID: "ref-2", Amount: 40}, 0, nil, w.ID)
+	require.NoError(t, err, "the reference of a failed write is free again")
+	assert.Equal(t, int64(40), wd.Amount)
+	res, err = m.FetchWallet(ctx, w.ID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(60), res.Balance)
+}
@@ -0,0 +1,98 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/williamchand/my-wallet/models"
+)
+
+// AdjustWallet record entry in the same transaction as the adjustment, replays included
+func (m *mysqlWalletRepository) AdjustWallet(ctx context.Context, req *models.ReqAdjustment, txType string, id string, entry *models.AuditEntry) (*models.Adjustment, error) {
+	var lastID int64
+	err := m.withTx(ctx, func(tx *sql.Tx) error {
+		wallet, err := m.lockWallet(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+		if err = m.insertAuditEntry(ctx, tx, entry); err != nil {
+			return err
+		}
+		journal, err := adjustmentJournal(txType, wallet.ID, models.Money{Amount: req.Amount, Currency: wallet.Currency})
+		if err != nil {
+			return err
+		}
+
+		prev, err := m.findReference(ctx, tx, req.ReferenceID)
+		if err != nil {
+			return err
+		}
+		if prev != nil {
+			lastID, _, err = prev.replay(wallet.ID, txType, req.Amount)
+			return err
+		}
+
+		query := `UPDATE wallet SET balance = balance + ? WHERE wallet_id = ?`
+		change := req.Amount
+		if txType == models.TransactionTypeDebit {
+			// a debit can not take the funds reserved by holds
+			held, err := m.heldAmount(ctx, tx, wallet.ID, time.Now())
+			if err != nil {
+				return err
+			}
+			if wallet.Balance-held < req.Amount {
+				return insufficientFunds(wallet, held, req.Amount)
+			}
+			change = -req.Amount
+		}
+		_, err = tx.ExecContext(ctx, query, change, wallet.ID)
+		if err != nil {
+			return err
+		}
+
+		lastID, err = m.insertTransaction(ctx, tx, &models.Transaction{
+			ReferenceID: req.ReferenceID,
+			ID:          wallet.ID,
+			Type:        txType,
+			Amount:      req.Amount,
+			Currency:    wallet.Currency,
+			Status:      models.TransactionStatusSuccess,
+			Reason:      req.Reason,
+			CreatedBy:   req.Actor,
+		}, 0)
+		if err != nil {
+			return err
+		}
+		return m.postJournal(ctx, tx, lastID, journal)
+	})
+	if isDuplicateEntry(err) {
+		// a concurrent request with the same reference_id committed first
+		prev, err := m.findReference(ctx, m.Conn, req.ReferenceID)
+		if err != nil {
+			return nil, err
+		}
+		if prev == nil {
+			return nil, models.ErrDuplicateReference
+		}
+		if lastID, _, err = prev.replay(id, txType, req.Amount); err != nil {
+			return nil, err
+		}
+		// the entry was rolled back with the transaction that lost
+		if err = m.InsertAuditEntry(ctx, entry); err != nil {
+			return nil, err
+		}
+	} else if err != nil {
+		return nil, err
+	}
+
+	list, err := m.fetchTransaction(ctx, m.Conn, `SELECT `+transactionColumns+` FROM transaction WHERE id = ?`, lastID)
+	if err != nil {
+		return nil, err
+	}
+	if len(list) == 0 {
+		return nil, models.ErrNotFound
+	}
+
+	return toAdjustment(list[0]), nil
+}
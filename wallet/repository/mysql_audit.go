@@ -0,0 +1,114 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/williamchand/my-wallet/models"
+)
+
+// execer is satisfied by both *sql.DB and *sql.Tx
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+func (m *mysqlWalletRepository) InsertAuditEntry(ctx context.Context, entry *models.AuditEntry) error {
+	return m.insertAuditEntry(ctx, m.Conn, entry)
+}
+
+// insertAuditEntry record entry with e, a transaction when the action it records has to be written along with it
+func (m *mysqlWalletRepository) insertAuditEntry(ctx context.Context, e execer, entry *models.AuditEntry) error {
+	query := `INSERT INTO audit_log (actor, action, wallet_id, detail, created_at) VALUES (?,?,?,?,?)`
+
+	entry.CreatedAt = time.Now().Truncate(time.Second)
+	walletID := sql.NullString{String: entry.WalletID, Valid: entry.WalletID != ""}
+	detail := sql.NullString{String: entry.Detail, Valid: entry.Detail != ""}
+	res, err := e.ExecContext(ctx, query, entry.Actor, entry.Action, walletID, detail, entry.CreatedAt)
+	if err != nil {
+		return err
+	}
+	entry.ID, err = res.LastInsertId()
+	return err
+}
+
+// ForceDisableWallet disable the wallet id whoever owns it and record entry in the same transaction,
+// a disabled wallet is left as it is but entry is still recorded
+func (m *mysqlWalletRepository) ForceDisableWallet(ctx context.Context, id string, entry *models.AuditEntry) (*models.Wallet, error) {
+	err := m.withTx(ctx, func(tx *sql.Tx) error {
+		w, err := m.lockAnyWallet(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+		if w.Status == "enabled" {
+			err = m.updateStatus(ctx, tx, id, "enabled", "disabled", models.EventWalletDisabled)
+			if err != nil {
+				return err
+			}
+		}
+		return m.insertAuditEntry(ctx, tx, entry)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return m.LookupWallet(ctx, id)
+}
+
+// ForceEnableWallet enable the wallet id whoever owns it and record entry in the same transaction,
+// enabling an enabled wallet only records entry
+func (m *mysqlWalletRepository) ForceEnableWallet(ctx context.Context, id string, entry *models.AuditEntry) (*models.Wallet, error) {
+	err := m.withTx(ctx, func(tx *sql.Tx) error {
+		w, err := m.lockAnyWallet(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+		if w.Status == "disabled" {
+			err = m.updateStatus(ctx, tx, id, "disabled", "enabled", models.EventWalletEnabled)
+			if err != nil {
+				return err
+			}
+		}
+		return m.insertAuditEntry(ctx, tx, entry)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return m.LookupWallet(ctx, id)
+}
+
+func (m *mysqlWalletRepository) FetchAuditEntries(ctx context.Context, walletID string, limit int) ([]*models.AuditEntry, error) {
+	query := `SELECT id, actor, action, wallet_id, detail, created_at FROM audit_log WHERE wallet_id = ? ORDER BY id DESC LIMIT ?`
+
+	rows, err := m.Conn.QueryContext(ctx, query, walletID, limit)
+	if err != nil {
+		logrus.WithContext(ctx).Error(err)
+		return nil, err
+	}
+
+	defer func() {
+		err := rows.Close()
+		if err != nil {
+			logrus.WithContext(ctx).Error(err)
+		}
+	}()
+
+	result := make([]*models.AuditEntry, 0)
+	for rows.Next() {
+		e := new(models.AuditEntry)
+		var id, detail sql.NullString
+		err = rows.Scan(&e.ID, &e.Actor, &e.Action, &id, &detail, &e.CreatedAt)
+		if err != nil {
+			logrus.WithContext(ctx).Error(err)
+			return nil, err
+		}
+		e.WalletID = id.String
+		e.Detail = detail.String
+		result = append(result, e)
+	}
+
+	return result, rows.Err()
+}
@@ -0,0 +1,272 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+
+	"github.com/williamchand/my-wallet/limits"
+	"github.com/williamchand/my-wallet/models"
+)
+
+const quoteColumns = `quote_id, wallet_id, amount, currency, converted_amount, to_currency, rate, spread_bps, fee, rounding, reference_id, expires_at, created_by, created_at`
+
+func (m *mysqlWalletRepository) CreateQuote(ctx context.Context, quote *models.Quote, id string) (*models.Quote, error) {
+	query := `SELECT wallet_id, owned_by, status, updated_at, balance, currency
+			  FROM wallet WHERE wallet_id = ? AND status = "enabled"`
+
+	list, err := m.fetchWallet(ctx, m.Conn, query, id)
+	if err != nil {
+		return nil, err
+	}
+	if len(list) == 0 {
+		return nil, models.ErrDisabled
+	}
+	wallet := list[0]
+	if quote.Currency != wallet.Currency {
+		return nil, models.ErrCurrencyMismatch
+	}
+	// the quote is only worth something when the customer also holds a wallet in the other currency
+	if _, err = m.ownedWallet(ctx, m.Conn, wallet.OwnedBy, quote.ToCurrency); err != nil {
+		return nil, err
+	}
+
+	quote.ID = uuid.New().String()
+	query = `INSERT INTO fx_quote (quote_id, wallet_id, amount, currency, converted_amount, to_currency, rate, spread_bps, fee, rounding, expires_at, created_by)
+			 VALUES (?,?,?,?,?,?,?,?,?,?,?,?)`
+	_, err = m.Conn.ExecContext(ctx, query, quote.ID, wallet.ID, quote.Amount, quote.Currency, quote.ConvertedAmount, quote.ToCurrency,
+		quote.Rate, quote.SpreadBps, quote.Fee, quote.Rounding, quote.ExpiresAt, wallet.OwnedBy)
+	if err != nil {
+		return nil, err
+	}
+
+	return m.findQuote(ctx, m.Conn, quote.ID, wallet.ID, false)
+}
+
+func (m *mysqlWalletRepository) FetchQuote(ctx context.Context, quoteID string, id string) (*models.Quote, error) {
+	return m.findQuote(ctx, m.Conn, quoteID, id, false)
+}
+
+func (m *mysqlWalletRepository) ConvertWallet(ctx context.Context, req *models.ReqConversion, limit limits.Checker, id string) (*models.Conversion, error) {
+	var lastID int64
+	var to string
+	err := m.withTx(ctx, func(tx *sql.Tx) error {
+		// owner and currencies of a quote never change, so the target wallet is known before anything is locked
+		quote, err := m.findQuote(ctx, tx, req.QuoteID, id, false)
+		if err != nil {
+			return err
+		}
+		to, err = m.ownedWallet(ctx, tx, quote.CreatedBy, quote.ToCurrency)
+		if err != nil {
+			return err
+		}
+		wallets, err := m.lockWallets(ctx, tx, id, to)
+		if err != nil {
+			return err
+		}
+		source, target := wallets[id], wallets[to]
+		quote, err = m.findQuote(ctx, tx, req.QuoteID, id, true)
+		if err != nil {
+			return err
+		}
+
+		prev, err := m.findReference(ctx, tx, req.ReferenceID)
+		if err != nil {
+			return err
+		}
+		if prev != nil {
+			lastID, err = replayConversion(prev, quote, req, id)
+			return err
+		}
+		if quote.ReferenceID != "" {
+			return models.ErrConflict
+		}
+		now := time.Now()
+		if quote.Expired(now) {
+			return models.ErrQuoteExpired
+		}
+		if err = m.checkLimits(ctx, tx, limit, source, models.TransactionTypeConvertOut, quote.Amount); err != nil {
+			return err
+		}
+		if err = m.checkLimits(ctx, tx, limit, target, models.TransactionTypeConvertIn, quote.ConvertedAmount); err != nil {
+			return err
+		}
+		held, err := m.heldAmount(ctx, tx, source.ID, now)
+		if err != nil {
+			return err
+		}
+		if source.Balance-held < quote.Amount {
+			return insufficientFunds(source, held, quote.Amount)
+		}
+
+		query := `UPDATE wallet SET balance = balance - ? WHERE wallet_id = ? AND balance >= ?`
+		_, err = tx.ExecContext(ctx, query, quote.Amount, source.ID, quote.Amount)
+		if err != nil {
+			return err
+		}
+		query = `UPDATE wallet SET balance = balance + ? WHERE wallet_id = ?`
+		_, err = tx.ExecContext(ctx, query, quote.ConvertedAmount, target.ID)
+		if err != nil {
+			return err
+		}
+
+		outJournal, inJournal := conversionJournals(quote, source.ID, target.ID)
+		lastID, err = m.insertTransaction(ctx, tx, &models.Transaction{
+			ReferenceID: req.ReferenceID,
+			ID:          source.ID,
+			Type:        models.TransactionTypeConvertOut,
+			Amount:      quote.Amount,
+			Currency:    quote.Currency,
+			Status:      models.TransactionStatusSuccess,
+			QuoteID:     quote.ID,
+			CreatedBy:   source.OwnedBy,
+		}, 0)
+		if err != nil {
+			return err
+		}
+		err = m.postJournal(ctx, tx, lastID, outJournal)
+		if err != nil {
+			return err
+		}
+		inID, err := m.insertTransaction(ctx, tx, &models.Transaction{
+			ReferenceID: linkedReference(req.ReferenceID, models.TransactionTypeConvertIn),
+			ID:          target.ID,
+			Type:        models.TransactionTypeConvertIn,
+			Amount:      quote.ConvertedAmount,
+			Currency:    quote.ToCurrency,
+			Status:      models.TransactionStatusSuccess,
+			QuoteID:     quote.ID,
+			CreatedBy:   source.OwnedBy,
+		}, lastID)
+		if err != nil {
+			return err
+		}
+		err = m.postJournal(ctx, tx, inID, inJournal)
+		if err != nil {
+			return err
+		}
+
+		query = `UPDATE fx_quote SET reference_id = ? WHERE quote_id = ?`
+		_, err = tx.ExecContext(ctx, query, req.ReferenceID, quote.ID)
+		return err
+	})
+	if isDuplicateEntry(err) {
+		// a concurrent request with the same reference_id committed first
+		prev, ferr := m.findReference(ctx, m.Conn, req.ReferenceID)
+		if ferr != nil {
+			return nil, ferr
+		}
+		if prev == nil {
+			return nil, models.ErrDuplicateReference
+		}
+		quote, ferr := m.findQuote(ctx, m.Conn, req.QuoteID, id, false)
+		if ferr != nil {
+			return nil, ferr
+		}
+		lastID, err = replayConversion(prev, quote, req, id)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return m.FetchConversion(ctx, lastID, to)
+}
+
+// replayConversion compare a replayed conversion with the recorded one, which must have used the same quote
+func replayConversion(prev *recordedTransaction, quote *models.Quote, req *models.ReqConversion, id string) (int64, error) {
+	if quote.ReferenceID != req.ReferenceID {
+		return 0, models.ErrDuplicateReference
+	}
+	lastID, _, err := prev.replay(id, models.TransactionTypeConvertOut, quote.Amount)
+	return lastID, err
+}
+
+func (m *mysqlWalletRepository) FetchConversion(ctx context.Context, id int64, to string) (*models.Conversion, error) {
+	query := `SELECT ` + transactionColumns + ` FROM transaction WHERE id = ?`
+
+	list, err := m.fetchTransaction(ctx, m.Conn, query, id)
+	if err != nil {
+		return nil, err
+	}
+	if len(list) == 0 {
+		return nil, models.ErrNotFound
+	}
+	quote, err := m.findQuote(ctx, m.Conn, list[0].QuoteID, list[0].ID, false)
+	if err != nil {
+		return nil, err
+	}
+
+	return toConversion(list[0], quote, to), nil
+}
+
+// ownedWallet return the id of the enabled wallet the customer holds in currency
+func (m *mysqlWalletRepository) ownedWallet(ctx context.Context, q queryer, customerID string, currency string) (string, error) {
+	query := `SELECT wallet_id, owned_by, status, updated_at, balance, currency
+			  FROM wallet WHERE owned_by = ? AND currency = ? AND status = "enabled"`
+
+	list, err := m.fetchWallet(ctx, q, query, customerID, currency)
+	if err != nil {
+		return "", err
+	}
+	if len(list) == 0 {
+		return "", models.ErrDisabled
+	}
+
+	return list[0].ID, nil
+}
+
+// findQuote read a quote of the wallet, forUpdate hold its row lock until tx ends
+func (m *mysqlWalletRepository) findQuote(ctx context.Context, q queryer, quoteID string, id string, forUpdate bool) (*models.Quote, error) {
+	query := `SELECT ` + quoteColumns + ` FROM fx_quote WHERE quote_id = ? AND wallet_id = ?`
+	if forUpdate {
+		query += ` FOR UPDATE`
+	}
+
+	rows, err := q.QueryContext(ctx, query, quoteID, id)
+	if err != nil {
+		logrus.WithContext(ctx).Error(err)
+		return nil, err
+	}
+
+	defer func() {
+		err := rows.Close()
+		if err != nil {
+			logrus.WithContext(ctx).Error(err)
+		}
+	}()
+
+	if !rows.Next() {
+		if err = rows.Err(); err != nil {
+			return nil, err
+		}
+		return nil, models.ErrNotFound
+	}
+	quote := new(models.Quote)
+	var referenceID sql.NullString
+	err = rows.Scan(
+		&quote.ID,
+		&quote.WalletID,
+		&quote.Amount,
+		&quote.Currency,
+		&quote.ConvertedAmount,
+		&quote.ToCurrency,
+		&quote.Rate,
+		&quote.SpreadBps,
+		&quote.Fee,
+		&quote.Rounding,
+		&referenceID,
+		&quote.ExpiresAt,
+		&quote.CreatedBy,
+		&quote.CreatedAt,
+	)
+	if err != nil {
+		logrus.WithContext(ctx).Error(err)
+		return nil, err
+	}
+	quote.ReferenceID = referenceID.String
+
+	return quote, nil
+}
@@ -0,0 +1,293 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+
+	"github.com/williamchand/my-wallet/limits"
+	"github.com/williamchand/my-wallet/models"
+)
+
+const holdColumns = `hold_id, reference_id, wallet_id, amount, currency, captured_amount, status, expires_at, created_by, created_at, updated_at`
+
+func (m *mysqlWalletRepository) CreateHold(ctx context.Context, req *models.ReqHold, expiresAt time.Time, id string) (*models.Hold, error) {
+	var holdID string
+	err := m.withTx(ctx, func(tx *sql.Tx) error {
+		wallet, err := m.lockWallet(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+		if _, err = models.MatchCurrency(req.Currency, wallet.Currency); err != nil {
+			return err
+		}
+
+		prev, err := m.fetchHold(ctx, tx, `SELECT `+holdColumns+` FROM hold WHERE reference_id = ?`, req.ReferenceID)
+		if err != nil {
+			return err
+		}
+		if len(prev) > 0 {
+			if prev[0].WalletID != wallet.ID || prev[0].Amount != req.Amount {
+				return models.ErrDuplicateReference
+			}
+			holdID = prev[0].ID
+			return nil
+		}
+
+		now := time.Now()
+		// expired holds are settled lazily, whenever the wallet places a new one
+		query := `UPDATE hold SET status = "expired", updated_at = expires_at WHERE wallet_id = ? AND status = "active" AND expires_at <= ?`
+		_, err = tx.ExecContext(ctx, query, wallet.ID, now)
+		if err != nil {
+			return err
+		}
+
+		held, err := m.heldAmount(ctx, tx, wallet.ID, now)
+		if err != nil {
+			return err
+		}
+		if wallet.Balance-held < req.Amount {
+			return insufficientFunds(wallet, held, req.Amount)
+		}
+
+		holdID = uuid.New().String()
+		query = `INSERT INTO hold (hold_id, reference_id, wallet_id, amount, currency, status, expires_at, created_by, updated_at) VALUES (?,?,?,?,?,"active",?,?,?)`
+		_, err = tx.ExecContext(ctx, query, holdID, req.ReferenceID, wallet.ID, req.Amount, wallet.Currency, expiresAt, wallet.OwnedBy, now)
+		if err != nil {
+			return err
+		}
+		now = now.Truncate(time.Second)
+		return m.insertEvent(ctx, tx, models.NewEvent(models.EventHoldCreated, wallet.ID, &models.Hold{
+			ID:          holdID,
+			ReferenceID: req.ReferenceID,
+			WalletID:    wallet.ID,
+			Amount:      req.Amount,
+			Currency:    wallet.Currency,
+			Status:      models.HoldStatusActive,
+			ExpiresAt:   expiresAt.Truncate(time.Second),
+			CreatedBy:   wallet.OwnedBy,
+			CreatedAt:   now,
+			UpdatedAt:   now,
+		}))
+	})
+	if isDuplicateEntry(err) {
+		// the wallet row is locked, so only a hold of another wallet can own the reference_id
+		return nil, models.ErrDuplicateReference
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return m.FetchHold(ctx, holdID, id)
+}
+
+func (m *mysqlWalletRepository) FetchHold(ctx context.Context, holdID string, id string) (*models.Hold, error) {
+	query := `SELECT ` + holdColumns + ` FROM hold WHERE hold_id = ? AND wallet_id = ?`
+
+	list, err := m.fetchHold(ctx, m.Conn, query, holdID, id)
+	if err != nil {
+		return nil, err
+	}
+	if len(list) == 0 {
+		return nil, models.ErrNotFound
+	}
+	list[0].Expire(time.Now())
+
+	return list[0], nil
+}
+
+func (m *mysqlWalletRepository) CaptureHold(ctx context.Context, req *models.ReqCapture, holdID string, limit limits.Checker, id string) (*models.Hold, error) {
+	err := m.withTx(ctx, func(tx *sql.Tx) error {
+		wallet, hold, err := m.lockHold(ctx, tx, holdID, id)
+		if err != nil {
+			return err
+		}
+
+		amount := req.Amount
+		if amount == 0 {
+			amount = hold.Amount
+		}
+		switch hold.Status {
+		case models.HoldStatusCaptured:
+			if req.Amount != 0 && req.Amount != hold.Captured {
+				return models.ErrConflict
+			}
+			return nil
+		case models.HoldStatusActive:
+		default:
+			return models.ErrConflict
+		}
+		// the hold reserved the amount, so the balance covers it unless the hold is over-captured
+		if amount > hold.Amount || wallet.Balance < amount {
+			return models.ErrBadParamInput
+		}
+		if err = m.checkLimits(ctx, tx, limit, wallet, models.TransactionTypeCapture, amount); err != nil {
+			return err
+		}
+
+		query := `UPDATE wallet SET balance = balance - ? WHERE wallet_id = ? AND balance >= ?`
+		_, err = tx.ExecContext(ctx, query, amount, wallet.ID, amount)
+		if err != nil {
+			return err
+		}
+		lastID, err := m.insertTransaction(ctx, tx, &models.Transaction{
+			ReferenceID: linkedReference(hold.ReferenceID, models.TransactionTypeCapture),
+			ID:          wallet.ID,
+			Type:        models.TransactionTypeCapture,
+			Amount:      amount,
+			Currency:    hold.Currency,
+			Status:      models.TransactionStatusSuccess,
+			CreatedBy:   wallet.OwnedBy,
+		}, 0)
+		if err != nil {
+			return err
+		}
+		captured := models.Money{Amount: amount, Currency: hold.Currency}
+		err = m.postJournal(ctx, tx, lastID, models.NewJournal(models.WalletAccount(wallet.ID), models.AccountCashOut, captured))
+		if err != nil {
+			return err
+		}
+
+		// the part of the hold that is not captured is released
+		now := time.Now()
+		query = `UPDATE hold SET status = "captured", captured_amount = ?, updated_at = ? WHERE hold_id = ?`
+		_, err = tx.ExecContext(ctx, query, amount, now, hold.ID)
+		if err != nil {
+			return err
+		}
+		hold.Status = models.HoldStatusCaptured
+		hold.Captured = amount
+		hold.UpdatedAt = now.Truncate(time.Second)
+		return m.insertEvent(ctx, tx, models.NewEvent(models.EventHoldCaptured, wallet.ID, hold))
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return m.FetchHold(ctx, holdID, id)
+}
+
+func (m *mysqlWalletRepository) VoidHold(ctx context.Context, holdID string, id string) (*models.Hold, error) {
+	err := m.withTx(ctx, func(tx *sql.Tx) error {
+		_, hold, err := m.lockHold(ctx, tx, holdID, id)
+		if err != nil {
+			return err
+		}
+
+		switch hold.Status {
+		case models.HoldStatusVoided:
+			return nil
+		case models.HoldStatusActive:
+		default:
+			return models.ErrConflict
+		}
+
+		now := time.Now()
+		query := `UPDATE hold SET status = "voided", updated_at = ? WHERE hold_id = ?`
+		_, err = tx.ExecContext(ctx, query, now, hold.ID)
+		if err != nil {
+			return err
+		}
+		hold.Status = models.HoldStatusVoided
+		hold.UpdatedAt = now.Truncate(time.Second)
+		return m.insertEvent(ctx, tx, models.NewEvent(models.EventHoldVoided, hold.WalletID, hold))
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return m.FetchHold(ctx, holdID, id)
+}
+
+// lockHold lock the wallet and then its hold, in the same order as every other wallet update
+func (m *mysqlWalletRepository) lockHold(ctx context.Context, tx *sql.Tx, holdID string, id string) (*models.Wallet, *models.Hold, error) {
+	wallet, err := m.lockWallet(ctx, tx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	query := `SELECT ` + holdColumns + ` FROM hold WHERE hold_id = ? AND wallet_id = ? FOR UPDATE`
+	list, err := m.fetchHold(ctx, tx, query, holdID, wallet.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(list) == 0 {
+		return nil, nil, models.ErrNotFound
+	}
+	list[0].Expire(time.Now())
+
+	return wallet, list[0], nil
+}
+
+// heldAmount sum the holds of the wallet that are still active at now
+func (m *mysqlWalletRepository) heldAmount(ctx context.Context, q queryer, id string, now time.Time) (int64, error) {
+	query := `SELECT COALESCE(SUM(amount), 0) FROM hold WHERE wallet_id = ? AND status = "active" AND expires_at > ?`
+
+	rows, err := q.QueryContext(ctx, query, id, now)
+	if err != nil {
+		logrus.WithContext(ctx).Error(err)
+		return 0, err
+	}
+
+	defer func() {
+		err := rows.Close()
+		if err != nil {
+			logrus.WithContext(ctx).Error(err)
+		}
+	}()
+
+	var held int64
+	if rows.Next() {
+		err = rows.Scan(&held)
+		if err != nil {
+			logrus.WithContext(ctx).Error(err)
+			return 0, err
+		}
+	}
+
+	return held, rows.Err()
+}
+
+func (m *mysqlWalletRepository) fetchHold(ctx context.Context, q queryer, query string, args ...interface{}) ([]*models.Hold, error) {
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		logrus.WithContext(ctx).Error(err)
+		return nil, err
+	}
+
+	defer func() {
+		err := rows.Close()
+		if err != nil {
+			logrus.WithContext(ctx).Error(err)
+		}
+	}()
+
+	result := make([]*models.Hold, 0)
+	for rows.Next() {
+		h := new(models.Hold)
+		err = rows.Scan(
+			&h.ID,
+			&h.ReferenceID,
+			&h.WalletID,
+			&h.Amount,
+			&h.Currency,
+			&h.Captured,
+			&h.Status,
+			&h.ExpiresAt,
+			&h.CreatedBy,
+			&h.CreatedAt,
+			&h.UpdatedAt,
+		)
+
+		if err != nil {
+			logrus.WithContext(ctx).Error(err)
+			return nil, err
+		}
+		result = append(result, h)
+	}
+
+	return result, nil
+}
@@ -0,0 +1,102 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/williamchand/my-wallet/models"
+	"github.com/williamchand/my-wallet/outbox"
+)
+
+// NewMysqlOutboxRepository will create an object that represent the outbox.Repository interface,
+// reading the events the MySQL wallet repository records
+func NewMysqlOutboxRepository(Conn *sql.DB) outbox.Repository {
+	return &mysqlWalletRepository{Conn}
+}
+
+// insertEvent record event in the outbox, within the transaction of the change it is about
+func (m *mysqlWalletRepository) insertEvent(ctx context.Context, tx *sql.Tx, event *models.Event) error {
+	payload, err := json.Marshal(event.Data)
+	if err != nil {
+		return err
+	}
+
+	query := `INSERT INTO outbox (event_id, event_type, wallet_id, payload, status, attempts, next_attempt_at, created_at)
+			  VALUES (?,?,?,?,?,0,?,?)`
+	_, err = tx.ExecContext(ctx, query, event.ID, event.Type, event.WalletID, string(payload), models.OutboxStatusPending,
+		event.CreatedAt, event.CreatedAt)
+	return err
+}
+
+// insertTransactionEvent record the event of the transaction row t, if it has one
+func (m *mysqlWalletRepository) insertTransactionEvent(ctx context.Context, tx *sql.Tx, t *models.Transaction) error {
+	eventType := transactionEvent(t)
+	if eventType == "" {
+		return nil
+	}
+	data := *t
+	data.CreatedAt = time.Now().Truncate(time.Second)
+	return m.insertEvent(ctx, tx, models.NewEvent(eventType, t.ID, &data))
+}
+
+func (m *mysqlWalletRepository) FetchPendingEvents(ctx context.Context, afterSeq int64, limit int) ([]*models.OutboxEntry, error) {
+	query := `SELECT id, event_id, event_type, wallet_id, payload, status, attempts, next_attempt_at, last_error, created_at
+			  FROM outbox WHERE status = ? AND id > ? ORDER BY id LIMIT ?`
+
+	rows, err := m.Conn.QueryContext(ctx, query, models.OutboxStatusPending, afterSeq, limit)
+	if err != nil {
+		logrus.WithContext(ctx).Error(err)
+		return nil, err
+	}
+
+	defer func() {
+		err := rows.Close()
+		if err != nil {
+			logrus.WithContext(ctx).Error(err)
+		}
+	}()
+
+	result := make([]*models.OutboxEntry, 0)
+	for rows.Next() {
+		var payload string
+		var lastError sql.NullString
+		e := &models.OutboxEntry{Event: new(models.Event)}
+		err = rows.Scan(
+			&e.Seq,
+			&e.Event.ID,
+			&e.Event.Type,
+			&e.Event.WalletID,
+			&payload,
+			&e.Status,
+			&e.Attempts,
+			&e.NextAttemptAt,
+			&lastError,
+			&e.Event.CreatedAt,
+		)
+		if err != nil {
+			logrus.WithContext(ctx).Error(err)
+			return nil, err
+		}
+		e.Event.Data = json.RawMessage(payload)
+		e.LastError = lastError.String
+		result = append(result, e)
+	}
+
+	return result, rows.Err()
+}
+
+func (m *mysqlWalletRepository) UpdateEvent(ctx context.Context, e *models.OutboxEntry) error {
+	query := `UPDATE outbox SET status = ?, attempts = ?, next_attempt_at = ?, last_error = ?, updated_at = ? WHERE id = ?`
+
+	lastError := sql.NullString{String: e.LastError, Valid: e.LastError != ""}
+	_, err := m.Conn.ExecContext(ctx, query, e.Status, e.Attempts, e.NextAttemptAt, lastError, time.Now(), e.Seq)
+	if err != nil {
+		logrus.WithContext(ctx).Error(err)
+	}
+
+	return err
+}
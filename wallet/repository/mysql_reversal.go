@@ -0,0 +1,182 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/williamchand/my-wallet/models"
+)
+
+// ReverseTransaction record entry in the same transaction as the reversal, replays included.
+// A disabled wallet can be reversed, e.g. to pay back a fraudulent deposit once the wallet is force-disabled.
+func (m *mysqlWalletRepository) ReverseTransaction(ctx context.Context, req *models.ReqReversal, referenceID string, id string, entry *models.AuditEntry) (*models.Reversal, error) {
+	var lastID int64
+	err := m.withTx(ctx, func(tx *sql.Tx) error {
+		wallet, err := m.lockAnyWallet(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+		if err = m.insertAuditEntry(ctx, tx, entry); err != nil {
+			return err
+		}
+
+		// the wallet lock also keeps the original row and its reversals from changing
+		query := `SELECT ` + transactionColumns + ` FROM transaction WHERE reference_id = ? AND wallet_id = ?`
+		list, err := m.fetchTransaction(ctx, tx, query, referenceID, wallet.ID)
+		if err != nil {
+			return err
+		}
+		if len(list) == 0 {
+			return models.ErrNotFound
+		}
+		orig := list[0]
+
+		prev, err := m.findReference(ctx, tx, req.ReferenceID)
+		if err != nil {
+			return err
+		}
+		if prev != nil {
+			if prev.txType != models.TransactionTypeReversal || prev.walletID != wallet.ID || prev.parentID.Int64 != orig.RowID ||
+				(req.Amount != 0 && req.Amount != prev.amount) {
+				return models.ErrDuplicateReference
+			}
+			lastID = prev.id
+			return nil
+		}
+
+		reversed, err := m.reversedAmount(ctx, tx, orig.RowID)
+		if err != nil {
+			return err
+		}
+		amount, status, journal, err := reversal(orig, reversed, req)
+		if err != nil {
+			return err
+		}
+
+		if debitsWallet(orig.Type) {
+			held, err := m.heldAmount(ctx, tx, wallet.ID, time.Now())
+			if err != nil {
+				return err
+			}
+			if wallet.Balance-held < amount {
+				return insufficientFunds(wallet, held, amount)
+			}
+			query = `UPDATE wallet SET balance = balance - ? WHERE wallet_id = ? AND balance >= ?`
+			_, err = tx.ExecContext(ctx, query, amount, wallet.ID, amount)
+			if err != nil {
+				return err
+			}
+		} else {
+			query = `UPDATE wallet SET balance = balance + ? WHERE wallet_id = ?`
+			_, err = tx.ExecContext(ctx, query, amount, wallet.ID)
+			if err != nil {
+				return err
+			}
+		}
+
+		lastID, err = m.insertTransaction(ctx, tx, &models.Transaction{
+			ReferenceID: req.ReferenceID,
+			ID:          wallet.ID,
+			Type:        models.TransactionTypeReversal,
+			Amount:      amount,
+			Currency:    orig.Currency,
+			Status:      models.TransactionStatusSuccess,
+			CreatedBy:   wallet.OwnedBy,
+		}, orig.RowID)
+		if err != nil {
+			return err
+		}
+		err = m.postJournal(ctx, tx, lastID, journal)
+		if err != nil {
+			return err
+		}
+
+		query = `UPDATE transaction SET status = ? WHERE id = ?`
+		_, err = tx.ExecContext(ctx, query, status, orig.RowID)
+		return err
+	})
+	if isDuplicateEntry(err) {
+		// the wallet row is locked, so only a transaction of another wallet can own the reference_id
+		return nil, models.ErrDuplicateReference
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return m.FetchReversal(ctx, lastID)
+}
+
+func (m *mysqlWalletRepository) FetchReversal(ctx context.Context, id int64) (*models.Reversal, error) {
+	query := `SELECT r.reference_id, p.reference_id, r.wallet_id, r.amount, r.currency, r.status, p.status, r.created_by, r.created_at
+			  FROM transaction r JOIN transaction p ON p.id = r.parent_id WHERE r.id = ?`
+
+	rows, err := m.Conn.QueryContext(ctx, query, id)
+	if err != nil {
+		logrus.WithContext(ctx).Error(err)
+		return nil, err
+	}
+
+	defer func() {
+		err := rows.Close()
+		if err != nil {
+			logrus.WithContext(ctx).Error(err)
+		}
+	}()
+
+	if !rows.Next() {
+		if err = rows.Err(); err != nil {
+			return nil, err
+		}
+		return nil, models.ErrNotFound
+	}
+	res := new(models.Reversal)
+	err = rows.Scan(
+		&res.ReferenceID,
+		&res.OriginalReferenceID,
+		&res.ID,
+		&res.Amount,
+		&res.Currency,
+		&res.Status,
+		&res.OriginalStatus,
+		&res.ReversedBy,
+		&res.ReversedAt,
+	)
+	if err != nil {
+		logrus.WithContext(ctx).Error(err)
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// reversedAmount sum what the reversals of the transaction row id already took back
+func (m *mysqlWalletRepository) reversedAmount(ctx context.Context, q queryer, id int64) (int64, error) {
+	query := `SELECT COALESCE(SUM(amount), 0) FROM transaction WHERE parent_id = ? AND type = ? AND status = ?`
+
+	rows, err := q.QueryContext(ctx, query, id, models.TransactionTypeReversal, models.TransactionStatusSuccess)
+	if err != nil {
+		logrus.WithContext(ctx).Error(err)
+		return 0, err
+	}
+
+	defer func() {
+		err := rows.Close()
+		if err != nil {
+			logrus.WithContext(ctx).Error(err)
+		}
+	}()
+
+	var reversed int64
+	if rows.Next() {
+		err = rows.Scan(&reversed)
+		if err != nil {
+			logrus.WithContext(ctx).Error(err)
+			return 0, err
+		}
+	}
+
+	return reversed, rows.Err()
+}
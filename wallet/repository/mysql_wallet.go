@@ -3,18 +3,33 @@ package repository
 import (
 	"context"
 	"database/sql"
+	"errors"
+	"strings"
 	"time"
 
+	"github.com/go-sql-driver/mysql"
+	"github.com/google/uuid"
 	"github.com/sirupsen/logrus"
 
+	"github.com/williamchand/my-wallet/limits"
 	"github.com/williamchand/my-wallet/models"
 	"github.com/williamchand/my-wallet/wallet"
 )
 
 const (
 	timeFormat = "2006-01-02T15:04:05.999Z07:00" // reduce precision from RFC3339Nano as date format
+
+	errDuplicateEntry = 1062 // ER_DUP_ENTRY
+
+	transactionColumns = `id, reference_id, wallet_id, type, amount, currency, status, created_by, created_at, quote_id, reason`
 )
 
+// queryer is satisfied by both *sql.DB and *sql.Tx
+type queryer interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
 type mysqlWalletRepository struct {
 	Conn *sql.DB
 }
@@ -25,24 +40,13 @@ func NewMysqlWalletRepository(Conn *sql.DB) wallet.Repository {
 }
 
 func (m *mysqlWalletRepository) EnableWallet(ctx context.Context, id string) (*models.FetchWallet, error) {
-	query := `UPDATE wallet set status = "enabled" updated_at=? WHERE wallet_id = ? AND status = "disabled"`
-
-	stmt, err := m.Conn.PrepareContext(ctx, query)
-	if err != nil {
-		return nil, err
-	}
-
-	rowUpdate, err := stmt.ExecContext(ctx, time.Now(), id)
-	if err != nil {
-		return nil, err
+	err := m.setStatus(ctx, id, "disabled", "enabled", models.EventWalletEnabled)
+	if errors.Is(err, models.ErrNotFound) {
+		return nil, models.ErrAlreadyEnabled
 	}
-	affect, err := rowUpdate.RowsAffected()
 	if err != nil {
 		return nil, err
 	}
-	if affect != 1 {
-		return nil, models.ErrAlreadyEnabled
-	}
 
 	res, err := m.FetchWallet(ctx, id)
 	if err != nil {
@@ -53,10 +57,10 @@ func (m *mysqlWalletRepository) EnableWallet(ctx context.Context, id string) (*m
 }
 
 func (m *mysqlWalletRepository) FetchWallet(ctx context.Context, id string) (*models.FetchWallet, error) {
-	query := `SELECT id, owned_by, status, updated_at, balance 
+	query := `SELECT wallet_id, owned_by, status, updated_at, balance, currency
 			  FROM wallet WHERE wallet_id = ? AND status = "enabled"`
 
-	list, err := m.fetchWallet(ctx, query, id)
+	list, err := m.fetchWallet(ctx, m.Conn, query, id)
 	if err != nil {
 		return nil, err
 	}
@@ -69,19 +73,46 @@ func (m *mysqlWalletRepository) FetchWallet(ctx context.Context, id string) (*mo
 			Status:    list[0].Status,
 			EnabledAt: list[0].UpdatedAt,
 			Balance:   list[0].Balance,
+			Currency:  list[0].Currency,
 		}
 	} else {
 		return nil, models.ErrDisabled
 	}
 
+	held, err := m.heldAmount(ctx, m.Conn, res.ID, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	res.AvailableBalance = res.Balance - held
+
 	return res, nil
 }
 
+func (m *mysqlWalletRepository) LookupWallet(ctx context.Context, id string) (*models.Wallet, error) {
+	query := `SELECT wallet_id, owned_by, status, updated_at, balance, currency FROM wallet WHERE wallet_id = ?`
+
+	list, err := m.fetchWallet(ctx, m.Conn, query, id)
+	if err != nil {
+		return nil, err
+	}
+	if len(list) == 0 {
+		return nil, models.ErrNotFound
+	}
+
+	return list[0], nil
+}
+
+func (m *mysqlWalletRepository) FetchWalletsByOwner(ctx context.Context, ownedBy string) ([]*models.Wallet, error) {
+	query := `SELECT wallet_id, owned_by, status, updated_at, balance, currency FROM wallet WHERE owned_by = ? ORDER BY currency`
+
+	return m.fetchWallet(ctx, m.Conn, query, ownedBy)
+}
+
 func (m *mysqlWalletRepository) FetchDisabledWallet(ctx context.Context, id string) (*models.WalletDisabled, error) {
-	query := `SELECT id, owned_by, status, updated_at, balance 
+	query := `SELECT wallet_id, owned_by, status, updated_at, balance, currency
 			  FROM wallet WHERE wallet_id = ? AND status = "disabled"`
 
-	list, err := m.fetchWallet(ctx, query, id)
+	list, err := m.fetchWallet(ctx, m.Conn, query, id)
 	if err != nil {
 		return nil, err
 	}
@@ -94,6 +125,7 @@ func (m *mysqlWalletRepository) FetchDisabledWallet(ctx context.Context, id stri
 			Status:     list[0].Status,
 			DisabledAt: list[0].UpdatedAt,
 			Balance:    list[0].Balance,
+			Currency:   list[0].Currency,
 		}
 	} else {
 		return nil, models.ErrNotFound
@@ -103,9 +135,8 @@ func (m *mysqlWalletRepository) FetchDisabledWallet(ctx context.Context, id stri
 }
 
 func (m *mysqlWalletRepository) FetchTransactionAdd(ctx context.Context, id int64) (*models.TransactionDeposit, error) {
-	query := `SELECT reference_id, wallet_id, amount, status, created_by, created_at
-			  FROM transaction WHERE id = ?`
-	list, err := m.fetchTransaction(ctx, query, id)
+	query := `SELECT ` + transactionColumns + ` FROM transaction WHERE id = ?`
+	list, err := m.fetchTransaction(ctx, m.Conn, query, id)
 	if err != nil {
 		return nil, err
 	}
@@ -116,6 +147,7 @@ func (m *mysqlWalletRepository) FetchTransactionAdd(ctx context.Context, id int6
 			ReferenceID: list[0].ReferenceID,
 			ID:          list[0].ID,
 			Amount:      list[0].Amount,
+			Currency:    list[0].Currency,
 			Status:      list[0].Status,
 			DepositBy:   list[0].CreatedBy,
 			DepositAt:   list[0].CreatedAt,
@@ -128,20 +160,27 @@ func (m *mysqlWalletRepository) FetchTransactionAdd(ctx context.Context, id int6
 }
 
 func (m *mysqlWalletRepository) FetchTransactionWithdraw(ctx context.Context, id int64) (*models.TransactionWithdraw, error) {
-	query := `SELECT reference_id, wallet_id, amount, status, created_by, created_at
-			  FROM transaction WHERE id = ?`
+	query := `SELECT ` + transactionColumns + ` FROM transaction WHERE id = ?`
 
-	list, err := m.fetchTransaction(ctx, query, id)
+	list, err := m.fetchTransaction(ctx, m.Conn, query, id)
 	if err != nil {
 		return nil, err
 	}
 
 	res := &models.TransactionWithdraw{}
 	if len(list) > 0 {
+		fee, err := m.fetchFee(ctx, id)
+		if err != nil {
+			return nil, err
+		}
 		res = &models.TransactionWithdraw{
 			ReferenceID: list[0].ReferenceID,
 			ID:          list[0].ID,
 			Amount:      list[0].Amount,
+			GrossAmount: list[0].Amount + fee,
+			Fee:         fee,
+			NetAmount:   list[0].Amount,
+			Currency:    list[0].Currency,
 			Status:      list[0].Status,
 			WithdrawnBy: list[0].CreatedBy,
 			WithdrawnAt: list[0].CreatedAt,
@@ -153,120 +192,706 @@ func (m *mysqlWalletRepository) FetchTransactionWithdraw(ctx context.Context, id
 	return res, nil
 }
 
-func (m *mysqlWalletRepository) AddWallet(ctx context.Context, req *models.ReqTransaction, id string) (*models.TransactionDeposit, error) {
-	wallet, err := m.FetchWallet(ctx, id)
+func (m *mysqlWalletRepository) AddWallet(ctx context.Context, req *models.ReqTransaction, limit limits.Checker, id string) (*models.TransactionDeposit, error) {
+	lastID, _, err := m.transact(ctx, req, id, models.TransactionTypeDeposit, 0, limit, func(tx *sql.Tx, wallet *models.Wallet) (string, models.Journal, error) {
+		query := `UPDATE wallet SET balance = balance + ? WHERE wallet_id = ?`
+		_, err := tx.ExecContext(ctx, query, req.Amount, wallet.ID)
+		if err != nil {
+			return "", nil, err
+		}
+		amount := models.Money{Amount: req.Amount, Currency: wallet.Currency}
+		return models.TransactionStatusSuccess, models.NewJournal(models.AccountCashIn, models.WalletAccount(wallet.ID), amount), nil
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	balance := wallet.Balance + req.Amount
-	query := `UPDATE wallet set balance = ? WHERE wallet_id = ?`
+	res, err := m.FetchTransactionAdd(ctx, lastID)
+	if err != nil {
+		return nil, err
+	}
 
-	stmt, err := m.Conn.PrepareContext(ctx, query)
+	return res, nil
+}
+
+func (m *mysqlWalletRepository) WithdrawWallet(ctx context.Context, req *models.ReqTransaction, fee int64, limit limits.Checker, id string) (*models.TransactionWithdraw, error) {
+	var funds *models.FundsError
+	lastID, status, err := m.transact(ctx, req, id, models.TransactionTypeWithdrawal, fee, limit, func(tx *sql.Tx, wallet *models.Wallet) (string, models.Journal, error) {
+		// the row is locked, so the balance and the holds can not change between this check and the update
+		held, err := m.heldAmount(ctx, tx, wallet.ID, time.Now())
+		if err != nil {
+			return "", nil, err
+		}
+		gross := req.Amount + fee
+		if wallet.Balance-held < gross {
+			funds = insufficientFunds(wallet, held, gross)
+			return models.TransactionStatusFailed, nil, nil
+		}
+
+		query := `UPDATE wallet SET balance = balance - ? WHERE wallet_id = ? AND balance >= ?`
+		_, err = tx.ExecContext(ctx, query, gross, wallet.ID, gross)
+		if err != nil {
+			return "", nil, err
+		}
+		amount := models.Money{Amount: req.Amount, Currency: wallet.Currency}
+		return models.TransactionStatusSuccess, models.NewJournal(models.WalletAccount(wallet.ID), models.AccountCashOut, amount), nil
+	})
 	if err != nil {
 		return nil, err
 	}
-	_, err = stmt.ExecContext(ctx, balance, id)
+	if status == models.TransactionStatusFailed {
+		if funds == nil {
+			return nil, m.replayedFunds(ctx, id, req.Amount+fee)
+		}
+		return nil, funds
+	}
+
+	res, err := m.FetchTransactionWithdraw(ctx, lastID)
 	if err != nil {
 		return nil, err
 	}
 
-	query2 := `INSERT INTO transaction (reference_id, wallet_id, type, amount, status, created_by) VALUES (?,?,0,?,?,?);`
+	return res, nil
+}
 
-	stmt, err = m.Conn.PrepareContext(ctx, query2)
+func (m *mysqlWalletRepository) TransferWallet(ctx context.Context, req *models.ReqTransfer, fee int64, limit limits.Checker, id string) (*models.Transfer, error) {
+	var lastID int64
+	var status string
+	var funds *models.FundsError
+	err := m.withTx(ctx, func(tx *sql.Tx) error {
+		wallets, err := m.lockWallets(ctx, tx, id, req.WalletID)
+		if err != nil {
+			return err
+		}
+		sender, receiver := wallets[id], wallets[req.WalletID]
+		if _, err := models.MatchCurrency(req.Currency, sender.Currency); err != nil {
+			return err
+		}
+		// a transfer never converts between currencies
+		if receiver.Currency != sender.Currency {
+			return models.ErrCurrencyMismatch
+		}
+
+		prev, err := m.findReference(ctx, tx, req.ReferenceID)
+		if err != nil {
+			return err
+		}
+		if prev != nil {
+			lastID, status, err = m.replayTransfer(ctx, tx, prev, req, id)
+			return err
+		}
+		if err = m.checkLimits(ctx, tx, limit, sender, models.TransactionTypeTransferOut, req.Amount); err != nil {
+			return err
+		}
+		if err = m.checkLimits(ctx, tx, limit, receiver, models.TransactionTypeTransferIn, req.Amount); err != nil {
+			return err
+		}
+
+		out := &models.Transaction{
+			ReferenceID: req.ReferenceID,
+			ID:          sender.ID,
+			Type:        models.TransactionTypeTransferOut,
+			Amount:      req.Amount,
+			Currency:    sender.Currency,
+			Status:      models.TransactionStatusSuccess,
+			CreatedBy:   sender.OwnedBy,
+		}
+		held, err := m.heldAmount(ctx, tx, sender.ID, time.Now())
+		if err != nil {
+			return err
+		}
+		gross := req.Amount + fee
+		if sender.Balance-held < gross {
+			funds = insufficientFunds(sender, held, gross)
+			status = models.TransactionStatusFailed
+			out.Status = status
+			out.Reason = models.ReasonInsufficientFunds
+			lastID, err = m.insertTransaction(ctx, tx, out, 0)
+			return err
+		}
+
+		query := `UPDATE wallet SET balance = balance - ? WHERE wallet_id = ? AND balance >= ?`
+		_, err = tx.ExecContext(ctx, query, gross, sender.ID, gross)
+		if err != nil {
+			return err
+		}
+		query = `UPDATE wallet SET balance = balance + ? WHERE wallet_id = ?`
+		_, err = tx.ExecContext(ctx, query, req.Amount, receiver.ID)
+		if err != nil {
+			return err
+		}
+
+		status = models.TransactionStatusSuccess
+		lastID, err = m.insertTransaction(ctx, tx, out, 0)
+		if err != nil {
+			return err
+		}
+		amount := models.Money{Amount: req.Amount, Currency: sender.Currency}
+		err = m.postJournal(ctx, tx, lastID, models.NewJournal(models.WalletAccount(sender.ID), models.WalletAccount(receiver.ID), amount))
+		if err != nil {
+			return err
+		}
+		if err = m.insertFee(ctx, tx, out, lastID, fee); err != nil {
+			return err
+		}
+		_, err = m.insertTransaction(ctx, tx, &models.Transaction{
+			ReferenceID: linkedReference(req.ReferenceID, models.TransactionTypeTransferIn),
+			ID:          receiver.ID,
+			Type:        models.TransactionTypeTransferIn,
+			Amount:      req.Amount,
+			Currency:    receiver.Currency,
+			Status:      models.TransactionStatusSuccess,
+			CreatedBy:   sender.OwnedBy,
+		}, lastID)
+		return err
+	})
+	if isDuplicateEntry(err) {
+		// a concurrent request with the same reference_id committed first
+		prev, ferr := m.findReference(ctx, m.Conn, req.ReferenceID)
+		if ferr != nil {
+			return nil, ferr
+		}
+		if prev == nil {
+			return nil, models.ErrDuplicateReference
+		}
+		lastID, status, err = m.replayTransfer(ctx, m.Conn, prev, req, id)
+	}
 	if err != nil {
 		return nil, err
 	}
+	if status == models.TransactionStatusFailed {
+		if funds == nil {
+			return nil, m.replayedFunds(ctx, id, req.Amount+fee)
+		}
+		return nil, funds
+	}
 
-	rowInsert, err := stmt.ExecContext(ctx, req.ReferenceID, wallet.ID, req.Amount, "success", wallet.OwnedBy)
+	res, err := m.FetchTransactionTransfer(ctx, lastID, req.WalletID)
 	if err != nil {
 		return nil, err
 	}
 
-	lastID, err := rowInsert.LastInsertId()
+	return res, nil
+}
+
+// replayTransfer compare a replayed transfer with the recorded one, including where the money went
+func (m *mysqlWalletRepository) replayTransfer(ctx context.Context, q queryer, prev *recordedTransaction, req *models.ReqTransfer, id string) (int64, string, error) {
+	lastID, status, err := prev.replay(id, models.TransactionTypeTransferOut, req.Amount)
+	if err != nil || status == models.TransactionStatusFailed {
+		return lastID, status, err
+	}
+
+	in, err := m.findReference(ctx, q, linkedReference(req.ReferenceID, models.TransactionTypeTransferIn))
+	if err != nil {
+		return 0, "", err
+	}
+	if in == nil || in.walletID != req.WalletID {
+		return 0, "", models.ErrDuplicateReference
+	}
+
+	return lastID, status, nil
+}
+
+func (m *mysqlWalletRepository) FetchTransactionTransfer(ctx context.Context, id int64, to string) (*models.Transfer, error) {
+	query := `SELECT ` + transactionColumns + ` FROM transaction WHERE id = ?`
+
+	list, err := m.fetchTransaction(ctx, m.Conn, query, id)
 	if err != nil {
 		return nil, err
 	}
+	if len(list) == 0 {
+		return nil, models.ErrNotFound
+	}
 
-	res, err := m.FetchTransactionAdd(ctx, lastID)
+	fee, err := m.fetchFee(ctx, id)
 	if err != nil {
 		return nil, err
 	}
 
-	return res, nil
+	return &models.Transfer{
+		ReferenceID:   list[0].ReferenceID,
+		From:          list[0].ID,
+		To:            to,
+		Amount:        list[0].Amount,
+		GrossAmount:   list[0].Amount + fee,
+		Fee:           fee,
+		NetAmount:     list[0].Amount,
+		Currency:      list[0].Currency,
+		Status:        list[0].Status,
+		TransferredBy: list[0].CreatedBy,
+		TransferredAt: list[0].CreatedAt,
+	}, nil
 }
 
-func (m *mysqlWalletRepository) WithdrawWallet(ctx context.Context, req *models.ReqTransaction, id string) (*models.TransactionWithdraw, error) {
-	wallet, err := m.FetchWallet(ctx, id)
+// transact lock the wallet, apply the balance change and record it as one transaction row with its journal, all in one db transaction.
+// A request replaying a known reference_id return the row recorded the first time and does not touch the balance again,
+// nor is it checked against limit.
+func (m *mysqlWalletRepository) transact(ctx context.Context, req *models.ReqTransaction, id string, txType string, fee int64, limit limits.Checker,
+	apply func(tx *sql.Tx, wallet *models.Wallet) (string, models.Journal, error)) (int64, string, error) {
+	var lastID int64
+	var status string
+	err := m.withTx(ctx, func(tx *sql.Tx) error {
+		wallet, err := m.lockWallet(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+		if _, err = models.MatchCurrency(req.Currency, wallet.Currency); err != nil {
+			return err
+		}
+
+		prev, err := m.findReference(ctx, tx, req.ReferenceID)
+		if err != nil {
+			return err
+		}
+		if prev != nil {
+			lastID, status, err = prev.replay(wallet.ID, txType, req.Amount)
+			return err
+		}
+		if err = m.checkLimits(ctx, tx, limit, wallet, txType, req.Amount); err != nil {
+			return err
+		}
+
+		var journal models.Journal
+		status, journal, err = apply(tx, wallet)
+		if err != nil {
+			return err
+		}
+
+		t := &models.Transaction{
+			ReferenceID: req.ReferenceID,
+			ID:          wallet.ID,
+			Type:        txType,
+			Amount:      req.Amount,
+			Currency:    wallet.Currency,
+			Status:      status,
+			CreatedBy:   wallet.OwnedBy,
+		}
+		if status == models.TransactionStatusFailed {
+			// apply only fails a transaction the wallet has not the funds for
+			t.Reason = models.ReasonInsufficientFunds
+		}
+		lastID, err = m.insertTransaction(ctx, tx, t, 0)
+		if err != nil {
+			return err
+		}
+		if err = m.postJournal(ctx, tx, lastID, journal); err != nil {
+			return err
+		}
+		if status != models.TransactionStatusSuccess {
+			return nil
+		}
+		return m.insertFee(ctx, tx, t, lastID, fee)
+	})
+	if isDuplicateEntry(err) {
+		// a concurrent request with the same reference_id committed first
+		prev, err := m.findReference(ctx, m.Conn, req.ReferenceID)
+		if err != nil {
+			return 0, "", err
+		}
+		if prev == nil {
+			return 0, "", models.ErrDuplicateReference
+		}
+		return prev.replay(id, txType, req.Amount)
+	}
 	if err != nil {
-		return nil, err
+		return 0, "", err
 	}
 
-	balance := wallet.Balance - req.Amount
-	query := `UPDATE wallet set balance = ? WHERE wallet_id = ?`
+	return lastID, status, nil
+}
 
-	stmt, err := m.Conn.PrepareContext(ctx, query)
+// replayedFunds return the FundsError of a replayed attempt that failed for a lack of funds, with the funds available now
+func (m *mysqlWalletRepository) replayedFunds(ctx context.Context, id string, required int64) error {
+	w, err := m.FetchWallet(ctx, id)
 	if err != nil {
-		return nil, err
+		return err
+	}
+	return &models.FundsError{Available: w.AvailableBalance, Required: required, Currency: w.Currency}
+}
+
+// recordedTransaction is the part of a transaction row needed to tell a replay from a conflicting reuse of its reference_id
+type recordedTransaction struct {
+	id       int64
+	walletID string
+	txType   string
+	amount   int64
+	status   string
+	parentID sql.NullInt64
+}
+
+func (r *recordedTransaction) replay(walletID string, txType string, amount int64) (int64, string, error) {
+	if r.walletID != walletID || r.txType != txType || r.amount != amount {
+		return 0, "", models.ErrDuplicateReference
 	}
-	_, err = stmt.ExecContext(ctx, balance, id)
+	return r.id, r.status, nil
+}
+
+func (m *mysqlWalletRepository) findReference(ctx context.Context, q queryer, referenceID string) (*recordedTransaction, error) {
+	query := `SELECT id, wallet_id, type, amount, status, parent_id FROM transaction WHERE reference_id = ?`
+
+	rows, err := q.QueryContext(ctx, query, referenceID)
 	if err != nil {
+		logrus.WithContext(ctx).Error(err)
 		return nil, err
 	}
 
-	query2 := `INSERT INTO transaction (reference_id, wallet_id, type, amount, status, created_by) VALUES (?,?,1,?,?,?);`
+	defer func() {
+		err := rows.Close()
+		if err != nil {
+			logrus.WithContext(ctx).Error(err)
+		}
+	}()
 
-	stmt, err = m.Conn.PrepareContext(ctx, query2)
+	if !rows.Next() {
+		return nil, rows.Err()
+	}
+	r := new(recordedTransaction)
+	err = rows.Scan(&r.id, &r.walletID, &r.txType, &r.amount, &r.status, &r.parentID)
 	if err != nil {
+		logrus.WithContext(ctx).Error(err)
 		return nil, err
 	}
 
-	status := "success"
-	if balance < 0 {
-		status = "failed"
+	return r, nil
+}
+
+// checkLimits apply limit to the wallet w locked by tx, reading what it already moved within tx so a concurrent
+// transaction on w is either counted or waits for tx
+func (m *mysqlWalletRepository) checkLimits(ctx context.Context, tx *sql.Tx, limit limits.Checker, w *models.Wallet, txType string, amount int64) error {
+	if limit == nil {
+		return nil
+	}
+	return limit.Check(ctx, txUsage{m, tx}, &models.FetchWallet{
+		ID:        w.ID,
+		OwnedBy:   w.OwnedBy,
+		Status:    w.Status,
+		EnabledAt: w.UpdatedAt,
+		Balance:   w.Balance,
+		Currency:  w.Currency,
+	}, txType, amount)
+}
+
+// txUsage is the limits.UsageReader of a db transaction
+type txUsage struct {
+	m  *mysqlWalletRepository
+	tx *sql.Tx
+}
+
+func (u txUsage) FetchUsage(ctx context.Context, id string, types []string, since time.Time) (*models.Usage, error) {
+	return u.m.fetchUsage(ctx, u.tx, id, types, since)
+}
+
+// lockWallet read the enabled wallet and hold its row lock until tx ends
+func (m *mysqlWalletRepository) lockWallet(ctx context.Context, tx *sql.Tx, id string) (*models.Wallet, error) {
+	query := `SELECT wallet_id, owned_by, status, updated_at, balance, currency
+			  FROM wallet WHERE wallet_id = ? AND status = "enabled" FOR UPDATE`
+
+	list, err := m.fetchWallet(ctx, tx, query, id)
+	if err != nil {
+		return nil, err
+	}
+	if len(list) == 0 {
+		return nil, models.ErrDisabled
 	}
 
-	rowInsert, err := stmt.ExecContext(ctx, req.ReferenceID, wallet.ID, req.Amount, status, wallet.OwnedBy)
+	return list[0], nil
+}
+
+// lockAnyWallet lock the wallet id whatever its status, for the admin actions that also work on disabled wallets
+func (m *mysqlWalletRepository) lockAnyWallet(ctx context.Context, tx *sql.Tx, id string) (*models.Wallet, error) {
+	query := `SELECT wallet_id, owned_by, status, updated_at, balance, currency FROM wallet WHERE wallet_id = ? FOR UPDATE`
+
+	list, err := m.fetchWallet(ctx, tx, query, id)
 	if err != nil {
 		return nil, err
 	}
+	if len(list) == 0 {
+		return nil, models.ErrNotFound
+	}
 
-	if balance < 0 {
-		return nil, models.ErrBadParamInput
+	return list[0], nil
+}
+
+// lockWallets lock both wallets in wallet_id order, so two updates of the same pair in opposite directions can not deadlock each other
+func (m *mysqlWalletRepository) lockWallets(ctx context.Context, tx *sql.Tx, id string, other string) (map[string]*models.Wallet, error) {
+	order := []string{id, other}
+	if order[1] < order[0] {
+		order[0], order[1] = order[1], order[0]
+	}
+	wallets := make(map[string]*models.Wallet, 2)
+	for _, walletID := range order {
+		w, err := m.lockWallet(ctx, tx, walletID)
+		if err != nil {
+			return nil, err
+		}
+		wallets[walletID] = w
 	}
+	return wallets, nil
+}
+
+// insertTransaction record t, parentID links a secondary row (e.g. the credit leg of a transfer) to its primary row
+func (m *mysqlWalletRepository) insertTransaction(ctx context.Context, tx *sql.Tx, t *models.Transaction, parentID int64) (int64, error) {
+	query := `INSERT INTO transaction (reference_id, wallet_id, type, amount, currency, status, created_by, parent_id, quote_id, reason)
+			  VALUES (?,?,?,?,?,?,?,?,?,?)`
 
+	parent := sql.NullInt64{Int64: parentID, Valid: parentID > 0}
+	quote := sql.NullString{String: t.QuoteID, Valid: t.QuoteID != ""}
+	reason := sql.NullString{String: t.Reason, Valid: t.Reason != ""}
+	rowInsert, err := tx.ExecContext(ctx, query, t.ReferenceID, t.ID, t.Type, t.Amount, t.Currency, t.Status, t.CreatedBy, parent, quote, reason)
+	if err != nil {
+		return 0, err
+	}
 	lastID, err := rowInsert.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	return lastID, m.insertTransactionEvent(ctx, tx, t)
+}
+
+// postJournal write the ledger entries of the transaction row transactionID, a failed transaction has no entries
+func (m *mysqlWalletRepository) postJournal(ctx context.Context, tx *sql.Tx, transactionID int64, journal models.Journal) error {
+	if !journal.Balanced() {
+		return models.ErrUnbalancedJournal
+	}
+
+	query := `INSERT INTO ledger_entry (transaction_id, account, debit, credit, currency) VALUES (?,?,?,?,?)`
+	for _, e := range journal {
+		_, err := tx.ExecContext(ctx, query, transactionID, e.Account, e.Debit, e.Credit, e.Currency)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// insertFee record the fee charged on top of the transaction row parentID and move it to the fees account,
+// nothing is recorded for a zero fee
+func (m *mysqlWalletRepository) insertFee(ctx context.Context, tx *sql.Tx, parent *models.Transaction, parentID int64, fee int64) error {
+	if fee <= 0 {
+		return nil
+	}
+	t := &models.Transaction{
+		ReferenceID: linkedReference(parent.ReferenceID, models.TransactionTypeFee),
+		ID:          parent.ID,
+		Type:        models.TransactionTypeFee,
+		Amount:      fee,
+		Currency:    parent.Currency,
+		Status:      models.TransactionStatusSuccess,
+		CreatedBy:   parent.CreatedBy,
+	}
+	feeID, err := m.insertTransaction(ctx, tx, t, parentID)
+	if err != nil {
+		return err
+	}
+	return m.postJournal(ctx, tx, feeID, models.NewJournal(models.WalletAccount(parent.ID), models.AccountFees, t.Money()))
+}
+
+// fetchFee return the fee charged on top of the transaction row id, zero when there was none
+func (m *mysqlWalletRepository) fetchFee(ctx context.Context, id int64) (int64, error) {
+	query := `SELECT amount FROM transaction WHERE parent_id = ? AND type = ?`
+
+	var fee int64
+	err := m.Conn.QueryRowContext(ctx, query, id, models.TransactionTypeFee).Scan(&fee)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, nil
+	}
+	if err != nil {
+		logrus.WithContext(ctx).Error(err)
+		return 0, err
+	}
+
+	return fee, nil
+}
+
+// linkedReference derive the reference_id of a secondary row from its primary row,
+// client references can not contain "#" so these never collide with them
+func linkedReference(referenceID string, txType string) string {
+	return referenceID + "#" + txType
+}
+
+// setStatus move the wallet from one status to another and record eventType, ErrNotFound when it is not in from
+func (m *mysqlWalletRepository) setStatus(ctx context.Context, id string, from string, to string, eventType string) error {
+	return m.withTx(ctx, func(tx *sql.Tx) error {
+		return m.updateStatus(ctx, tx, id, from, to, eventType)
+	})
+}
+
+// updateStatus move the wallet id from the status from to the status to within tx, ErrNotFound when it is not in from
+func (m *mysqlWalletRepository) updateStatus(ctx context.Context, tx *sql.Tx, id string, from string, to string, eventType string) error {
+	now := time.Now()
+	query := `UPDATE wallet set status = ?, updated_at=? WHERE wallet_id = ? AND status = ?`
+	rowUpdate, err := tx.ExecContext(ctx, query, to, now, id, from)
+	if err != nil {
+		return err
+	}
+	affect, err := rowUpdate.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affect != 1 {
+		return models.ErrNotFound
+	}
+
+	return m.insertEvent(ctx, tx, models.NewEvent(eventType, id, &models.WalletStatus{
+		ID:        id,
+		Status:    to,
+		UpdatedAt: now.Truncate(time.Second),
+	}))
+}
+
+// withTx run fn inside a database transaction, it commits when fn succeed and rolls back otherwise
+func (m *mysqlWalletRepository) withTx(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	tx, err := m.Conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	err = fn(tx)
+	if err != nil {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			logrus.WithContext(ctx).Error(rbErr)
+		}
+		if isDuplicateEntry(err) {
+			// callers that expect a duplicate key look through the wrapping
+			return models.ErrConflict.Wrap(err)
+		}
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (m *mysqlWalletRepository) FetchTransactions(ctx context.Context, filter *models.TransactionFilter, id string) (*models.TransactionList, error) {
+	after, err := decodeCursor(filter.Cursor)
 	if err != nil {
 		return nil, err
 	}
 
-	res, err := m.FetchTransactionWithdraw(ctx, lastID)
+	query := `SELECT ` + transactionColumns + ` FROM transaction WHERE wallet_id = ?`
+	args := []interface{}{id}
+	if filter.Type != "" {
+		query += ` AND type = ?`
+		args = append(args, filter.Type)
+	}
+	if filter.Status != "" {
+		query += ` AND status = ?`
+		args = append(args, filter.Status)
+	}
+	if filter.MinAmount > 0 {
+		query += ` AND amount >= ?`
+		args = append(args, filter.MinAmount)
+	}
+	if filter.MaxAmount > 0 {
+		query += ` AND amount <= ?`
+		args = append(args, filter.MaxAmount)
+	}
+	if !filter.From.IsZero() {
+		query += ` AND created_at >= ?`
+		args = append(args, filter.From)
+	}
+	if !filter.To.IsZero() {
+		query += ` AND created_at < ?`
+		args = append(args, filter.To)
+	}
+	if after > 0 {
+		query += ` AND id < ?`
+		args = append(args, after)
+	}
+	// one extra row tells whether there is a next page
+	query += ` ORDER BY id DESC LIMIT ?`
+	args = append(args, filter.Limit+1)
+
+	list, err := m.fetchTransaction(ctx, m.Conn, query, args...)
 	if err != nil {
 		return nil, err
 	}
 
-	return res, nil
+	return newTransactionList(list, filter.Limit), nil
 }
 
-func (m *mysqlWalletRepository) DisableWallet(ctx context.Context, isDisabled bool, id string) (*models.WalletDisabled, error) {
-	query := `UPDATE wallet set status = "disabled" updated_at=? WHERE wallet_id = ? AND status = "enabled"`
+func (m *mysqlWalletRepository) FetchUsage(ctx context.Context, id string, types []string, since time.Time) (*models.Usage, error) {
+	return m.fetchUsage(ctx, m.Conn, id, types, since)
+}
+
+func (m *mysqlWalletRepository) fetchUsage(ctx context.Context, q queryer, id string, types []string, since time.Time) (*models.Usage, error) {
+	if len(types) == 0 {
+		return &models.Usage{}, nil
+	}
+	query := `SELECT COUNT(*), COALESCE(SUM(amount), 0), MIN(created_at) FROM transaction
+			  WHERE wallet_id = ? AND status <> ? AND created_at >= ? AND type IN (?` + strings.Repeat(`, ?`, len(types)-1) + `)`
+	args := []interface{}{id, models.TransactionStatusFailed, since}
+	for _, t := range types {
+		args = append(args, t)
+	}
 
-	stmt, err := m.Conn.PrepareContext(ctx, query)
+	usage := new(models.Usage)
+	var first mysql.NullTime
+	err := q.QueryRowContext(ctx, query, args...).Scan(&usage.Count, &usage.Amount, &first)
 	if err != nil {
+		logrus.WithContext(ctx).Error(err)
 		return nil, err
 	}
+	usage.First = first.Time
+
+	return usage, nil
+}
 
-	rowUpdate, err := stmt.ExecContext(ctx, time.Now(), id)
+func (m *mysqlWalletRepository) FetchAccountBalances(ctx context.Context) ([]*models.AccountBalance, error) {
+	query := `SELECT account, currency, SUM(debit), SUM(credit) FROM ledger_entry GROUP BY account, currency ORDER BY account, currency`
+
+	rows, err := m.Conn.QueryContext(ctx, query)
 	if err != nil {
+		logrus.WithContext(ctx).Error(err)
 		return nil, err
 	}
-	affect, err := rowUpdate.RowsAffected()
+
+	defer func() {
+		err := rows.Close()
+		if err != nil {
+			logrus.WithContext(ctx).Error(err)
+		}
+	}()
+
+	result := make([]*models.AccountBalance, 0)
+	for rows.Next() {
+		a := new(models.AccountBalance)
+		err = rows.Scan(&a.Account, &a.Currency, &a.Debit, &a.Credit)
+		if err != nil {
+			logrus.WithContext(ctx).Error(err)
+			return nil, err
+		}
+		result = append(result, a)
+	}
+
+	return result, rows.Err()
+}
+
+func (m *mysqlWalletRepository) FetchWalletBalances(ctx context.Context) (map[string]int64, error) {
+	query := `SELECT wallet_id, owned_by, status, updated_at, balance, currency FROM wallet`
+
+	list, err := m.fetchWallet(ctx, m.Conn, query)
 	if err != nil {
 		return nil, err
 	}
-	if affect != 1 {
+
+	result := make(map[string]int64, len(list))
+	for _, w := range list {
+		result[w.ID] = w.Balance
+	}
+
+	return result, nil
+}
+
+func (m *mysqlWalletRepository) DisableWallet(ctx context.Context, isDisabled bool, id string) (*models.WalletDisabled, error) {
+	err := m.setStatus(ctx, id, "enabled", "disabled", models.EventWalletDisabled)
+	if errors.Is(err, models.ErrNotFound) {
 		return nil, models.ErrDisabled
 	}
+	if err != nil {
+		return nil, err
+	}
 
 	res, err := m.FetchDisabledWallet(ctx, id)
 	if err != nil {
@@ -276,20 +901,24 @@ func (m *mysqlWalletRepository) DisableWallet(ctx context.Context, isDisabled bo
 	return res, nil
 }
 
-func (m *mysqlWalletRepository) InitWallet(ctx context.Context, customer_id string) (*models.FetchWallet, error) {
-	query2 := `INSERT INTO wallet (wallet_id, owned_by, status, balance) VALUES (?,"william-chandra","enabled",0);`
+func (m *mysqlWalletRepository) InitWallet(ctx context.Context, customer_id string, currency string) (*models.FetchWallet, error) {
+	query2 := `INSERT INTO wallet (wallet_id, owned_by, status, balance, currency) VALUES (?,?,"enabled",0,?);`
 
 	stmt, err := m.Conn.PrepareContext(ctx, query2)
 	if err != nil {
 		return nil, err
 	}
 
-	_, err = stmt.ExecContext(ctx, customer_id)
+	id := uuid.New().String()
+	_, err = stmt.ExecContext(ctx, id, customer_id, currency)
+	if isDuplicateEntry(err) {
+		return nil, models.ErrConflict
+	}
 	if err != nil {
 		return nil, err
 	}
 
-	res, err := m.FetchWallet(ctx, customer_id)
+	res, err := m.FetchWallet(ctx, id)
 	if err != nil {
 		return nil, err
 	}
@@ -297,17 +926,17 @@ func (m *mysqlWalletRepository) InitWallet(ctx context.Context, customer_id stri
 	return res, nil
 }
 
-func (m *mysqlWalletRepository) fetchWallet(ctx context.Context, query string, args ...interface{}) ([]*models.Wallet, error) {
-	rows, err := m.Conn.QueryContext(ctx, query, args...)
+func (m *mysqlWalletRepository) fetchWallet(ctx context.Context, q queryer, query string, args ...interface{}) ([]*models.Wallet, error) {
+	rows, err := q.QueryContext(ctx, query, args...)
 	if err != nil {
-		logrus.Error(err)
+		logrus.WithContext(ctx).Error(err)
 		return nil, err
 	}
 
 	defer func() {
 		err := rows.Close()
 		if err != nil {
-			logrus.Error(err)
+			logrus.WithContext(ctx).Error(err)
 		}
 	}()
 
@@ -320,10 +949,11 @@ func (m *mysqlWalletRepository) fetchWallet(ctx context.Context, query string, a
 			&t.Status,
 			&t.UpdatedAt,
 			&t.Balance,
+			&t.Currency,
 		)
 
 		if err != nil {
-			logrus.Error(err)
+			logrus.WithContext(ctx).Error(err)
 			return nil, err
 		}
 		result = append(result, t)
@@ -332,39 +962,51 @@ func (m *mysqlWalletRepository) fetchWallet(ctx context.Context, query string, a
 	return result, nil
 }
 
-func (m *mysqlWalletRepository) fetchTransaction(ctx context.Context, query string, args ...interface{}) ([]*models.Transaction, error) {
-	rows, err := m.Conn.QueryContext(ctx, query, args...)
+func (m *mysqlWalletRepository) fetchTransaction(ctx context.Context, q queryer, query string, args ...interface{}) ([]*models.Transaction, error) {
+	rows, err := q.QueryContext(ctx, query, args...)
 	if err != nil {
-		logrus.Error(err)
+		logrus.WithContext(ctx).Error(err)
 		return nil, err
 	}
 
 	defer func() {
 		err := rows.Close()
 		if err != nil {
-			logrus.Error(err)
+			logrus.WithContext(ctx).Error(err)
 		}
 	}()
 
 	result := make([]*models.Transaction, 0)
 	for rows.Next() {
 		t := new(models.Transaction)
+		var quoteID, reason sql.NullString
 		err = rows.Scan(
+			&t.RowID,
 			&t.ReferenceID,
 			&t.ID,
 			&t.Type,
 			&t.Amount,
+			&t.Currency,
 			&t.Status,
 			&t.CreatedBy,
 			&t.CreatedAt,
+			&quoteID,
+			&reason,
 		)
 
 		if err != nil {
-			logrus.Error(err)
+			logrus.WithContext(ctx).Error(err)
 			return nil, err
 		}
+		t.QuoteID = quoteID.String
+		t.Reason = reason.String
 		result = append(result, t)
 	}
 
 	return result, nil
 }
+
+func isDuplicateEntry(err error) bool {
+	var me *mysql.MySQLError
+	return errors.As(err, &me) && me.Number == errDuplicateEntry
+}
@@ -0,0 +1,335 @@
+package repository_test
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	sqlmock "gopkg.in/DATA-DOG/go-sqlmock.v1"
+
+	"github.com/williamchand/my-wallet/models"
+	"github.com/williamchand/my-wallet/wallet/repository"
+)
+
+var walletColumns = []string{"wallet_id", "owned_by", "status", "updated_at", "balance", "currency"}
+var transactionColumns = []string{"id", "reference_id", "wallet_id", "type", "amount", "currency", "status", "created_by", "created_at", "quote_id", "reason"}
+var referenceColumns = []string{"id", "wallet_id", "type", "amount", "status", "parent_id"}
+
+func TestAddWallet(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	now := time.Now()
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT (.+) FROM wallet WHERE wallet_id = \\? AND status = \"enabled\" FOR UPDATE").
+		WithArgs("wallet-1").
+		WillReturnRows(sqlmock.NewRows(walletColumns).AddRow("wallet-1", "customer-1", "enabled", now, 100, "IDR"))
+	mock.ExpectQuery("SELECT (.+) FROM transaction WHERE reference_id = \\?").
+		WithArgs("ref-1").
+		WillReturnRows(sqlmock.NewRows(referenceColumns))
+	mock.ExpectExec("UPDATE wallet SET balance = balance \\+ \\?").
+		WithArgs(50, "wallet-1").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO transaction").
+		WithArgs("ref-1", "wallet-1", "deposit", 50, "IDR", "success", "customer-1", nil, nil, nil).
+		WillReturnResult(sqlmock.NewResult(7, 1))
+	mock.ExpectExec("INSERT INTO outbox").
+		WithArgs(sqlmock.AnyArg(), "deposit.succeeded", "wallet-1", sqlmock.AnyArg(), "pending", sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("INSERT INTO ledger_entry").
+		WithArgs(7, "system:cash_in", 50, 0, "IDR").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("INSERT INTO ledger_entry").
+		WithArgs(7, "wallet:wallet-1", 0, 50, "IDR").
+		WillReturnResult(sqlmock.NewResult(2, 1))
+	mock.ExpectCommit()
+	mock.ExpectQuery("SELECT (.+) FROM transaction WHERE id = \\?").
+		WithArgs(7).
+		WillReturnRows(sqlmock.NewRows(transactionColumns).AddRow(7, "ref-1", "wallet-1", "deposit", 50, "IDR", "success", "customer-1", now, nil, nil))
+
+	r := repository.NewMysqlWalletRepository(db)
+	res, err := r.AddWallet(context.TODO(), &models.ReqTransaction{ReferenceID: "ref-1", Amount: 50}, nil, "wallet-1")
+	require.NoError(t, err)
+	assert.Equal(t, "ref-1", res.ReferenceID)
+	assert.Equal(t, int64(50), res.Amount)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAddWalletRollbackOnInsertError(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT (.+) FROM wallet (.+) FOR UPDATE").
+		WillReturnRows(sqlmock.NewRows(walletColumns).AddRow("wallet-1", "customer-1", "enabled", time.Now(), 100, "IDR"))
+	mock.ExpectQuery("SELECT (.+) FROM transaction WHERE reference_id").
+		WillReturnRows(sqlmock.NewRows(referenceColumns))
+	mock.ExpectExec("UPDATE wallet SET balance").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO transaction").
+		WillReturnError(errors.New("insert failed"))
+	mock.ExpectRollback()
+
+	r := repository.NewMysqlWalletRepository(db)
+	_, err = r.AddWallet(context.TODO(), &models.ReqTransaction{ReferenceID: "ref-1", Amount: 50}, nil, "wallet-1")
+	assert.EqualError(t, err, "insert failed")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestWithdrawWalletInsufficientBalance(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT (.+) FROM wallet (.+) FOR UPDATE").
+		WillReturnRows(sqlmock.NewRows(walletColumns).AddRow("wallet-1", "customer-1", "enabled", time.Now(), 80, "IDR"))
+	mock.ExpectQuery("SELECT (.+) FROM transaction WHERE reference_id").
+		WillReturnRows(sqlmock.NewRows(referenceColumns))
+	// 40 of the 80 are held, so only 40 are available
+	mock.ExpectQuery("SELECT COALESCE\\(SUM\\(amount\\), 0\\) FROM hold").
+		WithArgs("wallet-1", sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"held"}).AddRow(40))
+	// no UPDATE and no ledger entries: the balance is left untouched and only the failed attempt is recorded
+	mock.ExpectExec("INSERT INTO transaction").
+		WithArgs("ref-1", "wallet-1", "withdrawal", 50, "IDR", "failed", "customer-1", nil, nil, models.ReasonInsufficientFunds).
+		WillReturnResult(sqlmock.NewResult(8, 1))
+	// the failure is still published
+	mock.ExpectExec("INSERT INTO outbox").
+		WithArgs(sqlmock.AnyArg(), "withdrawal.failed", "wallet-1", sqlmock.AnyArg(), "pending", sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	r := repository.NewMysqlWalletRepository(db)
+	_, err = r.WithdrawWallet(context.TODO(), &models.ReqTransaction{ReferenceID: "ref-1", Amount: 50}, 0, nil, "wallet-1")
+	assert.Equal(t, &models.FundsError{Available: 40, Required: 50, Currency: "IDR"}, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAddWalletReplay(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	now := time.Now()
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT (.+) FROM wallet (.+) FOR UPDATE").
+		WillReturnRows(sqlmock.NewRows(walletColumns).AddRow("wallet-1", "customer-1", "enabled", now, 100, "IDR"))
+	mock.ExpectQuery("SELECT (.+) FROM transaction WHERE reference_id").
+		WithArgs("ref-1").
+		WillReturnRows(sqlmock.NewRows(referenceColumns).AddRow(7, "wallet-1", "deposit", 50, "success", nil))
+	// neither UPDATE nor INSERT: the balance was already credited the first time
+	mock.ExpectCommit()
+	mock.ExpectQuery("SELECT (.+) FROM transaction WHERE id = \\?").
+		WithArgs(7).
+		WillReturnRows(sqlmock.NewRows(transactionColumns).AddRow(7, "ref-1", "wallet-1", "deposit", 50, "IDR", "success", "customer-1", now, nil, nil))
+
+	r := repository.NewMysqlWalletRepository(db)
+	res, err := r.AddWallet(context.TODO(), &models.ReqTransaction{ReferenceID: "ref-1", Amount: 50}, nil, "wallet-1")
+	require.NoError(t, err)
+	assert.Equal(t, "ref-1", res.ReferenceID)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAddWalletConcurrentReplay(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	now := time.Now()
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT (.+) FROM wallet (.+) FOR UPDATE").
+		WillReturnRows(sqlmock.NewRows(walletColumns).AddRow("wallet-1", "customer-1", "enabled", now, 100, "IDR"))
+	mock.ExpectQuery("SELECT (.+) FROM transaction WHERE reference_id").
+		WillReturnRows(sqlmock.NewRows(referenceColumns))
+	mock.ExpectExec("UPDATE wallet SET balance").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	// a concurrent request with the same reference_id committed first, the error comes wrapped
+	mock.ExpectExec("INSERT INTO transaction").
+		WillReturnError(fmt.Errorf("insert transaction: %w", &mysql.MySQLError{Number: 1062, Message: "Duplicate entry 'ref-1'"}))
+	mock.ExpectRollback()
+	mock.ExpectQuery("SELECT (.+) FROM transaction WHERE reference_id").
+		WithArgs("ref-1").
+		WillReturnRows(sqlmock.NewRows(referenceColumns).AddRow(7, "wallet-1", "deposit", 50, "success", nil))
+	mock.ExpectQuery("SELECT (.+) FROM transaction WHERE id = \\?").
+		WithArgs(7).
+		WillReturnRows(sqlmock.NewRows(transactionColumns).AddRow(7, "ref-1", "wallet-1", "deposit", 50, "IDR", "success", "customer-1", now, nil, nil))
+
+	r := repository.NewMysqlWalletRepository(db)
+	res, err := r.AddWallet(context.TODO(), &models.ReqTransaction{ReferenceID: "ref-1", Amount: 50}, nil, "wallet-1")
+	require.NoError(t, err, "the duplicate entry is found behind the wrapping")
+	assert.Equal(t, "ref-1", res.ReferenceID)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAddWalletReferenceConflict(t *testing.T) {
+	tests := map[string][]driver.Value{
+		"other amount": {7, "wallet-1", "deposit", 60, "success", nil},
+		"other wallet": {7, "wallet-2", "deposit", 50, "success", nil},
+		"other type":   {7, "wallet-1", "withdrawal", 50, "success", nil},
+	}
+	for name, recorded := range tests {
+		t.Run(name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			require.NoError(t, err)
+			defer db.Close()
+
+			mock.ExpectBegin()
+			mock.ExpectQuery("SELECT (.+) FROM wallet (.+) FOR UPDATE").
+				WillReturnRows(sqlmock.NewRows(walletColumns).AddRow("wallet-1", "customer-1", "enabled", time.Now(), 100, "IDR"))
+			mock.ExpectQuery("SELECT (.+) FROM transaction WHERE reference_id").
+				WillReturnRows(sqlmock.NewRows(referenceColumns).AddRow(recorded...))
+			mock.ExpectRollback()
+
+			r := repository.NewMysqlWalletRepository(db)
+			_, err = r.AddWallet(context.TODO(), &models.ReqTransaction{ReferenceID: "ref-1", Amount: 50}, nil, "wallet-1")
+			assert.Equal(t, models.ErrDuplicateReference, err)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestFetchTransactions(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	now := time.Now()
+	rows := sqlmock.NewRows(transactionColumns)
+	for id := 9; id >= 7; id-- {
+		rows.AddRow(id, fmt.Sprintf("ref-%d", id), "wallet-1", "deposit", 50, "IDR", "success", "customer-1", now, nil, nil)
+	}
+	mock.ExpectQuery("SELECT (.+) FROM transaction WHERE wallet_id = \\? AND type = \\? AND amount >= \\? ORDER BY id DESC LIMIT \\?").
+		WithArgs("wallet-1", "deposit", 10, 3).
+		WillReturnRows(rows)
+
+	r := repository.NewMysqlWalletRepository(db)
+	filter := &models.TransactionFilter{Type: "deposit", MinAmount: 10, Limit: 2}
+	res, err := r.FetchTransactions(context.TODO(), filter, "wallet-1")
+	require.NoError(t, err)
+	require.Len(t, res.Transactions, 2)
+	assert.Equal(t, "ref-8", res.Transactions[1].ReferenceID)
+	require.NotEmpty(t, res.NextCursor)
+
+	// the next page starts right after the last row returned
+	mock.ExpectQuery("SELECT (.+) FROM transaction WHERE wallet_id = \\? AND id < \\? ORDER BY id DESC LIMIT \\?").
+		WithArgs("wallet-1", 8, 3).
+		WillReturnRows(sqlmock.NewRows(transactionColumns).AddRow(7, "ref-7", "wallet-1", "deposit", 50, "IDR", "success", "customer-1", now, nil, nil))
+
+	res, err = r.FetchTransactions(context.TODO(), &models.TransactionFilter{Cursor: res.NextCursor, Limit: 2}, "wallet-1")
+	require.NoError(t, err)
+	require.Len(t, res.Transactions, 1)
+	assert.Empty(t, res.NextCursor)
+	assert.NoError(t, mock.ExpectationsWereMet())
+
+	_, err = r.FetchTransactions(context.TODO(), &models.TransactionFilter{Cursor: "garbage", Limit: 2}, "wallet-1")
+	assert.Equal(t, models.ErrBadParamInput, err)
+}
+
+func TestTransferWallet(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	now := time.Now()
+	mock.ExpectBegin()
+	// "wallet-a" sorts first, so it is locked first even though it is the receiver
+	mock.ExpectQuery("SELECT (.+) FROM wallet (.+) FOR UPDATE").
+		WithArgs("wallet-a").
+		WillReturnRows(sqlmock.NewRows(walletColumns).AddRow("wallet-a", "customer-a", "enabled", now, 0, "IDR"))
+	mock.ExpectQuery("SELECT (.+) FROM wallet (.+) FOR UPDATE").
+		WithArgs("wallet-b").
+		WillReturnRows(sqlmock.NewRows(walletColumns).AddRow("wallet-b", "customer-b", "enabled", now, 100, "IDR"))
+	mock.ExpectQuery("SELECT (.+) FROM transaction WHERE reference_id").
+		WithArgs("ref-1").
+		WillReturnRows(sqlmock.NewRows(referenceColumns))
+	mock.ExpectQuery("SELECT COALESCE\\(SUM\\(amount\\), 0\\) FROM hold").
+		WithArgs("wallet-b", sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"held"}).AddRow(0))
+	// the fee of 2 is taken on top of the 40 transferred
+	mock.ExpectExec("UPDATE wallet SET balance = balance - \\?").
+		WithArgs(42, "wallet-b", 42).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("UPDATE wallet SET balance = balance \\+ \\?").
+		WithArgs(40, "wallet-a").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO transaction").
+		WithArgs("ref-1", "wallet-b", "transfer_out", 40, "IDR", "success", "customer-b", nil, nil, nil).
+		WillReturnResult(sqlmock.NewResult(11, 1))
+	mock.ExpectExec("INSERT INTO outbox").
+		WithArgs(sqlmock.AnyArg(), "transfer.succeeded", "wallet-b", sqlmock.AnyArg(), "pending", sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("INSERT INTO ledger_entry").
+		WithArgs(11, "wallet:wallet-b", 40, 0, "IDR").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("INSERT INTO ledger_entry").
+		WithArgs(11, "wallet:wallet-a", 0, 40, "IDR").
+		WillReturnResult(sqlmock.NewResult(2, 1))
+	mock.ExpectExec("INSERT INTO transaction").
+		WithArgs("ref-1#fee", "wallet-b", "fee", 2, "IDR", "success", "customer-b", 11, nil, nil).
+		WillReturnResult(sqlmock.NewResult(12, 1))
+	mock.ExpectExec("INSERT INTO ledger_entry").
+		WithArgs(12, "wallet:wallet-b", 2, 0, "IDR").
+		WillReturnResult(sqlmock.NewResult(3, 1))
+	mock.ExpectExec("INSERT INTO ledger_entry").
+		WithArgs(12, "system:fees", 0, 2, "IDR").
+		WillReturnResult(sqlmock.NewResult(4, 1))
+	mock.ExpectExec("INSERT INTO transaction").
+		WithArgs("ref-1#transfer_in", "wallet-a", "transfer_in", 40, "IDR", "success", "customer-b", 11, nil, nil).
+		WillReturnResult(sqlmock.NewResult(13, 1))
+	mock.ExpectExec("INSERT INTO outbox").
+		WithArgs(sqlmock.AnyArg(), "transfer.received", "wallet-a", sqlmock.AnyArg(), "pending", sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+	mock.ExpectQuery("SELECT (.+) FROM transaction WHERE id = \\?").
+		WithArgs(11).
+		WillReturnRows(sqlmock.NewRows(transactionColumns).AddRow(11, "ref-1", "wallet-b", "transfer_out", 40, "IDR", "success", "customer-b", now, nil, nil))
+	mock.ExpectQuery("SELECT amount FROM transaction WHERE parent_id = \\? AND type = \\?").
+		WithArgs(11, "fee").
+		WillReturnRows(sqlmock.NewRows([]string{"amount"}).AddRow(2))
+
+	r := repository.NewMysqlWalletRepository(db)
+	res, err := r.TransferWallet(context.TODO(), &models.ReqTransfer{ReferenceID: "ref-1", WalletID: "wallet-a", Amount: 40}, 2, nil, "wallet-b")
+	require.NoError(t, err)
+	assert.Equal(t, "wallet-b", res.From)
+	assert.Equal(t, "wallet-a", res.To)
+	assert.Equal(t, int64(42), res.GrossAmount)
+	assert.Equal(t, int64(2), res.Fee)
+	assert.Equal(t, int64(40), res.NetAmount)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestForceDisableWalletRollbackOnAuditError(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT (.+) FROM wallet WHERE wallet_id = \\? FOR UPDATE").
+		WithArgs("wallet-1").
+		WillReturnRows(sqlmock.NewRows(walletColumns).AddRow("wallet-1", "customer-1", "enabled", time.Now(), 100, "IDR"))
+	mock.ExpectExec("UPDATE wallet set status = \\?").
+		WithArgs("disabled", sqlmock.AnyArg(), "wallet-1", "enabled").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO outbox").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("INSERT INTO audit_log").
+		WithArgs("admin-1", models.AuditForceDisableWallet, "wallet-1", "fraud", sqlmock.AnyArg()).
+		WillReturnError(errors.New("audit_log is full"))
+	mock.ExpectRollback()
+
+	r := repository.NewMysqlWalletRepository(db)
+	_, err = r.ForceDisableWallet(context.TODO(), "wallet-1", &models.AuditEntry{
+		Actor: "admin-1", Action: models.AuditForceDisableWallet, WalletID: "wallet-1", Detail: "fraud",
+	})
+	assert.Error(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet(), "the wallet is not disabled without its audit entry")
+}
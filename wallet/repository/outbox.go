@@ -0,0 +1,35 @@
+package repository
+
+import (
+	"github.com/williamchand/my-wallet/models"
+)
+
+// transactionEvent return the event recorded along with t, fees and captures have none of their own:
+// a fee shows in the event of its parent and a capture in hold.captured
+func transactionEvent(t *models.Transaction) string {
+	failed := t.Status == models.TransactionStatusFailed
+	switch t.Type {
+	case models.TransactionTypeDeposit:
+		return models.EventDepositSucceeded
+	case models.TransactionTypeWithdrawal:
+		if failed {
+			return models.EventWithdrawalFailed
+		}
+		return models.EventWithdrawalSucceeded
+	case models.TransactionTypeTransferOut:
+		if failed {
+			return models.EventTransferFailed
+		}
+		return models.EventTransferSucceeded
+	case models.TransactionTypeTransferIn:
+		return models.EventTransferReceived
+	case models.TransactionTypeReversal:
+		return models.EventReversalSucceeded
+	case models.TransactionTypeConvertOut, models.TransactionTypeConvertIn:
+		return models.EventConversionSucceeded
+	case models.TransactionTypeCredit, models.TransactionTypeDebit:
+		return models.EventBalanceAdjusted
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,911 @@
+package repository_test
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	_ "github.com/go-sql-driver/mysql"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/williamchand/my-wallet/models"
+	"github.com/williamchand/my-wallet/outbox"
+	"github.com/williamchand/my-wallet/wallet"
+	"github.com/williamchand/my-wallet/wallet/repository"
+)
+
+// The conformance suite below runs against every wallet.Repository implementation,
+// so the in-memory one can not drift away from MySQL.
+
+func TestMemoryRepository(t *testing.T) {
+	testRepository(t, func(t *testing.T) wallet.Repository {
+		return repository.NewMemoryWalletRepository()
+	}, func(r wallet.Repository) outbox.Repository {
+		o, err := repository.NewMemoryOutboxRepository(r)
+		require.NoError(t, err)
+		return o
+	})
+}
+
+// TestMysqlRepository needs a MySQL loaded with wallet.sql, e.g.
+// MYSQL_TEST_DSN="user:pass@tcp(localhost:3306)/f0W32R1gtc?parseTime=1"
+func TestMysqlRepository(t *testing.T) {
+	dsn := os.Getenv("MYSQL_TEST_DSN")
+	if dsn == "" || testing.Short() {
+		t.Skip("MYSQL_TEST_DSN is not set")
+	}
+	db, err := sql.Open("mysql", dsn)
+	require.NoError(t, err)
+	defer db.Close()
+	require.NoError(t, db.Ping())
+
+	testRepository(t, func(t *testing.T) wallet.Repository {
+		return repository.NewMysqlWalletRepository(db)
+	}, func(wallet.Repository) outbox.Repository {
+		return repository.NewMysqlOutboxRepository(db)
+	})
+}
+
+func testRepository(t *testing.T, newRepo func(t *testing.T) wallet.Repository, outboxOf func(wallet.Repository) outbox.Repository) {
+	ctx := context.Background()
+
+	// every test works on fresh customers, so the MySQL suite can share one database
+	initWallet := func(t *testing.T, r wallet.Repository, balance int64) *models.FetchWallet {
+		w, err := r.InitWallet(ctx, uuid.New().String(), models.DefaultCurrency)
+		require.NoError(t, err)
+		if balance > 0 {
+			_, err = r.AddWallet(ctx, &models.ReqTransaction{ReferenceID: uuid.New().String(), Amount: balance}, nil, w.ID)
+			require.NoError(t, err)
+		}
+		return w
+	}
+
+	t.Run("init", func(t *testing.T) {
+		r := newRepo(t)
+		customer := uuid.New().String()
+		w, err := r.InitWallet(ctx, customer, "IDR")
+		require.NoError(t, err)
+		assert.NotEmpty(t, w.ID)
+		assert.Equal(t, customer, w.OwnedBy)
+		assert.Equal(t, "enabled", w.Status)
+		assert.Equal(t, int64(0), w.Balance)
+		assert.Equal(t, "IDR", w.Currency)
+		assert.False(t, w.EnabledAt.IsZero())
+
+		_, err = r.InitWallet(ctx, customer, "IDR")
+		assert.Equal(t, models.ErrConflict, err)
+	})
+
+	t.Run("currencies", func(t *testing.T) {
+		r := newRepo(t)
+		customer := uuid.New().String()
+		idr, err := r.InitWallet(ctx, customer, "IDR")
+		require.NoError(t, err)
+		usd, err := r.InitWallet(ctx, customer, "USD")
+		require.NoError(t, err)
+		assert.NotEqual(t, idr.ID, usd.ID)
+		assert.Equal(t, "USD", usd.Currency)
+
+		d, err := r.AddWallet(ctx, &models.ReqTransaction{ReferenceID: uuid.New().String(), Amount: 1050, Currency: "USD"}, nil, usd.ID)
+		require.NoError(t, err)
+		assert.Equal(t, "USD", d.Currency)
+		_, err = r.AddWallet(ctx, &models.ReqTransaction{ReferenceID: uuid.New().String(), Amount: 100, Currency: "USD"}, nil, idr.ID)
+		assert.Equal(t, models.ErrCurrencyMismatch, err)
+		_, err = r.TransferWallet(ctx, &models.ReqTransfer{ReferenceID: uuid.New().String(), WalletID: idr.ID, Amount: 50}, 0, nil, usd.ID)
+		assert.Equal(t, models.ErrCurrencyMismatch, err)
+		_, err = r.CreateHold(ctx, &models.ReqHold{ReferenceID: uuid.New().String(), Amount: 50, Currency: "IDR"}, time.Now().Add(time.Hour), usd.ID)
+		assert.Equal(t, models.ErrCurrencyMismatch, err)
+
+		res, err := r.FetchWallet(ctx, usd.ID)
+		require.NoError(t, err)
+		assert.Equal(t, int64(1050), res.Balance)
+		assert.Equal(t, "USD", res.Currency)
+
+		accounts, err := r.FetchAccountBalances(ctx)
+		require.NoError(t, err)
+		var found bool
+		for _, a := range accounts {
+			if a.Account == models.WalletAccount(usd.ID) {
+				found = true
+				assert.Equal(t, &models.AccountBalance{Account: a.Account, Currency: "USD", Credit: 1050}, a)
+			}
+		}
+		assert.True(t, found)
+	})
+
+	t.Run("disable and enable", func(t *testing.T) {
+		r := newRepo(t)
+		w := initWallet(t, r, 100)
+
+		_, err := r.EnableWallet(ctx, w.ID)
+		assert.Equal(t, models.ErrAlreadyEnabled, err)
+
+		d, err := r.DisableWallet(ctx, true, w.ID)
+		require.NoError(t, err)
+		assert.Equal(t, "disabled", d.Status)
+		assert.Equal(t, int64(100), d.Balance)
+		assert.False(t, d.DisabledAt.IsZero())
+
+		_, err = r.DisableWallet(ctx, true, w.ID)
+		assert.Equal(t, models.ErrDisabled, err)
+		_, err = r.FetchWallet(ctx, w.ID)
+		assert.Equal(t, models.ErrDisabled, err)
+		_, err = r.AddWallet(ctx, &models.ReqTransaction{ReferenceID: uuid.New().String(), Amount: 1}, nil, w.ID)
+		assert.Equal(t, models.ErrDisabled, err)
+
+		e, err := r.EnableWallet(ctx, w.ID)
+		require.NoError(t, err)
+		assert.Equal(t, "enabled", e.Status)
+		assert.Equal(t, int64(100), e.Balance)
+	})
+
+	t.Run("unknown wallet", func(t *testing.T) {
+		r := newRepo(t)
+		_, err := r.FetchWallet(ctx, uuid.New().String())
+		assert.Equal(t, models.ErrDisabled, err)
+		_, err = r.WithdrawWallet(ctx, &models.ReqTransaction{ReferenceID: uuid.New().String(), Amount: 1}, 0, nil, uuid.New().String())
+		assert.Equal(t, models.ErrDisabled, err)
+	})
+
+	t.Run("deposit and withdraw", func(t *testing.T) {
+		r := newRepo(t)
+		w := initWallet(t, r, 0)
+
+		ref := uuid.New().String()
+		d, err := r.AddWallet(ctx, &models.ReqTransaction{ReferenceID: ref, Amount: 100}, nil, w.ID)
+		require.NoError(t, err)
+		assert.Equal(t, ref, d.ReferenceID)
+		assert.Equal(t, w.ID, d.ID)
+		assert.Equal(t, int64(100), d.Amount)
+		assert.Equal(t, models.TransactionStatusSuccess, d.Status)
+		assert.Equal(t, w.OwnedBy, d.DepositBy)
+		assert.False(t, d.DepositAt.IsZero())
+
+		wd, err := r.WithdrawWallet(ctx, &models.ReqTransaction{ReferenceID: uuid.New().String(), Amount: 30}, 0, nil, w.ID)
+		require.NoError(t, err)
+		assert.Equal(t, int64(30), wd.Amount)
+		assert.Equal(t, w.OwnedBy, wd.WithdrawnBy)
+
+		res, err := r.FetchWallet(ctx, w.ID)
+		require.NoError(t, err)
+		assert.Equal(t, int64(70), res.Balance)
+	})
+
+	t.Run("insufficient funds", func(t *testing.T) {
+		r := newRepo(t)
+		w := initWallet(t, r, 50)
+
+		ref := uuid.New().String()
+		_, err := r.WithdrawWallet(ctx, &models.ReqTransaction{ReferenceID: ref, Amount: 80}, 0, nil, w.ID)
+		assert.Equal(t, &models.FundsError{Available: 50, Required: 80, Currency: w.Currency}, err)
+
+		res, err := r.FetchWallet(ctx, w.ID)
+		require.NoError(t, err)
+		assert.Equal(t, int64(50), res.Balance)
+
+		list, err := r.FetchTransactions(ctx, &models.TransactionFilter{Status: models.TransactionStatusFailed, Limit: 10}, w.ID)
+		require.NoError(t, err)
+		require.Len(t, list.Transactions, 1)
+		assert.Equal(t, ref, list.Transactions[0].ReferenceID)
+		assert.Equal(t, models.ReasonInsufficientFunds, list.Transactions[0].Reason)
+
+		// a replay fails the same way, against the funds available now
+		_, err = r.AddWallet(ctx, &models.ReqTransaction{ReferenceID: uuid.New().String(), Amount: 10}, nil, w.ID)
+		require.NoError(t, err)
+		_, err = r.WithdrawWallet(ctx, &models.ReqTransaction{ReferenceID: ref, Amount: 80}, 0, nil, w.ID)
+		assert.Equal(t, &models.FundsError{Available: 60, Required: 80, Currency: w.Currency}, err)
+	})
+
+	t.Run("usage", func(t *testing.T) {
+		r := newRepo(t)
+		w := initWallet(t, r, 100)
+		other := initWallet(t, r, 100)
+		since := time.Now().Add(-time.Minute)
+
+		_, err := r.WithdrawWallet(ctx, &models.ReqTransaction{ReferenceID: uuid.New().String(), Amount: 30}, 0, nil, w.ID)
+		require.NoError(t, err)
+		_, err = r.TransferWallet(ctx, &models.ReqTransfer{ReferenceID: uuid.New().String(), WalletID: other.ID, Amount: 20}, 0, nil, w.ID)
+		require.NoError(t, err)
+		// failed withdrawals are not counted
+		_, err = r.WithdrawWallet(ctx, &models.ReqTransaction{ReferenceID: uuid.New().String(), Amount: 500}, 0, nil, w.ID)
+		assert.IsType(t, &models.FundsError{}, err)
+
+		out := []string{models.TransactionTypeWithdrawal, models.TransactionTypeTransferOut}
+		usage, err := r.FetchUsage(ctx, w.ID, out, since)
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), usage.Count)
+		assert.Equal(t, int64(50), usage.Amount)
+		assert.WithinDuration(t, time.Now(), usage.First, time.Minute)
+
+		usage, err = r.FetchUsage(ctx, w.ID, []string{models.TransactionTypeDeposit}, since)
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), usage.Count)
+		assert.Equal(t, int64(100), usage.Amount)
+
+		usage, err = r.FetchUsage(ctx, w.ID, out, time.Now().Add(time.Minute))
+		require.NoError(t, err)
+		assert.Equal(t, int64(0), usage.Count)
+		assert.Equal(t, int64(0), usage.Amount)
+		assert.True(t, usage.First.IsZero())
+	})
+
+	t.Run("reference replay", func(t *testing.T) {
+		r := newRepo(t)
+		w := initWallet(t, r, 0)
+		other := initWallet(t, r, 0)
+
+		req := &models.ReqTransaction{ReferenceID: uuid.New().String(), Amount: 40}
+		first, err := r.AddWallet(ctx, req, nil, w.ID)
+		require.NoError(t, err)
+		again, err := r.AddWallet(ctx, req, nil, w.ID)
+		require.NoError(t, err)
+		assert.Equal(t, first, again)
+
+		res, err := r.FetchWallet(ctx, w.ID)
+		require.NoError(t, err)
+		assert.Equal(t, int64(40), res.Balance)
+
+		_, err = r.AddWallet(ctx, &models.ReqTransaction{ReferenceID: req.ReferenceID, Amount: 41}, nil, w.ID)
+		assert.Equal(t, models.ErrDuplicateReference, err)
+		_, err = r.WithdrawWallet(ctx, req, 0, nil, w.ID)
+		assert.Equal(t, models.ErrDuplicateReference, err)
+		_, err = r.AddWallet(ctx, req, nil, other.ID)
+		assert.Equal(t, models.ErrDuplicateReference, err)
+	})
+
+	t.Run("transfer", func(t *testing.T) {
+		r := newRepo(t)
+		sender := initWallet(t, r, 100)
+		receiver := initWallet(t, r, 0)
+
+		req := &models.ReqTransfer{ReferenceID: uuid.New().String(), WalletID: receiver.ID, Amount: 60}
+		res, err := r.TransferWallet(ctx, req, 0, nil, sender.ID)
+		require.NoError(t, err)
+		assert.Equal(t, sender.ID, res.From)
+		assert.Equal(t, receiver.ID, res.To)
+		assert.Equal(t, int64(60), res.Amount)
+
+		again, err := r.TransferWallet(ctx, req, 0, nil, sender.ID)
+		require.NoError(t, err)
+		assert.Equal(t, res, again)
+
+		_, err = r.TransferWallet(ctx, &models.ReqTransfer{ReferenceID: uuid.New().String(), WalletID: receiver.ID, Amount: 60}, 0, nil, sender.ID)
+		assert.IsType(t, &models.FundsError{}, err)
+
+		s, err := r.FetchWallet(ctx, sender.ID)
+		require.NoError(t, err)
+		assert.Equal(t, int64(40), s.Balance)
+		rc, err := r.FetchWallet(ctx, receiver.ID)
+		require.NoError(t, err)
+		assert.Equal(t, int64(60), rc.Balance)
+
+		in, err := r.FetchTransactions(ctx, &models.TransactionFilter{Type: models.TransactionTypeTransferIn, Limit: 10}, receiver.ID)
+		require.NoError(t, err)
+		require.Len(t, in.Transactions, 1)
+		assert.Equal(t, int64(60), in.Transactions[0].Amount)
+
+		_, err = r.DisableWallet(ctx, true, receiver.ID)
+		require.NoError(t, err)
+		_, err = r.TransferWallet(ctx, &models.ReqTransfer{ReferenceID: uuid.New().String(), WalletID: receiver.ID, Amount: 1}, 0, nil, sender.ID)
+		assert.Equal(t, models.ErrDisabled, err)
+	})
+
+	t.Run("fees", func(t *testing.T) {
+		r := newRepo(t)
+		w := initWallet(t, r, 200)
+		other := initWallet(t, r, 0)
+
+		req := &models.ReqTransaction{ReferenceID: uuid.New().String(), Amount: 100}
+		wd, err := r.WithdrawWallet(ctx, req, 5, nil, w.ID)
+		require.NoError(t, err)
+		assert.Equal(t, int64(100), wd.Amount)
+		assert.Equal(t, int64(105), wd.GrossAmount)
+		assert.Equal(t, int64(5), wd.Fee)
+		assert.Equal(t, int64(100), wd.NetAmount)
+		// a replay reports the fee charged the first time
+		again, err := r.WithdrawWallet(ctx, req, 7, nil, w.ID)
+		require.NoError(t, err)
+		assert.Equal(t, wd, again)
+
+		// 91 plus the fee is over the 95 left, nothing is charged
+		_, err = r.WithdrawWallet(ctx, &models.ReqTransaction{ReferenceID: uuid.New().String(), Amount: 91}, 5, nil, w.ID)
+		assert.Equal(t, &models.FundsError{Available: 95, Required: 96, Currency: w.Currency}, err)
+
+		tr, err := r.TransferWallet(ctx, &models.ReqTransfer{ReferenceID: uuid.New().String(), WalletID: other.ID, Amount: 50}, 3, nil, w.ID)
+		require.NoError(t, err)
+		assert.Equal(t, int64(53), tr.GrossAmount)
+		assert.Equal(t, int64(3), tr.Fee)
+		assert.Equal(t, int64(50), tr.NetAmount)
+
+		res, err := r.FetchWallet(ctx, w.ID)
+		require.NoError(t, err)
+		assert.Equal(t, int64(42), res.Balance)
+		res, err = r.FetchWallet(ctx, other.ID)
+		require.NoError(t, err)
+		assert.Equal(t, int64(50), res.Balance)
+
+		list, err := r.FetchTransactions(ctx, &models.TransactionFilter{Type: models.TransactionTypeFee, Limit: 10}, w.ID)
+		require.NoError(t, err)
+		require.Len(t, list.Transactions, 2)
+		assert.Equal(t, tr.ReferenceID+"#fee", list.Transactions[0].ReferenceID)
+		assert.Equal(t, int64(3), list.Transactions[0].Amount)
+		assert.Equal(t, req.ReferenceID+"#fee", list.Transactions[1].ReferenceID)
+		assert.Equal(t, int64(5), list.Transactions[1].Amount)
+
+		balances, err := r.FetchWalletBalances(ctx)
+		require.NoError(t, err)
+		accounts, err := r.FetchAccountBalances(ctx)
+		require.NoError(t, err)
+		for _, a := range accounts {
+			if a.Account == models.WalletAccount(w.ID) {
+				assert.Equal(t, balances[w.ID], a.Credit-a.Debit)
+			}
+		}
+	})
+
+	t.Run("reversal", func(t *testing.T) {
+		r := newRepo(t)
+		w := initWallet(t, r, 0)
+		other := initWallet(t, r, 0)
+		audit := func() *models.AuditEntry {
+			return &models.AuditEntry{Actor: "admin-1", Action: models.AuditReverseTransaction, WalletID: w.ID}
+		}
+
+		deposit := uuid.New().String()
+		_, err := r.AddWallet(ctx, &models.ReqTransaction{ReferenceID: deposit, Amount: 100}, nil, w.ID)
+		require.NoError(t, err)
+
+		req := &models.ReqReversal{ReferenceID: uuid.New().String(), Amount: 30}
+		rev, err := r.ReverseTransaction(ctx, req, deposit, w.ID, audit())
+		require.NoError(t, err)
+		assert.Equal(t, req.ReferenceID, rev.ReferenceID)
+		assert.Equal(t, deposit, rev.OriginalReferenceID)
+		assert.Equal(t, int64(30), rev.Amount)
+		assert.Equal(t, models.TransactionStatusPartiallyReversed, rev.OriginalStatus)
+		again, err := r.ReverseTransaction(ctx, req, deposit, w.ID, audit())
+		require.NoError(t, err)
+		assert.Equal(t, rev, again)
+
+		// no more than the 70 left can be reversed
+		_, err = r.ReverseTransaction(ctx, &models.ReqReversal{ReferenceID: uuid.New().String(), Amount: 71}, deposit, w.ID, audit())
+		assert.Equal(t, models.ErrBadParamInput, err)
+		rest, err := r.ReverseTransaction(ctx, &models.ReqReversal{ReferenceID: uuid.New().String()}, deposit, w.ID, audit())
+		require.NoError(t, err)
+		assert.Equal(t, int64(70), rest.Amount)
+		assert.Equal(t, models.TransactionStatusReversed, rest.OriginalStatus)
+		_, err = r.ReverseTransaction(ctx, &models.ReqReversal{ReferenceID: uuid.New().String()}, deposit, w.ID, audit())
+		assert.Equal(t, models.ErrBadParamInput, err)
+
+		_, err = r.ReverseTransaction(ctx, &models.ReqReversal{ReferenceID: uuid.New().String()}, deposit, other.ID, audit())
+		assert.Equal(t, models.ErrNotFound, err)
+		_, err = r.ReverseTransaction(ctx, &models.ReqReversal{ReferenceID: req.ReferenceID}, rest.ReferenceID, w.ID, audit())
+		assert.Equal(t, models.ErrDuplicateReference, err)
+
+		_, err = r.AddWallet(ctx, &models.ReqTransaction{ReferenceID: uuid.New().String(), Amount: 50}, nil, w.ID)
+		require.NoError(t, err)
+		withdrawal := uuid.New().String()
+		_, err = r.WithdrawWallet(ctx, &models.ReqTransaction{ReferenceID: withdrawal, Amount: 50}, 0, nil, w.ID)
+		require.NoError(t, err)
+		_, err = r.ReverseTransaction(ctx, &models.ReqReversal{ReferenceID: uuid.New().String(), Amount: 20}, withdrawal, w.ID, audit())
+		require.NoError(t, err)
+
+		res, err := r.FetchWallet(ctx, w.ID)
+		require.NoError(t, err)
+		assert.Equal(t, int64(20), res.Balance)
+
+		list, err := r.FetchTransactions(ctx, &models.TransactionFilter{Status: models.TransactionStatusReversed, Limit: 10}, w.ID)
+		require.NoError(t, err)
+		require.Len(t, list.Transactions, 1)
+		assert.Equal(t, deposit, list.Transactions[0].ReferenceID)
+
+		entries, err := r.FetchAuditEntries(ctx, w.ID, 10)
+		require.NoError(t, err)
+		assert.Len(t, entries, 4, "the replay is recorded, the refused reversals are not")
+
+		_, err = r.ForceDisableWallet(ctx, w.ID, &models.AuditEntry{Actor: "admin-1", Action: models.AuditForceDisableWallet, WalletID: w.ID})
+		require.NoError(t, err)
+		rest, err = r.ReverseTransaction(ctx, &models.ReqReversal{ReferenceID: uuid.New().String()}, withdrawal, w.ID, audit())
+		require.NoError(t, err, "a disabled wallet can be reversed")
+		assert.Equal(t, int64(30), rest.Amount)
+		disabled, err := r.LookupWallet(ctx, w.ID)
+		require.NoError(t, err)
+		assert.Equal(t, "disabled", disabled.Status)
+		assert.Equal(t, int64(50), disabled.Balance)
+		_, err = r.ReverseTransaction(ctx, &models.ReqReversal{ReferenceID: uuid.New().String()}, withdrawal, uuid.New().String(), audit())
+		assert.Equal(t, models.ErrNotFound, err)
+	})
+
+	t.Run("force disable", func(t *testing.T) {
+		r := newRepo(t)
+		w := initWallet(t, r, 100)
+		audit := func(reason string) *models.AuditEntry {
+			return &models.AuditEntry{Actor: "admin-1", Action: models.AuditForceDisableWallet, WalletID: w.ID, Detail: reason}
+		}
+
+		res, err := r.ForceDisableWallet(ctx, w.ID, audit("fraud"))
+		require.NoError(t, err)
+		assert.Equal(t, "disabled", res.Status)
+		assert.Equal(t, int64(100), res.Balance)
+		_, err = r.FetchWallet(ctx, w.ID)
+		assert.Equal(t, models.ErrDisabled, err)
+
+		res, err = r.ForceDisableWallet(ctx, w.ID, audit("again"))
+		require.NoError(t, err)
+		assert.Equal(t, "disabled", res.Status)
+		_, err = r.ForceDisableWallet(ctx, uuid.New().String(), audit("unknown"))
+		assert.Equal(t, models.ErrNotFound, err)
+
+		entries, err := r.FetchAuditEntries(ctx, w.ID, 10)
+		require.NoError(t, err)
+		require.Len(t, entries, 2)
+		assert.Equal(t, "again", entries[0].Detail)
+		assert.Equal(t, "fraud", entries[1].Detail)
+	})
+
+	t.Run("force enable", func(t *testing.T) {
+		r := newRepo(t)
+		w := initWallet(t, r, 100)
+		audit := func(reason string) *models.AuditEntry {
+			return &models.AuditEntry{Actor: "admin-1", Action: models.AuditForceEnableWallet, WalletID: w.ID, Detail: reason}
+		}
+
+		res, err := r.ForceEnableWallet(ctx, w.ID, audit("already enabled"))
+		require.NoError(t, err)
+		assert.Equal(t, "enabled", res.Status)
+		_, err = r.DisableWallet(ctx, true, w.ID)
+		require.NoError(t, err)
+		res, err = r.ForceEnableWallet(ctx, w.ID, audit("customer verified"))
+		require.NoError(t, err)
+		assert.Equal(t, "enabled", res.Status)
+		_, err = r.FetchWallet(ctx, w.ID)
+		require.NoError(t, err)
+		_, err = r.ForceEnableWallet(ctx, uuid.New().String(), audit("unknown"))
+		assert.Equal(t, models.ErrNotFound, err)
+
+		entries, err := r.FetchAuditEntries(ctx, w.ID, 10)
+		require.NoError(t, err)
+		require.Len(t, entries, 2)
+		assert.Equal(t, "customer verified", entries[0].Detail)
+	})
+
+	t.Run("adjustments", func(t *testing.T) {
+		r := newRepo(t)
+		w := initWallet(t, r, 100)
+		audit := &models.AuditEntry{Actor: "ops-1", Action: models.AuditAdjustWallet, WalletID: w.ID}
+
+		req := &models.ReqAdjustment{ReferenceID: uuid.New().String(), Amount: 30, Reason: "goodwill credit", Actor: "ops-1"}
+		credit, err := r.AdjustWallet(ctx, req, models.TransactionTypeCredit, w.ID, audit)
+		require.NoError(t, err)
+		assert.Equal(t, models.TransactionTypeCredit, credit.Type)
+		assert.Equal(t, "goodwill credit", credit.Reason)
+		assert.Equal(t, "ops-1", credit.AdjustedBy)
+		again, err := r.AdjustWallet(ctx, req, models.TransactionTypeCredit, w.ID, audit)
+		require.NoError(t, err)
+		assert.Equal(t, credit, again)
+		_, err = r.AdjustWallet(ctx, req, models.TransactionTypeDebit, w.ID, audit)
+		assert.Equal(t, models.ErrDuplicateReference, err)
+
+		debit := &models.ReqAdjustment{ReferenceID: uuid.New().String(), Amount: 131, Reason: "duplicate deposit", Actor: "ops-1"}
+		_, err = r.AdjustWallet(ctx, debit, models.TransactionTypeDebit, w.ID, audit)
+		assert.IsType(t, &models.FundsError{}, err)
+		debit.Amount = 50
+		_, err = r.AdjustWallet(ctx, debit, models.TransactionTypeDebit, w.ID, audit)
+		require.NoError(t, err)
+
+		res, err := r.LookupWallet(ctx, w.ID)
+		require.NoError(t, err)
+		assert.Equal(t, int64(80), res.Balance)
+		_, err = r.LookupWallet(ctx, uuid.New().String())
+		assert.Equal(t, models.ErrNotFound, err)
+
+		list, err := r.FetchTransactions(ctx, &models.TransactionFilter{Type: models.TransactionTypeDebit, Limit: 10}, w.ID)
+		require.NoError(t, err)
+		require.Len(t, list.Transactions, 1)
+		assert.Equal(t, "duplicate deposit", list.Transactions[0].Reason)
+
+		entries, err := r.FetchAuditEntries(ctx, w.ID, 10)
+		require.NoError(t, err)
+		assert.Len(t, entries, 3, "the replay is recorded, the refused adjustments are not")
+
+		accounts, err := r.FetchAccountBalances(ctx)
+		require.NoError(t, err)
+		var debits, credits int64
+		for _, a := range accounts {
+			debits += a.Debit
+			credits += a.Credit
+		}
+		assert.Equal(t, debits, credits)
+	})
+
+	t.Run("holds", func(t *testing.T) {
+		r := newRepo(t)
+		w := initWallet(t, r, 100)
+		later := time.Now().Add(time.Hour)
+
+		req := &models.ReqHold{ReferenceID: uuid.New().String(), Amount: 70}
+		h, err := r.CreateHold(ctx, req, later, w.ID)
+		require.NoError(t, err)
+		assert.NotEmpty(t, h.ID)
+		assert.Equal(t, models.HoldStatusActive, h.Status)
+		again, err := r.CreateHold(ctx, req, later, w.ID)
+		require.NoError(t, err)
+		assert.Equal(t, h.ID, again.ID)
+		_, err = r.CreateHold(ctx, &models.ReqHold{ReferenceID: req.ReferenceID, Amount: 71}, later, w.ID)
+		assert.Equal(t, models.ErrDuplicateReference, err)
+
+		res, err := r.FetchWallet(ctx, w.ID)
+		require.NoError(t, err)
+		assert.Equal(t, int64(100), res.Balance)
+		assert.Equal(t, int64(30), res.AvailableBalance)
+
+		// held funds can not be spent nor held twice
+		_, err = r.WithdrawWallet(ctx, &models.ReqTransaction{ReferenceID: uuid.New().String(), Amount: 40}, 0, nil, w.ID)
+		assert.IsType(t, &models.FundsError{}, err)
+		_, err = r.CreateHold(ctx, &models.ReqHold{ReferenceID: uuid.New().String(), Amount: 40}, later, w.ID)
+		assert.IsType(t, &models.FundsError{}, err)
+
+		_, err = r.CaptureHold(ctx, &models.ReqCapture{Amount: 80}, h.ID, nil, w.ID)
+		assert.Equal(t, models.ErrBadParamInput, err)
+		c, err := r.CaptureHold(ctx, &models.ReqCapture{Amount: 50}, h.ID, nil, w.ID)
+		require.NoError(t, err)
+		assert.Equal(t, models.HoldStatusCaptured, c.Status)
+		assert.Equal(t, int64(50), c.Captured)
+		c, err = r.CaptureHold(ctx, &models.ReqCapture{}, h.ID, nil, w.ID)
+		require.NoError(t, err)
+		assert.Equal(t, int64(50), c.Captured)
+		_, err = r.VoidHold(ctx, h.ID, w.ID)
+		assert.Equal(t, models.ErrConflict, err)
+
+		// the uncaptured 20 are released
+		res, err = r.FetchWallet(ctx, w.ID)
+		require.NoError(t, err)
+		assert.Equal(t, int64(50), res.Balance)
+		assert.Equal(t, int64(50), res.AvailableBalance)
+
+		v, err := r.CreateHold(ctx, &models.ReqHold{ReferenceID: uuid.New().String(), Amount: 50}, later, w.ID)
+		require.NoError(t, err)
+		v, err = r.VoidHold(ctx, v.ID, w.ID)
+		require.NoError(t, err)
+		assert.Equal(t, models.HoldStatusVoided, v.Status)
+		_, err = r.CaptureHold(ctx, &models.ReqCapture{}, v.ID, nil, w.ID)
+		assert.Equal(t, models.ErrConflict, err)
+
+		e, err := r.CreateHold(ctx, &models.ReqHold{ReferenceID: uuid.New().String(), Amount: 50}, time.Now().Add(-time.Second), w.ID)
+		require.NoError(t, err)
+		assert.Equal(t, models.HoldStatusExpired, e.Status)
+		_, err = r.CaptureHold(ctx, &models.ReqCapture{}, e.ID, nil, w.ID)
+		assert.Equal(t, models.ErrConflict, err)
+		res, err = r.FetchWallet(ctx, w.ID)
+		require.NoError(t, err)
+		assert.Equal(t, int64(50), res.AvailableBalance)
+
+		_, err = r.FetchHold(ctx, h.ID, uuid.New().String())
+		assert.Equal(t, models.ErrNotFound, err)
+		list, err := r.FetchTransactions(ctx, &models.TransactionFilter{Type: models.TransactionTypeCapture, Limit: 10}, w.ID)
+		require.NoError(t, err)
+		require.Len(t, list.Transactions, 1)
+		assert.Equal(t, int64(50), list.Transactions[0].Amount)
+	})
+
+	t.Run("conversion", func(t *testing.T) {
+		r := newRepo(t)
+		customer := uuid.New().String()
+		usd, err := r.InitWallet(ctx, customer, "USD")
+		require.NoError(t, err)
+		idr, err := r.InitWallet(ctx, customer, "IDR")
+		require.NoError(t, err)
+		_, err = r.AddWallet(ctx, &models.ReqTransaction{ReferenceID: uuid.New().String(), Amount: 10000}, nil, usd.ID)
+		require.NoError(t, err)
+
+		// $10 at 15500 less a 0.5% spread
+		newQuote := func(amount int64, currency string, expiresAt time.Time) *models.Quote {
+			return &models.Quote{Amount: amount, Currency: "USD", ConvertedAmount: amount * 15500 * 995 / 1000, ToCurrency: currency,
+				Rate: "15500", SpreadBps: 50, Fee: amount * 15500 * 5 / 1000, Rounding: "down", ExpiresAt: expiresAt}
+		}
+		later := time.Now().Add(time.Minute)
+		q, err := r.CreateQuote(ctx, newQuote(1000, "IDR", later), usd.ID)
+		require.NoError(t, err)
+		assert.NotEmpty(t, q.ID)
+		assert.Equal(t, usd.ID, q.WalletID)
+		assert.Equal(t, customer, q.CreatedBy)
+		_, err = r.CreateQuote(ctx, newQuote(1000, "IDR", later), idr.ID)
+		assert.Equal(t, models.ErrCurrencyMismatch, err)
+		_, err = r.CreateQuote(ctx, newQuote(1000, "SGD", later), usd.ID)
+		assert.Equal(t, models.ErrDisabled, err)
+
+		req := &models.ReqConversion{ReferenceID: uuid.New().String(), QuoteID: q.ID}
+		c, err := r.ConvertWallet(ctx, req, nil, usd.ID)
+		require.NoError(t, err)
+		assert.Equal(t, usd.ID, c.From)
+		assert.Equal(t, idr.ID, c.To)
+		assert.Equal(t, int64(1000), c.Amount)
+		assert.Equal(t, int64(15422500), c.ConvertedAmount)
+		assert.Equal(t, int64(77500), c.Fee)
+		assert.Equal(t, "15500", c.Rate)
+		assert.Equal(t, models.TransactionStatusSuccess, c.Status)
+		again, err := r.ConvertWallet(ctx, req, nil, usd.ID)
+		require.NoError(t, err)
+		assert.Equal(t, c, again)
+
+		// a quote is used once, by its own wallet, before it expires
+		_, err = r.ConvertWallet(ctx, &models.ReqConversion{ReferenceID: uuid.New().String(), QuoteID: q.ID}, nil, usd.ID)
+		assert.Equal(t, models.ErrConflict, err)
+		_, err = r.ConvertWallet(ctx, &models.ReqConversion{ReferenceID: uuid.New().String(), QuoteID: q.ID}, nil, idr.ID)
+		assert.Equal(t, models.ErrNotFound, err)
+		expired, err := r.CreateQuote(ctx, newQuote(1000, "IDR", time.Now().Add(-time.Minute)), usd.ID)
+		require.NoError(t, err)
+		_, err = r.ConvertWallet(ctx, &models.ReqConversion{ReferenceID: uuid.New().String(), QuoteID: expired.ID}, nil, usd.ID)
+		assert.Equal(t, models.ErrQuoteExpired, err)
+		large, err := r.CreateQuote(ctx, newQuote(20000, "IDR", later), usd.ID)
+		require.NoError(t, err)
+		_, err = r.ConvertWallet(ctx, &models.ReqConversion{ReferenceID: uuid.New().String(), QuoteID: large.ID}, nil, usd.ID)
+		assert.IsType(t, &models.FundsError{}, err)
+
+		res, err := r.FetchWallet(ctx, usd.ID)
+		require.NoError(t, err)
+		assert.Equal(t, int64(9000), res.Balance)
+		res, err = r.FetchWallet(ctx, idr.ID)
+		require.NoError(t, err)
+		assert.Equal(t, int64(15422500), res.Balance)
+
+		list, err := r.FetchTransactions(ctx, &models.TransactionFilter{Type: models.TransactionTypeConvertIn, Limit: 10}, idr.ID)
+		require.NoError(t, err)
+		require.Len(t, list.Transactions, 1)
+		assert.Equal(t, q.ID, list.Transactions[0].QuoteID)
+		assert.Equal(t, "IDR", list.Transactions[0].Currency)
+
+		accounts, err := r.FetchAccountBalances(ctx)
+		require.NoError(t, err)
+		for _, a := range accounts {
+			switch a.Account {
+			case models.WalletAccount(usd.ID):
+				assert.Equal(t, &models.AccountBalance{Account: a.Account, Currency: "USD", Debit: 1000, Credit: 10000}, a)
+			case models.WalletAccount(idr.ID):
+				assert.Equal(t, &models.AccountBalance{Account: a.Account, Currency: "IDR", Credit: 15422500}, a)
+			}
+		}
+	})
+
+	t.Run("outbox", func(t *testing.T) {
+		r := newRepo(t)
+		o := outboxOf(r)
+		w := initWallet(t, r, 100)
+		other := initWallet(t, r, 0)
+
+		_, err := r.WithdrawWallet(ctx, &models.ReqTransaction{ReferenceID: uuid.New().String(), Amount: 500}, 0, nil, w.ID)
+		assert.IsType(t, &models.FundsError{}, err)
+		tr, err := r.TransferWallet(ctx, &models.ReqTransfer{ReferenceID: uuid.New().String(), WalletID: other.ID, Amount: 30}, 1, nil, w.ID)
+		require.NoError(t, err)
+		h, err := r.CreateHold(ctx, &models.ReqHold{ReferenceID: uuid.New().String(), Amount: 10}, time.Now().Add(time.Hour), w.ID)
+		require.NoError(t, err)
+		_, err = r.VoidHold(ctx, h.ID, w.ID)
+		require.NoError(t, err)
+		_, err = r.DisableWallet(ctx, true, other.ID)
+		require.NoError(t, err)
+
+		// pending reads every pending event of the given wallets, other tests may have left some behind
+		pending := func(ids ...string) []*models.OutboxEntry {
+			var list []*models.OutboxEntry
+			var after int64
+			for {
+				page, err := o.FetchPendingEvents(ctx, after, 100)
+				require.NoError(t, err)
+				for _, e := range page {
+					after = e.Seq
+					for _, id := range ids {
+						if e.Event.WalletID == id {
+							list = append(list, e)
+						}
+					}
+				}
+				if len(page) < 100 {
+					return list
+				}
+			}
+		}
+		types := func(list []*models.OutboxEntry) []string {
+			res := make([]string, 0, len(list))
+			for _, e := range list {
+				res = append(res, e.Event.Type)
+			}
+			return res
+		}
+
+		events := pending(w.ID)
+		assert.Equal(t, []string{
+			models.EventDepositSucceeded,
+			models.EventWithdrawalFailed,
+			models.EventTransferSucceeded,
+			models.EventHoldCreated,
+			models.EventHoldVoided,
+		}, types(events))
+		assert.Equal(t, []string{models.EventTransferReceived, models.EventWalletDisabled}, types(pending(other.ID)))
+
+		transfer := events[2]
+		assert.Equal(t, models.OutboxStatusPending, transfer.Status)
+		assert.False(t, transfer.Event.CreatedAt.IsZero())
+		var data models.Transaction
+		require.NoError(t, json.Unmarshal(transfer.Event.Data.(json.RawMessage), &data))
+		assert.Equal(t, tr.ReferenceID, data.ReferenceID)
+		assert.Equal(t, models.TransactionTypeTransferOut, data.Type)
+		assert.Equal(t, int64(30), data.Amount)
+
+		events[0].Status = models.OutboxStatusSent
+		events[0].Attempts = 1
+		require.NoError(t, o.UpdateEvent(ctx, events[0]))
+		retry := time.Now().Add(time.Minute).Truncate(time.Second)
+		events[1].Attempts = 1
+		events[1].NextAttemptAt = retry
+		events[1].LastError = "sink down"
+		require.NoError(t, o.UpdateEvent(ctx, events[1]))
+
+		left := pending(w.ID)
+		require.Len(t, left, 4)
+		assert.Equal(t, events[1].Seq, left[0].Seq)
+		assert.Equal(t, 1, left[0].Attempts)
+		assert.Equal(t, "sink down", left[0].LastError)
+		assert.True(t, retry.Equal(left[0].NextAttemptAt))
+	})
+
+	t.Run("ledger", func(t *testing.T) {
+		r := newRepo(t)
+		sender := initWallet(t, r, 100)
+		receiver := initWallet(t, r, 0)
+
+		_, err := r.WithdrawWallet(ctx, &models.ReqTransaction{ReferenceID: uuid.New().String(), Amount: 30}, 0, nil, sender.ID)
+		require.NoError(t, err)
+		_, err = r.WithdrawWallet(ctx, &models.ReqTransaction{ReferenceID: uuid.New().String(), Amount: 500}, 0, nil, sender.ID)
+		assert.IsType(t, &models.FundsError{}, err)
+		_, err = r.TransferWallet(ctx, &models.ReqTransfer{ReferenceID: uuid.New().String(), WalletID: receiver.ID, Amount: 20}, 0, nil, sender.ID)
+		require.NoError(t, err)
+
+		accounts, err := r.FetchAccountBalances(ctx)
+		require.NoError(t, err)
+		byAccount := make(map[string]*models.AccountBalance)
+		var debit, credit int64
+		for _, a := range accounts {
+			byAccount[a.Account] = a
+			debit += a.Debit
+			credit += a.Credit
+		}
+		assert.Equal(t, debit, credit)
+		assert.Equal(t, &models.AccountBalance{Account: models.WalletAccount(sender.ID), Currency: "IDR", Debit: 50, Credit: 100}, byAccount[models.WalletAccount(sender.ID)])
+		assert.Equal(t, &models.AccountBalance{Account: models.WalletAccount(receiver.ID), Currency: "IDR", Credit: 20}, byAccount[models.WalletAccount(receiver.ID)])
+		assert.NotNil(t, byAccount[models.AccountCashIn])
+		assert.NotNil(t, byAccount[models.AccountCashOut])
+
+		balances, err := r.FetchWalletBalances(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, int64(50), balances[sender.ID])
+		assert.Equal(t, int64(20), balances[receiver.ID])
+	})
+
+	t.Run("history pages", func(t *testing.T) {
+		r := newRepo(t)
+		w := initWallet(t, r, 0)
+		for i := 1; i <= 5; i++ {
+			_, err := r.AddWallet(ctx, &models.ReqTransaction{ReferenceID: uuid.New().String(), Amount: int64(i * 10)}, nil, w.ID)
+			require.NoError(t, err)
+		}
+
+		filter := &models.TransactionFilter{Limit: 2}
+		var amounts []int64
+		for {
+			page, err := r.FetchTransactions(ctx, filter, w.ID)
+			require.NoError(t, err)
+			for _, tx := range page.Transactions {
+				amounts = append(amounts, tx.Amount)
+			}
+			if page.NextCursor == "" {
+				break
+			}
+			// a row inserted while paging must not show up on the following pages
+			_, err = r.AddWallet(ctx, &models.ReqTransaction{ReferenceID: uuid.New().String(), Amount: 1}, nil, w.ID)
+			require.NoError(t, err)
+			filter.Cursor = page.NextCursor
+		}
+		assert.Equal(t, []int64{50, 40, 30, 20, 10}, amounts)
+
+		list, err := r.FetchTransactions(ctx, &models.TransactionFilter{MinAmount: 20, MaxAmount: 40, Limit: 10}, w.ID)
+		require.NoError(t, err)
+		assert.Len(t, list.Transactions, 3)
+	})
+
+	t.Run("owner lookup", func(t *testing.T) {
+		r := newRepo(t)
+		customer := uuid.New().String()
+		usd, err := r.InitWallet(ctx, customer, "USD")
+		require.NoError(t, err)
+		idr, err := r.InitWallet(ctx, customer, "IDR")
+		require.NoError(t, err)
+		_, err = r.DisableWallet(ctx, true, usd.ID)
+		require.NoError(t, err)
+
+		list, err := r.FetchWalletsByOwner(ctx, customer)
+		require.NoError(t, err)
+		require.Len(t, list, 2, "disabled wallets are listed too")
+		assert.Equal(t, idr.ID, list[0].ID)
+		assert.Equal(t, usd.ID, list[1].ID)
+		assert.Equal(t, "disabled", list[1].Status)
+
+		list, err = r.FetchWalletsByOwner(ctx, uuid.New().String())
+		require.NoError(t, err)
+		assert.Empty(t, list)
+	})
+
+	t.Run("audit log", func(t *testing.T) {
+		r := newRepo(t)
+		w := initWallet(t, r, 0)
+		for _, action := range []string{models.AuditLookupWallet, models.AuditFetchTransactions, models.AuditForceDisableWallet} {
+			entry := &models.AuditEntry{Actor: "admin-1", Action: action, WalletID: w.ID}
+			require.NoError(t, r.InsertAuditEntry(ctx, entry))
+			assert.NotZero(t, entry.ID)
+			assert.False(t, entry.CreatedAt.IsZero())
+		}
+		require.NoError(t, r.InsertAuditEntry(ctx, &models.AuditEntry{Actor: "admin-1", Action: models.AuditLookupWallets, Detail: "customer-1"}))
+
+		list, err := r.FetchAuditEntries(ctx, w.ID, 2)
+		require.NoError(t, err)
+		require.Len(t, list, 2)
+		assert.Equal(t, models.AuditForceDisableWallet, list[0].Action, "newest first")
+		assert.Equal(t, models.AuditFetchTransactions, list[1].Action)
+		assert.Equal(t, "admin-1", list[0].Actor)
+		assert.Equal(t, w.ID, list[0].WalletID)
+
+		list, err = r.FetchAuditEntries(ctx, uuid.New().String(), 10)
+		require.NoError(t, err)
+		assert.Empty(t, list)
+	})
+
+	t.Run("concurrent updates", func(t *testing.T) {
+		r := newRepo(t)
+		w := initWallet(t, r, 0)
+
+		const workers = 20
+		var wg sync.WaitGroup
+		for i := 0; i < workers; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_, err := r.AddWallet(ctx, &models.ReqTransaction{ReferenceID: uuid.New().String(), Amount: 10}, nil, w.ID)
+				assert.NoError(t, err)
+			}()
+		}
+		wg.Wait()
+
+		res, err := r.FetchWallet(ctx, w.ID)
+		require.NoError(t, err)
+		require.Equal(t, int64(workers*10), res.Balance, "lost deposit")
+
+		// twice as many withdrawals as the balance can cover
+		var mu sync.Mutex
+		succeeded := 0
+		for i := 0; i < workers*2; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_, err := r.WithdrawWallet(ctx, &models.ReqTransaction{ReferenceID: uuid.New().String(), Amount: 10}, 0, nil, w.ID)
+				if err == nil {
+					mu.Lock()
+					succeeded++
+					mu.Unlock()
+					return
+				}
+				assert.IsType(t, &models.FundsError{}, err)
+			}()
+		}
+		wg.Wait()
+
+		res, err = r.FetchWallet(ctx, w.ID)
+		require.NoError(t, err)
+		assert.Equal(t, workers, succeeded, fmt.Sprintf("balance left %d", res.Balance))
+		assert.Equal(t, int64(0), res.Balance)
+	})
+}
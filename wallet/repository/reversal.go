@@ -0,0 +1,48 @@
+package repository
+
+import (
+	"github.com/williamchand/my-wallet/models"
+)
+
+// reversal work out how much of orig the request reverses, given what earlier reversals already took back,
+// and return the status orig is left in along with the journal moving the money back
+func reversal(orig *models.Transaction, reversed int64, req *models.ReqReversal) (int64, string, models.Journal, error) {
+	var journal func(amount models.Money) models.Journal
+	switch orig.Type {
+	case models.TransactionTypeDeposit:
+		journal = func(amount models.Money) models.Journal {
+			return models.NewJournal(models.WalletAccount(orig.ID), models.AccountCashIn, amount)
+		}
+	case models.TransactionTypeWithdrawal, models.TransactionTypeCapture:
+		journal = func(amount models.Money) models.Journal {
+			return models.NewJournal(models.AccountCashOut, models.WalletAccount(orig.ID), amount)
+		}
+	default:
+		// transfers involve a second wallet and reversals are not reversed again
+		return 0, "", nil, models.ErrBadParamInput
+	}
+	if orig.Status == models.TransactionStatusFailed {
+		return 0, "", nil, models.ErrBadParamInput
+	}
+
+	remaining := orig.Amount - reversed
+	amount := req.Amount
+	if amount == 0 {
+		amount = remaining
+	}
+	if amount <= 0 || amount > remaining {
+		return 0, "", nil, models.ErrBadParamInput
+	}
+
+	status := models.TransactionStatusPartiallyReversed
+	if amount == remaining {
+		status = models.TransactionStatusReversed
+	}
+
+	return amount, status, journal(models.Money{Amount: amount, Currency: orig.Currency}), nil
+}
+
+// debitsWallet tell whether reversing a transaction of txType takes money out of the wallet
+func debitsWallet(txType string) bool {
+	return txType == models.TransactionTypeDeposit
+}
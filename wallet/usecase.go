@@ -8,10 +8,29 @@ import (
 
 // Usecase represent the wallet's usecases
 type Usecase interface {
-	EnableWallet(ctx context.Context, authorization string) (*models.FetchWallet, error)
-	FetchWallet(ctx context.Context, authorization string) (*models.FetchWallet, error)
-	AddWallet(ctx context.Context, req *models.ReqTransaction, authorization string) (*models.TransactionDeposit, error)
-	WithdrawWallet(ctx context.Context, req *models.ReqTransaction, authorization string) (*models.TransactionWithdraw, error)
-	DisableWallet(ctx context.Context, isDisabled bool, authorization string) (*models.WalletDisabled, error)
-	InitWallet(ctx context.Context, customer_id string) (*models.FetchWallet, error)
+	EnableWallet(ctx context.Context, principal *models.Principal) (*models.FetchWallet, error)
+	FetchWallet(ctx context.Context, principal *models.Principal) (*models.FetchWallet, error)
+	AddWallet(ctx context.Context, req *models.ReqTransaction, principal *models.Principal) (*models.TransactionDeposit, error)
+	WithdrawWallet(ctx context.Context, req *models.ReqTransaction, principal *models.Principal) (*models.TransactionWithdraw, error)
+	TransferWallet(ctx context.Context, req *models.ReqTransfer, principal *models.Principal) (*models.Transfer, error)
+	FetchTransactions(ctx context.Context, filter *models.TransactionFilter, principal *models.Principal) (*models.TransactionList, error)
+	CreateHold(ctx context.Context, req *models.ReqHold, principal *models.Principal) (*models.Hold, error)
+	FetchHold(ctx context.Context, holdID string, principal *models.Principal) (*models.Hold, error)
+	CaptureHold(ctx context.Context, req *models.ReqCapture, holdID string, principal *models.Principal) (*models.Hold, error)
+	VoidHold(ctx context.Context, holdID string, principal *models.Principal) (*models.Hold, error)
+	CreateQuote(ctx context.Context, req *models.ReqQuote, principal *models.Principal) (*models.Quote, error)
+	ConvertWallet(ctx context.Context, req *models.ReqConversion, principal *models.Principal) (*models.Conversion, error)
+	QuoteFee(ctx context.Context, req *models.ReqFeeQuote, principal *models.Principal) (*models.FeeQuote, error)
+	DisableWallet(ctx context.Context, isDisabled bool, principal *models.Principal) (*models.WalletDisabled, error)
+	InitWallet(ctx context.Context, customer_id string, currency string, principal *models.Principal) (*models.Account, error)
+	RefreshToken(ctx context.Context, principal *models.Principal) (*models.Token, error)
+	TrialBalance(ctx context.Context) (*models.TrialBalance, error)
+	LookupWallet(ctx context.Context, walletID string, principal *models.Principal) (*models.Wallet, error)
+	LookupWallets(ctx context.Context, ownedBy string, principal *models.Principal) ([]*models.Wallet, error)
+	FetchWalletTransactions(ctx context.Context, filter *models.TransactionFilter, walletID string, principal *models.Principal) (*models.TransactionList, error)
+	FetchAuditEntries(ctx context.Context, walletID string, principal *models.Principal) ([]*models.AuditEntry, error)
+	ForceDisableWallet(ctx context.Context, req *models.ReqForceDisable, walletID string, principal *models.Principal) (*models.Wallet, error)
+	ForceEnableWallet(ctx context.Context, req *models.ReqForceEnable, walletID string, principal *models.Principal) (*models.Wallet, error)
+	AdjustWallet(ctx context.Context, req *models.ReqAdjustment, txType string, walletID string, principal *models.Principal) (*models.Adjustment, error)
+	ReverseTransaction(ctx context.Context, req *models.ReqReversal, walletID string, referenceID string, principal *models.Principal) (*models.Reversal, error)
 }
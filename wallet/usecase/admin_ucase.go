@@ -0,0 +1,164 @@
+package usecase
+
+import (
+	"context"
+
+	"github.com/williamchand/my-wallet/models"
+)
+
+// auditLimit is how many audit entries of a wallet are returned, newest first
+const auditLimit = 100
+
+// The methods below serve the support staff, the routes check their scopes and every call is recorded in
+// the audit log under the principal who made it
+
+func (a *walletUsecase) LookupWallet(c context.Context, walletID string, principal *models.Principal) (*models.Wallet, error) {
+
+	ctx, cancel := context.WithTimeout(c, a.contextTimeout)
+	defer cancel()
+	if principal == nil {
+		return nil, models.ErrUnauthorized
+	}
+	res, err := a.walletRepo.LookupWallet(ctx, walletID)
+	if err != nil {
+		return nil, err
+	}
+	if err = a.audit(ctx, principal, models.AuditLookupWallet, walletID, ""); err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+func (a *walletUsecase) LookupWallets(c context.Context, ownedBy string, principal *models.Principal) ([]*models.Wallet, error) {
+
+	ctx, cancel := context.WithTimeout(c, a.contextTimeout)
+	defer cancel()
+	if principal == nil {
+		return nil, models.ErrUnauthorized
+	}
+	res, err := a.walletRepo.FetchWalletsByOwner(ctx, ownedBy)
+	if err != nil {
+		return nil, err
+	}
+	if err = a.audit(ctx, principal, models.AuditLookupWallets, "", "owned_by="+ownedBy); err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+func (a *walletUsecase) FetchWalletTransactions(c context.Context, filter *models.TransactionFilter, walletID string, principal *models.Principal) (*models.TransactionList, error) {
+
+	ctx, cancel := context.WithTimeout(c, a.contextTimeout)
+	defer cancel()
+	if principal == nil {
+		return nil, models.ErrUnauthorized
+	}
+	if _, err := a.walletRepo.LookupWallet(ctx, walletID); err != nil {
+		return nil, err
+	}
+	if filter.Limit <= 0 {
+		filter.Limit = defaultHistoryLimit
+	}
+	if filter.Limit > maxHistoryLimit {
+		filter.Limit = maxHistoryLimit
+	}
+	res, err := a.walletRepo.FetchTransactions(ctx, filter, walletID)
+	if err != nil {
+		return nil, err
+	}
+	if err = a.audit(ctx, principal, models.AuditFetchTransactions, walletID, ""); err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+func (a *walletUsecase) FetchAuditEntries(c context.Context, walletID string, principal *models.Principal) ([]*models.AuditEntry, error) {
+
+	ctx, cancel := context.WithTimeout(c, a.contextTimeout)
+	defer cancel()
+	if principal == nil {
+		return nil, models.ErrUnauthorized
+	}
+	// the read is recorded first, so it shows in what it returns
+	if err := a.audit(ctx, principal, models.AuditFetchAuditEntries, walletID, ""); err != nil {
+		return nil, err
+	}
+
+	return a.walletRepo.FetchAuditEntries(ctx, walletID, auditLimit)
+}
+
+func (a *walletUsecase) ForceDisableWallet(c context.Context, req *models.ReqForceDisable, walletID string, principal *models.Principal) (*models.Wallet, error) {
+
+	ctx, cancel := context.WithTimeout(c, a.contextTimeout)
+	defer cancel()
+	if principal == nil {
+		return nil, models.ErrUnauthorized
+	}
+	// disabling a disabled wallet changes nothing, the call is still recorded
+	return a.walletRepo.ForceDisableWallet(ctx, walletID, newAuditEntry(principal, models.AuditForceDisableWallet, walletID, req.Reason))
+}
+
+func (a *walletUsecase) ForceEnableWallet(c context.Context, req *models.ReqForceEnable, walletID string, principal *models.Principal) (*models.Wallet, error) {
+
+	ctx, cancel := context.WithTimeout(c, a.contextTimeout)
+	defer cancel()
+	if principal == nil {
+		return nil, models.ErrUnauthorized
+	}
+	// enabling an enabled wallet changes nothing, the call is still recorded
+	return a.walletRepo.ForceEnableWallet(ctx, walletID, newAuditEntry(principal, models.AuditForceEnableWallet, walletID, req.Reason))
+}
+
+// AdjustWallet post a manual credit or debit on the wallet walletID, the principal is recorded as its creator
+func (a *walletUsecase) AdjustWallet(c context.Context, req *models.ReqAdjustment, txType string, walletID string, principal *models.Principal) (*models.Adjustment, error) {
+
+	ctx, cancel := context.WithTimeout(c, a.contextTimeout)
+	defer cancel()
+	if principal == nil {
+		return nil, models.ErrUnauthorized
+	}
+	req.Actor = principal.CustomerID
+	entry := newAuditEntry(principal, models.AuditAdjustWallet, walletID, txType+" reference_id="+req.ReferenceID)
+	res, err := a.walletRepo.AdjustWallet(ctx, req, txType, walletID, entry)
+	if err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// ReverseTransaction give back all or part of a transaction of the wallet walletID, owners can not reverse their own
+// debits as the money would come back to them from system:cash_out
+func (a *walletUsecase) ReverseTransaction(c context.Context, req *models.ReqReversal, walletID string, referenceID string, principal *models.Principal) (*models.Reversal, error) {
+
+	ctx, cancel := context.WithTimeout(c, a.contextTimeout)
+	defer cancel()
+	if principal == nil {
+		return nil, models.ErrUnauthorized
+	}
+	entry := newAuditEntry(principal, models.AuditReverseTransaction, walletID, "reference_id="+referenceID)
+	res, err := a.walletRepo.ReverseTransaction(ctx, req, referenceID, walletID, entry)
+	if err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// audit record that principal took action on the wallet walletID, an admin call fails when its entry can not be recorded
+func (a *walletUsecase) audit(ctx context.Context, principal *models.Principal, action string, walletID string, detail string) error {
+	return a.walletRepo.InsertAuditEntry(ctx, newAuditEntry(principal, action, walletID, detail))
+}
+
+// newAuditEntry create the entry of an action changing a wallet, the repository records it in the same transaction
+func newAuditEntry(principal *models.Principal, action string, walletID string, detail string) *models.AuditEntry {
+	return &models.AuditEntry{
+		Actor:    principal.CustomerID,
+		Action:   action,
+		WalletID: walletID,
+		Detail:   detail,
+	}
+}
@@ -0,0 +1,187 @@
+package usecase_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/williamchand/my-wallet/models"
+	"github.com/williamchand/my-wallet/wallet"
+	"github.com/williamchand/my-wallet/wallet/repository"
+	"github.com/williamchand/my-wallet/wallet/usecase"
+)
+
+var admin = &models.Principal{CustomerID: "admin-1", Scopes: []string{models.ScopeAdminRead, models.ScopeAdminAdjust}}
+
+// failingAudit refuse every audit entry recorded on its own
+type failingAudit struct {
+	wallet.Repository
+}
+
+func (failingAudit) InsertAuditEntry(ctx context.Context, entry *models.AuditEntry) error {
+	return errors.New("audit_log is unavailable")
+}
+
+func newAdminUsecase(r wallet.Repository) wallet.Usecase {
+	return usecase.NewWalletUsecase(r, nil, nil, nil, nil, time.Second)
+}
+
+func initWallet(t *testing.T, r wallet.Repository, balance int64) *models.FetchWallet {
+	w, err := r.InitWallet(context.Background(), uuid.New().String(), models.DefaultCurrency)
+	require.NoError(t, err)
+	if balance > 0 {
+		_, err = r.AddWallet(context.Background(), &models.ReqTransaction{ReferenceID: uuid.New().String(), Amount: balance}, nil, w.ID)
+		require.NoError(t, err)
+	}
+	return w
+}
+
+func TestAdminLookups(t *testing.T) {
+	ctx := context.Background()
+	r := repository.NewMemoryWalletRepository()
+	u := newAdminUsecase(r)
+	w := initWallet(t, r, 100)
+
+	res, err := u.LookupWallet(ctx, w.ID, admin)
+	require.NoError(t, err)
+	assert.Equal(t, int64(100), res.Balance)
+	list, err := u.LookupWallets(ctx, w.OwnedBy, admin)
+	require.NoError(t, err)
+	assert.Len(t, list, 1)
+	history, err := u.FetchWalletTransactions(ctx, &models.TransactionFilter{}, w.ID, admin)
+	require.NoError(t, err)
+	assert.Len(t, history.Transactions, 1)
+
+	_, err = u.LookupWallet(ctx, uuid.New().String(), admin)
+	assert.Equal(t, models.ErrNotFound, err)
+	_, err = u.LookupWallet(ctx, w.ID, nil)
+	assert.Equal(t, models.ErrUnauthorized, err)
+
+	entries, err := u.FetchAuditEntries(ctx, w.ID, admin)
+	require.NoError(t, err)
+	require.Len(t, entries, 3, "the lookup by owner is not on the wallet")
+	assert.Equal(t, models.AuditFetchAuditEntries, entries[0].Action, "the read of the audit log is recorded first")
+	assert.Equal(t, models.AuditFetchTransactions, entries[1].Action)
+	assert.Equal(t, models.AuditLookupWallet, entries[2].Action)
+	for _, e := range entries {
+		assert.Equal(t, admin.CustomerID, e.Actor)
+	}
+
+	_, err = newAdminUsecase(failingAudit{r}).LookupWallet(ctx, w.ID, admin)
+	assert.Error(t, err, "a lookup that can not be recorded fails")
+}
+
+func TestForceDisableWallet(t *testing.T) {
+	ctx := context.Background()
+	r := repository.NewMemoryWalletRepository()
+	u := newAdminUsecase(r)
+	w := initWallet(t, r, 100)
+
+	_, err := u.ForceDisableWallet(ctx, &models.ReqForceDisable{Reason: "fraud"}, w.ID, nil)
+	assert.Equal(t, models.ErrUnauthorized, err)
+	_, err = u.ForceDisableWallet(ctx, &models.ReqForceDisable{Reason: "fraud"}, uuid.New().String(), admin)
+	assert.Equal(t, models.ErrNotFound, err)
+
+	res, err := u.ForceDisableWallet(ctx, &models.ReqForceDisable{Reason: "fraud"}, w.ID, admin)
+	require.NoError(t, err)
+	assert.Equal(t, "disabled", res.Status)
+	_, err = r.FetchWallet(ctx, w.ID)
+	assert.Equal(t, models.ErrDisabled, err)
+
+	entries, err := r.FetchAuditEntries(ctx, w.ID, 10)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, &models.AuditEntry{
+		ID:        entries[0].ID,
+		Actor:     admin.CustomerID,
+		Action:    models.AuditForceDisableWallet,
+		WalletID:  w.ID,
+		Detail:    "fraud",
+		CreatedAt: entries[0].CreatedAt,
+	}, entries[0])
+}
+
+func TestForceEnableWallet(t *testing.T) {
+	ctx := context.Background()
+	r := repository.NewMemoryWalletRepository()
+	u := newAdminUsecase(r)
+	w := initWallet(t, r, 100)
+	_, err := r.DisableWallet(ctx, true, w.ID)
+	require.NoError(t, err)
+
+	_, err = u.ForceEnableWallet(ctx, &models.ReqForceEnable{Reason: "verified"}, w.ID, nil)
+	assert.Equal(t, models.ErrUnauthorized, err)
+	res, err := u.ForceEnableWallet(ctx, &models.ReqForceEnable{Reason: "verified"}, w.ID, admin)
+	require.NoError(t, err)
+	assert.Equal(t, "enabled", res.Status)
+
+	entries, err := r.FetchAuditEntries(ctx, w.ID, 10)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, models.AuditForceEnableWallet, entries[0].Action)
+	assert.Equal(t, "verified", entries[0].Detail)
+}
+
+func TestAdjustWallet(t *testing.T) {
+	ctx := context.Background()
+	r := repository.NewMemoryWalletRepository()
+	u := newAdminUsecase(r)
+	w := initWallet(t, r, 100)
+	req := &models.ReqAdjustment{ReferenceID: uuid.New().String(), Amount: 30, Reason: "duplicate deposit", Actor: "someone-else"}
+
+	_, err := u.AdjustWallet(ctx, req, models.TransactionTypeDebit, w.ID, nil)
+	assert.Equal(t, models.ErrUnauthorized, err)
+	res, err := u.AdjustWallet(ctx, req, models.TransactionTypeDebit, w.ID, admin)
+	require.NoError(t, err)
+	assert.Equal(t, admin.CustomerID, res.AdjustedBy, "the adjustment is made by the principal")
+	_, err = newAdminUsecase(failingAudit{r}).AdjustWallet(ctx, &models.ReqAdjustment{ReferenceID: uuid.New().String(), Amount: 30, Reason: "again"}, models.TransactionTypeDebit, w.ID, admin)
+	require.NoError(t, err, "the entry is recorded by the repository along with the adjustment")
+
+	entries, err := r.FetchAuditEntries(ctx, w.ID, 10)
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	assert.Equal(t, &models.AuditEntry{
+		ID:        entries[1].ID,
+		Actor:     admin.CustomerID,
+		Action:    models.AuditAdjustWallet,
+		WalletID:  w.ID,
+		Detail:    models.TransactionTypeDebit + " reference_id=" + req.ReferenceID,
+		CreatedAt: entries[1].CreatedAt,
+	}, entries[1])
+}
+
+func TestReverseTransaction(t *testing.T) {
+	ctx := context.Background()
+	r := repository.NewMemoryWalletRepository()
+	u := newAdminUsecase(r)
+	w := initWallet(t, r, 100)
+	withdrawal := uuid.New().String()
+	_, err := r.WithdrawWallet(ctx, &models.ReqTransaction{ReferenceID: withdrawal, Amount: 60}, 0, nil, w.ID)
+	require.NoError(t, err)
+
+	req := &models.ReqReversal{ReferenceID: uuid.New().String(), Amount: 60}
+	_, err = u.ReverseTransaction(ctx, req, w.ID, withdrawal, nil)
+	assert.Equal(t, models.ErrUnauthorized, err)
+
+	res, err := u.ReverseTransaction(ctx, req, w.ID, withdrawal, admin)
+	require.NoError(t, err)
+	assert.Equal(t, int64(60), res.Amount)
+	balance, err := r.FetchWallet(ctx, w.ID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(100), balance.Balance)
+
+	_, err = u.ReverseTransaction(ctx, &models.ReqReversal{ReferenceID: uuid.New().String()}, w.ID, withdrawal, admin)
+	assert.Equal(t, models.ErrBadParamInput, err, "nothing is left to reverse")
+
+	entries, err := r.FetchAuditEntries(ctx, w.ID, 10)
+	require.NoError(t, err)
+	require.Len(t, entries, 1, "the refused reversal is not recorded")
+	assert.Equal(t, models.AuditReverseTransaction, entries[0].Action)
+	assert.Equal(t, "reference_id="+withdrawal, entries[0].Detail)
+	assert.Equal(t, admin.CustomerID, entries[0].Actor)
+}
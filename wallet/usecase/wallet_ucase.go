@@ -2,22 +2,42 @@ package usecase
 
 import (
 	"context"
-	"strings"
+	"sort"
 	"time"
 
+	"github.com/williamchand/my-wallet/auth"
+	"github.com/williamchand/my-wallet/exchange"
+	"github.com/williamchand/my-wallet/fees"
+	"github.com/williamchand/my-wallet/limits"
 	"github.com/williamchand/my-wallet/models"
 	"github.com/williamchand/my-wallet/wallet"
 )
 
+const (
+	defaultHistoryLimit = 20
+	maxHistoryLimit     = 100
+
+	defaultHoldTTL = 7 * 24 * time.Hour
+	maxHoldTTL     = 30 * 24 * time.Hour
+)
+
 type walletUsecase struct {
 	walletRepo     wallet.Repository
+	tokens         auth.Issuer
+	quoter         exchange.Quoter
+	limits         limits.Checker
+	fees           fees.Schedule
 	contextTimeout time.Duration
 }
 
 // NewWalletUsecase will create new an walletUsecase object representation of wallet.Usecase interface
-func NewWalletUsecase(a wallet.Repository, timeout time.Duration) wallet.Usecase {
+func NewWalletUsecase(a wallet.Repository, t auth.Issuer, q exchange.Quoter, l limits.Checker, f fees.Schedule, timeout time.Duration) wallet.Usecase {
 	return &walletUsecase{
 		walletRepo:     a,
+		tokens:         t,
+		quoter:         q,
+		limits:         l,
+		fees:           f,
 		contextTimeout: timeout,
 	}
 }
@@ -28,12 +48,151 @@ func NewWalletUsecase(a wallet.Repository, timeout time.Duration) wallet.Usecase
 * in godoc: https://godoc.org/golang.org/x/sync/errgroup#ex-Group--Pipeline
  */
 
-func (a *walletUsecase) EnableWallet(c context.Context, authorization string) (*models.FetchWallet, error) {
+func (a *walletUsecase) EnableWallet(c context.Context, principal *models.Principal) (*models.FetchWallet, error) {
+
+	ctx, cancel := context.WithTimeout(c, a.contextTimeout)
+	defer cancel()
+	if principal == nil {
+		return nil, models.ErrUnauthorized
+	}
+	res, err := a.walletRepo.EnableWallet(ctx, principal.WalletID)
+	if err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+func (a *walletUsecase) FetchWallet(c context.Context, principal *models.Principal) (*models.FetchWallet, error) {
+
+	ctx, cancel := context.WithTimeout(c, a.contextTimeout)
+	defer cancel()
+	if principal == nil {
+		return nil, models.ErrUnauthorized
+	}
+	res, err := a.walletRepo.FetchWallet(ctx, principal.WalletID)
+	if err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+func (a *walletUsecase) AddWallet(c context.Context, req *models.ReqTransaction, principal *models.Principal) (*models.TransactionDeposit, error) {
+
+	ctx, cancel := context.WithTimeout(c, a.contextTimeout)
+	defer cancel()
+	if principal == nil {
+		return nil, models.ErrUnauthorized
+	}
+	res, err := a.walletRepo.AddWallet(ctx, req, a.limits, principal.WalletID)
+	if err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+func (a *walletUsecase) WithdrawWallet(c context.Context, req *models.ReqTransaction, principal *models.Principal) (*models.TransactionWithdraw, error) {
+
+	ctx, cancel := context.WithTimeout(c, a.contextTimeout)
+	defer cancel()
+	if principal == nil {
+		return nil, models.ErrUnauthorized
+	}
+	w, err := a.walletRepo.FetchWallet(ctx, principal.WalletID)
+	if err != nil {
+		return nil, err
+	}
+	fee, err := a.fees.Fee(fees.OperationWithdrawal, models.Money{Amount: req.Amount, Currency: w.Currency})
+	if err != nil {
+		return nil, err
+	}
+	res, err := a.walletRepo.WithdrawWallet(ctx, req, fee, a.limits, principal.WalletID)
+	if err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+func (a *walletUsecase) TransferWallet(c context.Context, req *models.ReqTransfer, principal *models.Principal) (*models.Transfer, error) {
+
+	ctx, cancel := context.WithTimeout(c, a.contextTimeout)
+	defer cancel()
+	if principal == nil {
+		return nil, models.ErrUnauthorized
+	}
+	if req.WalletID == principal.WalletID {
+		return nil, models.ErrBadParamInput
+	}
+	w, err := a.walletRepo.FetchWallet(ctx, principal.WalletID)
+	if err != nil {
+		return nil, err
+	}
+	fee, err := a.fees.Fee(fees.OperationTransfer, models.Money{Amount: req.Amount, Currency: w.Currency})
+	if err != nil {
+		return nil, err
+	}
+	res, err := a.walletRepo.TransferWallet(ctx, req, fee, a.limits, principal.WalletID)
+	if err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+func (a *walletUsecase) FetchTransactions(c context.Context, filter *models.TransactionFilter, principal *models.Principal) (*models.TransactionList, error) {
+
+	ctx, cancel := context.WithTimeout(c, a.contextTimeout)
+	defer cancel()
+	if principal == nil {
+		return nil, models.ErrUnauthorized
+	}
+	if filter.Limit <= 0 {
+		filter.Limit = defaultHistoryLimit
+	}
+	if filter.Limit > maxHistoryLimit {
+		filter.Limit = maxHistoryLimit
+	}
+	res, err := a.walletRepo.FetchTransactions(ctx, filter, principal.WalletID)
+	if err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+func (a *walletUsecase) CreateHold(c context.Context, req *models.ReqHold, principal *models.Principal) (*models.Hold, error) {
+
+	ctx, cancel := context.WithTimeout(c, a.contextTimeout)
+	defer cancel()
+	if principal == nil {
+		return nil, models.ErrUnauthorized
+	}
+	ttl := defaultHoldTTL
+	if req.ExpiresIn > 0 {
+		ttl = time.Duration(req.ExpiresIn) * time.Second
+	}
+	if ttl > maxHoldTTL {
+		return nil, models.ErrBadParamInput
+	}
+	res, err := a.walletRepo.CreateHold(ctx, req, time.Now().Add(ttl), principal.WalletID)
+	if err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+func (a *walletUsecase) FetchHold(c context.Context, holdID string, principal *models.Principal) (*models.Hold, error) {
 
 	ctx, cancel := context.WithTimeout(c, a.contextTimeout)
 	defer cancel()
-	data := JWT(authorization)
-	res, err := a.walletRepo.EnableWallet(ctx, data.ID)
+	if principal == nil {
+		return nil, models.ErrUnauthorized
+	}
+	res, err := a.walletRepo.FetchHold(ctx, holdID, principal.WalletID)
 	if err != nil {
 		return nil, err
 	}
@@ -41,12 +200,14 @@ func (a *walletUsecase) EnableWallet(c context.Context, authorization string) (*
 	return res, nil
 }
 
-func (a *walletUsecase) FetchWallet(c context.Context, authorization string) (*models.FetchWallet, error) {
+func (a *walletUsecase) CaptureHold(c context.Context, req *models.ReqCapture, holdID string, principal *models.Principal) (*models.Hold, error) {
 
 	ctx, cancel := context.WithTimeout(c, a.contextTimeout)
 	defer cancel()
-	data := JWT(authorization)
-	res, err := a.walletRepo.FetchWallet(ctx, data.ID)
+	if principal == nil {
+		return nil, models.ErrUnauthorized
+	}
+	res, err := a.walletRepo.CaptureHold(ctx, req, holdID, a.limits, principal.WalletID)
 	if err != nil {
 		return nil, err
 	}
@@ -54,12 +215,14 @@ func (a *walletUsecase) FetchWallet(c context.Context, authorization string) (*m
 	return res, nil
 }
 
-func (a *walletUsecase) AddWallet(c context.Context, req *models.ReqTransaction, authorization string) (*models.TransactionDeposit, error) {
+func (a *walletUsecase) VoidHold(c context.Context, holdID string, principal *models.Principal) (*models.Hold, error) {
 
 	ctx, cancel := context.WithTimeout(c, a.contextTimeout)
 	defer cancel()
-	data := JWT(authorization)
-	res, err := a.walletRepo.AddWallet(ctx, req, data.ID)
+	if principal == nil {
+		return nil, models.ErrUnauthorized
+	}
+	res, err := a.walletRepo.VoidHold(ctx, holdID, principal.WalletID)
 	if err != nil {
 		return nil, err
 	}
@@ -67,12 +230,26 @@ func (a *walletUsecase) AddWallet(c context.Context, req *models.ReqTransaction,
 	return res, nil
 }
 
-func (a *walletUsecase) WithdrawWallet(c context.Context, req *models.ReqTransaction, authorization string) (*models.TransactionWithdraw, error) {
+func (a *walletUsecase) CreateQuote(c context.Context, req *models.ReqQuote, principal *models.Principal) (*models.Quote, error) {
 
 	ctx, cancel := context.WithTimeout(c, a.contextTimeout)
 	defer cancel()
-	data := JWT(authorization)
-	res, err := a.walletRepo.WithdrawWallet(ctx, req, data.ID)
+	if principal == nil {
+		return nil, models.ErrUnauthorized
+	}
+	w, err := a.walletRepo.FetchWallet(ctx, principal.WalletID)
+	if err != nil {
+		return nil, err
+	}
+	currency, err := models.MatchCurrency(req.Currency, w.Currency)
+	if err != nil {
+		return nil, err
+	}
+	quote, err := a.quoter.Quote(ctx, models.Money{Amount: req.Amount, Currency: currency}, req.ToCurrency)
+	if err != nil {
+		return nil, err
+	}
+	res, err := a.walletRepo.CreateQuote(ctx, quote, principal.WalletID)
 	if err != nil {
 		return nil, err
 	}
@@ -80,12 +257,58 @@ func (a *walletUsecase) WithdrawWallet(c context.Context, req *models.ReqTransac
 	return res, nil
 }
 
-func (a *walletUsecase) DisableWallet(c context.Context, isDisabled bool, authorization string) (*models.WalletDisabled, error) {
+func (a *walletUsecase) ConvertWallet(c context.Context, req *models.ReqConversion, principal *models.Principal) (*models.Conversion, error) {
+
+	ctx, cancel := context.WithTimeout(c, a.contextTimeout)
+	defer cancel()
+	if principal == nil {
+		return nil, models.ErrUnauthorized
+	}
+	res, err := a.walletRepo.ConvertWallet(ctx, req, a.limits, principal.WalletID)
+	if err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+func (a *walletUsecase) QuoteFee(c context.Context, req *models.ReqFeeQuote, principal *models.Principal) (*models.FeeQuote, error) {
+
+	ctx, cancel := context.WithTimeout(c, a.contextTimeout)
+	defer cancel()
+	if principal == nil {
+		return nil, models.ErrUnauthorized
+	}
+	w, err := a.walletRepo.FetchWallet(ctx, principal.WalletID)
+	if err != nil {
+		return nil, err
+	}
+	currency, err := models.MatchCurrency(req.Currency, w.Currency)
+	if err != nil {
+		return nil, err
+	}
+	fee, err := a.fees.Fee(req.Operation, models.Money{Amount: req.Amount, Currency: currency})
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.FeeQuote{
+		Operation:   req.Operation,
+		GrossAmount: req.Amount + fee,
+		Fee:         fee,
+		NetAmount:   req.Amount,
+		Currency:    currency,
+	}, nil
+}
+
+func (a *walletUsecase) DisableWallet(c context.Context, isDisabled bool, principal *models.Principal) (*models.WalletDisabled, error) {
 
 	ctx, cancel := context.WithTimeout(c, a.contextTimeout)
 	defer cancel()
-	data := JWT(authorization)
-	res, err := a.walletRepo.DisableWallet(ctx, isDisabled, data.ID)
+	if principal == nil {
+		return nil, models.ErrUnauthorized
+	}
+	res, err := a.walletRepo.DisableWallet(ctx, isDisabled, principal.WalletID)
 	if err != nil {
 		return nil, err
 	}
@@ -93,11 +316,56 @@ func (a *walletUsecase) DisableWallet(c context.Context, isDisabled bool, author
 	return res, nil
 }
 
-func (a *walletUsecase) InitWallet(c context.Context, costumer_id string) (*models.FetchWallet, error) {
+// InitWallet create the wallet of a new customer, or another wallet of a customer calling with a token of its own.
+// Anyone else would get the token of a wallet the customer converts into.
+func (a *walletUsecase) InitWallet(c context.Context, costumer_id string, currency string, principal *models.Principal) (*models.Account, error) {
 
 	ctx, cancel := context.WithTimeout(c, a.contextTimeout)
 	defer cancel()
-	res, err := a.walletRepo.InitWallet(ctx, costumer_id)
+	if currency == "" {
+		currency = models.DefaultCurrency
+	}
+	if !models.ValidCurrency(currency) {
+		return nil, models.ErrBadParamInput
+	}
+	wallets, err := a.walletRepo.FetchWalletsByOwner(ctx, costumer_id)
+	if err != nil {
+		return nil, err
+	}
+	if len(wallets) > 0 {
+		if principal == nil {
+			return nil, models.ErrUnauthorized
+		}
+		if principal.CustomerID != costumer_id || !principal.HasScope(models.ScopeWalletWrite) {
+			return nil, models.ErrForbidden
+		}
+	}
+	res, err := a.walletRepo.InitWallet(ctx, costumer_id, currency)
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := a.tokens.Issue(&models.User{
+		ID:       res.OwnedBy,
+		WalletID: res.ID,
+		Scopes:   models.OwnerScopes,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.Account{
+		Wallet: res,
+		Token:  token,
+	}, nil
+}
+
+func (a *walletUsecase) RefreshToken(c context.Context, principal *models.Principal) (*models.Token, error) {
+
+	if principal == nil {
+		return nil, models.ErrUnauthorized
+	}
+	res, err := a.tokens.Issue(principal.User())
 	if err != nil {
 		return nil, err
 	}
@@ -105,13 +373,71 @@ func (a *walletUsecase) InitWallet(c context.Context, costumer_id string) (*mode
 	return res, nil
 }
 
-//this part for translate authorization to id and owner_id I need your API Method to translate this
-func JWT(token string) *models.User {
-	ss := strings.Fields(token)
-	newToken := ss[1]
-	wallet := models.User{
-		ID:   newToken,
-		Name: "william-chandra",
+// TrialBalance check that the ledger debits equal its credits in every currency
+// and that every wallet balance equals the sum of the entries on its account
+func (a *walletUsecase) TrialBalance(c context.Context) (*models.TrialBalance, error) {
+
+	ctx, cancel := context.WithTimeout(c, a.contextTimeout)
+	defer cancel()
+	accounts, err := a.walletRepo.FetchAccountBalances(ctx)
+	if err != nil {
+		return nil, err
 	}
-	return &wallet
+	balances, err := a.walletRepo.FetchWalletBalances(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &models.TrialBalance{
+		Accounts:   accounts,
+		Totals:     make([]*models.CurrencyTotal, 0),
+		Mismatches: make([]*models.WalletMismatch, 0),
+	}
+	totals := make(map[string]*models.CurrencyTotal)
+	ledger := make(map[string]int64, len(balances))
+	for _, acc := range accounts {
+		total, ok := totals[acc.Currency]
+		if !ok {
+			total = &models.CurrencyTotal{Currency: acc.Currency}
+			totals[acc.Currency] = total
+			res.Totals = append(res.Totals, total)
+		}
+		total.Debit += acc.Debit
+		total.Credit += acc.Credit
+		// a wallet account only ever holds its wallet's currency
+		if walletID, ok := models.WalletOfAccount(acc.Account); ok {
+			ledger[walletID] += acc.Credit - acc.Debit
+		}
+	}
+	for walletID, balance := range balances {
+		if ledger[walletID] != balance {
+			res.Mismatches = append(res.Mismatches, &models.WalletMismatch{
+				WalletID:      walletID,
+				Balance:       balance,
+				LedgerBalance: ledger[walletID],
+			})
+		}
+		delete(ledger, walletID)
+	}
+	// entries posted to a wallet that does not exist
+	for walletID, amount := range ledger {
+		res.Mismatches = append(res.Mismatches, &models.WalletMismatch{
+			WalletID:      walletID,
+			LedgerBalance: amount,
+		})
+	}
+	sort.Slice(res.Mismatches, func(i, j int) bool {
+		return res.Mismatches[i].WalletID < res.Mismatches[j].WalletID
+	})
+	sort.Slice(res.Totals, func(i, j int) bool {
+		return res.Totals[i].Currency < res.Totals[j].Currency
+	})
+	res.Balanced = len(res.Mismatches) == 0
+	for _, total := range res.Totals {
+		if total.Debit != total.Credit {
+			res.Balanced = false
+		}
+	}
+
+	return res, nil
 }
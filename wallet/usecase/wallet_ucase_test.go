@@ -0,0 +1,264 @@
+package usecase_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/williamchand/my-wallet/fees"
+	"github.com/williamchand/my-wallet/limits"
+	"github.com/williamchand/my-wallet/models"
+	"github.com/williamchand/my-wallet/wallet"
+	"github.com/williamchand/my-wallet/wallet/repository"
+	"github.com/williamchand/my-wallet/wallet/usecase"
+)
+
+func newLimitedUsecase(t *testing.T, r wallet.Repository) wallet.Usecase {
+	checker, err := limits.NewChecker(limits.Config{Currencies: map[string]limits.Rule{
+		"IDR": {MaxBalance: 500, DailyOut: 300},
+		"USD": {MaxBalance: 50},
+	}})
+	require.NoError(t, err)
+	schedule, err := fees.NewSchedule(fees.Config{})
+	require.NoError(t, err)
+	return usecase.NewWalletUsecase(r, nil, nil, checker, schedule, time.Second)
+}
+
+func ownerOf(w *models.FetchWallet) *models.Principal {
+	return &models.Principal{CustomerID: w.OwnedBy, WalletID: w.ID, Scopes: models.OwnerScopes}
+}
+
+// assertLimit check err is the LimitError of the limit name
+func assertLimit(t *testing.T, name string, err error) {
+	limit, ok := err.(*models.LimitError)
+	if assert.True(t, ok, "%v is not a LimitError", err) {
+		assert.Equal(t, name, limit.Limit)
+	}
+}
+
+func TestTransferLimits(t *testing.T) {
+	ctx := context.Background()
+	r := repository.NewMemoryWalletRepository()
+	u := newLimitedUsecase(t, r)
+	sender := initWallet(t, r, 400)
+	receiver := initWallet(t, r, 450)
+
+	_, err := u.TransferWallet(ctx, &models.ReqTransfer{ReferenceID: uuid.New().String(), WalletID: receiver.ID, Amount: 301}, ownerOf(sender))
+	assertLimit(t, limits.LimitDailyOut, err)
+	_, err = u.TransferWallet(ctx, &models.ReqTransfer{ReferenceID: uuid.New().String(), WalletID: receiver.ID, Amount: 100}, ownerOf(sender))
+	assertLimit(t, limits.LimitMaxBalance, err)
+
+	_, err = u.TransferWallet(ctx, &models.ReqTransfer{ReferenceID: uuid.New().String(), WalletID: receiver.ID, Amount: 50}, ownerOf(sender))
+	require.NoError(t, err)
+	_, err = u.WithdrawWallet(ctx, &models.ReqTransaction{ReferenceID: uuid.New().String(), Amount: 251}, ownerOf(sender))
+	assertLimit(t, limits.LimitDailyOut, err)
+}
+
+func TestCaptureLimits(t *testing.T) {
+	ctx := context.Background()
+	r := repository.NewMemoryWalletRepository()
+	u := newLimitedUsecase(t, r)
+	w := initWallet(t, r, 400)
+	hold, err := r.CreateHold(ctx, &models.ReqHold{ReferenceID: uuid.New().String(), Amount: 350}, time.Now().Add(time.Hour), w.ID)
+	require.NoError(t, err)
+
+	_, err = u.CaptureHold(ctx, &models.ReqCapture{}, hold.ID, ownerOf(w))
+	assertLimit(t, limits.LimitDailyOut, err)
+
+	res, err := u.CaptureHold(ctx, &models.ReqCapture{Amount: 200}, hold.ID, ownerOf(w))
+	require.NoError(t, err)
+	assert.Equal(t, int64(200), res.Captured)
+	again, err := u.CaptureHold(ctx, &models.ReqCapture{Amount: 200}, hold.ID, ownerOf(w))
+	require.NoError(t, err, "the replay of a capture is not counted again")
+	assert.Equal(t, res, again)
+}
+
+func TestConvertLimits(t *testing.T) {
+	ctx := context.Background()
+	r := repository.NewMemoryWalletRepository()
+	u := newLimitedUsecase(t, r)
+	idr := initWallet(t, r, 400)
+	_, err := r.InitWallet(ctx, idr.OwnedBy, "USD")
+	require.NoError(t, err)
+
+	quote := func(amount, converted int64) string {
+		q, err := r.CreateQuote(ctx, &models.Quote{Amount: amount, Currency: "IDR", ConvertedAmount: converted, ToCurrency: "USD",
+			Rate: "0.5", ExpiresAt: time.Now().Add(time.Hour)}, idr.ID)
+		require.NoError(t, err)
+		return q.ID
+	}
+
+	_, err = u.ConvertWallet(ctx, &models.ReqConversion{ReferenceID: uuid.New().String(), QuoteID: quote(350, 20)}, ownerOf(idr))
+	assertLimit(t, limits.LimitDailyOut, err)
+	_, err = u.ConvertWallet(ctx, &models.ReqConversion{ReferenceID: uuid.New().String(), QuoteID: quote(120, 60)}, ownerOf(idr))
+	assertLimit(t, limits.LimitMaxBalance, err)
+
+	req := &models.ReqConversion{ReferenceID: uuid.New().String(), QuoteID: quote(100, 50)}
+	res, err := u.ConvertWallet(ctx, req, ownerOf(idr))
+	require.NoError(t, err)
+	assert.Equal(t, int64(50), res.ConvertedAmount)
+	_, err = u.ConvertWallet(ctx, req, ownerOf(idr))
+	require.NoError(t, err, "the replay of a conversion is not counted again")
+}
+
+func TestReplayAfterLimit(t *testing.T) {
+	ctx := context.Background()
+	r := repository.NewMemoryWalletRepository()
+	u := newLimitedUsecase(t, r)
+	w := initWallet(t, r, 0)
+	other := initWallet(t, r, 0)
+
+	deposit := &models.ReqTransaction{ReferenceID: uuid.New().String(), Amount: 450}
+	d, err := u.AddWallet(ctx, deposit, ownerOf(w))
+	require.NoError(t, err)
+	_, err = u.AddWallet(ctx, &models.ReqTransaction{ReferenceID: uuid.New().String(), Amount: 100}, ownerOf(w))
+	assertLimit(t, limits.LimitMaxBalance, err)
+	again, err := u.AddWallet(ctx, deposit, ownerOf(w))
+	require.NoError(t, err, "the replay of a deposit is not counted again")
+	assert.Equal(t, d, again)
+
+	withdrawal := &models.ReqTransaction{ReferenceID: uuid.New().String(), Amount: 250}
+	wd, err := u.WithdrawWallet(ctx, withdrawal, ownerOf(w))
+	require.NoError(t, err)
+	transfer := &models.ReqTransfer{ReferenceID: uuid.New().String(), WalletID: other.ID, Amount: 50}
+	tr, err := u.TransferWallet(ctx, transfer, ownerOf(w))
+	require.NoError(t, err)
+	_, err = u.WithdrawWallet(ctx, &models.ReqTransaction{ReferenceID: uuid.New().String(), Amount: 1}, ownerOf(w))
+	assertLimit(t, limits.LimitDailyOut, err)
+
+	wdAgain, err := u.WithdrawWallet(ctx, withdrawal, ownerOf(w))
+	require.NoError(t, err, "the replay of a withdrawal is not counted again")
+	assert.Equal(t, wd, wdAgain)
+	trAgain, err := u.TransferWallet(ctx, transfer, ownerOf(w))
+	require.NoError(t, err, "the replay of a transfer is not counted again")
+	assert.Equal(t, tr, trAgain)
+}
+
+func TestConcurrentLimit(t *testing.T) {
+	ctx := context.Background()
+	r := repository.NewMemoryWalletRepository()
+	u := newLimitedUsecase(t, r)
+	w := initWallet(t, r, 500)
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 10)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := u.WithdrawWallet(ctx, &models.ReqTransaction{ReferenceID: uuid.New().String(), Amount: 100}, ownerOf(w))
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	passed := 0
+	for err := range errs {
+		if err == nil {
+			passed++
+			continue
+		}
+		assertLimit(t, limits.LimitDailyOut, err)
+	}
+	assert.Equal(t, 3, passed, "only the withdrawals fitting the daily limit pass")
+	res, err := r.FetchWallet(ctx, w.ID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(200), res.Balance)
+}
+
+type issuerStub struct{}
+
+func (issuerStub) Issue(user *models.User) (*models.Token, error) {
+	return &models.Token{Token: user.WalletID}, nil
+}
+
+func TestInitWalletOfKnownCustomer(t *testing.T) {
+	ctx := context.Background()
+	r := repository.NewMemoryWalletRepository()
+	u := usecase.NewWalletUsecase(r, issuerStub{}, nil, nil, nil, time.Second)
+
+	first, err := u.InitWallet(ctx, "customer-1", "IDR", nil)
+	require.NoError(t, err, "a new customer needs no token")
+	other, err := u.InitWallet(ctx, "customer-2", "IDR", nil)
+	require.NoError(t, err)
+
+	_, err = u.InitWallet(ctx, "customer-1", "USD", nil)
+	assert.Equal(t, models.ErrUnauthorized, err)
+	_, err = u.InitWallet(ctx, "customer-1", "USD", ownerOf(other.Wallet))
+	assert.Equal(t, models.ErrForbidden, err, "the token of another customer is refused")
+
+	res, err := u.InitWallet(ctx, "customer-1", "USD", ownerOf(first.Wallet))
+	require.NoError(t, err)
+	assert.Equal(t, "customer-1", res.Wallet.OwnedBy)
+	assert.Equal(t, "USD", res.Wallet.Currency)
+}
+
+// skewedLedger add a debit without its credit to the account balances of the ledger
+type skewedLedger struct {
+	wallet.Repository
+	account string
+}
+
+func (r skewedLedger) FetchAccountBalances(ctx context.Context) ([]*models.AccountBalance, error) {
+	accounts, err := r.Repository.FetchAccountBalances(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return append(accounts, &models.AccountBalance{Account: r.account, Currency: models.DefaultCurrency, Debit: 5}), nil
+}
+
+// driftedWallet report a wallet balance off by 5 from its entries, as a balance updated outside the ledger would be
+type driftedWallet struct {
+	wallet.Repository
+	walletID string
+}
+
+func (r driftedWallet) FetchWalletBalances(ctx context.Context) (map[string]int64, error) {
+	balances, err := r.Repository.FetchWalletBalances(ctx)
+	if err != nil {
+		return nil, err
+	}
+	balances[r.walletID] += 5
+	return balances, nil
+}
+
+func TestTrialBalance(t *testing.T) {
+	ctx := context.Background()
+	r := repository.NewMemoryWalletRepository()
+	w := initWallet(t, r, 100)
+	other := initWallet(t, r, 50)
+	_, err := r.WithdrawWallet(ctx, &models.ReqTransaction{ReferenceID: uuid.New().String(), Amount: 30}, 0, nil, w.ID)
+	require.NoError(t, err)
+
+	res, err := usecase.NewWalletUsecase(r, nil, nil, nil, nil, time.Second).TrialBalance(ctx)
+	require.NoError(t, err)
+	assert.True(t, res.Balanced)
+	assert.Empty(t, res.Mismatches)
+	assert.Equal(t, []*models.CurrencyTotal{{Currency: models.DefaultCurrency, Debit: 180, Credit: 180}}, res.Totals)
+
+	res, err = usecase.NewWalletUsecase(skewedLedger{r, models.AccountCashIn}, nil, nil, nil, nil, time.Second).TrialBalance(ctx)
+	require.NoError(t, err)
+	assert.False(t, res.Balanced, "the debits exceed the credits")
+	assert.Empty(t, res.Mismatches)
+	assert.Equal(t, []*models.CurrencyTotal{{Currency: models.DefaultCurrency, Debit: 185, Credit: 180}}, res.Totals)
+
+	res, err = usecase.NewWalletUsecase(skewedLedger{r, models.WalletAccount(other.ID)}, nil, nil, nil, nil, time.Second).TrialBalance(ctx)
+	require.NoError(t, err)
+	assert.False(t, res.Balanced)
+	assert.Equal(t, []*models.WalletMismatch{{WalletID: other.ID, Balance: 50, LedgerBalance: 45}}, res.Mismatches,
+		"an entry on a wallet account must move its balance")
+
+	res, err = usecase.NewWalletUsecase(driftedWallet{r, w.ID}, nil, nil, nil, nil, time.Second).TrialBalance(ctx)
+	require.NoError(t, err)
+	assert.False(t, res.Balanced, "a balanced ledger does not hide a drifted wallet")
+	assert.Equal(t, []*models.WalletMismatch{{WalletID: w.ID, Balance: 75, LedgerBalance: 70}}, res.Mismatches)
+	for _, total := range res.Totals {
+		assert.Equal(t, total.Debit, total.Credit)
+	}
+}
@@ -0,0 +1,399 @@
+-- Migrasi dari skema awal wallet.sql, jalankan sekali pada basis data yang sudah ada.
+-- Basis data baru cukup memuat wallet.sql.
+--
+-- phpMyAdmin SQL Dump
+-- version 4.8.5
+-- https://www.phpmyadmin.net/
+--
+-- Host: localhost
+-- Waktu pembuatan: 01 Des 2019 pada 14.37
+-- Versi server: 8.0.13-4
+-- Versi PHP: 7.2.24-0ubuntu0.18.04.1
+
+SET SQL_MODE = "NO_AUTO_VALUE_ON_ZERO";
+SET AUTOCOMMIT = 0;
+START TRANSACTION;
+SET time_zone = "+00:00";
+
+
+/*!40101 SET @OLD_CHARACTER_SET_CLIENT=@@CHARACTER_SET_CLIENT */;
+/*!40101 SET @OLD_CHARACTER_SET_RESULTS=@@CHARACTER_SET_RESULTS */;
+/*!40101 SET @OLD_COLLATION_CONNECTION=@@COLLATION_CONNECTION */;
+/*!40101 SET NAMES utf8mb4 */;
+
+-- --------------------------------------------------------
+
+--
+-- Tabel baru
+--
+
+--
+-- Struktur dari tabel `hold`
+--
+
+CREATE TABLE `hold` (
+  `id` int(64) NOT NULL,
+  `hold_id` varchar(150) COLLATE utf8_unicode_ci NOT NULL,
+  `reference_id` varchar(100) COLLATE utf8_unicode_ci NOT NULL,
+  `wallet_id` varchar(150) COLLATE utf8_unicode_ci NOT NULL,
+  `amount` bigint(20) NOT NULL,
+  `currency` char(3) COLLATE utf8_unicode_ci NOT NULL DEFAULT 'IDR',
+  `captured_amount` bigint(20) NOT NULL DEFAULT '0',
+  `status` varchar(20) COLLATE utf8_unicode_ci NOT NULL,
+  `expires_at` datetime NOT NULL,
+  `created_by` varchar(100) COLLATE utf8_unicode_ci NOT NULL,
+  `created_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `updated_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP
+) ENGINE=InnoDB DEFAULT CHARSET=utf8 COLLATE=utf8_unicode_ci;
+
+-- --------------------------------------------------------
+
+--
+-- Struktur dari tabel `ledger_entry`
+--
+
+CREATE TABLE `ledger_entry` (
+  `id` int(64) NOT NULL,
+  `transaction_id` int(64) NOT NULL,
+  `account` varchar(170) COLLATE utf8_unicode_ci NOT NULL,
+  `debit` bigint(20) NOT NULL DEFAULT '0',
+  `credit` bigint(20) NOT NULL DEFAULT '0',
+  `currency` char(3) COLLATE utf8_unicode_ci NOT NULL DEFAULT 'IDR',
+  `created_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP
+) ENGINE=InnoDB DEFAULT CHARSET=utf8 COLLATE=utf8_unicode_ci;
+
+-- --------------------------------------------------------
+
+--
+-- Struktur dari tabel `fx_quote`
+--
+
+CREATE TABLE `fx_quote` (
+  `id` int(64) NOT NULL,
+  `quote_id` varchar(150) COLLATE utf8_unicode_ci NOT NULL,
+  `wallet_id` varchar(150) COLLATE utf8_unicode_ci NOT NULL,
+  `amount` bigint(20) NOT NULL,
+  `currency` char(3) COLLATE utf8_unicode_ci NOT NULL,
+  `converted_amount` bigint(20) NOT NULL,
+  `to_currency` char(3) COLLATE utf8_unicode_ci NOT NULL,
+  `rate` varchar(40) COLLATE utf8_unicode_ci NOT NULL,
+  `spread_bps` int(11) NOT NULL DEFAULT '0',
+  `fee` bigint(20) NOT NULL DEFAULT '0',
+  `rounding` varchar(20) COLLATE utf8_unicode_ci NOT NULL,
+  `reference_id` varchar(100) COLLATE utf8_unicode_ci DEFAULT NULL,
+  `expires_at` datetime NOT NULL,
+  `created_by` varchar(100) COLLATE utf8_unicode_ci NOT NULL,
+  `created_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP
+) ENGINE=InnoDB DEFAULT CHARSET=utf8 COLLATE=utf8_unicode_ci;
+
+-- --------------------------------------------------------
+
+--
+-- Struktur dari tabel `outbox`
+--
+
+CREATE TABLE `outbox` (
+  `id` int(64) NOT NULL,
+  `event_id` varchar(150) COLLATE utf8_unicode_ci NOT NULL,
+  `event_type` varchar(50) COLLATE utf8_unicode_ci NOT NULL,
+  `wallet_id` varchar(150) COLLATE utf8_unicode_ci NOT NULL,
+  `payload` text COLLATE utf8_unicode_ci NOT NULL,
+  `status` varchar(20) COLLATE utf8_unicode_ci NOT NULL,
+  `attempts` int(11) NOT NULL DEFAULT '0',
+  `next_attempt_at` datetime NOT NULL,
+  `last_error` varchar(1000) COLLATE utf8_unicode_ci DEFAULT NULL,
+  `created_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `updated_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP
+) ENGINE=InnoDB DEFAULT CHARSET=utf8 COLLATE=utf8_unicode_ci;
+
+-- --------------------------------------------------------
+
+--
+-- Struktur dari tabel `webhook_subscription`
+--
+
+CREATE TABLE `webhook_subscription` (
+  `id` int(64) NOT NULL,
+  `subscription_id` varchar(150) COLLATE utf8_unicode_ci NOT NULL,
+  `wallet_id` varchar(150) COLLATE utf8_unicode_ci NOT NULL,
+  `url` varchar(2048) COLLATE utf8_unicode_ci NOT NULL,
+  `events` varchar(1000) COLLATE utf8_unicode_ci NOT NULL,
+  `secret` varchar(100) COLLATE utf8_unicode_ci NOT NULL,
+  `status` varchar(20) COLLATE utf8_unicode_ci NOT NULL,
+  `created_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP
+) ENGINE=InnoDB DEFAULT CHARSET=utf8 COLLATE=utf8_unicode_ci;
+
+-- --------------------------------------------------------
+
+--
+-- Struktur dari tabel `webhook_delivery`
+--
+
+CREATE TABLE `webhook_delivery` (
+  `id` int(64) NOT NULL,
+  `delivery_id` varchar(150) COLLATE utf8_unicode_ci NOT NULL,
+  `subscription_id` varchar(150) COLLATE utf8_unicode_ci NOT NULL,
+  `event_id` varchar(150) COLLATE utf8_unicode_ci NOT NULL,
+  `event_type` varchar(50) COLLATE utf8_unicode_ci NOT NULL,
+  `payload` text COLLATE utf8_unicode_ci NOT NULL,
+  `status` varchar(20) COLLATE utf8_unicode_ci NOT NULL,
+  `attempts` int(11) NOT NULL DEFAULT '0',
+  `next_attempt_at` datetime NOT NULL,
+  `last_error` varchar(1000) COLLATE utf8_unicode_ci DEFAULT NULL,
+  `created_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `updated_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP
+) ENGINE=InnoDB DEFAULT CHARSET=utf8 COLLATE=utf8_unicode_ci;
+
+-- --------------------------------------------------------
+
+--
+-- Struktur dari tabel `webhook_attempt`
+--
+
+CREATE TABLE `webhook_attempt` (
+  `id` int(64) NOT NULL,
+  `delivery_id` varchar(150) COLLATE utf8_unicode_ci NOT NULL,
+  `attempt` int(11) NOT NULL,
+  `status_code` int(11) NOT NULL DEFAULT '0',
+  `error` varchar(1000) COLLATE utf8_unicode_ci DEFAULT NULL,
+  `duration_ms` bigint(20) NOT NULL DEFAULT '0',
+  `created_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP
+) ENGINE=InnoDB DEFAULT CHARSET=utf8 COLLATE=utf8_unicode_ci;
+
+-- --------------------------------------------------------
+
+--
+-- Struktur dari tabel `audit_log`
+--
+
+CREATE TABLE `audit_log` (
+  `id` int(64) NOT NULL,
+  `actor` varchar(100) COLLATE utf8_unicode_ci NOT NULL,
+  `action` varchar(50) COLLATE utf8_unicode_ci NOT NULL,
+  `wallet_id` varchar(150) COLLATE utf8_unicode_ci DEFAULT NULL,
+  `detail` varchar(255) COLLATE utf8_unicode_ci DEFAULT NULL,
+  `created_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP
+) ENGINE=InnoDB DEFAULT CHARSET=utf8 COLLATE=utf8_unicode_ci;
+
+--
+-- Indexes for dumped tables
+--
+
+--
+-- Indeks untuk tabel `hold`
+--
+ALTER TABLE `hold`
+  ADD PRIMARY KEY (`id`),
+  ADD UNIQUE KEY `hold_id` (`hold_id`),
+  ADD UNIQUE KEY `reference_id` (`reference_id`),
+  ADD KEY `hold_bind_1` (`wallet_id`,`status`);
+
+--
+-- Indeks untuk tabel `ledger_entry`
+--
+ALTER TABLE `ledger_entry`
+  ADD PRIMARY KEY (`id`),
+  ADD KEY `ledger_entry_bind_1` (`transaction_id`),
+  ADD KEY `account` (`account`);
+
+--
+-- Indeks untuk tabel `fx_quote`
+--
+ALTER TABLE `fx_quote`
+  ADD PRIMARY KEY (`id`),
+  ADD UNIQUE KEY `quote_id` (`quote_id`),
+  ADD UNIQUE KEY `reference_id` (`reference_id`),
+  ADD KEY `fx_quote_bind_1` (`wallet_id`);
+
+--
+-- Indeks untuk tabel `outbox`
+--
+ALTER TABLE `outbox`
+  ADD PRIMARY KEY (`id`),
+  ADD UNIQUE KEY `event_id` (`event_id`),
+  ADD KEY `pending` (`status`,`id`);
+
+--
+-- Indeks untuk tabel `webhook_subscription`
+--
+ALTER TABLE `webhook_subscription`
+  ADD PRIMARY KEY (`id`),
+  ADD UNIQUE KEY `subscription_id` (`subscription_id`),
+  ADD KEY `webhook_subscription_bind_1` (`wallet_id`,`status`);
+
+--
+-- Indeks untuk tabel `webhook_delivery`
+--
+ALTER TABLE `webhook_delivery`
+  ADD PRIMARY KEY (`id`),
+  ADD UNIQUE KEY `delivery_id` (`delivery_id`),
+  ADD UNIQUE KEY `event` (`subscription_id`,`event_id`),
+  ADD KEY `webhook_delivery_bind_1` (`subscription_id`),
+  ADD KEY `due` (`status`,`next_attempt_at`);
+
+--
+-- Indeks untuk tabel `webhook_attempt`
+--
+ALTER TABLE `webhook_attempt`
+  ADD PRIMARY KEY (`id`),
+  ADD KEY `webhook_attempt_bind_1` (`delivery_id`);
+
+--
+-- Indeks untuk tabel `audit_log`
+--
+ALTER TABLE `audit_log`
+  ADD PRIMARY KEY (`id`),
+  ADD KEY `audit_log_bind_1` (`wallet_id`);
+
+--
+-- AUTO_INCREMENT untuk tabel yang dibuang
+--
+
+--
+-- AUTO_INCREMENT untuk tabel `hold`
+--
+ALTER TABLE `hold`
+  MODIFY `id` int(64) NOT NULL AUTO_INCREMENT;
+
+--
+-- AUTO_INCREMENT untuk tabel `ledger_entry`
+--
+ALTER TABLE `ledger_entry`
+  MODIFY `id` int(64) NOT NULL AUTO_INCREMENT;
+
+--
+-- AUTO_INCREMENT untuk tabel `fx_quote`
+--
+ALTER TABLE `fx_quote`
+  MODIFY `id` int(64) NOT NULL AUTO_INCREMENT;
+
+--
+-- AUTO_INCREMENT untuk tabel `outbox`
+--
+ALTER TABLE `outbox`
+  MODIFY `id` int(64) NOT NULL AUTO_INCREMENT;
+
+--
+-- AUTO_INCREMENT untuk tabel `webhook_subscription`
+--
+ALTER TABLE `webhook_subscription`
+  MODIFY `id` int(64) NOT NULL AUTO_INCREMENT;
+
+--
+-- AUTO_INCREMENT untuk tabel `webhook_delivery`
+--
+ALTER TABLE `webhook_delivery`
+  MODIFY `id` int(64) NOT NULL AUTO_INCREMENT;
+
+--
+-- AUTO_INCREMENT untuk tabel `webhook_attempt`
+--
+ALTER TABLE `webhook_attempt`
+  MODIFY `id` int(64) NOT NULL AUTO_INCREMENT;
+
+--
+-- AUTO_INCREMENT untuk tabel `audit_log`
+--
+ALTER TABLE `audit_log`
+  MODIFY `id` int(64) NOT NULL AUTO_INCREMENT;
+
+--
+-- Ketidakleluasaan untuk tabel pelimpahan (Dumped Tables)
+--
+
+--
+-- Ketidakleluasaan untuk tabel `hold`
+--
+ALTER TABLE `hold`
+  ADD CONSTRAINT `hold_bind_1` FOREIGN KEY (`wallet_id`) REFERENCES `wallet` (`wallet_id`) ON DELETE RESTRICT ON UPDATE RESTRICT;
+
+--
+-- Ketidakleluasaan untuk tabel `ledger_entry`
+--
+ALTER TABLE `ledger_entry`
+  ADD CONSTRAINT `ledger_entry_bind_1` FOREIGN KEY (`transaction_id`) REFERENCES `transaction` (`id`) ON DELETE RESTRICT ON UPDATE RESTRICT;
+
+--
+-- Ketidakleluasaan untuk tabel `fx_quote`
+--
+ALTER TABLE `fx_quote`
+  ADD CONSTRAINT `fx_quote_bind_1` FOREIGN KEY (`wallet_id`) REFERENCES `wallet` (`wallet_id`) ON DELETE RESTRICT ON UPDATE RESTRICT;
+
+--
+-- Ketidakleluasaan untuk tabel `webhook_subscription`
+--
+ALTER TABLE `webhook_subscription`
+  ADD CONSTRAINT `webhook_subscription_bind_1` FOREIGN KEY (`wallet_id`) REFERENCES `wallet` (`wallet_id`) ON DELETE RESTRICT ON UPDATE RESTRICT;
+
+--
+-- Ketidakleluasaan untuk tabel `webhook_delivery`
+--
+ALTER TABLE `webhook_delivery`
+  ADD CONSTRAINT `webhook_delivery_bind_1` FOREIGN KEY (`subscription_id`) REFERENCES `webhook_subscription` (`subscription_id`) ON DELETE RESTRICT ON UPDATE RESTRICT;
+
+--
+-- Ketidakleluasaan untuk tabel `webhook_attempt`
+--
+ALTER TABLE `webhook_attempt`
+  ADD CONSTRAINT `webhook_attempt_bind_1` FOREIGN KEY (`delivery_id`) REFERENCES `webhook_delivery` (`delivery_id`) ON DELETE RESTRICT ON UPDATE RESTRICT;
+
+-- --------------------------------------------------------
+
+--
+-- Kolom baru: `currency` and the bigint amounts come with multi-currency wallets, `parent_id` with transfers,
+-- `quote_id` with conversions and `reason` with the manual adjustments and failed transactions
+--
+ALTER TABLE `wallet`
+  MODIFY `balance` bigint(20) NOT NULL DEFAULT '0',
+  ADD `currency` char(3) COLLATE utf8_unicode_ci NOT NULL DEFAULT 'IDR' AFTER `balance`;
+
+ALTER TABLE `transaction`
+  MODIFY `amount` bigint(20) NOT NULL,
+  ADD `currency` char(3) COLLATE utf8_unicode_ci NOT NULL DEFAULT 'IDR' AFTER `amount`,
+  ADD `parent_id` int(64) DEFAULT NULL,
+  ADD `quote_id` varchar(150) COLLATE utf8_unicode_ci DEFAULT NULL,
+  ADD `reason` varchar(255) COLLATE utf8_unicode_ci DEFAULT NULL,
+  ADD KEY `parent_id` (`parent_id`),
+  ADD KEY `quote_id` (`quote_id`);
+
+--
+-- Pemilik dompet: the first wallet.sql wrote the owner "william-chandra" on every wallet and used the customer_id
+-- sent to /api/v1/init as its wallet_id, so that is the owner a wallet gets back before one wallet per owner and
+-- currency is enforced
+--
+UPDATE `wallet` SET `owned_by` = `wallet_id` WHERE `owned_by` = 'william-chandra';
+ALTER TABLE `wallet`
+  ADD UNIQUE KEY `owned_by` (`owned_by`,`currency`);
+
+--
+-- Jenis transaksi: `type` was a tinyint(1), written 0 by deposits and 1 by withdrawals
+--
+ALTER TABLE `transaction`
+  MODIFY `type` varchar(20) COLLATE utf8_unicode_ci NOT NULL;
+UPDATE `transaction` SET `type` = CASE `type` WHEN '0' THEN 'deposit' ELSE 'withdrawal' END WHERE `type` IN ('0', '1');
+
+--
+-- Saldo awal: the balance a wallet had before the ledger is posted as an `opening` journal from `system:opening`,
+-- so its wallet account matches `wallet`.`balance`.
+--
+INSERT INTO `transaction` (`reference_id`, `wallet_id`, `type`, `amount`, `currency`, `status`, `created_by`, `created_at`)
+SELECT CONCAT('opening:', w.`wallet_id`), w.`wallet_id`, 'opening', w.`balance` - COALESCE(l.`balance`, 0), w.`currency`, 'success', 'system', NOW()
+  FROM `wallet` w
+  LEFT JOIN (SELECT `account`, SUM(`credit` - `debit`) AS `balance` FROM `ledger_entry` GROUP BY `account`) l
+    ON l.`account` = CONCAT('wallet:', w.`wallet_id`)
+ WHERE w.`balance` > COALESCE(l.`balance`, 0)
+   AND NOT EXISTS (SELECT 1 FROM `transaction` o WHERE o.`reference_id` = CONCAT('opening:', w.`wallet_id`));
+
+INSERT INTO `ledger_entry` (`transaction_id`, `account`, `debit`, `credit`, `currency`, `created_at`)
+SELECT t.`id`, 'system:opening', t.`amount`, 0, t.`currency`, t.`created_at`
+  FROM `transaction` t
+ WHERE t.`type` = 'opening' AND NOT EXISTS (SELECT 1 FROM `ledger_entry` e WHERE e.`transaction_id` = t.`id`)
+UNION ALL
+SELECT t.`id`, CONCAT('wallet:', t.`wallet_id`), 0, t.`amount`, t.`currency`, t.`created_at`
+  FROM `transaction` t
+ WHERE t.`type` = 'opening' AND NOT EXISTS (SELECT 1 FROM `ledger_entry` e WHERE e.`transaction_id` = t.`id`);
+COMMIT;
+
+/*!40101 SET CHARACTER_SET_CLIENT=@OLD_CHARACTER_SET_CLIENT */;
+/*!40101 SET CHARACTER_SET_RESULTS=@OLD_CHARACTER_SET_RESULTS */;
+/*!40101 SET COLLATION_CONNECTION=@OLD_COLLATION_CONNECTION */;
@@ -0,0 +1,171 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo"
+	"github.com/sirupsen/logrus"
+	validator "gopkg.in/go-playground/validator.v9"
+
+	"github.com/williamchand/my-wallet/auth"
+	"github.com/williamchand/my-wallet/middleware"
+	"github.com/williamchand/my-wallet/models"
+	"github.com/williamchand/my-wallet/webhook"
+)
+
+// Response represent the response envelope
+type Response struct {
+	Status       string      `json:"status"`
+	ResponseData interface{} `json:"data"`
+}
+type ResponseSubscription struct {
+	Subscription interface{} `json:"subscription"`
+}
+type ResponseSubscriptions struct {
+	Subscriptions interface{} `json:"subscriptions"`
+}
+type ResponseDeliveries struct {
+	Deliveries interface{} `json:"deliveries"`
+}
+type ResponseError struct {
+	Error interface{} `json:"error"`
+	Code  string      `json:"code"`
+}
+
+// WebhookHandler  represent the httphandler for webhook subscriptions
+type WebhookHandler struct {
+	WUsecase webhook.Usecase
+}
+
+// NewWebhookHandler will initialize the webhooks/ resources endpoint
+func NewWebhookHandler(e *echo.Echo, us webhook.Usecase) {
+	handler := &WebhookHandler{
+		WUsecase: us,
+	}
+	read := middleware.RequireScope(models.ScopeWalletRead)
+	write := middleware.RequireScope(models.ScopeWalletWrite)
+	e.POST("/api/v1/wallet/webhooks", handler.Subscribe, write)
+	e.GET("/api/v1/wallet/webhooks", handler.FetchSubscriptions, read)
+	e.DELETE("/api/v1/wallet/webhooks/:subscription_id", handler.DeleteSubscription, write)
+	e.GET("/api/v1/wallet/webhooks/:subscription_id/deliveries", handler.FetchDeliveries, read)
+}
+
+// Subscribe will register a URL receiving the events of the wallet
+func (w *WebhookHandler) Subscribe(c echo.Context) error {
+	principal := auth.FromContext(c.Request().Context())
+	var subscription models.ReqSubscription
+	err := c.Bind(&subscription)
+	if err != nil {
+		return fail(c, invalidBody(err))
+	}
+
+	if ok, err := isRequestValid(&subscription); !ok {
+		return fail(c, invalidParam(err))
+	}
+	ctx := c.Request().Context()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	res, err := w.WUsecase.Subscribe(ctx, &subscription, principal)
+
+	if err != nil {
+		return fail(c, err)
+	}
+	return c.JSON(http.StatusCreated, Response{Status: "success", ResponseData: ResponseSubscription{
+		Subscription: res,
+	}})
+}
+
+// FetchSubscriptions will list the webhooks of the wallet
+func (w *WebhookHandler) FetchSubscriptions(c echo.Context) error {
+	principal := auth.FromContext(c.Request().Context())
+	ctx := c.Request().Context()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	res, err := w.WUsecase.FetchSubscriptions(ctx, principal)
+
+	if err != nil {
+		return fail(c, err)
+	}
+	return c.JSON(http.StatusOK, Response{Status: "success", ResponseData: ResponseSubscriptions{
+		Subscriptions: res,
+	}})
+}
+
+// DeleteSubscription will stop the deliveries to a webhook, the pending ones are never sent
+func (w *WebhookHandler) DeleteSubscription(c echo.Context) error {
+	principal := auth.FromContext(c.Request().Context())
+	ctx := c.Request().Context()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	err := w.WUsecase.DeleteSubscription(ctx, c.Param("subscription_id"), principal)
+
+	if err != nil {
+		return fail(c, err)
+	}
+	return c.JSON(http.StatusOK, Response{Status: "success", ResponseData: nil})
+}
+
+// FetchDeliveries will fetch the latest deliveries of a webhook with the log of their attempts
+func (w *WebhookHandler) FetchDeliveries(c echo.Context) error {
+	principal := auth.FromContext(c.Request().Context())
+	ctx := c.Request().Context()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	res, err := w.WUsecase.FetchDeliveries(ctx, c.Param("subscription_id"), principal)
+
+	if err != nil {
+		return fail(c, err)
+	}
+	return c.JSON(http.StatusOK, Response{Status: "success", ResponseData: ResponseDeliveries{
+		Deliveries: res,
+	}})
+}
+
+func isRequestValid(m interface{}) (bool, error) {
+	validate := validator.New()
+	err := validate.Struct(m)
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// invalidParam report a request that does not pass validation, the validator message names the field
+func invalidParam(err error) error {
+	return models.NewError(models.CodeInvalidParameter, http.StatusBadRequest, err.Error())
+}
+
+// invalidBody report a body that can not be bound to the request
+func invalidBody(err error) error {
+	return models.NewError(models.CodeInvalidBody, http.StatusUnprocessableEntity, err.Error())
+}
+
+// fail write err in the error envelope, or as an RFC 7807 problem when the client accepts application/problem+json
+func fail(c echo.Context, err error) error {
+	e := models.ErrorOf(err)
+	logrus.WithContext(c.Request().Context()).Error(err)
+	c.Set(middleware.ErrorCodeKey, e.Code)
+
+	if strings.Contains(c.Request().Header.Get(echo.HeaderAccept), models.MIMEProblemJSON) {
+		b, jerr := json.Marshal(models.NewProblem(e, c.Request().URL.Path))
+		if jerr != nil {
+			return jerr
+		}
+		return c.Blob(e.Status, models.MIMEProblemJSON, b)
+	}
+	return c.JSON(e.Status, Response{Status: "fail", ResponseData: ResponseError{
+		Error: e.Message,
+		Code:  e.Code,
+	}})
+}
@@ -0,0 +1,147 @@
+package dispatcher
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/williamchand/my-wallet/models"
+	"github.com/williamchand/my-wallet/retry"
+	"github.com/williamchand/my-wallet/webhook"
+)
+
+const batchSize = 100
+
+// Config represent the webhooks section of config.json, durations are in seconds
+type Config struct {
+	Timeout      int `mapstructure:"timeout"`
+	retry.Policy `mapstructure:",squash"`
+	// AllowPrivateNetworks lets the deliveries reach the loopback, link-local and private addresses, for tests only
+	AllowPrivateNetworks bool `mapstructure:"allow_private_networks"`
+}
+
+// defaultPolicy holds the defaults of the zero values of Config.Policy
+var defaultPolicy = retry.Policy{MaxAttempts: 8, Backoff: 30, MaxBackoff: 6 * 60 * 60, Interval: 5}
+
+// Dispatcher send the pending deliveries to their subscriptions. A failed attempt is retried after
+// Backoff seconds, doubled on every further failure up to MaxBackoff, and the delivery is dead once
+// it failed MaxAttempts times.
+type Dispatcher struct {
+	repo   webhook.Repository
+	client *http.Client
+	policy retry.Policy
+}
+
+// NewDispatcher will create a Dispatcher sending the deliveries of repo, zero values of cfg take their defaults
+func NewDispatcher(repo webhook.Repository, cfg Config) *Dispatcher {
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 10
+	}
+	timeout := time.Duration(cfg.Timeout) * time.Second
+	dialer := &net.Dialer{Timeout: timeout, KeepAlive: 30 * time.Second}
+	if !cfg.AllowPrivateNetworks {
+		dialer.Control = webhook.DialControl
+	}
+	// no proxy, the dialer must see the address of the receiver itself
+	transport := &http.Transport{
+		DialContext:         dialer.DialContext,
+		MaxIdleConns:        100,
+		IdleConnTimeout:     90 * time.Second,
+		TLSHandshakeTimeout: timeout,
+	}
+	return &Dispatcher{
+		repo:   repo,
+		client: &http.Client{Timeout: timeout, Transport: transport},
+		policy: cfg.Policy.WithDefaults(defaultPolicy),
+	}
+}
+
+// Run deliver what is due every interval until ctx is done
+func (d *Dispatcher) Run(ctx context.Context) {
+	d.policy.Run(ctx, d.DeliverDue)
+}
+
+// DeliverDue send every delivery due at now and return how many were attempted
+func (d *Dispatcher) DeliverDue(ctx context.Context, now time.Time) (int, error) {
+	due, err := d.repo.FetchDueDeliveries(ctx, now, batchSize)
+	if err != nil {
+		return 0, err
+	}
+	sent := 0
+	for _, delivery := range due {
+		// the lease outlives the request, so no other dispatcher sends it meanwhile. It starts now rather than when
+		// the batch was fetched, as the deliveries before it may have taken up to their timeout each.
+		ok, err := d.repo.ClaimDelivery(ctx, delivery, time.Now().Add(2*d.client.Timeout))
+		if err != nil {
+			return sent, err
+		}
+		if !ok {
+			continue
+		}
+		if err = d.deliver(ctx, delivery, now); err != nil {
+			return sent, err
+		}
+		sent++
+	}
+
+	return sent, nil
+}
+
+func (d *Dispatcher) deliver(ctx context.Context, delivery *models.Delivery, now time.Time) error {
+	start := time.Now()
+	code, sendErr := d.send(ctx, delivery)
+	attempt := &models.DeliveryAttempt{
+		Attempt:    delivery.Attempts + 1,
+		StatusCode: code,
+		DurationMs: int64(time.Since(start) / time.Millisecond),
+		CreatedAt:  now,
+	}
+
+	delivery.Attempts = attempt.Attempt
+	switch {
+	case sendErr == nil:
+		delivery.Status = models.DeliveryStatusDelivered
+		delivery.LastError = ""
+	case delivery.Attempts >= d.policy.MaxAttempts:
+		attempt.Error = sendErr.Error()
+		delivery.Status = models.DeliveryStatusDead
+		delivery.LastError = attempt.Error
+	default:
+		attempt.Error = sendErr.Error()
+		delivery.LastError = attempt.Error
+		delivery.NextAttemptAt = now.Add(d.policy.BackoffAfter(delivery.Attempts))
+	}
+
+	return d.repo.RecordAttempt(ctx, delivery, attempt)
+}
+
+func (d *Dispatcher) send(ctx context.Context, delivery *models.Delivery) (int, error) {
+	body := []byte(delivery.Payload)
+	req, err := http.NewRequest(http.MethodPost, delivery.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Wallet-Event", delivery.EventType)
+	req.Header.Set("X-Wallet-Delivery", delivery.ID)
+	req.Header.Set(webhook.SignatureHeader, webhook.Sign(delivery.Secret, time.Now(), body))
+
+	res, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer res.Body.Close()
+	// drained so the connection can be reused
+	_, _ = io.Copy(ioutil.Discard, io.LimitReader(res.Body, 64<<10))
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return res.StatusCode, fmt.Errorf("unexpected status %d", res.StatusCode)
+	}
+	return res.StatusCode, nil
+}
@@ -0,0 +1,213 @@
+package dispatcher_test
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/williamchand/my-wallet/models"
+	"github.com/williamchand/my-wallet/retry"
+	"github.com/williamchand/my-wallet/webhook"
+	"github.com/williamchand/my-wallet/webhook/dispatcher"
+	"github.com/williamchand/my-wallet/webhook/repository"
+	"github.com/williamchand/my-wallet/webhook/usecase"
+)
+
+// receiver is a local endpoint answering with the next of its status codes, the last one repeats
+type receiver struct {
+	*httptest.Server
+	mu       sync.Mutex
+	statuses []int
+	requests []*http.Request
+	bodies   [][]byte
+}
+
+func (rc *receiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := ioutil.ReadAll(r.Body)
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	rc.requests = append(rc.requests, r)
+	rc.bodies = append(rc.bodies, body)
+	status := rc.statuses[0]
+	if len(rc.statuses) > 1 {
+		rc.statuses = rc.statuses[1:]
+	}
+	w.WriteHeader(status)
+}
+
+func setup(t *testing.T, statuses ...int) (webhook.Repository, *models.Subscription, *receiver) {
+	rc := &receiver{statuses: statuses}
+	rc.Server = httptest.NewServer(rc)
+
+	repo := repository.NewMemoryWebhookRepository()
+	walletID := uuid.New().String()
+	sub, err := repo.CreateSubscription(context.Background(), &models.Subscription{
+		ID:       uuid.New().String(),
+		WalletID: walletID,
+		URL:      rc.URL,
+		Events:   []string{models.EventDepositSucceeded},
+		Secret:   "whsec_test",
+	})
+	require.NoError(t, err)
+
+	publisher := usecase.NewWebhookUsecase(repo, time.Second)
+	event := models.NewEvent(models.EventDepositSucceeded, walletID, map[string]int64{"amount": 100})
+	require.NoError(t, publisher.Publish(context.Background(), event))
+	// not subscribed to
+	require.NoError(t, publisher.Publish(context.Background(), models.NewEvent(models.EventWalletDisabled, walletID, nil)))
+
+	return repo, sub, rc
+}
+
+func deliveries(t *testing.T, repo webhook.Repository, sub *models.Subscription) []*models.Delivery {
+	list, err := repo.FetchDeliveries(context.Background(), sub.ID, 10)
+	require.NoError(t, err)
+	return list
+}
+
+func TestDeliverDue(t *testing.T) {
+	repo, sub, rc := setup(t, http.StatusNoContent)
+	defer rc.Close()
+	d := dispatcher.NewDispatcher(repo, dispatcher.Config{AllowPrivateNetworks: true})
+	now := time.Now().Add(time.Second)
+
+	n, err := d.DeliverDue(context.Background(), now)
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+
+	require.Len(t, rc.requests, 1)
+	req, body := rc.requests[0], rc.bodies[0]
+	assert.Equal(t, "application/json", req.Header.Get("Content-Type"))
+	assert.Equal(t, models.EventDepositSucceeded, req.Header.Get("X-Wallet-Event"))
+	assert.NoError(t, webhook.Verify(sub.Secret, req.Header.Get(webhook.SignatureHeader), body, time.Minute, now))
+	assert.Error(t, webhook.Verify("whsec_other", req.Header.Get(webhook.SignatureHeader), body, time.Minute, now))
+	assert.Error(t, webhook.Verify(sub.Secret, req.Header.Get(webhook.SignatureHeader), body, time.Minute, now.Add(time.Hour)))
+
+	var event models.Event
+	require.NoError(t, json.Unmarshal(body, &event))
+	assert.Equal(t, models.EventDepositSucceeded, event.Type)
+	assert.Equal(t, sub.WalletID, event.WalletID)
+
+	list := deliveries(t, repo, sub)
+	require.Len(t, list, 1)
+	assert.Equal(t, req.Header.Get("X-Wallet-Delivery"), list[0].ID)
+	assert.Equal(t, models.DeliveryStatusDelivered, list[0].Status)
+	require.Len(t, list[0].Log, 1)
+	assert.Equal(t, http.StatusNoContent, list[0].Log[0].StatusCode)
+
+	n, err = d.DeliverDue(context.Background(), now.Add(time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, 0, n, "a delivered event is not sent again")
+}
+
+func TestRetryWithBackoff(t *testing.T) {
+	repo, sub, rc := setup(t, http.StatusInternalServerError, http.StatusBadGateway, http.StatusOK)
+	defer rc.Close()
+	d := dispatcher.NewDispatcher(repo, dispatcher.Config{Policy: retry.Policy{Backoff: 10, MaxBackoff: 15}, AllowPrivateNetworks: true})
+	now := time.Now().Add(time.Second)
+
+	for _, step := range []struct {
+		at   time.Duration
+		sent int
+	}{
+		{0, 1},
+		{9 * time.Second, 0},
+		{10 * time.Second, 1},
+		// 20s doubled, capped at 15s
+		{24 * time.Second, 0},
+		{25 * time.Second, 1},
+	} {
+		n, err := d.DeliverDue(context.Background(), now.Add(step.at))
+		require.NoError(t, err)
+		assert.Equal(t, step.sent, n, "at %s", step.at)
+	}
+
+	assert.Len(t, rc.requests, 3)
+	list := deliveries(t, repo, sub)
+	require.Len(t, list, 1)
+	assert.Equal(t, models.DeliveryStatusDelivered, list[0].Status)
+	assert.Equal(t, 3, list[0].Attempts)
+	require.Len(t, list[0].Log, 3)
+	assert.Equal(t, http.StatusInternalServerError, list[0].Log[0].StatusCode)
+	assert.Equal(t, "unexpected status 500", list[0].Log[0].Error)
+	assert.Equal(t, http.StatusBadGateway, list[0].Log[1].StatusCode)
+	assert.Equal(t, http.StatusOK, list[0].Log[2].StatusCode)
+	assert.Empty(t, list[0].LastError)
+}
+
+func TestDeadLetter(t *testing.T) {
+	repo, sub, rc := setup(t, http.StatusServiceUnavailable)
+	defer rc.Close()
+	d := dispatcher.NewDispatcher(repo, dispatcher.Config{Policy: retry.Policy{MaxAttempts: 3, Backoff: 1, MaxBackoff: 1}, AllowPrivateNetworks: true})
+	now := time.Now().Add(time.Second)
+
+	for i := 0; i < 5; i++ {
+		_, err := d.DeliverDue(context.Background(), now.Add(time.Duration(i)*time.Minute))
+		require.NoError(t, err)
+	}
+
+	assert.Len(t, rc.requests, 3)
+	list := deliveries(t, repo, sub)
+	require.Len(t, list, 1)
+	assert.Equal(t, models.DeliveryStatusDead, list[0].Status)
+	assert.Equal(t, 3, list[0].Attempts)
+	assert.Equal(t, "unexpected status 503", list[0].LastError)
+	assert.Len(t, list[0].Log, 3)
+}
+
+func TestUnreachableReceiver(t *testing.T) {
+	repo, sub, rc := setup(t, http.StatusOK)
+	defer rc.Close()
+	list := deliveries(t, repo, sub)
+	require.Len(t, list, 1)
+
+	srv := httptest.NewServer(http.NotFoundHandler())
+	srv.Close()
+	dead, err := repo.CreateSubscription(context.Background(), &models.Subscription{
+		ID:       uuid.New().String(),
+		WalletID: sub.WalletID,
+		URL:      srv.URL,
+		Events:   []string{models.EventAll},
+		Secret:   "whsec_test",
+	})
+	require.NoError(t, err)
+	publisher := usecase.NewWebhookUsecase(repo, time.Second)
+	require.NoError(t, publisher.Publish(context.Background(), models.NewEvent(models.EventWalletEnabled, sub.WalletID, nil)))
+
+	d := dispatcher.NewDispatcher(repo, dispatcher.Config{Timeout: 1, AllowPrivateNetworks: true})
+	n, err := d.DeliverDue(context.Background(), time.Now().Add(time.Second))
+	require.NoError(t, err)
+	assert.Equal(t, 2, n)
+
+	list = deliveries(t, repo, dead)
+	require.Len(t, list, 1)
+	assert.Equal(t, models.DeliveryStatusPending, list[0].Status)
+	require.Len(t, list[0].Log, 1)
+	assert.Zero(t, list[0].Log[0].StatusCode)
+	assert.NotEmpty(t, list[0].Log[0].Error)
+}
+
+func TestPrivateAddress(t *testing.T) {
+	repo, sub, rc := setup(t, http.StatusOK)
+	defer rc.Close()
+	d := dispatcher.NewDispatcher(repo, dispatcher.Config{})
+
+	n, err := d.DeliverDue(context.Background(), time.Now().Add(time.Second))
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+
+	assert.Empty(t, rc.requests, "the dialer refuses the loopback address")
+	list := deliveries(t, repo, sub)
+	require.Len(t, list, 1)
+	assert.Equal(t, models.DeliveryStatusPending, list[0].Status)
+	assert.Contains(t, list[0].LastError, webhook.ErrForbiddenAddress.Error())
+}
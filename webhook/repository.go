@@ -0,0 +1,21 @@
+package webhook
+
+import (
+	"context"
+	"time"
+
+	"github.com/williamchand/my-wallet/models"
+)
+
+// Repository represent the webhook's repository contract
+type Repository interface {
+	CreateSubscription(ctx context.Context, s *models.Subscription) (*models.Subscription, error)
+	FetchSubscription(ctx context.Context, subscriptionID string, walletID string) (*models.Subscription, error)
+	FetchSubscriptions(ctx context.Context, walletID string) ([]*models.Subscription, error)
+	DeleteSubscription(ctx context.Context, subscriptionID string, walletID string) error
+	CreateDeliveries(ctx context.Context, deliveries []*models.Delivery) error
+	FetchDeliveries(ctx context.Context, subscriptionID string, limit int) ([]*models.Delivery, error)
+	FetchDueDeliveries(ctx context.Context, now time.Time, limit int) ([]*models.Delivery, error)
+	ClaimDelivery(ctx context.Context, d *models.Delivery, until time.Time) (bool, error)
+	RecordAttempt(ctx context.Context, d *models.Delivery, attempt *models.DeliveryAttempt) error
+}
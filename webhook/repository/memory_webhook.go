@@ -0,0 +1,216 @@
+package repository
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/williamchand/my-wallet/models"
+	"github.com/williamchand/my-wallet/webhook"
+)
+
+type memoryWebhookRepository struct {
+	mu            sync.Mutex
+	subscriptions map[string]*models.Subscription
+	deliveries    []*models.Delivery
+}
+
+// NewMemoryWebhookRepository will create an in-memory object that represent the webhook.Repository interface,
+// it behaves like the MySQL repository and is meant for tests and local runs
+func NewMemoryWebhookRepository() webhook.Repository {
+	return &memoryWebhookRepository{
+		subscriptions: make(map[string]*models.Subscription),
+	}
+}
+
+// now is truncated to seconds, like the DATETIME columns of the MySQL schema
+func now() time.Time {
+	return time.Now().Truncate(time.Second)
+}
+
+func (m *memoryWebhookRepository) CreateSubscription(ctx context.Context, s *models.Subscription) (*models.Subscription, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	c := *s
+	c.Events = append([]string(nil), s.Events...)
+	c.Status = models.SubscriptionStatusActive
+	c.CreatedAt = now()
+	m.subscriptions[c.ID] = &c
+
+	res := c
+	return &res, nil
+}
+
+func (m *memoryWebhookRepository) FetchSubscription(ctx context.Context, subscriptionID string, walletID string) (*models.Subscription, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	s, ok := m.subscriptions[subscriptionID]
+	if !ok || s.WalletID != walletID || s.Status != models.SubscriptionStatusActive {
+		return nil, models.ErrNotFound
+	}
+	c := *s
+	return &c, nil
+}
+
+func (m *memoryWebhookRepository) FetchSubscriptions(ctx context.Context, walletID string) ([]*models.Subscription, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	list := make([]*models.Subscription, 0)
+	for _, s := range m.subscriptions {
+		if s.WalletID == walletID && s.Status == models.SubscriptionStatusActive {
+			c := *s
+			list = append(list, &c)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].CreatedAt.Equal(list[j].CreatedAt) {
+			return list[i].ID < list[j].ID
+		}
+		return list[i].CreatedAt.Before(list[j].CreatedAt)
+	})
+
+	return list, nil
+}
+
+func (m *memoryWebhookRepository) DeleteSubscription(ctx context.Context, subscriptionID string, walletID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	s, ok := m.subscriptions[subscriptionID]
+	if !ok || s.WalletID != walletID || s.Status != models.SubscriptionStatusActive {
+		return models.ErrNotFound
+	}
+	s.Status = models.SubscriptionStatusDeleted
+	for _, d := range m.deliveries {
+		if d.SubscriptionID == subscriptionID && d.Status == models.DeliveryStatusPending {
+			d.Status = models.DeliveryStatusDead
+			d.LastError = errSubscriptionDeleted
+			d.UpdatedAt = now()
+		}
+	}
+
+	return nil
+}
+
+func (m *memoryWebhookRepository) CreateDeliveries(ctx context.Context, deliveries []*models.Delivery) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, d := range deliveries {
+		if m.queued(d.SubscriptionID, d.EventID) {
+			continue
+		}
+		c := *d
+		c.Status = models.DeliveryStatusPending
+		c.NextAttemptAt = d.NextAttemptAt.Truncate(time.Second)
+		c.CreatedAt = now()
+		c.UpdatedAt = c.CreatedAt
+		c.Log = nil
+		m.deliveries = append(m.deliveries, &c)
+	}
+
+	return nil
+}
+
+// queued report whether the event already has a delivery for the subscription, the caller holds the lock
+func (m *memoryWebhookRepository) queued(subscriptionID, eventID string) bool {
+	for _, d := range m.deliveries {
+		if d.SubscriptionID == subscriptionID && d.EventID == eventID {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *memoryWebhookRepository) FetchDeliveries(ctx context.Context, subscriptionID string, limit int) ([]*models.Delivery, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	list := make([]*models.Delivery, 0)
+	for i := len(m.deliveries) - 1; i >= 0 && len(list) < limit; i-- {
+		if d := m.deliveries[i]; d.SubscriptionID == subscriptionID {
+			list = append(list, m.copyDelivery(d))
+		}
+	}
+
+	return list, nil
+}
+
+func (m *memoryWebhookRepository) FetchDueDeliveries(ctx context.Context, due time.Time, limit int) ([]*models.Delivery, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	list := make([]*models.Delivery, 0)
+	for _, d := range m.deliveries {
+		if d.Status != models.DeliveryStatusPending || d.NextAttemptAt.After(due) {
+			continue
+		}
+		c := m.copyDelivery(d)
+		s := m.subscriptions[d.SubscriptionID]
+		c.URL, c.Secret = s.URL, s.Secret
+		list = append(list, c)
+	}
+	sort.SliceStable(list, func(i, j int) bool { return list[i].NextAttemptAt.Before(list[j].NextAttemptAt) })
+	if len(list) > limit {
+		list = list[:limit]
+	}
+
+	return list, nil
+}
+
+func (m *memoryWebhookRepository) ClaimDelivery(ctx context.Context, d *models.Delivery, until time.Time) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stored := m.findDelivery(d.ID)
+	if stored == nil || stored.Status != models.DeliveryStatusPending || !stored.NextAttemptAt.Equal(d.NextAttemptAt) {
+		return false, nil
+	}
+	stored.NextAttemptAt = until.Truncate(time.Second)
+	d.NextAttemptAt = stored.NextAttemptAt
+
+	return true, nil
+}
+
+func (m *memoryWebhookRepository) RecordAttempt(ctx context.Context, d *models.Delivery, attempt *models.DeliveryAttempt) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stored := m.findDelivery(d.ID)
+	if stored == nil {
+		return models.ErrNotFound
+	}
+	stored.Status = d.Status
+	stored.Attempts = d.Attempts
+	stored.NextAttemptAt = d.NextAttemptAt.Truncate(time.Second)
+	stored.LastError = d.LastError
+	stored.UpdatedAt = attempt.CreatedAt.Truncate(time.Second)
+	a := *attempt
+	a.CreatedAt = stored.UpdatedAt
+	stored.Log = append(stored.Log, &a)
+
+	return nil
+}
+
+func (m *memoryWebhookRepository) findDelivery(id string) *models.Delivery {
+	for _, d := range m.deliveries {
+		if d.ID == id {
+			return d
+		}
+	}
+	return nil
+}
+
+func (m *memoryWebhookRepository) copyDelivery(d *models.Delivery) *models.Delivery {
+	c := *d
+	c.Log = make([]*models.DeliveryAttempt, 0, len(d.Log))
+	for _, a := range d.Log {
+		ac := *a
+		c.Log = append(c.Log, &ac)
+	}
+	return &c
+}
@@ -0,0 +1,319 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/williamchand/my-wallet/models"
+	"github.com/williamchand/my-wallet/webhook"
+)
+
+const (
+	subscriptionColumns = `subscription_id, wallet_id, url, events, secret, status, created_at`
+	deliveryColumns     = `d.delivery_id, d.subscription_id, d.event_id, d.event_type, d.payload, d.status, d.attempts,
+			  d.next_attempt_at, d.last_error, d.created_at, d.updated_at`
+
+	errSubscriptionDeleted = "subscription deleted"
+)
+
+type mysqlWebhookRepository struct {
+	Conn *sql.DB
+}
+
+// NewMysqlWebhookRepository will create an object that represent the webhook.Repository interface
+func NewMysqlWebhookRepository(Conn *sql.DB) webhook.Repository {
+	return &mysqlWebhookRepository{Conn}
+}
+
+func (m *mysqlWebhookRepository) CreateSubscription(ctx context.Context, s *models.Subscription) (*models.Subscription, error) {
+	query := `INSERT INTO webhook_subscription (subscription_id, wallet_id, url, events, secret, status, created_at)
+			  VALUES (?,?,?,?,?,?,?)`
+
+	_, err := m.Conn.ExecContext(ctx, query, s.ID, s.WalletID, s.URL, strings.Join(s.Events, ","), s.Secret,
+		models.SubscriptionStatusActive, time.Now())
+	if err != nil {
+		logrus.WithContext(ctx).Error(err)
+		return nil, err
+	}
+
+	return m.FetchSubscription(ctx, s.ID, s.WalletID)
+}
+
+func (m *mysqlWebhookRepository) FetchSubscription(ctx context.Context, subscriptionID string, walletID string) (*models.Subscription, error) {
+	query := `SELECT ` + subscriptionColumns + ` FROM webhook_subscription
+			  WHERE subscription_id = ? AND wallet_id = ? AND status = ?`
+
+	list, err := m.fetchSubscription(ctx, query, subscriptionID, walletID, models.SubscriptionStatusActive)
+	if err != nil {
+		return nil, err
+	}
+	if len(list) == 0 {
+		return nil, models.ErrNotFound
+	}
+
+	return list[0], nil
+}
+
+func (m *mysqlWebhookRepository) FetchSubscriptions(ctx context.Context, walletID string) ([]*models.Subscription, error) {
+	query := `SELECT ` + subscriptionColumns + ` FROM webhook_subscription
+			  WHERE wallet_id = ? AND status = ? ORDER BY created_at, subscription_id`
+
+	return m.fetchSubscription(ctx, query, walletID, models.SubscriptionStatusActive)
+}
+
+func (m *mysqlWebhookRepository) DeleteSubscription(ctx context.Context, subscriptionID string, walletID string) error {
+	tx, err := m.Conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	err = func() error {
+		query := `UPDATE webhook_subscription SET status = ? WHERE subscription_id = ? AND wallet_id = ? AND status = ?`
+		res, err := tx.ExecContext(ctx, query, models.SubscriptionStatusDeleted, subscriptionID, walletID, models.SubscriptionStatusActive)
+		if err != nil {
+			return err
+		}
+		affect, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if affect != 1 {
+			return models.ErrNotFound
+		}
+
+		// what was still waiting is never sent
+		query = `UPDATE webhook_delivery SET status = ?, last_error = ?, updated_at = ? WHERE subscription_id = ? AND status = ?`
+		_, err = tx.ExecContext(ctx, query, models.DeliveryStatusDead, errSubscriptionDeleted, time.Now(), subscriptionID, models.DeliveryStatusPending)
+		return err
+	}()
+	if err != nil {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			logrus.WithContext(ctx).Error(rbErr)
+		}
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// CreateDeliveries queue deliveries, skipping the events already queued for their subscription
+// so an event published again is not delivered twice
+func (m *mysqlWebhookRepository) CreateDeliveries(ctx context.Context, deliveries []*models.Delivery) error {
+	if len(deliveries) == 0 {
+		return nil
+	}
+	query := `INSERT IGNORE INTO webhook_delivery (delivery_id, subscription_id, event_id, event_type, payload, status, attempts,
+			  next_attempt_at, created_at, updated_at) VALUES ` + strings.TrimSuffix(strings.Repeat(`(?,?,?,?,?,?,0,?,?,?),`, len(deliveries)), ",")
+
+	now := time.Now()
+	args := make([]interface{}, 0, len(deliveries)*9)
+	for _, d := range deliveries {
+		args = append(args, d.ID, d.SubscriptionID, d.EventID, d.EventType, d.Payload, models.DeliveryStatusPending, d.NextAttemptAt, now, now)
+	}
+	_, err := m.Conn.ExecContext(ctx, query, args...)
+	if err != nil {
+		logrus.WithContext(ctx).Error(err)
+	}
+
+	return err
+}
+
+func (m *mysqlWebhookRepository) FetchDeliveries(ctx context.Context, subscriptionID string, limit int) ([]*models.Delivery, error) {
+	query := `SELECT ` + deliveryColumns + `, "", "" FROM webhook_delivery d
+			  WHERE d.subscription_id = ? ORDER BY d.id DESC LIMIT ?`
+
+	list, err := m.fetchDelivery(ctx, query, subscriptionID, limit)
+	if err != nil {
+		return nil, err
+	}
+	if err = m.fetchLog(ctx, list); err != nil {
+		return nil, err
+	}
+
+	return list, nil
+}
+
+func (m *mysqlWebhookRepository) FetchDueDeliveries(ctx context.Context, due time.Time, limit int) ([]*models.Delivery, error) {
+	query := `SELECT ` + deliveryColumns + `, s.url, s.secret FROM webhook_delivery d
+			  JOIN webhook_subscription s ON s.subscription_id = d.subscription_id
+			  WHERE d.status = ? AND d.next_attempt_at <= ? ORDER BY d.next_attempt_at, d.id LIMIT ?`
+
+	return m.fetchDelivery(ctx, query, models.DeliveryStatusPending, due, limit)
+}
+
+// ClaimDelivery push the next attempt of d to until, unless another dispatcher got to it first.
+// A dispatcher dying while it sends leaves the delivery to be picked up again once until has passed.
+func (m *mysqlWebhookRepository) ClaimDelivery(ctx context.Context, d *models.Delivery, until time.Time) (bool, error) {
+	query := `UPDATE webhook_delivery SET next_attempt_at = ? WHERE delivery_id = ? AND status = ? AND next_attempt_at = ?`
+
+	until = until.Truncate(time.Second)
+	res, err := m.Conn.ExecContext(ctx, query, until, d.ID, models.DeliveryStatusPending, d.NextAttemptAt)
+	if err != nil {
+		logrus.WithContext(ctx).Error(err)
+		return false, err
+	}
+	affect, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	if affect != 1 {
+		return false, nil
+	}
+	d.NextAttemptAt = until
+
+	return true, nil
+}
+
+func (m *mysqlWebhookRepository) RecordAttempt(ctx context.Context, d *models.Delivery, attempt *models.DeliveryAttempt) error {
+	tx, err := m.Conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	err = func() error {
+		query := `UPDATE webhook_delivery SET status = ?, attempts = ?, next_attempt_at = ?, last_error = ?, updated_at = ?
+				  WHERE delivery_id = ?`
+		_, err := tx.ExecContext(ctx, query, d.Status, d.Attempts, d.NextAttemptAt, nullString(d.LastError), attempt.CreatedAt, d.ID)
+		if err != nil {
+			return err
+		}
+
+		query = `INSERT INTO webhook_attempt (delivery_id, attempt, status_code, error, duration_ms, created_at) VALUES (?,?,?,?,?,?)`
+		_, err = tx.ExecContext(ctx, query, d.ID, attempt.Attempt, attempt.StatusCode, nullString(attempt.Error),
+			attempt.DurationMs, attempt.CreatedAt)
+		return err
+	}()
+	if err != nil {
+		logrus.WithContext(ctx).Error(err)
+		if rbErr := tx.Rollback(); rbErr != nil {
+			logrus.WithContext(ctx).Error(rbErr)
+		}
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// fetchLog load the attempts of every delivery of list
+func (m *mysqlWebhookRepository) fetchLog(ctx context.Context, list []*models.Delivery) error {
+	if len(list) == 0 {
+		return nil
+	}
+	byID := make(map[string]*models.Delivery, len(list))
+	args := make([]interface{}, 0, len(list))
+	for _, d := range list {
+		byID[d.ID] = d
+		args = append(args, d.ID)
+	}
+	query := `SELECT delivery_id, attempt, status_code, error, duration_ms, created_at FROM webhook_attempt
+			  WHERE delivery_id IN (?` + strings.Repeat(`,?`, len(list)-1) + `) ORDER BY id`
+
+	rows, err := m.Conn.QueryContext(ctx, query, args...)
+	if err != nil {
+		logrus.WithContext(ctx).Error(err)
+		return err
+	}
+
+	defer func() {
+		err := rows.Close()
+		if err != nil {
+			logrus.WithContext(ctx).Error(err)
+		}
+	}()
+
+	for rows.Next() {
+		var deliveryID string
+		var failure sql.NullString
+		a := new(models.DeliveryAttempt)
+		err = rows.Scan(&deliveryID, &a.Attempt, &a.StatusCode, &failure, &a.DurationMs, &a.CreatedAt)
+		if err != nil {
+			logrus.WithContext(ctx).Error(err)
+			return err
+		}
+		a.Error = failure.String
+		byID[deliveryID].Log = append(byID[deliveryID].Log, a)
+	}
+
+	return rows.Err()
+}
+
+func (m *mysqlWebhookRepository) fetchSubscription(ctx context.Context, query string, args ...interface{}) ([]*models.Subscription, error) {
+	rows, err := m.Conn.QueryContext(ctx, query, args...)
+	if err != nil {
+		logrus.WithContext(ctx).Error(err)
+		return nil, err
+	}
+
+	defer func() {
+		err := rows.Close()
+		if err != nil {
+			logrus.WithContext(ctx).Error(err)
+		}
+	}()
+
+	result := make([]*models.Subscription, 0)
+	for rows.Next() {
+		var events string
+		s := new(models.Subscription)
+		err = rows.Scan(&s.ID, &s.WalletID, &s.URL, &events, &s.Secret, &s.Status, &s.CreatedAt)
+		if err != nil {
+			logrus.WithContext(ctx).Error(err)
+			return nil, err
+		}
+		s.Events = strings.Split(events, ",")
+		result = append(result, s)
+	}
+
+	return result, rows.Err()
+}
+
+func (m *mysqlWebhookRepository) fetchDelivery(ctx context.Context, query string, args ...interface{}) ([]*models.Delivery, error) {
+	rows, err := m.Conn.QueryContext(ctx, query, args...)
+	if err != nil {
+		logrus.WithContext(ctx).Error(err)
+		return nil, err
+	}
+
+	defer func() {
+		err := rows.Close()
+		if err != nil {
+			logrus.WithContext(ctx).Error(err)
+		}
+	}()
+
+	result := make([]*models.Delivery, 0)
+	for rows.Next() {
+		var lastError sql.NullString
+		d := new(models.Delivery)
+		err = rows.Scan(
+			&d.ID,
+			&d.SubscriptionID,
+			&d.EventID,
+			&d.EventType,
+			&d.Payload,
+			&d.Status,
+			&d.Attempts,
+			&d.NextAttemptAt,
+			&lastError,
+			&d.CreatedAt,
+			&d.UpdatedAt,
+			&d.URL,
+			&d.Secret,
+		)
+		if err != nil {
+			logrus.WithContext(ctx).Error(err)
+			return nil, err
+		}
+		d.LastError = lastError.String
+		d.Log = make([]*models.DeliveryAttempt, 0)
+		result = append(result, d)
+	}
+
+	return result, rows.Err()
+}
+
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
@@ -0,0 +1,214 @@
+package repository_test
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"testing"
+	"time"
+
+	_ "github.com/go-sql-driver/mysql"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/williamchand/my-wallet/models"
+	_walletRepo "github.com/williamchand/my-wallet/wallet/repository"
+	"github.com/williamchand/my-wallet/webhook"
+	"github.com/williamchand/my-wallet/webhook/repository"
+)
+
+// The conformance suite below runs against every webhook.Repository implementation,
+// newWallet returns the id of a fresh wallet the subscriptions can belong to.
+
+func TestMemoryRepository(t *testing.T) {
+	testRepository(t, repository.NewMemoryWebhookRepository, func(t *testing.T) string {
+		return uuid.New().String()
+	})
+}
+
+// TestMysqlRepository needs a MySQL loaded with wallet.sql, e.g.
+// MYSQL_TEST_DSN="user:pass@tcp(localhost:3306)/f0W32R1gtc?parseTime=1"
+func TestMysqlRepository(t *testing.T) {
+	dsn := os.Getenv("MYSQL_TEST_DSN")
+	if dsn == "" || testing.Short() {
+		t.Skip("MYSQL_TEST_DSN is not set")
+	}
+	db, err := sql.Open("mysql", dsn)
+	require.NoError(t, err)
+	defer db.Close()
+	require.NoError(t, db.Ping())
+
+	wallets := _walletRepo.NewMysqlWalletRepository(db)
+	testRepository(t, func() webhook.Repository {
+		return repository.NewMysqlWebhookRepository(db)
+	}, func(t *testing.T) string {
+		w, err := wallets.InitWallet(context.Background(), uuid.New().String(), models.DefaultCurrency)
+		require.NoError(t, err)
+		return w.ID
+	})
+}
+
+func testRepository(t *testing.T, newRepo func() webhook.Repository, newWallet func(t *testing.T) string) {
+	ctx := context.Background()
+
+	subscribe := func(t *testing.T, r webhook.Repository, walletID string, events ...string) *models.Subscription {
+		s, err := r.CreateSubscription(ctx, &models.Subscription{
+			ID:       uuid.New().String(),
+			WalletID: walletID,
+			URL:      "https://example.com/hooks",
+			Events:   events,
+			Secret:   "whsec_test",
+		})
+		require.NoError(t, err)
+		return s
+	}
+	queue := func(t *testing.T, r webhook.Repository, s *models.Subscription, at time.Time) *models.Delivery {
+		d := &models.Delivery{
+			ID:             uuid.New().String(),
+			SubscriptionID: s.ID,
+			EventID:        uuid.New().String(),
+			EventType:      models.EventDepositSucceeded,
+			Payload:        `{"type":"deposit.succeeded"}`,
+			NextAttemptAt:  at,
+		}
+		require.NoError(t, r.CreateDeliveries(ctx, []*models.Delivery{d}))
+		return d
+	}
+
+	t.Run("subscriptions", func(t *testing.T) {
+		r := newRepo()
+		walletID := newWallet(t)
+		s := subscribe(t, r, walletID, models.EventDepositSucceeded, models.EventTransferReceived)
+		assert.Equal(t, models.SubscriptionStatusActive, s.Status)
+		assert.Equal(t, []string{models.EventDepositSucceeded, models.EventTransferReceived}, s.Events)
+		assert.Equal(t, "whsec_test", s.Secret)
+		assert.False(t, s.CreatedAt.IsZero())
+
+		got, err := r.FetchSubscription(ctx, s.ID, walletID)
+		require.NoError(t, err)
+		assert.Equal(t, s.URL, got.URL)
+		_, err = r.FetchSubscription(ctx, s.ID, newWallet(t))
+		assert.Equal(t, models.ErrNotFound, err)
+
+		other := subscribe(t, r, walletID, models.EventAll)
+		list, err := r.FetchSubscriptions(ctx, walletID)
+		require.NoError(t, err)
+		assert.Len(t, list, 2)
+
+		require.NoError(t, r.DeleteSubscription(ctx, s.ID, walletID))
+		assert.Equal(t, models.ErrNotFound, r.DeleteSubscription(ctx, s.ID, walletID))
+		_, err = r.FetchSubscription(ctx, s.ID, walletID)
+		assert.Equal(t, models.ErrNotFound, err)
+		list, err = r.FetchSubscriptions(ctx, walletID)
+		require.NoError(t, err)
+		require.Len(t, list, 1)
+		assert.Equal(t, other.ID, list[0].ID)
+	})
+
+	t.Run("deliveries", func(t *testing.T) {
+		r := newRepo()
+		s := subscribe(t, r, newWallet(t), models.EventAll)
+		now := time.Now().Truncate(time.Second)
+		d := queue(t, r, s, now.Add(-time.Minute))
+		queue(t, r, s, now.Add(time.Hour))
+
+		due, err := r.FetchDueDeliveries(ctx, now, 100)
+		require.NoError(t, err)
+		var got *models.Delivery
+		for _, c := range due {
+			if c.ID == d.ID {
+				got = c
+			}
+		}
+		require.NotNil(t, got)
+		assert.Equal(t, models.DeliveryStatusPending, got.Status)
+		assert.Equal(t, s.URL, got.URL)
+		assert.Equal(t, s.Secret, got.Secret)
+		assert.Equal(t, d.Payload, got.Payload)
+
+		stale := *got
+		ok, err := r.ClaimDelivery(ctx, got, now.Add(time.Minute))
+		require.NoError(t, err)
+		assert.True(t, ok)
+		ok, err = r.ClaimDelivery(ctx, &stale, now.Add(time.Minute))
+		require.NoError(t, err)
+		assert.False(t, ok, "a delivery can only be claimed once")
+
+		got.Attempts = 1
+		got.LastError = "unexpected status 500"
+		got.NextAttemptAt = now.Add(30 * time.Second)
+		require.NoError(t, r.RecordAttempt(ctx, got, &models.DeliveryAttempt{
+			Attempt: 1, StatusCode: 500, Error: got.LastError, DurationMs: 12, CreatedAt: now,
+		}))
+		got.Attempts = 2
+		got.Status = models.DeliveryStatusDelivered
+		got.LastError = ""
+		require.NoError(t, r.RecordAttempt(ctx, got, &models.DeliveryAttempt{
+			Attempt: 2, StatusCode: 204, DurationMs: 3, CreatedAt: now.Add(30 * time.Second),
+		}))
+
+		list, err := r.FetchDeliveries(ctx, s.ID, 10)
+		require.NoError(t, err)
+		require.Len(t, list, 2)
+		assert.Equal(t, models.DeliveryStatusPending, list[0].Status, "newest first")
+		assert.Empty(t, list[0].Log)
+		assert.Equal(t, d.ID, list[1].ID)
+		assert.Equal(t, models.DeliveryStatusDelivered, list[1].Status)
+		assert.Equal(t, 2, list[1].Attempts)
+		assert.Empty(t, list[1].LastError)
+		require.Len(t, list[1].Log, 2)
+		assert.Equal(t, 500, list[1].Log[0].StatusCode)
+		assert.Equal(t, "unexpected status 500", list[1].Log[0].Error)
+		assert.Equal(t, 204, list[1].Log[1].StatusCode)
+		assert.Empty(t, list[1].Log[1].Error)
+
+		list, err = r.FetchDeliveries(ctx, s.ID, 1)
+		require.NoError(t, err)
+		assert.Len(t, list, 1)
+	})
+
+	t.Run("an event is queued once per subscription", func(t *testing.T) {
+		r := newRepo()
+		walletID := newWallet(t)
+		s := subscribe(t, r, walletID, models.EventAll)
+		other := subscribe(t, r, walletID, models.EventAll)
+		d := queue(t, r, s, time.Now())
+
+		again := *d
+		again.ID = uuid.New().String()
+		forOther := *d
+		forOther.ID = uuid.New().String()
+		forOther.SubscriptionID = other.ID
+		require.NoError(t, r.CreateDeliveries(ctx, []*models.Delivery{&again, &forOther}))
+
+		list, err := r.FetchDeliveries(ctx, s.ID, 10)
+		require.NoError(t, err)
+		require.Len(t, list, 1)
+		assert.Equal(t, d.ID, list[0].ID)
+		list, err = r.FetchDeliveries(ctx, other.ID, 10)
+		require.NoError(t, err)
+		require.Len(t, list, 1)
+		assert.Equal(t, forOther.ID, list[0].ID)
+	})
+
+	t.Run("delete kills pending deliveries", func(t *testing.T) {
+		r := newRepo()
+		walletID := newWallet(t)
+		s := subscribe(t, r, walletID, models.EventAll)
+		queue(t, r, s, time.Now().Add(-time.Minute))
+
+		require.NoError(t, r.DeleteSubscription(ctx, s.ID, walletID))
+		list, err := r.FetchDeliveries(ctx, s.ID, 10)
+		require.NoError(t, err)
+		require.Len(t, list, 1)
+		assert.Equal(t, models.DeliveryStatusDead, list[0].Status)
+		assert.NotEmpty(t, list[0].LastError)
+
+		due, err := r.FetchDueDeliveries(ctx, time.Now(), 100)
+		require.NoError(t, err)
+		for _, d := range due {
+			assert.NotEqual(t, s.ID, d.SubscriptionID)
+		}
+	})
+}
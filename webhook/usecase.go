@@ -0,0 +1,21 @@
+package webhook
+
+import (
+	"context"
+
+	"github.com/williamchand/my-wallet/models"
+)
+
+// Publisher represent the contract to hand an event over to the subscribed webhooks
+type Publisher interface {
+	Publish(ctx context.Context, event *models.Event) error
+}
+
+// Usecase represent the webhook's usecases
+type Usecase interface {
+	Publisher
+	Subscribe(ctx context.Context, req *models.ReqSubscription, principal *models.Principal) (*models.Subscription, error)
+	FetchSubscriptions(ctx context.Context, principal *models.Principal) ([]*models.Subscription, error)
+	DeleteSubscription(ctx context.Context, subscriptionID string, principal *models.Principal) error
+	FetchDeliveries(ctx context.Context, subscriptionID string, principal *models.Principal) ([]*models.Delivery, error)
+}
@@ -0,0 +1,147 @@
+package usecase
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"net/url"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/williamchand/my-wallet/models"
+	"github.com/williamchand/my-wallet/webhook"
+)
+
+const deliveryLogLimit = 50
+
+type webhookUsecase struct {
+	webhookRepo    webhook.Repository
+	contextTimeout time.Duration
+}
+
+// NewWebhookUsecase will create new an webhookUsecase object representation of webhook.Usecase interface
+func NewWebhookUsecase(r webhook.Repository, timeout time.Duration) webhook.Usecase {
+	return &webhookUsecase{
+		webhookRepo:    r,
+		contextTimeout: timeout,
+	}
+}
+
+func (w *webhookUsecase) Subscribe(c context.Context, req *models.ReqSubscription, principal *models.Principal) (*models.Subscription, error) {
+
+	ctx, cancel := context.WithTimeout(c, w.contextTimeout)
+	defer cancel()
+	if principal == nil {
+		return nil, models.ErrUnauthorized
+	}
+	u, err := url.Parse(req.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, models.ErrBadParamInput
+	}
+	if err = webhook.CheckHost(ctx, u.Hostname()); err != nil {
+		return nil, models.ErrBadParamInput.Wrap(err)
+	}
+	for _, e := range req.Events {
+		if !models.ValidEventType(e) {
+			return nil, models.ErrBadParamInput
+		}
+	}
+	secret, err := newSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	return w.webhookRepo.CreateSubscription(ctx, &models.Subscription{
+		ID:       uuid.New().String(),
+		WalletID: principal.WalletID,
+		URL:      req.URL,
+		Events:   req.Events,
+		Secret:   secret,
+	})
+}
+
+func (w *webhookUsecase) FetchSubscriptions(c context.Context, principal *models.Principal) ([]*models.Subscription, error) {
+
+	ctx, cancel := context.WithTimeout(c, w.contextTimeout)
+	defer cancel()
+	if principal == nil {
+		return nil, models.ErrUnauthorized
+	}
+	res, err := w.webhookRepo.FetchSubscriptions(ctx, principal.WalletID)
+	if err != nil {
+		return nil, err
+	}
+	// the secret is only shown once, when subscribing
+	for _, s := range res {
+		s.Secret = ""
+	}
+
+	return res, nil
+}
+
+func (w *webhookUsecase) DeleteSubscription(c context.Context, subscriptionID string, principal *models.Principal) error {
+
+	ctx, cancel := context.WithTimeout(c, w.contextTimeout)
+	defer cancel()
+	if principal == nil {
+		return models.ErrUnauthorized
+	}
+
+	return w.webhookRepo.DeleteSubscription(ctx, subscriptionID, principal.WalletID)
+}
+
+func (w *webhookUsecase) FetchDeliveries(c context.Context, subscriptionID string, principal *models.Principal) ([]*models.Delivery, error) {
+
+	ctx, cancel := context.WithTimeout(c, w.contextTimeout)
+	defer cancel()
+	if principal == nil {
+		return nil, models.ErrUnauthorized
+	}
+	if _, err := w.webhookRepo.FetchSubscription(ctx, subscriptionID, principal.WalletID); err != nil {
+		return nil, err
+	}
+
+	return w.webhookRepo.FetchDeliveries(ctx, subscriptionID, deliveryLogLimit)
+}
+
+// Publish queue a delivery of event to every subscription of its wallet that wants it,
+// the dispatcher sends them
+func (w *webhookUsecase) Publish(c context.Context, event *models.Event) error {
+
+	ctx, cancel := context.WithTimeout(c, w.contextTimeout)
+	defer cancel()
+	subs, err := w.webhookRepo.FetchSubscriptions(ctx, event.WalletID)
+	if err != nil {
+		return err
+	}
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	deliveries := make([]*models.Delivery, 0, len(subs))
+	for _, s := range subs {
+		if !s.Matches(event.Type) {
+			continue
+		}
+		deliveries = append(deliveries, &models.Delivery{
+			ID:             uuid.New().String(),
+			SubscriptionID: s.ID,
+			EventID:        event.ID,
+			EventType:      event.Type,
+			Payload:        string(payload),
+			NextAttemptAt:  event.CreatedAt,
+		})
+	}
+
+	return w.webhookRepo.CreateDeliveries(ctx, deliveries)
+}
+
+func newSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return "whsec_" + hex.EncodeToString(b), nil
+}
@@ -0,0 +1,44 @@
+package usecase_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/williamchand/my-wallet/models"
+	"github.com/williamchand/my-wallet/webhook/repository"
+	"github.com/williamchand/my-wallet/webhook/usecase"
+)
+
+func TestSubscribe(t *testing.T) {
+	u := usecase.NewWebhookUsecase(repository.NewMemoryWebhookRepository(), time.Second)
+	principal := &models.Principal{CustomerID: uuid.New().String(), WalletID: uuid.New().String(), Scopes: models.OwnerScopes}
+
+	for _, url := range []string{
+		"ftp://93.184.216.34/hooks",
+		"http:///hooks",
+		"http://127.0.0.1:8080/hooks",
+		"http://localhost/hooks",
+		"http://[::1]/hooks",
+		"http://0.0.0.0/hooks",
+		"http://169.254.169.254/latest/meta-data",
+		"http://10.0.0.1/hooks",
+		"http://172.16.5.4/hooks",
+		"http://192.168.1.1/hooks",
+		"http://100.64.0.1/hooks",
+		"http://[fd00::1]/hooks",
+		"http://[::ffff:127.0.0.1]/hooks",
+	} {
+		_, err := u.Subscribe(context.Background(), &models.ReqSubscription{URL: url, Events: []string{models.EventAll}}, principal)
+		assert.Equal(t, models.CodeInvalidParameter, models.ErrorOf(err).Code, url)
+	}
+
+	sub, err := u.Subscribe(context.Background(), &models.ReqSubscription{URL: "https://93.184.216.34/hooks", Events: []string{models.EventAll}}, principal)
+	require.NoError(t, err)
+	assert.Equal(t, principal.WalletID, sub.WalletID)
+	assert.NotEmpty(t, sub.Secret)
+}